@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"auth-service/pkg/password"
+)
+
+// breach-filter builds the bloom filter read from BREACHED_PASSWORDS_PATH out of a Pwned
+// Passwords style dump: one SHA-1 digest per line, optionally followed by ":COUNT".
+func main() {
+	in := flag.String("in", "", "input file of SHA-1 digests (HASH or HASH:COUNT per line)")
+	out := flag.String("out", "breached-passwords.bloom", "output bloom filter file")
+	expected := flag.Int("n", 0, "expected number of digests (default: count the input)")
+	rate := flag.Float64("fp", 0.001, "target false-positive rate")
+	flag.Parse()
+
+	if *in == "" {
+		log.Fatal("-in is required")
+	}
+
+	n := *expected
+	if n == 0 {
+		count, err := countLines(*in)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *in, err)
+		}
+		n = count
+	}
+
+	filter := password.NewBloomFilter(n, *rate)
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *in, err)
+	}
+	defer f.Close()
+
+	added, skipped := 0, 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		digest, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.TrimSpace(digest) == "" {
+			continue
+		}
+		if err := filter.AddHash(digest); err != nil {
+			skipped++
+			continue
+		}
+		added++
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Failed to read %s: %v", *in, err)
+	}
+
+	dst, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+	w := bufio.NewWriter(dst)
+	if _, err := filter.WriteTo(w); err != nil {
+		log.Fatalf("Failed to write filter: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write filter: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		log.Fatalf("Failed to write filter: %v", err)
+	}
+
+	log.Printf("Wrote %s: %d digests, %d malformed lines skipped", *out, added, skipped)
+}
+
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n++
+	}
+	return n, scanner.Err()
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -20,16 +21,19 @@ import (
 	"auth-service/internal/config"
 	"auth-service/internal/handler"
 	"auth-service/internal/middleware"
+	"auth-service/internal/models"
 	"auth-service/internal/repository"
 	"auth-service/internal/seeder"
 	"auth-service/internal/service"
 	"auth-service/pkg/email"
+	"auth-service/pkg/geoip"
 	"auth-service/pkg/hashutil"
 	"auth-service/pkg/jwt"
 	"auth-service/pkg/logger"
 	"auth-service/pkg/metrics"
 	"auth-service/pkg/password"
 	"auth-service/pkg/tracing"
+	"auth-service/pkg/webauthn"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -54,10 +58,27 @@ func main() {
 		"port":        cfg.Server.Port,
 	})
 
-	// Initialize HMAC secret for deterministic token hashing
-	if err := hashutil.InitializeHMACSecret(); err != nil {
+	// Initialize the versioned HMAC keys for deterministic token hashing and the password pepper
+	hmacKeyring, err := hashutil.LoadKeyring(os.Getenv("HMAC_SECRET"), cfg.Security.HMACKeys, cfg.Security.HMACKeysDir, cfg.Security.HMACActiveKeyVersion)
+	if err != nil {
 		logger.FatalMsg("Failed to initialize HMAC secret", err)
 	}
+	hashutil.SetKeyring(hmacKeyring)
+	logger.InfoMsg("HMAC keys loaded", map[string]interface{}{
+		"active_version": hmacKeyring.Active(),
+	})
+
+	// Offline GeoIP lookups place sessions, failed sign-ins and audit events
+	if cfg.Security.GeoIPDatabasePath != "" {
+		resolver, err := geoip.OpenResolver(cfg.Security.GeoIPDatabasePath)
+		if err != nil {
+			logger.FatalMsg("Failed to load GeoIP database", err)
+		}
+		geoip.SetDefault(resolver)
+		logger.InfoMsg("GeoIP lookups enabled", map[string]interface{}{
+			"databases": resolver.DatabaseTypes(),
+		})
+	}
 
 	// Initialize database
 	db := initDatabase(cfg)
@@ -116,14 +137,54 @@ func main() {
 	if err != nil {
 		logger.FatalMsg("Failed to initialize JWT service", err)
 	}
+
+	// Rotate signing keys in the background; retired keys stay in the JWKS for the overlap window
+	keyRotationCtx, stopKeyRotation := context.WithCancel(context.Background())
+	defer stopKeyRotation()
+	go jwtService.KeyStore().StartRotation(keyRotationCtx, 1*time.Hour, func(err error) {
+		logger.ErrorMsg("Signing key rotation failed", err)
+	})
 	passwordService := password.NewService()
+	passwordService.SetPepper(hmacKeyring)
+	if cfg.Security.BreachedPasswordsPath != "" {
+		checker, err := password.OpenBreachChecker(cfg.Security.BreachedPasswordsPath)
+		if err != nil {
+			logger.FatalMsg("Failed to load breached password corpus", err)
+		}
+		passwordService.SetBreachChecker(checker)
+		logger.InfoMsg("Breached password check enabled", map[string]interface{}{
+			"path": cfg.Security.BreachedPasswordsPath,
+		})
+	}
 	emailSvc := email.NewService(&cfg.Email)
 	authService := service.NewAuthService(repo, jwtService, passwordService, emailSvc, redisClient)
 
+	// Move stored lookup hashes to the active HMAC key; lookups accept older versions meanwhile
+	go func() {
+		if err := authService.BackgroundJobService().RehashLookupHashes(context.Background()); err != nil {
+			logger.ErrorMsg("Failed to rehash lookup hashes", err)
+		}
+	}()
+
 	// Set Redis and Email clients for user service
 	userSvc := authService.UserService()
 	userSvc.SetRedisClient(redisClient)
 	userSvc.SetEmailService(emailSvc)
+	mfaService := service.NewMFAService(repo, redisClient, cfg.Email.FromName)
+	userSvc.SetMFAService(mfaService)
+	passkeyService := service.NewPasskeyService(repo, redisClient, &webauthn.RelyingParty{
+		ID:      cfg.WebAuthn.RPID,
+		Name:    cfg.WebAuthn.RPName,
+		Origins: cfg.WebAuthn.Origins,
+	})
+	userSvc.SetPasskeyService(passkeyService)
+	userSvc.SetEmailLoginService(service.NewEmailLoginService(redisClient, emailSvc))
+	deviceService := service.NewTrustedDeviceService(repo, authService.SessionService(), cfg.Security.RememberDeviceDays)
+	userSvc.SetTrustedDeviceService(deviceService)
+	userSvc.SetPasswordPolicy(models.PasswordPolicy{
+		HistoryCount: cfg.Security.PasswordHistoryCount,
+		MaxAgeDays:   cfg.Security.PasswordMaxAgeDays,
+	})
 
 	// Initialize OAuth2 services
 	clientAppService := service.NewClientAppService(repo)
@@ -133,6 +194,9 @@ func main() {
 	// Initialize audit service
 	auditService := service.NewAuditService(db)
 
+	// Score each login; unusual ones need a second factor and the riskiest are refused
+	userSvc.SetLoginRiskService(service.NewLoginRiskService(repo, redisClient, auditService, service.DefaultLoginRiskConfig()))
+
 	// Get database/SQL connection for health checks
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -146,21 +210,35 @@ func main() {
 	roleHandler := handler.NewRoleHandler(authService, auditService)
 	rbacHandler := handler.NewRBACHandler(authService.RoleService())
 	clientAppHandler := handler.NewClientAppHandler(clientAppService)
-	oauth2Handler := handler.NewOAuth2Handler(oauth2Service, clientAppService, userSvc)
-	oauth2ConsentHandler := handler.NewOAuth2ConsentHandler(oauth2Service, clientAppService, userSvc)
+	clientRegistrationHandler := handler.NewClientRegistrationHandler(clientAppService, auditService, jwtService.IssuerURL())
+	deviceAuthService := service.NewDeviceAuthorizationService(redisClient, strings.TrimSuffix(jwtService.IssuerURL(), "/")+"/api/v1/oauth/device")
+	introspectionService := service.NewIntrospectionService(repo, jwtService, authService.RevocationService())
+	clientAssertionService := service.NewClientAssertionService(repo, redisClient, nil, jwtService.IssuerURL())
+	dpopService := service.NewDPoPService(redisClient, jwtService.IssuerURL())
+	parService := service.NewPushedAuthorizationService(redisClient)
+	consentService := service.NewConsentService(repo, redisClient)
+	tokenExchangeService := service.NewTokenExchangeService(repo, jwtService, authService.RevocationService())
+	oauth2Handler := handler.NewOAuth2Handler(oauth2Service, clientAppService, userSvc, auditService, introspectionService, authService.RevocationService(), deviceAuthService, clientAssertionService, parService, dpopService, consentService, tokenExchangeService)
+	oauth2ConsentHandler := handler.NewOAuth2ConsentHandler(oauth2Service, clientAppService, userSvc, deviceAuthService, parService, consentService)
+	connectedAppsHandler := handler.NewConnectedAppsHandler(consentService, auditService)
+	mfaHandler := handler.NewMFAHandler(mfaService, auditService)
+	passkeyHandler := handler.NewPasskeyHandler(passkeyService, userSvc, auditService)
+	deviceHandler := handler.NewDeviceHandler(deviceService, auditService)
+	sessionHandler := handler.NewSessionHandler(userSvc, auditService)
 	oauthAuditHandler := handler.NewOAuthAuditHandler(db)
 	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
 	revocationHandler := handler.NewRevocationHandler(authService.RevocationService())
 	healthHandler := handler.NewHealthHandler(sqlDB, redisClient)
+	wellKnownHandler := handler.NewWellKnownHandler(jwtService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(authService, repo)
+	authMiddleware := middleware.NewAuthMiddleware(authService, repo, dpopService)
 	organizationMiddleware := middleware.NewOrganizationMiddleware(authService)
 	rateLimiter := middleware.NewRateLimiter(redisClient, &cfg.RateLimit)
 	revocationMiddleware := middleware.RevocationMiddleware(jwtService, authService.RevocationService())
 
 	// Initialize Gin router
-	router := setupRouter(cfg, authHandler, adminHandler, organizationHandler, roleHandler, rbacHandler, clientAppHandler, oauth2Handler, oauth2ConsentHandler, oauthAuditHandler, apiKeyHandler, revocationHandler, healthHandler, authMiddleware, organizationMiddleware, rateLimiter, revocationMiddleware)
+	router := setupRouter(cfg, authHandler, adminHandler, organizationHandler, roleHandler, rbacHandler, clientAppHandler, clientRegistrationHandler, oauth2Handler, oauth2ConsentHandler, connectedAppsHandler, mfaHandler, passkeyHandler, deviceHandler, sessionHandler, oauthAuditHandler, apiKeyHandler, revocationHandler, healthHandler, wellKnownHandler, authMiddleware, organizationMiddleware, rateLimiter, revocationMiddleware)
 
 	// Start server
 	srv := &http.Server{
@@ -272,7 +350,7 @@ func runSeeders(db *gorm.DB) error {
 	return seeder.Seed(ctx)
 }
 
-func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHandler *handler.AdminHandler, organizationHandler *handler.OrganizationHandler, roleHandler *handler.RoleHandler, rbacHandler *handler.RBACHandler, clientAppHandler *handler.ClientAppHandler, oauth2Handler *handler.OAuth2Handler, oauth2ConsentHandler *handler.OAuth2ConsentHandler, oauthAuditHandler *handler.OAuthAuditHandler, apiKeyHandler *handler.APIKeyHandler, revocationHandler *handler.RevocationHandler, healthHandler *handler.HealthHandler, authMiddleware *middleware.AuthMiddleware, organizationMiddleware *middleware.OrganizationMiddleware, rateLimiter *middleware.RateLimiter, revocationMiddleware gin.HandlerFunc) *gin.Engine {
+func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHandler *handler.AdminHandler, organizationHandler *handler.OrganizationHandler, roleHandler *handler.RoleHandler, rbacHandler *handler.RBACHandler, clientAppHandler *handler.ClientAppHandler, clientRegistrationHandler *handler.ClientRegistrationHandler, oauth2Handler *handler.OAuth2Handler, oauth2ConsentHandler *handler.OAuth2ConsentHandler, connectedAppsHandler *handler.ConnectedAppsHandler, mfaHandler *handler.MFAHandler, passkeyHandler *handler.PasskeyHandler, deviceHandler *handler.DeviceHandler, sessionHandler *handler.SessionHandler, oauthAuditHandler *handler.OAuthAuditHandler, apiKeyHandler *handler.APIKeyHandler, revocationHandler *handler.RevocationHandler, healthHandler *handler.HealthHandler, wellKnownHandler *handler.WellKnownHandler, authMiddleware *middleware.AuthMiddleware, organizationMiddleware *middleware.OrganizationMiddleware, rateLimiter *middleware.RateLimiter, revocationMiddleware gin.HandlerFunc) *gin.Engine {
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -349,6 +427,13 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 	csrfConfig := middleware.DefaultCSRFConfig(cfg.JWT.Secret, cfg.Environment == "production")
 	csrfConfig.SkipPaths = []string{
 		"/api/v1/oauth/token",
+		"/api/v1/oauth/introspect",
+		"/api/v1/oauth/revoke",
+		"/api/v1/oauth/device_authorization",
+		"/api/v1/oauth/par",
+		"/api/v1/oauth/register", // Dynamic registration is authenticated by bearer tokens
+		"/api/v1/oauth/register/*",
+		"/api/v1/oauth/device",    // Device verification form has its own CSRF token handling
 		"/api/v1/oauth/authorize", // OAuth consent form has its own CSRF token handling
 		"/api/v1/auth/login",
 		"/api/v1/auth/register",
@@ -364,6 +449,10 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 	// Prometheus metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Public signing keys for token verification by downstream services
+	router.GET("/.well-known/jwks.json", wellKnownHandler.JWKS)
+	router.GET("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
+
 	// OAuth2 test page (development only)
 	router.GET("/oauth-test", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "oauth_test_traditional.html", nil)
@@ -374,6 +463,9 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 		c.HTML(http.StatusOK, "oauth_callback.html", nil)
 	})
 
+	// Sensitive operations need a sign-in no older than the step-up window
+	recentAuth := authMiddleware.RequireRecentAuth(time.Duration(cfg.JWT.StepUpMaxAge)*time.Minute, jwt.ACRSingleFactor)
+
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
@@ -382,6 +474,11 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 		{
 			auth.POST("/register", rateLimiter.ByIP(middleware.ScopeRegistration), authHandler.RegisterGlobal)
 			auth.POST("/login", rateLimiter.ByIP(middleware.ScopeLogin), authHandler.LoginGlobal)
+			auth.POST("/mfa/verify", rateLimiter.ByIP(middleware.ScopeLogin), authHandler.VerifyMFA)
+			auth.POST("/passkey/login/options", rateLimiter.ByIP(middleware.ScopeLogin), passkeyHandler.BeginLogin)
+			auth.POST("/passkey/login", rateLimiter.ByIP(middleware.ScopeLogin), passkeyHandler.FinishLogin)
+			auth.POST("/email-login", rateLimiter.ByIP(middleware.ScopeEmailLogin), rateLimiter.ByEmail(middleware.ScopeEmailLogin, "email"), authHandler.RequestEmailLogin)
+			auth.POST("/email-login/verify", rateLimiter.ByIP(middleware.ScopeEmailLogin), authHandler.VerifyEmailLogin)
 			auth.POST("/refresh", rateLimiter.ByUserID(middleware.ScopeTokenRefresh), authHandler.RefreshToken)
 			auth.POST("/forgot-password", rateLimiter.ByEmail(middleware.ScopePasswordReset, "email"), authHandler.ForgotPassword)
 			auth.POST("/reset-password", rateLimiter.ByIP(middleware.ScopePasswordReset), authHandler.ResetPassword)
@@ -389,6 +486,9 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 			auth.POST("/resend-verification", rateLimiter.ByEmail(middleware.ScopePasswordReset, "email"), authHandler.ResendVerificationEmail)
 		}
 
+		// Step-up: fresh tokens for the current session after re-checking credentials
+		auth.POST("/reauthenticate", authMiddleware.AuthRequired(), rateLimiter.ByUserID(middleware.ScopeLogin), authHandler.Reauthenticate)
+
 		// Organization selection (requires valid credentials from login)
 		auth.POST("/select-organization", authHandler.SelectOrganization)
 		auth.POST("/create-organization", authHandler.CreateOrganization)
@@ -404,6 +504,22 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 			user.POST("/change-password", authHandler.ChangePassword)
 			user.POST("/logout", authHandler.Logout)
 			user.GET("/organizations", authHandler.GetMyOrganizations)
+			user.GET("/connected-apps", connectedAppsHandler.ListConnectedApps)
+			user.DELETE("/connected-apps/:clientId", connectedAppsHandler.RevokeConnectedApp)
+			user.GET("/mfa", mfaHandler.GetStatus)
+			user.POST("/mfa/enroll", recentAuth, mfaHandler.BeginEnrollment)
+			user.POST("/mfa/enroll/verify", recentAuth, mfaHandler.ConfirmEnrollment)
+			user.POST("/mfa/disable", recentAuth, mfaHandler.Disable)
+			user.POST("/mfa/recovery-codes", recentAuth, mfaHandler.RegenerateRecoveryCodes)
+			user.GET("/passkeys", passkeyHandler.ListPasskeys)
+			user.POST("/passkeys/register/options", recentAuth, passkeyHandler.BeginRegistration)
+			user.POST("/passkeys/register", recentAuth, passkeyHandler.FinishRegistration)
+			user.DELETE("/passkeys/:id", recentAuth, passkeyHandler.DeletePasskey)
+			user.GET("/devices", deviceHandler.ListDevices)
+			user.DELETE("/devices/:id", deviceHandler.RevokeDevice)
+			user.GET("/sessions", sessionHandler.ListSessions)
+			user.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			user.POST("/sessions/revoke-others", sessionHandler.RevokeOtherSessions)
 		}
 
 		// Organization routes
@@ -447,6 +563,11 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 			org.GET("/:orgId/invitations", organizationMiddleware.MembershipRequired(""), authMiddleware.RequirePermission("invitation:view"), organizationHandler.GetOrganizationInvitations)
 			org.POST("/:orgId/invitations/:invitationId/resend", organizationMiddleware.MembershipRequired(""), authMiddleware.RequirePermission("invitation:resend"), organizationHandler.ResendInvitation)
 			org.DELETE("/:orgId/invitations/:invitationId", organizationMiddleware.MembershipRequired(""), authMiddleware.RequirePermission("invitation:cancel"), organizationHandler.CancelInvitation)
+
+			// Initial access tokens for dynamic client registration (org admin only)
+			org.POST("/:orgId/registration-tokens", organizationMiddleware.OrgAdminRequired(), clientRegistrationHandler.CreateInitialAccessToken)
+			org.GET("/:orgId/registration-tokens", organizationMiddleware.OrgAdminRequired(), clientRegistrationHandler.ListInitialAccessTokens)
+			org.DELETE("/:orgId/registration-tokens/:tokenId", organizationMiddleware.OrgAdminRequired(), clientRegistrationHandler.RevokeInitialAccessToken)
 		}
 
 		// Invitation acceptance (requires authentication but NOT organization membership)
@@ -472,7 +593,7 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 			admin.GET("/users", adminHandler.ListUsers)
 			admin.PUT("/users/:userId/activate", adminHandler.ActivateUser)
 			admin.PUT("/users/:userId/deactivate", adminHandler.DeactivateUser)
-			admin.DELETE("/users/:userId", adminHandler.DeleteUser)
+			admin.DELETE("/users/:userId", recentAuth, adminHandler.DeleteUser)
 
 			// Global organization management
 			admin.GET("/organizations", adminHandler.ListOrganizations)
@@ -505,7 +626,7 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 			admin.GET("/client-apps/:id", clientAppHandler.GetClientApp)
 			admin.PUT("/client-apps/:id", clientAppHandler.UpdateClientApp)
 			admin.DELETE("/client-apps/:id", clientAppHandler.DeleteClientApp)
-			admin.POST("/client-apps/:id/rotate-secret", clientAppHandler.RotateClientSecret)
+			admin.POST("/client-apps/:id/rotate-secret", recentAuth, clientAppHandler.RotateClientSecret)
 		}
 
 		// OAuth2 endpoints (public/authenticated as needed)
@@ -519,7 +640,27 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 			oauth.POST("/authorize-with-credentials", rateLimiter.ByIP(middleware.ScopeLogin), oauth2Handler.AuthorizeWithCredentials)
 
 			// Token endpoint (public - validates client credentials) with rate limiting
-			oauth.POST("/token", rateLimiter.ByIP(middleware.ScopeOAuth2Token), oauth2Handler.Token)
+			oauth.POST("/token", rateLimiter.ByIP(middleware.ScopeOAuth2Token), rateLimiter.ByClientID(middleware.ScopeClientCredentials, "client_credentials"), oauth2Handler.Token)
+
+			// Pushed authorization requests (RFC 9126): the client posts the request, the browser only carries request_uri
+			oauth.POST("/par", rateLimiter.ByIP(middleware.ScopeOAuth2Token), oauth2Handler.PushAuthorizationRequest)
+
+			// Device authorization grant (RFC 8628): the device starts here, the user approves on /device
+			oauth.POST("/device_authorization", rateLimiter.ByIP(middleware.ScopeOAuth2Token), oauth2Handler.DeviceAuthorization)
+			oauth.GET("/device", oauth2ConsentHandler.ShowDeviceVerification)
+			oauth.POST("/device", rateLimiter.ByIP(middleware.ScopeLogin), oauth2ConsentHandler.ProcessDeviceVerification)
+
+			// Dynamic client registration (RFC 7591/7592, authenticated by initial/registration access tokens)
+			oauth.POST("/register", rateLimiter.ByIP(middleware.ScopeOAuth2Token), clientRegistrationHandler.Register)
+			oauth.GET("/register/:client_id", rateLimiter.ByIP(middleware.ScopeOAuth2Token), clientRegistrationHandler.GetRegistration)
+			oauth.PUT("/register/:client_id", rateLimiter.ByIP(middleware.ScopeOAuth2Token), clientRegistrationHandler.UpdateRegistration)
+			oauth.DELETE("/register/:client_id", rateLimiter.ByIP(middleware.ScopeOAuth2Token), clientRegistrationHandler.DeleteRegistration)
+
+			// Token introspection (RFC 7662, authenticated by client credentials)
+			oauth.POST("/introspect", rateLimiter.ByIP(middleware.ScopeOAuth2Token), oauth2Handler.Introspect)
+
+			// Token revocation (RFC 7009, authenticated by client credentials)
+			oauth.POST("/revoke", rateLimiter.ByIP(middleware.ScopeOAuth2Token), oauth2Handler.Revoke)
 
 			// UserInfo endpoint (requires valid OAuth2 access token)
 			oauth.GET("/userinfo", authMiddleware.AuthRequired(), oauth2Handler.UserInfo)
@@ -544,8 +685,8 @@ func setupRouter(cfg *config.Config, authHandler *handler.AuthHandler, adminHand
 		dev.Use(authMiddleware.AuthRequired())
 		dev.Use(authMiddleware.LoadUser()) // Load user object for handlers that use c.Get("user")
 		{
-			handler.RegisterAPIKeyRoutes(dev, apiKeyHandler)
-			handler.RegisterClientAppRoutes(dev, clientAppHandler)
+			handler.RegisterAPIKeyRoutes(dev, apiKeyHandler, recentAuth)
+			handler.RegisterClientAppRoutes(dev, clientAppHandler, recentAuth)
 		}
 
 		// Token revocation endpoints (requires authentication)
@@ -20,6 +20,8 @@ type Config struct {
 	Email       EmailConfig
 	Logging     LoggingConfig
 	Tracing     TracingConfig
+	WebAuthn    WebAuthnConfig
+	Security    SecurityConfig
 	Environment string
 }
 
@@ -50,7 +52,12 @@ type JWTConfig struct {
 	AccessTokenTTL  int // in minutes
 	RefreshTokenTTL int // in days
 	Issuer          string
+	IssuerURL       string // public base URL used as the OIDC issuer
 	SigningMethod   string
+	KeysDir         string // directory of <kid>.pem signing keys; empty = in-memory key
+	KeyRotationDays int    // rotate the active signing key after this many days (0 = never)
+	KeyOverlapDays  int    // keep retired keys for verification this long (at least RefreshTokenTTL)
+	StepUpMaxAge    int    // in minutes; how recent a sign-in sensitive endpoints require
 }
 
 type LoggingConfig struct {
@@ -67,6 +74,28 @@ type TracingConfig struct {
 	SamplingRate float64 // Trace sampling rate (0.0 to 1.0)
 }
 
+type SecurityConfig struct {
+	RememberDeviceDays    int    // how long "remember this device" skips MFA (0 = never remember)
+	BreachedPasswordsPath string // hash-prefix range directory or bloom filter file; empty disables the check
+
+	// Platform password rotation default; organizations can only make it stricter
+	PasswordHistoryCount int // reject reuse of the last N passwords (0 = no history)
+	PasswordMaxAgeDays   int // force a change this many days after a password is set (0 = never)
+
+	// Versioned HMAC keys; HMAC_SECRET is version 1 and also peppers password hashes
+	HMACKeys             string // extra versions as "2=secret,3=secret"
+	HMACKeysDir          string // directory of "<version>.key" files
+	HMACActiveKeyVersion int    // version new hashes use (0 = highest loaded)
+
+	GeoIPDatabasePath string // comma-separated .mmdb files (e.g. a city and an ASN database); empty disables lookups
+}
+
+type WebAuthnConfig struct {
+	RPID    string   // Relying party ID: the registrable domain passkeys are bound to (e.g. "example.com")
+	RPName  string   // Shown by authenticators when creating a passkey
+	Origins []string // Exact origins allowed to run ceremonies (e.g. "https://app.example.com")
+}
+
 type CORSConfig struct {
 	AllowedOrigins []string // List of allowed origins or patterns like "*.sprout.com"
 }
@@ -86,6 +115,12 @@ type RateLimitConfig struct {
 	APICalls            int  // General API calls per APICallsWindow
 	APICallsWindow      int  // Window in seconds (default: 60 = 1 min)
 	MaxSessions         int  // Max concurrent sessions per user
+
+	ClientCredentials       int // Max client_credentials token requests per client per window
+	ClientCredentialsWindow int // Window in seconds (default: 60 = 1 min)
+
+	EmailLogin       int // Max sign-in link/code requests and redemptions per EmailLoginWindow
+	EmailLoginWindow int // Window in seconds (default: 900 = 15 min)
 }
 
 type EmailConfig struct {
@@ -131,7 +166,12 @@ func Load() *Config {
 			AccessTokenTTL:  getEnvAsInt("JWT_ACCESS_TOKEN_EXPIRY", 60),  // 1 hour
 			RefreshTokenTTL: getEnvAsInt("JWT_REFRESH_TOKEN_EXPIRY", 30), // 30 days
 			Issuer:          getEnv("JWT_ISSUER", "auth-service"),
+			IssuerURL:       getEnv("JWT_ISSUER_URL", "http://localhost:8080"),
 			SigningMethod:   getEnv("JWT_SIGNING_METHOD", "HS256"),
+			KeysDir:         getEnv("JWT_KEYS_DIR", "keys"),
+			KeyRotationDays: getEnvAsInt("JWT_KEY_ROTATION_DAYS", 30),
+			KeyOverlapDays:  getEnvAsInt("JWT_KEY_OVERLAP_DAYS", 31),
+			StepUpMaxAge:    getEnvAsInt("JWT_STEP_UP_MAX_AGE", 10),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "*.localhost:3000"}),
@@ -151,6 +191,12 @@ func Load() *Config {
 			APICalls:            getEnvAsInt("RATE_LIMIT_API_CALLS", 1000),
 			APICallsWindow:      getEnvAsInt("RATE_LIMIT_API_CALLS_WINDOW", 60), // 1 minute
 			MaxSessions:         getEnvAsInt("MAX_CONCURRENT_SESSIONS", 5),
+
+			ClientCredentials:       getEnvAsInt("RATE_LIMIT_CLIENT_CREDENTIALS", 60),
+			ClientCredentialsWindow: getEnvAsInt("RATE_LIMIT_CLIENT_CREDENTIALS_WINDOW", 60), // 1 minute
+
+			EmailLogin:       getEnvAsInt("RATE_LIMIT_EMAIL_LOGIN", 5),
+			EmailLoginWindow: getEnvAsInt("RATE_LIMIT_EMAIL_LOGIN_WINDOW", 900), // 15 minutes
 		},
 		Email: EmailConfig{
 			Host:         getEnv("SMTP_HOST", "sandbox.smtp.mailtrap.io"),
@@ -175,6 +221,21 @@ func Load() *Config {
 			OTLPInsecure: getEnv("TRACING_OTLP_INSECURE", "false") == "true",
 			SamplingRate: getEnvAsFloat("TRACING_SAMPLING_RATE", 1.0),
 		},
+		WebAuthn: WebAuthnConfig{
+			RPID:    getEnv("WEBAUTHN_RP_ID", "localhost"),
+			RPName:  getEnv("WEBAUTHN_RP_NAME", "Auth Service"),
+			Origins: getEnvAsSlice("WEBAUTHN_ORIGINS", []string{"http://localhost:3000"}),
+		},
+		Security: SecurityConfig{
+			RememberDeviceDays:    getEnvAsInt("MFA_REMEMBER_DEVICE_DAYS", 30),
+			BreachedPasswordsPath: getEnv("BREACHED_PASSWORDS_PATH", ""),
+			PasswordHistoryCount:  getEnvAsInt("PASSWORD_HISTORY_COUNT", 0),
+			PasswordMaxAgeDays:    getEnvAsInt("PASSWORD_MAX_AGE_DAYS", 0),
+			HMACKeys:              getEnv("HMAC_KEYS", ""),
+			HMACKeysDir:           getEnv("HMAC_KEYS_DIR", ""),
+			HMACActiveKeyVersion:  getEnvAsInt("HMAC_ACTIVE_KEY_VERSION", 0),
+			GeoIPDatabasePath:     getEnv("GEOIP_DATABASE_PATH", ""),
+		},
 		Environment: getEnv("ENVIRONMENT", "development"),
 	}
 
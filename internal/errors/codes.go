@@ -17,6 +17,14 @@ const (
 	ErrCodeTwoFactorRequired  ErrorCode = "AUTH_2FA_REQUIRED"
 	ErrCodeTwoFactorInvalid   ErrorCode = "AUTH_2FA_INVALID"
 
+	// The organization's security policy requires MFA and the user has not enrolled
+	ErrCodeMFAEnrollmentRequired ErrorCode = "mfa_enrollment_required"
+
+	// The user's password was found in a breach corpus and must be reset
+	ErrCodePasswordResetRequired ErrorCode = "password_reset_required"
+	// The password is older than the strictest maximum age among the user's organizations
+	ErrCodePasswordExpired ErrorCode = "password_expired"
+
 	// Token errors
 	ErrCodeTokenExpired        ErrorCode = "TOKEN_EXPIRED"
 	ErrCodeTokenInvalid        ErrorCode = "TOKEN_INVALID"
@@ -100,6 +108,9 @@ var ErrorMapping = map[ErrorCode]int{
 	ErrCodeSuspiciousActivity:      http.StatusForbidden,
 	ErrCodeEmailNotVerified:        http.StatusForbidden,
 	ErrCodeOrgAccessDenied:         http.StatusForbidden,
+	ErrCodeMFAEnrollmentRequired:   http.StatusForbidden,
+	ErrCodePasswordResetRequired:   http.StatusForbidden,
+	ErrCodePasswordExpired:         http.StatusForbidden,
 
 	// 404 Not Found
 	ErrCodeUserNotFound:       http.StatusNotFound,
@@ -28,6 +28,34 @@ func (em *ErrorMapper) MapServiceError(err error) (ErrorCode, string) {
 		return ErrCodeInvalidCredentials, "Invalid username or password"
 	}
 
+	// MFA errors
+	if errors.Is(err, service.ErrMFARequired) {
+		return ErrCodeTwoFactorRequired, "Two-factor authentication is required"
+	}
+	if errors.Is(err, service.ErrInvalidMFACode) {
+		return ErrCodeTwoFactorInvalid, "Invalid authentication code"
+	}
+	if errors.Is(err, service.ErrInvalidMFAChallenge) {
+		return ErrCodeTwoFactorInvalid, "MFA challenge is invalid or has expired; sign in again"
+	}
+	if errors.Is(err, service.ErrMFAEnrollmentRequired) {
+		return ErrCodeMFAEnrollmentRequired, "This organization requires multi-factor authentication. Enable it in your account settings to continue"
+	}
+
+	// Password policy errors
+	if errors.Is(err, service.ErrPasswordBreached) {
+		return ErrCodeValidationFailed, "This password has appeared in a data breach. Choose a different one"
+	}
+	if errors.Is(err, service.ErrPasswordResetRequired) {
+		return ErrCodePasswordResetRequired, "Your password was found in a data breach. Use the link we emailed you to reset it"
+	}
+	if errors.Is(err, service.ErrPasswordExpired) {
+		return ErrCodePasswordExpired, "Your password has expired. Sign in again to choose a new one"
+	}
+	if errors.Is(err, service.ErrPasswordReused) {
+		return ErrCodeValidationFailed, "Choose a password you have not used recently"
+	}
+
 	// Role-related errors
 	if errors.Is(err, service.ErrRoleNotFound) {
 		return ErrCodeRoleNotFound, "Role not found"
@@ -378,11 +378,11 @@ func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
 	})
 }
 
-// RegisterAPIKeyRoutes registers all API key routes
-func RegisterAPIKeyRoutes(r *gin.RouterGroup, handler *APIKeyHandler) {
+// RegisterAPIKeyRoutes registers all API key routes. recentAuth guards key creation.
+func RegisterAPIKeyRoutes(r *gin.RouterGroup, handler *APIKeyHandler, recentAuth gin.HandlerFunc) {
 	apiKeys := r.Group("/api-keys")
 	{
-		apiKeys.POST("", handler.CreateAPIKey)
+		apiKeys.POST("", recentAuth, handler.CreateAPIKey)
 		apiKeys.GET("", handler.ListAPIKeys)
 		apiKeys.GET("/:id", handler.GetAPIKey)
 		apiKeys.DELETE("/:id", handler.RevokeAPIKey)
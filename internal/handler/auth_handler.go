@@ -1,8 +1,10 @@
 package handler
 
 import (
+	stderrors "errors"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,6 +14,10 @@ import (
 	"auth-service/internal/service"
 )
 
+// passwordExpiredMessage accompanies login responses that carry a password_reset_token
+// instead of organizations
+const passwordExpiredMessage = "Your password has expired. Choose a new one to continue."
+
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
 	authService  service.AuthService
@@ -74,6 +80,10 @@ func (h *AuthHandler) LoginGlobal(c *gin.Context) {
 		return
 	}
 
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.DeviceToken = deviceToken(c)
+
 	response, err := h.authService.UserService().LoginGlobal(c.Request.Context(), &req)
 
 	// Audit log: login attempt
@@ -101,6 +111,13 @@ func (h *AuthHandler) LoginGlobal(c *gin.Context) {
 		"organizations_count": orgCount,
 	}, err)
 
+	if stderrors.Is(err, service.ErrLoginBlocked) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
@@ -109,9 +126,156 @@ func (h *AuthHandler) LoginGlobal(c *gin.Context) {
 		return
 	}
 
+	setDeviceCookie(c, response.DeviceToken)
+
 	// Different message based on whether user is superadmin (has token) or needs to select org
 	message := "Login successful. Please select an organization."
-	if response != nil && response.Token != nil && response.Token.AccessToken != "" {
+	if response != nil && response.PasswordExpired {
+		message = passwordExpiredMessage
+	} else if response != nil && response.MFARequired {
+		message = "Password accepted. Enter the code from your authenticator app."
+	} else if response != nil && response.StepUpRequired {
+		message = "Password accepted. To confirm it's you, enter the code we emailed you."
+	} else if response != nil && response.Token != nil && response.Token.AccessToken != "" {
+		message = "Login successful. Welcome back, superadmin!"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+		"message": message,
+	})
+}
+
+// VerifyMFA completes a login paused for MFA and returns the user's organizations
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req service.VerifyLoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.SendErrorResponse(c, errors.ErrCodeValidationFailed, "Invalid request data", map[string]interface{}{
+			"details": err.Error(),
+		})
+		return
+	}
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.DeviceToken = deviceToken(c)
+
+	response, err := h.authService.UserService().VerifyLoginMFA(c.Request.Context(), &req)
+
+	var userID *uuid.UUID
+	if response != nil && response.User != nil {
+		if parsedID, parseErr := uuid.Parse(response.User.ID); parseErr == nil {
+			userID = &parsedID
+		}
+	}
+	h.auditService.LogAuth(c.Request.Context(), models.ActionMFAVerify, userID, err == nil, map[string]interface{}{
+		"remember_device": req.RememberDevice,
+	}, err)
+
+	if err != nil {
+		errorCode, message := h.errorMapper.MapServiceError(err)
+		errors.SendErrorResponse(c, errorCode, message, nil)
+		return
+	}
+	setDeviceCookie(c, response.DeviceToken)
+
+	message := "Login successful. Please select an organization."
+	if response.PasswordExpired {
+		message = passwordExpiredMessage
+	} else if response.Token != nil && response.Token.AccessToken != "" {
+		message = "Login successful. Welcome back, superadmin!"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+		"message": message,
+	})
+}
+
+// RequestEmailLogin emails a one-time sign-in link or code. The response is the same
+// whether or not the address belongs to an account.
+func (h *AuthHandler) RequestEmailLogin(c *gin.Context) {
+	var req service.EmailLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.SendErrorResponse(c, errors.ErrCodeValidationFailed, "Invalid request data", map[string]interface{}{
+			"details": err.Error(),
+		})
+		return
+	}
+	req.ClientIP = c.ClientIP()
+
+	err := h.authService.UserService().RequestEmailLogin(c.Request.Context(), &req)
+	h.auditService.LogAuth(c.Request.Context(), models.ActionEmailLoginRequest, nil, err == nil, map[string]interface{}{
+		"email":  req.Email,
+		"method": req.Method,
+	}, err)
+
+	if err != nil {
+		if stderrors.Is(err, service.ErrEmailLoginUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "If an account exists for this email, a sign-in email is on its way",
+	})
+}
+
+// VerifyEmailLogin redeems a sign-in link token or code and returns the same response as password login
+func (h *AuthHandler) VerifyEmailLogin(c *gin.Context) {
+	var req service.VerifyEmailLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errors.SendErrorResponse(c, errors.ErrCodeValidationFailed, "Invalid request data", map[string]interface{}{
+			"details": err.Error(),
+		})
+		return
+	}
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.DeviceToken = deviceToken(c)
+
+	response, err := h.authService.UserService().VerifyEmailLogin(c.Request.Context(), &req)
+
+	var userID *uuid.UUID
+	if response != nil && response.User != nil {
+		if parsedID, parseErr := uuid.Parse(response.User.ID); parseErr == nil {
+			userID = &parsedID
+		}
+	}
+	action := models.ActionLogin
+	if err != nil {
+		action = models.ActionLoginFailed
+	}
+	method := "email_link"
+	if req.Token == "" {
+		method = "email_code"
+	}
+	h.auditService.LogAuth(c.Request.Context(), action, userID, err == nil, map[string]interface{}{
+		"email":  req.Email,
+		"method": method,
+	}, err)
+
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	setDeviceCookie(c, response.DeviceToken)
+
+	message := "Login successful. Please select an organization."
+	if response.PasswordExpired {
+		message = passwordExpiredMessage
+	} else if response.MFARequired {
+		message = "Email verified. Enter the code from your authenticator app."
+	} else if response.Token != nil && response.Token.AccessToken != "" {
 		message = "Login successful. Welcome back, superadmin!"
 	}
 
@@ -133,9 +297,18 @@ func (h *AuthHandler) SelectOrganization(c *gin.Context) {
 		})
 		return
 	}
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.DeviceToken = deviceToken(c)
 
 	response, err := h.authService.UserService().SelectOrganization(c.Request.Context(), &req)
 	if err != nil {
+		if stderrors.Is(err, service.ErrMFARequired) || stderrors.Is(err, service.ErrMFAEnrollmentRequired) ||
+			stderrors.Is(err, service.ErrPasswordResetRequired) || stderrors.Is(err, service.ErrPasswordExpired) {
+			errorCode, message := h.errorMapper.MapServiceError(err)
+			errors.SendErrorResponse(c, errorCode, message, nil)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -181,6 +354,12 @@ func (h *AuthHandler) CreateOrganization(c *gin.Context) {
 	response, err := h.authService.UserService().CreateOrganization(c.Request.Context(), userID, &req)
 	if err != nil {
 		log.Printf("CreateOrganization: Service error: %v", err)
+		if stderrors.Is(err, service.ErrMFARequired) ||
+			stderrors.Is(err, service.ErrPasswordResetRequired) || stderrors.Is(err, service.ErrPasswordExpired) {
+			errorCode, message := h.errorMapper.MapServiceError(err)
+			errors.SendErrorResponse(c, errorCode, message, nil)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -244,6 +423,66 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	})
 }
 
+// Reauthenticate answers a step-up challenge: it re-checks the user's password (and MFA
+// code) or a passkey and returns tokens for the same session with a fresh auth_time
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	var req service.ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"errors":  err.Error(),
+		})
+		return
+	}
+	if fields := strings.Fields(c.GetHeader("Authorization")); len(fields) == 2 {
+		req.AccessToken = fields[1]
+	}
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.DeviceToken = deviceToken(c)
+
+	response, err := h.authService.UserService().Reauthenticate(c.Request.Context(), &req)
+
+	var userID *uuid.UUID
+	userIDStr, _ := c.Request.Context().Value("user_id").(string)
+	if parsedID, parseErr := uuid.Parse(userIDStr); parseErr == nil {
+		userID = &parsedID
+	}
+	details := map[string]interface{}{"method": "password"}
+	if req.Passkey != nil {
+		details["method"] = "passkey"
+	}
+	if response != nil {
+		details["acr"] = response.ACR
+	}
+	h.auditService.LogAuth(c.Request.Context(), models.ActionReauthenticate, userID, err == nil, details, err)
+
+	if err != nil {
+		if stderrors.Is(err, service.ErrInvalidCredentials) || stderrors.Is(err, service.ErrInvalidMFACode) {
+			errorCode, message := h.errorMapper.MapServiceError(err)
+			errors.SendErrorResponse(c, errorCode, message, nil)
+			return
+		}
+		status := http.StatusUnauthorized
+		if stderrors.Is(err, service.ErrLoginBlocked) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	setDeviceCookie(c, response.DeviceToken)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+		"message": "Re-authenticated successfully",
+	})
+}
+
 // Logout handles user logout
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, _ := c.Request.Context().Value("user_id").(string)
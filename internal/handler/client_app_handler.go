@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -375,6 +376,8 @@ func (h *ClientAppHandler) UpdateClientApp(c *gin.Context) {
 		status := http.StatusForbidden
 		if err.Error() == "client app not found" {
 			status = http.StatusNotFound
+		} else if errors.Is(err, service.ErrInvalidData) || errors.Is(err, service.ErrInvalidClientMetadata) {
+			status = http.StatusBadRequest
 		}
 		c.JSON(status, gin.H{
 			"success": false,
@@ -503,8 +506,8 @@ func (h *ClientAppHandler) RotateClientSecret(c *gin.Context) {
 	})
 }
 
-// RegisterClientAppRoutes registers all OAuth2 client app routes
-func RegisterClientAppRoutes(r *gin.RouterGroup, handler *ClientAppHandler) {
+// RegisterClientAppRoutes registers all OAuth2 client app routes. recentAuth guards secret rotation.
+func RegisterClientAppRoutes(r *gin.RouterGroup, handler *ClientAppHandler, recentAuth gin.HandlerFunc) {
 	clientApps := r.Group("/client-apps")
 	{
 		clientApps.POST("", handler.CreateClientApp)
@@ -512,6 +515,6 @@ func RegisterClientAppRoutes(r *gin.RouterGroup, handler *ClientAppHandler) {
 		clientApps.GET("/:id", handler.GetClientApp)
 		clientApps.PUT("/:id", handler.UpdateClientApp)
 		clientApps.DELETE("/:id", handler.DeleteClientApp)
-		clientApps.POST("/:id/rotate-secret", handler.RotateClientSecret)
+		clientApps.POST("/:id/rotate-secret", recentAuth, handler.RotateClientSecret)
 	}
 }
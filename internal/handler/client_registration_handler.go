@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ClientRegistrationHandler handles OAuth2 dynamic client registration (RFC 7591/7592)
+// and the organization endpoints that issue initial access tokens for it
+type ClientRegistrationHandler struct {
+	clientAppSvc service.ClientAppService
+	auditService service.AuditService
+	issuerURL    string
+}
+
+// NewClientRegistrationHandler creates a new client registration handler.
+// issuerURL is used to build registration_client_uri.
+func NewClientRegistrationHandler(clientAppSvc service.ClientAppService, auditService service.AuditService, issuerURL string) *ClientRegistrationHandler {
+	return &ClientRegistrationHandler{
+		clientAppSvc: clientAppSvc,
+		auditService: auditService,
+		issuerURL:    strings.TrimSuffix(issuerURL, "/"),
+	}
+}
+
+// Register godoc
+// @Summary Dynamic client registration (RFC 7591)
+// @Description Registers an OAuth2 client in the organization that issued the initial access token
+// @Tags oauth2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.ClientRegistrationRequest true "Client metadata"
+// @Success 201 {object} service.ClientRegistrationResponse
+// @Failure 400 {object} gin.H{error=string}
+// @Failure 401 {object} gin.H{error=string}
+// @Router /oauth/register [post]
+func (h *ClientRegistrationHandler) Register(c *gin.Context) {
+	initialAccessToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	var req service.ClientRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_client_metadata",
+			"error_description": "request body must be a JSON client metadata document",
+		})
+		return
+	}
+
+	resp, err := h.clientAppSvc.RegisterClient(c.Request.Context(), initialAccessToken, &req)
+	if err != nil {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionClientCreate, nil, nil, false, map[string]interface{}{
+			"registration": "dynamic",
+			"client_name":  req.ClientName,
+		}, err)
+		writeRegistrationError(c, err)
+		return
+	}
+
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionClientCreate, nil, nil, true, map[string]interface{}{
+		"registration": "dynamic",
+		"client_id":    resp.ClientID,
+		"client_name":  resp.ClientName,
+		"grant_types":  resp.GrantTypes,
+	}, nil)
+
+	resp.RegistrationClientURI = h.registrationClientURI(resp.ClientID)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetRegistration godoc
+// @Summary Read a dynamically registered client (RFC 7592)
+// @Tags oauth2
+// @Produce json
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} service.ClientRegistrationResponse
+// @Failure 401 {object} gin.H{error=string}
+// @Router /oauth/register/{client_id} [get]
+func (h *ClientRegistrationHandler) GetRegistration(c *gin.Context) {
+	registrationToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.clientAppSvc.GetClientRegistration(c.Request.Context(), c.Param("client_id"), registrationToken)
+	if err != nil {
+		writeRegistrationError(c, err)
+		return
+	}
+
+	resp.RegistrationClientURI = h.registrationClientURI(resp.ClientID)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateRegistration godoc
+// @Summary Replace the metadata of a dynamically registered client (RFC 7592)
+// @Description The response carries a new registration_access_token; the one used for the request stops working.
+// @Tags oauth2
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Param request body service.ClientRegistrationRequest true "Client metadata"
+// @Success 200 {object} service.ClientRegistrationResponse
+// @Failure 400 {object} gin.H{error=string}
+// @Failure 401 {object} gin.H{error=string}
+// @Router /oauth/register/{client_id} [put]
+func (h *ClientRegistrationHandler) UpdateRegistration(c *gin.Context) {
+	registrationToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	var req service.ClientRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_client_metadata",
+			"error_description": "request body must be a JSON client metadata document",
+		})
+		return
+	}
+
+	clientID := c.Param("client_id")
+	details := map[string]interface{}{
+		"registration": "dynamic",
+		"client_id":    clientID,
+	}
+
+	resp, err := h.clientAppSvc.UpdateClientRegistration(c.Request.Context(), clientID, registrationToken, &req)
+	if err != nil {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionClientUpdate, nil, nil, false, details, err)
+		writeRegistrationError(c, err)
+		return
+	}
+
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionClientUpdate, nil, nil, true, details, nil)
+
+	resp.RegistrationClientURI = h.registrationClientURI(resp.ClientID)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteRegistration godoc
+// @Summary Delete a dynamically registered client (RFC 7592)
+// @Tags oauth2
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Success 204
+// @Failure 401 {object} gin.H{error=string}
+// @Router /oauth/register/{client_id} [delete]
+func (h *ClientRegistrationHandler) DeleteRegistration(c *gin.Context) {
+	registrationToken, ok := bearerToken(c)
+	if !ok {
+		return
+	}
+
+	clientID := c.Param("client_id")
+	details := map[string]interface{}{
+		"registration": "dynamic",
+		"client_id":    clientID,
+	}
+
+	if err := h.clientAppSvc.DeleteClientRegistration(c.Request.Context(), clientID, registrationToken); err != nil {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionClientDelete, nil, nil, false, details, err)
+		writeRegistrationError(c, err)
+		return
+	}
+
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionClientDelete, nil, nil, true, details, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// CreateInitialAccessToken godoc
+// @Summary Issue an initial access token for dynamic client registration (org admin only)
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param request body service.CreateInitialAccessTokenRequest true "Token options"
+// @Success 201 {object} gin.H{success=true,data=models.InitialAccessToken,token=string}
+// @Failure 400 {object} gin.H{success=false,message=string}
+// @Router /organizations/{orgId}/registration-tokens [post]
+func (h *ClientRegistrationHandler) CreateInitialAccessToken(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("orgId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "invalid organization ID",
+		})
+		return
+	}
+
+	userIDStr, _ := c.Request.Context().Value("user_id").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "unauthorized",
+		})
+		return
+	}
+
+	var req service.CreateInitialAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	token, plainToken, err := h.clientAppSvc.CreateInitialAccessToken(c.Request.Context(), orgID, &req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    token,
+		"token":   plainToken,
+		"message": "Store this token securely. It will not be shown again.",
+	})
+}
+
+// ListInitialAccessTokens godoc
+// @Summary List the organization's initial access tokens (org admin only)
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Success 200 {object} gin.H{success=true,data=[]models.InitialAccessToken}
+// @Router /organizations/{orgId}/registration-tokens [get]
+func (h *ClientRegistrationHandler) ListInitialAccessTokens(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("orgId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "invalid organization ID",
+		})
+		return
+	}
+
+	tokens, err := h.clientAppSvc.ListInitialAccessTokens(c.Request.Context(), orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// RevokeInitialAccessToken godoc
+// @Summary Revoke an initial access token (org admin only)
+// @Description Clients already registered with the token keep working
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param orgId path string true "Organization ID"
+// @Param tokenId path string true "Initial access token ID"
+// @Success 200 {object} gin.H{success=true,message=string}
+// @Failure 404 {object} gin.H{success=false,message=string}
+// @Router /organizations/{orgId}/registration-tokens/{tokenId} [delete]
+func (h *ClientRegistrationHandler) RevokeInitialAccessToken(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("orgId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "invalid organization ID",
+		})
+		return
+	}
+	tokenID, err := uuid.Parse(c.Param("tokenId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "invalid token ID",
+		})
+		return
+	}
+
+	if err := h.clientAppSvc.RevokeInitialAccessToken(c.Request.Context(), orgID, tokenID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Initial access token revoked",
+	})
+}
+
+func (h *ClientRegistrationHandler) registrationClientURI(clientID string) string {
+	return h.issuerURL + "/api/v1/oauth/register/" + clientID
+}
+
+// bearerToken extracts the bearer token used by the registration endpoints,
+// answering 401 invalid_token when it is missing
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
+		if token := strings.TrimSpace(authHeader[7:]); token != "" {
+			return token, true
+		}
+	}
+
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":             "invalid_token",
+		"error_description": "a bearer token is required",
+	})
+	return "", false
+}
+
+// writeRegistrationError maps registration errors to RFC 7591 error responses
+func writeRegistrationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInitialAccessToken), errors.Is(err, service.ErrInvalidRegistrationToken):
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_token",
+			"error_description": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidRedirectURI):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_redirect_uri",
+			"error_description": err.Error(),
+		})
+	case errors.Is(err, service.ErrInvalidClientMetadata):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_client_metadata",
+			"error_description": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "failed to process client registration",
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ConnectedAppsHandler lets users review and revoke the OAuth clients they have authorized
+type ConnectedAppsHandler struct {
+	consentSvc   service.ConsentService
+	auditService service.AuditService
+}
+
+// NewConnectedAppsHandler creates a new connected apps handler
+func NewConnectedAppsHandler(consentSvc service.ConsentService, auditService service.AuditService) *ConnectedAppsHandler {
+	return &ConnectedAppsHandler{
+		consentSvc:   consentSvc,
+		auditService: auditService,
+	}
+}
+
+// ListConnectedApps godoc
+// @Summary List connected apps
+// @Description Lists the OAuth clients the user has approved, with granted scopes and active refresh token families
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /user/connected-apps [get]
+func (h *ConnectedAppsHandler) ListConnectedApps(c *gin.Context) {
+	userID, err := uuid.Parse(c.Request.Context().Value("user_id").(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	apps, err := h.consentSvc.ListConnectedApps(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to list connected apps",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    apps,
+	})
+}
+
+// RevokeConnectedApp godoc
+// @Summary Revoke a connected app
+// @Description Removes the user's consent for the client and revokes all of its refresh token families
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param clientId path string true "OAuth client_id"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /user/connected-apps/{clientId} [delete]
+func (h *ConnectedAppsHandler) RevokeConnectedApp(c *gin.Context) {
+	userID, err := uuid.Parse(c.Request.Context().Value("user_id").(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+	clientID := c.Param("clientId")
+	details := map[string]interface{}{"client_id": clientID}
+
+	if err := h.consentSvc.RevokeConnectedApp(c.Request.Context(), userID, clientID); err != nil {
+		if errors.Is(err, service.ErrConnectedAppNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Connected app not found",
+			})
+			return
+		}
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthConsentRevoke, &userID, nil, false, details, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to revoke connected app",
+		})
+		return
+	}
+
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthConsentRevoke, &userID, nil, true, details, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Connected app revoked",
+	})
+}
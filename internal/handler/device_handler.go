@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeviceCookieName is the long-lived cookie that identifies a browser in the device registry
+const DeviceCookieName = "device_token"
+
+// deviceCookieMaxAge outlives any "remember this device" period; the registry decides trust
+const deviceCookieMaxAge = 400 * 24 * 60 * 60
+
+// DeviceHandler lets the signed-in user review and revoke the devices they have signed in from
+type DeviceHandler struct {
+	deviceSvc    service.TrustedDeviceService
+	auditService service.AuditService
+}
+
+// NewDeviceHandler creates a new device handler
+func NewDeviceHandler(deviceSvc service.TrustedDeviceService, auditService service.AuditService) *DeviceHandler {
+	return &DeviceHandler{
+		deviceSvc:    deviceSvc,
+		auditService: auditService,
+	}
+}
+
+// ListDevices godoc
+// @Summary List devices
+// @Description Devices the user has signed in from, with last activity and whether MFA is remembered
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/devices [get]
+func (h *DeviceHandler) ListDevices(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	devices, err := h.deviceSvc.List(c.Request.Context(), userID, deviceToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to load devices",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    devices,
+	})
+}
+
+// RevokeDevice godoc
+// @Summary Revoke a device
+// @Description Forgets the device, so it needs MFA again, and signs out every session started from it
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /user/devices/{id} [delete]
+func (h *DeviceHandler) RevokeDevice(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Device not found",
+		})
+		return
+	}
+
+	err = h.deviceSvc.Revoke(c.Request.Context(), userID, id)
+	if errors.Is(err, service.ErrDeviceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Device not found",
+		})
+		return
+	}
+	h.auditService.LogAuth(c.Request.Context(), models.ActionDeviceRevoke, &userID, err == nil, map[string]interface{}{"device_id": id.String()}, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to revoke device",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Device revoked",
+	})
+}
+
+// deviceToken returns the device cookie sent with the request, if any
+func deviceToken(c *gin.Context) string {
+	token, err := c.Cookie(DeviceCookieName)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// setDeviceCookie stores a newly registered device's token. An empty token means the
+// request came from an already known device and the cookie is left alone.
+func setDeviceCookie(c *gin.Context, token string) {
+	if token == "" {
+		return
+	}
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(DeviceCookieName, token, deviceCookieMaxAge, "/", "", secure, true)
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MFAHandler manages the signed-in user's authenticator app and recovery codes
+type MFAHandler struct {
+	mfaSvc       service.MFAService
+	auditService service.AuditService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(mfaSvc service.MFAService, auditService service.AuditService) *MFAHandler {
+	return &MFAHandler{
+		mfaSvc:       mfaSvc,
+		auditService: auditService,
+	}
+}
+
+// MFACodeRequest carries a TOTP code or, where accepted, a recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetStatus godoc
+// @Summary Get MFA status
+// @Description Reports whether MFA is enabled and how many recovery codes are left
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/mfa [get]
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfaSvc.Status(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to load MFA status",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// BeginEnrollment godoc
+// @Summary Start MFA enrollment
+// @Description Creates a TOTP secret and otpauth URI; MFA is enabled once a code is confirmed
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /user/mfa/enroll [post]
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	account, _ := c.Request.Context().Value("user_email").(string)
+
+	enrollment, err := h.mfaSvc.BeginEnrollment(c.Request.Context(), userID, account)
+	if err != nil {
+		if errors.Is(err, service.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "MFA is already enabled",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to start MFA enrollment",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    enrollment,
+		"message": "Add the secret to your authenticator app, then confirm with a code",
+	})
+}
+
+// ConfirmEnrollment godoc
+// @Summary Confirm MFA enrollment
+// @Description Enables MFA after checking a code from the authenticator app and returns recovery codes
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "Code from the authenticator app"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/mfa/enroll/verify [post]
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req MFACodeRequest
+	if !bindMFACode(c, &req) {
+		return
+	}
+
+	codes, err := h.mfaSvc.ConfirmEnrollment(c.Request.Context(), userID, req.Code)
+	h.auditService.LogAuth(c.Request.Context(), models.ActionMFAEnable, &userID, err == nil, nil, err)
+	if err != nil {
+		respondMFAError(c, err, "Failed to enable MFA")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"recovery_codes": codes},
+		"message": "MFA enabled. Store the recovery codes somewhere safe; they are shown only once",
+	})
+}
+
+// Disable godoc
+// @Summary Disable MFA
+// @Description Removes the authenticator and recovery codes after checking a current code
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/mfa/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req MFACodeRequest
+	if !bindMFACode(c, &req) {
+		return
+	}
+
+	err := h.mfaSvc.Disable(c.Request.Context(), userID, req.Code)
+	h.auditService.LogAuth(c.Request.Context(), models.ActionMFADisable, &userID, err == nil, nil, err)
+	if err != nil {
+		respondMFAError(c, err, "Failed to disable MFA")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "MFA disabled",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes after checking a current code
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req MFACodeRequest
+	if !bindMFACode(c, &req) {
+		return
+	}
+
+	codes, err := h.mfaSvc.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	h.auditService.LogAuth(c.Request.Context(), models.ActionMFARecoveryCodes, &userID, err == nil, nil, err)
+	if err != nil {
+		respondMFAError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"recovery_codes": codes},
+		"message": "New recovery codes generated; the previous ones no longer work",
+	})
+}
+
+func authenticatedUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDStr, _ := c.Request.Context().Value("user_id").(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid user ID",
+		})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func bindMFACode(c *gin.Context, req *MFACodeRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"errors":  err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func respondMFAError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "Invalid authentication code"})
+	case errors.Is(err, service.ErrMFANotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "MFA is not enabled"})
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "MFA is already enabled"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": fallback})
+	}
+}
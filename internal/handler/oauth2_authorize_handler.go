@@ -1,30 +1,64 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
+	"time"
 
+	"auth-service/internal/models"
 	"auth-service/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-// OAuth2AuthorizeWithCredentialsRequest represents the request to authorize with credentials
+// OAuth2AuthorizeWithCredentialsRequest represents the request to authorize with credentials.
+// With request_uri only client_id is needed; the rest comes from the pushed request.
 type OAuth2AuthorizeWithCredentialsRequest struct {
 	Email               string `json:"email" binding:"required,email"`
 	Password            string `json:"password" binding:"required"`
 	ClientID            string `json:"client_id" binding:"required"`
-	RedirectURI         string `json:"redirect_uri" binding:"required"`
-	ResponseType        string `json:"response_type" binding:"required"`
+	RedirectURI         string `json:"redirect_uri" binding:"required_without=RequestURI"`
+	ResponseType        string `json:"response_type" binding:"required_without=RequestURI"`
 	Scope               string `json:"scope"`
 	State               string `json:"state"`
-	CodeChallenge       string `json:"code_challenge" binding:"required"`
-	CodeChallengeMethod string `json:"code_challenge_method" binding:"required"`
+	CodeChallenge       string `json:"code_challenge" binding:"required_without=RequestURI"`
+	CodeChallengeMethod string `json:"code_challenge_method" binding:"required_without=RequestURI"`
+	Nonce               string `json:"nonce"`
+	RequestURI          string `json:"request_uri"`
+	Consent             bool   `json:"consent"`  // The user approved the requested scopes on the caller's consent page
+	MFACode             string `json:"mfa_code"` // TOTP or recovery code; required when the user has MFA enabled
+}
+
+// param exposes the request fields under their OAuth2 parameter names
+func (r *OAuth2AuthorizeWithCredentialsRequest) param(name string) string {
+	switch name {
+	case "client_id":
+		return r.ClientID
+	case "redirect_uri":
+		return r.RedirectURI
+	case "response_type":
+		return r.ResponseType
+	case "scope":
+		return r.Scope
+	case "state":
+		return r.State
+	case "code_challenge":
+		return r.CodeChallenge
+	case "code_challenge_method":
+		return r.CodeChallengeMethod
+	case "nonce":
+		return r.Nonce
+	case "request_uri":
+		return r.RequestURI
+	}
+	return ""
 }
 
 // AuthorizeWithCredentials godoc
 // @Summary OAuth2 authorization with credentials (for consent page)
-// @Description Authenticates user and generates authorization code if user belongs to the OAuth app's organization
+// @Description Authenticates user and generates authorization code if user belongs to the OAuth app's organization.
+// @Description Scopes the user has not approved before need consent=true, otherwise 403 consent_required is returned.
 // @Tags oauth2
 // @Accept json
 // @Produce json
@@ -44,6 +78,23 @@ func (h *OAuth2Handler) AuthorizeWithCredentials(c *gin.Context) {
 		return
 	}
 
+	// Take the parameters from the pushed request when a request_uri is given
+	params, err := resolveAuthorizationParams(c.Request.Context(), h.clientAppSvc, h.parSvc, req.param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": err.Error(),
+		})
+		return
+	}
+	req.RedirectURI = params.RedirectURI
+	req.ResponseType = params.ResponseType
+	req.Scope = params.Scope
+	req.State = params.State
+	req.CodeChallenge = params.CodeChallenge
+	req.CodeChallengeMethod = params.CodeChallengeMethod
+	req.Nonce = params.Nonce
+
 	// Validate response type
 	if req.ResponseType != "code" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -82,7 +133,21 @@ func (h *OAuth2Handler) AuthorizeWithCredentials(c *gin.Context) {
 	}
 
 	// Step 3: Authenticate user with email/password
-	user, err := h.userService.AuthenticateByEmail(c.Request.Context(), req.Email, req.Password)
+	user, err := h.userService.AuthenticateByEmail(c.Request.Context(), req.Email, req.Password, c.ClientIP())
+	if errors.Is(err, service.ErrPasswordResetRequired) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":             "password_reset_required",
+			"error_description": "the password was found in a data breach and must be reset",
+		})
+		return
+	}
+	if errors.Is(err, service.ErrLoginBlocked) || errors.Is(err, service.ErrLoginStepUpRequired) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":             "access_denied",
+			"error_description": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":             "invalid_credentials",
@@ -91,6 +156,22 @@ func (h *OAuth2Handler) AuthorizeWithCredentials(c *gin.Context) {
 		return
 	}
 
+	amr, err := h.userService.VerifySecondFactor(c.Request.Context(), user.ID, req.MFACode)
+	if err != nil {
+		if errors.Is(err, service.ErrMFARequired) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":             "mfa_required",
+				"error_description": "a code from the user's authenticator app is required",
+			})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_credentials",
+			"error_description": "invalid authentication code",
+		})
+		return
+	}
+
 	// Step 4: Check if user belongs to the organization that owns this OAuth2 app
 	isMember, err := h.userService.IsOrgMember(c.Request.Context(), user.ID, clientApp.OrganizationID)
 	if err != nil || !isMember {
@@ -99,7 +180,50 @@ func (h *OAuth2Handler) AuthorizeWithCredentials(c *gin.Context) {
 		return
 	}
 
-	// Step 5: Create authorization code
+	// Step 5: Scopes the user has not approved for this client yet need explicit consent
+	granted, err := h.consentSvc.HasGrant(c.Request.Context(), user.ID, req.ClientID, clientApp.OrganizationID, req.Scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "failed to look up consent",
+		})
+		return
+	}
+	if !granted {
+		if !req.Consent {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":             "consent_required",
+				"error_description": "the user has not approved the requested scopes for this client",
+				"scopes":            parseScopes(req.Scope),
+			})
+			return
+		}
+		if err := h.consentSvc.Grant(c.Request.Context(), user.ID, req.ClientID, clientApp.OrganizationID, req.Scope); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":             "server_error",
+				"error_description": "failed to record consent",
+			})
+			return
+		}
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthConsentGrant, &user.ID, &clientApp.ID, true, map[string]interface{}{
+			"client_id": req.ClientID,
+			"scope":     req.Scope,
+		}, nil)
+	}
+
+	// Step 6: A pushed request yields exactly one authorization code
+	if req.RequestURI != "" {
+		if _, err := h.parSvc.Consume(c.Request.Context(), req.ClientID, req.RequestURI); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_request",
+				"error_description": "request_uri has expired or was already used",
+			})
+			return
+		}
+	}
+
+	// Step 7: Create authorization code (user just authenticated with a password and maybe a code)
+	authTime := time.Now()
 	authReq := &service.AuthorizationRequest{
 		ClientID:            req.ClientID,
 		RedirectURI:         req.RedirectURI,
@@ -109,6 +233,9 @@ func (h *OAuth2Handler) AuthorizeWithCredentials(c *gin.Context) {
 		CodeChallengeMethod: req.CodeChallengeMethod,
 		UserID:              user.ID,
 		OrganizationID:      &clientApp.OrganizationID,
+		Nonce:               req.Nonce,
+		AuthTime:            &authTime,
+		AMR:                 amr,
 	}
 
 	code, err := h.oauth2Service.CreateAuthorizationCode(c.Request.Context(), authReq)
@@ -120,7 +247,7 @@ func (h *OAuth2Handler) AuthorizeWithCredentials(c *gin.Context) {
 		return
 	}
 
-	// Step 6: Build redirect URL with code
+	// Step 8: Build redirect URL with code
 	redirectURL, _ := url.Parse(req.RedirectURI)
 	q := redirectURL.Query()
 	q.Set("code", code)
@@ -1,14 +1,18 @@
 package handler
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"net/http"
 	"net/url"
+	"time"
 
 	"auth-service/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // OAuth2ConsentHandler handles the traditional OAuth2 consent flow with HTML forms
@@ -16,6 +20,9 @@ type OAuth2ConsentHandler struct {
 	oauth2Service service.OAuth2Service
 	clientAppSvc  service.ClientAppService
 	userService   service.UserService
+	deviceSvc     service.DeviceAuthorizationService
+	parSvc        service.PushedAuthorizationService
+	consentSvc    service.ConsentService
 }
 
 // NewOAuth2ConsentHandler creates a new consent handler
@@ -23,11 +30,17 @@ func NewOAuth2ConsentHandler(
 	oauth2Service service.OAuth2Service,
 	clientAppSvc service.ClientAppService,
 	userService service.UserService,
+	deviceSvc service.DeviceAuthorizationService,
+	parSvc service.PushedAuthorizationService,
+	consentSvc service.ConsentService,
 ) *OAuth2ConsentHandler {
 	return &OAuth2ConsentHandler{
 		oauth2Service: oauth2Service,
 		clientAppSvc:  clientAppSvc,
 		userService:   userService,
+		deviceSvc:     deviceSvc,
+		parSvc:        parSvc,
+		consentSvc:    consentSvc,
 	}
 }
 
@@ -43,18 +56,30 @@ func NewOAuth2ConsentHandler(
 // @Param state query string false "State parameter for CSRF protection"
 // @Param code_challenge query string true "PKCE code challenge (S256)"
 // @Param code_challenge_method query string true "PKCE method (must be 'S256')"
+// @Param nonce query string false "OIDC nonce (echoed into the id_token)"
+// @Param request_uri query string false "request_uri from /oauth/par (replaces all other parameters except client_id)"
 // @Success 200 {string} html "HTML consent/login form"
 // @Failure 400 {object} gin.H{error=string}
 // @Router /oauth/authorize [get]
 func (h *OAuth2ConsentHandler) ShowConsentForm(c *gin.Context) {
-	// 1. Extract and validate OAuth2 parameters
-	clientID := c.Query("client_id")
-	redirectURI := c.Query("redirect_uri")
-	responseType := c.Query("response_type")
-	scope := c.Query("scope")
-	state := c.Query("state")
-	codeChallenge := c.Query("code_challenge")
-	codeChallengeMethod := c.Query("code_challenge_method")
+	// 1. Extract and validate OAuth2 parameters (from the pushed request when request_uri is given)
+	params, err := resolveAuthorizationParams(c.Request.Context(), h.clientAppSvc, h.parSvc, c.Query)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error":       "invalid_request",
+			"description": err.Error(),
+		})
+		return
+	}
+	requestURI := c.Query("request_uri")
+	clientID := params.ClientID
+	redirectURI := params.RedirectURI
+	responseType := params.ResponseType
+	scope := params.Scope
+	state := params.State
+	codeChallenge := params.CodeChallenge
+	codeChallengeMethod := params.CodeChallengeMethod
+	nonce := params.Nonce
 
 	// Validate required parameters
 	if clientID == "" || redirectURI == "" || responseType == "" {
@@ -136,6 +161,8 @@ func (h *OAuth2ConsentHandler) ShowConsentForm(c *gin.Context) {
 		"state":                 state,
 		"code_challenge":        codeChallenge,
 		"code_challenge_method": codeChallengeMethod,
+		"nonce":                 nonce,
+		"request_uri":           requestURI,
 		"csrf_token":            csrfToken,
 		"organization_required": true, // User must belong to client's org
 	})
@@ -143,7 +170,8 @@ func (h *OAuth2ConsentHandler) ShowConsentForm(c *gin.Context) {
 
 // ProcessConsent godoc
 // @Summary Process OAuth2 authorization form submission (POST /oauth/authorize)
-// @Description Authenticates user and generates authorization code
+// @Description Authenticates the user, then asks for consent unless the requested scopes were
+// @Description approved before. The consent prompt posts back here with consent_ticket and decision.
 // @Tags oauth2
 // @Accept application/x-www-form-urlencoded
 // @Produce html
@@ -156,25 +184,47 @@ func (h *OAuth2ConsentHandler) ShowConsentForm(c *gin.Context) {
 // @Param state formData string false "State parameter"
 // @Param code_challenge formData string true "PKCE code challenge"
 // @Param code_challenge_method formData string true "PKCE method"
+// @Param request_uri formData string false "request_uri from /oauth/par (replaces the other authorization parameters)"
+// @Param consent_ticket formData string false "Ticket from the consent prompt (second step)"
+// @Param decision formData string false "allow or deny (second step)"
 // @Param csrf_token formData string true "CSRF token"
+// @Success 200 {string} html "Consent prompt"
 // @Success 302 {string} string "Redirects to redirect_uri with authorization code"
 // @Failure 400 {object} gin.H{error=string}
 // @Failure 401 {object} gin.H{error=string}
 // @Failure 403 {object} gin.H{error=string}
 // @Router /oauth/authorize [post]
 func (h *OAuth2ConsentHandler) ProcessConsent(c *gin.Context) {
+	// The user is answering the consent prompt shown after signing in
+	if ticketID := c.PostForm("consent_ticket"); ticketID != "" {
+		h.processConsentDecision(c, ticketID)
+		return
+	}
+
 	// 1. Extract form parameters
 	email := c.PostForm("email")
 	password := c.PostForm("password")
-	clientID := c.PostForm("client_id")
-	redirectURI := c.PostForm("redirect_uri")
-	responseType := c.PostForm("response_type")
-	scope := c.PostForm("scope")
-	state := c.PostForm("state")
-	codeChallenge := c.PostForm("code_challenge")
-	codeChallengeMethod := c.PostForm("code_challenge_method")
 	csrfToken := c.PostForm("csrf_token")
 
+	// Pushed requests are only looked up here; the request_uri is used up when the code is issued
+	params, err := resolveAuthorizationParams(c.Request.Context(), h.clientAppSvc, h.parSvc, c.PostForm)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error":       "invalid_request",
+			"description": err.Error(),
+		})
+		return
+	}
+	requestURI := c.PostForm("request_uri")
+	clientID := params.ClientID
+	redirectURI := params.RedirectURI
+	responseType := params.ResponseType
+	scope := params.Scope
+	state := params.State
+	codeChallenge := params.CodeChallenge
+	codeChallengeMethod := params.CodeChallengeMethod
+	nonce := params.Nonce
+
 	// 2. Validate CSRF token
 	cookieCSRF, err := c.Cookie("oauth_csrf")
 	if err != nil || cookieCSRF != csrfToken || csrfToken == "" {
@@ -216,12 +266,19 @@ func (h *OAuth2ConsentHandler) ProcessConsent(c *gin.Context) {
 	}
 
 	// 7. Authenticate user with email and password (using Argon2/bcrypt)
-	user, err := h.userService.AuthenticateByEmail(c.Request.Context(), email, password)
+	user, err := h.userService.AuthenticateByEmail(c.Request.Context(), email, password, c.ClientIP())
 	if err != nil {
 		// Return to form with error - don't expose whether email exists
+		errorCode, description := "invalid_credentials", "Invalid email or password"
+		switch {
+		case errors.Is(err, service.ErrPasswordResetRequired):
+			errorCode, description = "password_reset_required", "Your password was found in a data breach. Use the link we emailed you to reset it"
+		case errors.Is(err, service.ErrLoginBlocked), errors.Is(err, service.ErrLoginStepUpRequired):
+			errorCode, description = "access_denied", err.Error()
+		}
 		c.HTML(http.StatusUnauthorized, "oauth_consent.html", gin.H{
-			"error":                 "invalid_credentials",
-			"error_description":     "Invalid email or password",
+			"error":                 errorCode,
+			"error_description":     description,
 			"client_name":           clientApp.Name,
 			"client_id":             clientID,
 			"redirect_uri":          redirectURI,
@@ -230,11 +287,38 @@ func (h *OAuth2ConsentHandler) ProcessConsent(c *gin.Context) {
 			"state":                 state,
 			"code_challenge":        codeChallenge,
 			"code_challenge_method": codeChallengeMethod,
+			"nonce":                 nonce,
+			"request_uri":           requestURI,
 			"email":                 email, // Pre-fill email on error
 		})
 		return
 	}
 
+	// 7b. Second factor for users with MFA enabled
+	amr, err := h.userService.VerifySecondFactor(c.Request.Context(), user.ID, c.PostForm("mfa_code"))
+	if err != nil {
+		description := "Invalid authentication code"
+		if errors.Is(err, service.ErrMFARequired) {
+			description = "Enter the code from your authenticator app"
+		}
+		c.HTML(http.StatusUnauthorized, "oauth_consent.html", gin.H{
+			"error":                 "mfa_required",
+			"error_description":     description,
+			"client_name":           clientApp.Name,
+			"client_id":             clientID,
+			"redirect_uri":          redirectURI,
+			"response_type":         responseType,
+			"scope":                 scope,
+			"state":                 state,
+			"code_challenge":        codeChallenge,
+			"code_challenge_method": codeChallengeMethod,
+			"nonce":                 nonce,
+			"request_uri":           requestURI,
+			"email":                 email,
+		})
+		return
+	}
+
 	// 8. **CRITICAL**: Verify user belongs to the organization that owns this OAuth2 client
 	isMember, err := h.userService.IsOrgMember(c.Request.Context(), user.ID, clientApp.OrganizationID)
 	if err != nil || !isMember {
@@ -247,16 +331,115 @@ func (h *OAuth2ConsentHandler) ProcessConsent(c *gin.Context) {
 		return
 	}
 
-	// 9. Create authorization code (short-lived: 10 minutes)
+	// 9. Skip the consent prompt when the user already approved these scopes
+	authTime := time.Now()
+	granted, err := h.consentSvc.HasGrant(c.Request.Context(), user.ID, clientID, clientApp.OrganizationID, scope)
+	if err != nil {
+		redirectErrorHTML(c, redirectURI, "server_error", "Failed to look up consent", state)
+		return
+	}
+	if !granted {
+		h.showConsentPrompt(c, clientApp.Name, &service.ConsentTicket{
+			UserID:         user.ID,
+			OrganizationID: clientApp.OrganizationID,
+			Params:         *params,
+			RequestURI:     requestURI,
+			AuthTime:       authTime,
+			AMR:            amr,
+		})
+		return
+	}
+
+	h.issueAuthorizationCode(c, user.ID, clientApp.OrganizationID, params, requestURI, authTime, amr)
+}
+
+// showConsentPrompt asks the signed-in user to allow or deny the requested scopes
+func (h *OAuth2ConsentHandler) showConsentPrompt(c *gin.Context, clientName string, ticket *service.ConsentTicket) {
+	ticketID, err := h.consentSvc.CreateTicket(c.Request.Context(), ticket)
+	if err != nil {
+		redirectErrorHTML(c, ticket.Params.RedirectURI, "server_error", "Failed to start consent", ticket.Params.State)
+		return
+	}
+
+	csrfToken, err := generateCSRFToken()
+	if err != nil {
+		redirectErrorHTML(c, ticket.Params.RedirectURI, "server_error", "Failed to generate CSRF token", ticket.Params.State)
+		return
+	}
+	c.SetCookie("oauth_csrf", csrfToken, int(5*time.Minute/time.Second), "/", "", false, true)
+
+	c.HTML(http.StatusOK, "oauth_consent.html", gin.H{
+		"consent_step":   true,
+		"client_name":    clientName,
+		"scopes":         parseScopes(ticket.Params.Scope),
+		"consent_ticket": ticketID,
+		"csrf_token":     csrfToken,
+	})
+}
+
+// processConsentDecision handles the answer to the consent prompt. The authorization
+// parameters come from the ticket, not the form, so they cannot be changed in between.
+func (h *OAuth2ConsentHandler) processConsentDecision(c *gin.Context, ticketID string) {
+	csrfToken := c.PostForm("csrf_token")
+	cookieCSRF, err := c.Cookie("oauth_csrf")
+	if err != nil || cookieCSRF != csrfToken || csrfToken == "" {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error":       "invalid_request",
+			"description": "CSRF token validation failed",
+		})
+		return
+	}
+	c.SetCookie("oauth_csrf", "", -1, "/", "", false, true)
+
+	ticket, err := h.consentSvc.ConsumeTicket(c.Request.Context(), ticketID)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error":       "invalid_request",
+			"description": "The consent request has expired. Please start again from the application.",
+		})
+		return
+	}
+	params := &ticket.Params
+
+	if c.PostForm("decision") != "allow" {
+		redirectErrorHTML(c, params.RedirectURI, "access_denied", "The user denied the request", params.State)
+		return
+	}
+
+	if err := h.consentSvc.Grant(c.Request.Context(), ticket.UserID, params.ClientID, ticket.OrganizationID, params.Scope); err != nil {
+		redirectErrorHTML(c, params.RedirectURI, "server_error", "Failed to record consent", params.State)
+		return
+	}
+
+	h.issueAuthorizationCode(c, ticket.UserID, ticket.OrganizationID, params, ticket.RequestURI, ticket.AuthTime, ticket.AMR)
+}
+
+// issueAuthorizationCode redirects back to the client with a code for an authenticated, consented request
+func (h *OAuth2ConsentHandler) issueAuthorizationCode(c *gin.Context, userID, orgID uuid.UUID, params *service.AuthorizationParams, requestURI string, authTime time.Time, amr []string) {
+	redirectURI := params.RedirectURI
+	state := params.State
+
+	// A pushed request yields exactly one authorization code
+	if requestURI != "" {
+		if _, err := h.parSvc.Consume(c.Request.Context(), params.ClientID, requestURI); err != nil {
+			redirectErrorHTML(c, redirectURI, "invalid_request", "request_uri has expired or was already used", state)
+			return
+		}
+	}
+
+	// Create authorization code (short-lived: 10 minutes)
 	authReq := &service.AuthorizationRequest{
-		ClientID:            clientID,
+		ClientID:            params.ClientID,
 		RedirectURI:         redirectURI,
-		Scope:               scope,
+		Scope:               params.Scope,
 		State:               state,
-		CodeChallenge:       codeChallenge,
-		CodeChallengeMethod: codeChallengeMethod,
-		UserID:              user.ID,
-		OrganizationID:      &clientApp.OrganizationID,
+		CodeChallenge:       params.CodeChallenge,
+		CodeChallengeMethod: params.CodeChallengeMethod,
+		UserID:              userID,
+		OrganizationID:      &orgID,
+		Nonce:               params.Nonce,
+		AuthTime:            &authTime,
+		AMR:                 amr,
 	}
 
 	code, err := h.oauth2Service.CreateAuthorizationCode(c.Request.Context(), authReq)
@@ -265,7 +448,7 @@ func (h *OAuth2ConsentHandler) ProcessConsent(c *gin.Context) {
 		return
 	}
 
-	// 10. Build redirect URL with authorization code
+	// Build redirect URL with authorization code
 	redirectURL, _ := url.Parse(redirectURI)
 	q := redirectURL.Query()
 	q.Set("code", code)
@@ -274,10 +457,155 @@ func (h *OAuth2ConsentHandler) ProcessConsent(c *gin.Context) {
 	}
 	redirectURL.RawQuery = q.Encode()
 
-	// 11. Redirect user back to client application with authorization code
+	// Redirect user back to client application with authorization code
 	c.Redirect(http.StatusFound, redirectURL.String())
 }
 
+// ShowDeviceVerification godoc
+// @Summary Device verification page (RFC 8628 verification_uri)
+// @Description Lets the user enter the code shown on their device and approve or deny it
+// @Tags oauth2
+// @Produce html
+// @Param user_code query string false "User code (prefilled from verification_uri_complete)"
+// @Success 200 {string} html "HTML device verification form"
+// @Router /oauth/device [get]
+func (h *OAuth2ConsentHandler) ShowDeviceVerification(c *gin.Context) {
+	csrfToken, err := generateCSRFToken()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error":       "server_error",
+			"description": "Failed to generate CSRF token",
+		})
+		return
+	}
+	c.SetCookie("oauth_csrf", csrfToken, 600, "/", "", false, true)
+
+	data := gin.H{"csrf_token": csrfToken}
+
+	// Show what is being authorized when the code arrived via verification_uri_complete
+	if userCode := c.Query("user_code"); userCode != "" {
+		data["user_code"] = service.FormatUserCode(userCode)
+		if auth, err := h.deviceSvc.GetByUserCode(c.Request.Context(), userCode); err == nil {
+			if clientApp, err := h.clientAppSvc.GetClientAppByClientID(c.Request.Context(), auth.ClientID); err == nil {
+				data["client_name"] = clientApp.Name
+				data["scopes"] = parseScopes(auth.Scope)
+			}
+		}
+	}
+
+	c.HTML(http.StatusOK, "oauth_device.html", data)
+}
+
+// ProcessDeviceVerification godoc
+// @Summary Approve or deny a device authorization request
+// @Tags oauth2
+// @Accept application/x-www-form-urlencoded
+// @Produce html
+// @Param user_code formData string true "User code shown on the device"
+// @Param email formData string false "User email (required to approve)"
+// @Param password formData string false "User password (required to approve)"
+// @Param action formData string true "approve or deny"
+// @Param csrf_token formData string true "CSRF token"
+// @Success 200 {string} html "Confirmation page"
+// @Failure 400 {string} html "Form with error"
+// @Router /oauth/device [post]
+func (h *OAuth2ConsentHandler) ProcessDeviceVerification(c *gin.Context) {
+	userCode := c.PostForm("user_code")
+	email := c.PostForm("email")
+	csrfToken := c.PostForm("csrf_token")
+
+	cookieCSRF, err := c.Cookie("oauth_csrf")
+	if err != nil || cookieCSRF != csrfToken || csrfToken == "" {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error":       "invalid_request",
+			"description": "CSRF token validation failed",
+		})
+		return
+	}
+
+	// Keep the CSRF cookie so the user can retry after a typo; it expires on its own
+	renderForm := func(status int, errorCode, description string, extra gin.H) {
+		data := gin.H{
+			"error":             errorCode,
+			"error_description": description,
+			"user_code":         service.FormatUserCode(userCode),
+			"email":             email,
+			"csrf_token":        csrfToken,
+		}
+		for k, v := range extra {
+			data[k] = v
+		}
+		c.HTML(status, "oauth_device.html", data)
+	}
+
+	auth, err := h.deviceSvc.GetByUserCode(c.Request.Context(), userCode)
+	if err != nil {
+		renderForm(http.StatusBadRequest, "invalid_code", "The code is invalid or has expired", nil)
+		return
+	}
+
+	clientApp, err := h.clientAppSvc.GetClientAppByClientID(c.Request.Context(), auth.ClientID)
+	if err != nil {
+		renderForm(http.StatusBadRequest, "invalid_client", "Client application not found", nil)
+		return
+	}
+	clientInfo := gin.H{"client_name": clientApp.Name, "scopes": parseScopes(auth.Scope)}
+
+	if c.PostForm("action") == "deny" {
+		if err := h.deviceSvc.Deny(c.Request.Context(), userCode); err != nil {
+			renderForm(http.StatusBadRequest, "invalid_code", "The code is invalid or has expired", nil)
+			return
+		}
+		c.SetCookie("oauth_csrf", "", -1, "/", "", false, true)
+		c.HTML(http.StatusOK, "oauth_device.html", gin.H{
+			"completed": true,
+			"message":   "Access was denied.",
+		})
+		return
+	}
+
+	user, err := h.userService.AuthenticateByEmail(c.Request.Context(), email, c.PostForm("password"), c.ClientIP())
+	if errors.Is(err, service.ErrPasswordResetRequired) {
+		renderForm(http.StatusForbidden, "password_reset_required", "Your password was found in a data breach. Use the link we emailed you to reset it", clientInfo)
+		return
+	}
+	if errors.Is(err, service.ErrLoginBlocked) || errors.Is(err, service.ErrLoginStepUpRequired) {
+		renderForm(http.StatusForbidden, "access_denied", err.Error(), clientInfo)
+		return
+	}
+	if err != nil {
+		renderForm(http.StatusUnauthorized, "invalid_credentials", "Invalid email or password", clientInfo)
+		return
+	}
+	amr, err := h.userService.VerifySecondFactor(c.Request.Context(), user.ID, c.PostForm("mfa_code"))
+	if err != nil {
+		renderForm(http.StatusUnauthorized, "mfa_required", "Enter a valid code from your authenticator app", clientInfo)
+		return
+	}
+
+	// Same rule as the browser flow: only members of the client's organization may authorize it
+	isMember, err := h.userService.IsOrgMember(c.Request.Context(), user.ID, clientApp.OrganizationID)
+	if err != nil || !isMember {
+		renderForm(http.StatusForbidden, "access_denied", "You are not a member of the organization that owns this application", clientInfo)
+		return
+	}
+
+	if err := h.deviceSvc.Approve(c.Request.Context(), userCode, user.ID, &clientApp.OrganizationID, amr); err != nil {
+		renderForm(http.StatusBadRequest, "invalid_code", "The code is invalid or has expired", nil)
+		return
+	}
+
+	// Approving the device is the user's consent; record it so the app is listed under connected apps
+	_ = h.consentSvc.Grant(c.Request.Context(), user.ID, clientApp.ClientID, clientApp.OrganizationID, auth.Scope)
+
+	c.SetCookie("oauth_csrf", "", -1, "/", "", false, true)
+	c.HTML(http.StatusOK, "oauth_device.html", gin.H{
+		"completed":   true,
+		"client_name": clientApp.Name,
+		"message":     clientApp.Name + " is now connected.",
+	})
+}
+
 // Helper: Generate cryptographically secure CSRF token
 func generateCSRFToken() (string, error) {
 	bytes := make([]byte, 32)
@@ -320,6 +648,29 @@ func splitBySpace(s string) []string {
 	return result
 }
 
+// resolveAuthorizationParams reads the authorization request from param, or from the pushed request when request_uri is set
+func resolveAuthorizationParams(ctx context.Context, clientAppSvc service.ClientAppService, parSvc service.PushedAuthorizationService, param func(string) string) (*service.AuthorizationParams, error) {
+	clientID := param("client_id")
+	if requestURI := param("request_uri"); requestURI != "" {
+		return parSvc.Get(ctx, clientID, requestURI)
+	}
+
+	if clientApp, err := clientAppSvc.GetClientAppByClientID(ctx, clientID); err == nil && clientApp.RequirePushedAuthorizationRequests {
+		return nil, service.ErrPushedRequestNeeded
+	}
+
+	return &service.AuthorizationParams{
+		ClientID:            clientID,
+		ResponseType:        param("response_type"),
+		RedirectURI:         param("redirect_uri"),
+		Scope:               param("scope"),
+		State:               param("state"),
+		CodeChallenge:       param("code_challenge"),
+		CodeChallengeMethod: param("code_challenge_method"),
+		Nonce:               param("nonce"),
+	}, nil
+}
+
 // Helper: Validate requested scopes against allowed scopes
 func validateScopes(requested []string, allowed []string) bool {
 	if len(requested) == 0 {
@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"auth-service/internal/models"
 	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,6 +21,15 @@ type OAuth2Handler struct {
 	oauth2Service service.OAuth2Service
 	clientAppSvc  service.ClientAppService
 	userService   service.UserService
+	auditService  service.AuditService
+	introspection service.IntrospectionService
+	revocationSvc service.RevocationService
+	deviceSvc     service.DeviceAuthorizationService
+	assertionSvc  service.ClientAssertionService
+	parSvc        service.PushedAuthorizationService
+	dpopSvc       service.DPoPService
+	consentSvc    service.ConsentService
+	exchangeSvc   service.TokenExchangeService
 }
 
 // NewOAuth2Handler creates a new OAuth2 handler
@@ -24,11 +37,29 @@ func NewOAuth2Handler(
 	oauth2Service service.OAuth2Service,
 	clientAppSvc service.ClientAppService,
 	userService service.UserService,
+	auditService service.AuditService,
+	introspection service.IntrospectionService,
+	revocationSvc service.RevocationService,
+	deviceSvc service.DeviceAuthorizationService,
+	assertionSvc service.ClientAssertionService,
+	parSvc service.PushedAuthorizationService,
+	dpopSvc service.DPoPService,
+	consentSvc service.ConsentService,
+	exchangeSvc service.TokenExchangeService,
 ) *OAuth2Handler {
 	return &OAuth2Handler{
 		oauth2Service: oauth2Service,
 		clientAppSvc:  clientAppSvc,
 		userService:   userService,
+		auditService:  auditService,
+		introspection: introspection,
+		revocationSvc: revocationSvc,
+		deviceSvc:     deviceSvc,
+		assertionSvc:  assertionSvc,
+		parSvc:        parSvc,
+		dpopSvc:       dpopSvc,
+		consentSvc:    consentSvc,
+		exchangeSvc:   exchangeSvc,
 	}
 }
 
@@ -44,19 +75,30 @@ func NewOAuth2Handler(
 // @Param code_challenge query string true "PKCE code challenge (S256)"
 // @Param code_challenge_method query string true "PKCE method (must be 'S256')"
 // @Param prompt query string false "Prompt type (login, none)"
+// @Param nonce query string false "OIDC nonce (echoed into the id_token)"
+// @Param request_uri query string false "request_uri from /oauth/par (replaces all other parameters except client_id)"
 // @Success 302 {string} string "Redirects to redirect_uri with authorization code"
 // @Failure 400 {object} gin.H{error=string}
 // @Failure 401 {object} gin.H{error=string}
 // @Router /oauth/authorize [get]
 func (h *OAuth2Handler) Authorize(c *gin.Context) {
-	// Extract query parameters
-	clientID := c.Query("client_id")
-	redirectURI := c.Query("redirect_uri")
-	responseType := c.Query("response_type")
-	scope := c.Query("scope")
-	state := c.Query("state")
-	codeChallenge := c.Query("code_challenge")
-	codeChallengeMethod := c.Query("code_challenge_method")
+	// Extract query parameters (or the pushed request they refer to)
+	params, err := resolveAuthorizationParams(c.Request.Context(), h.clientAppSvc, h.parSvc, c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": err.Error(),
+		})
+		return
+	}
+	requestURI := c.Query("request_uri")
+	clientID := params.ClientID
+	redirectURI := params.RedirectURI
+	responseType := params.ResponseType
+	scope := params.Scope
+	state := params.State
+	codeChallenge := params.CodeChallenge
+	codeChallengeMethod := params.CodeChallengeMethod
 	prompt := c.Query("prompt")
 
 	// Validate required parameters
@@ -111,6 +153,13 @@ func (h *OAuth2Handler) Authorize(c *gin.Context) {
 		}
 	}
 
+	if requestURI != "" {
+		if _, err := h.parSvc.Consume(c.Request.Context(), clientID, requestURI); err != nil {
+			redirectError(c, redirectURI, "invalid_request", "request_uri has expired or was already used", state)
+			return
+		}
+	}
+
 	// Create authorization code
 	authReq := &service.AuthorizationRequest{
 		ClientID:            clientID,
@@ -121,6 +170,7 @@ func (h *OAuth2Handler) Authorize(c *gin.Context) {
 		CodeChallengeMethod: codeChallengeMethod,
 		UserID:              user.ID,
 		OrganizationID:      orgID,
+		Nonce:               params.Nonce,
 	}
 
 	code, err := h.oauth2Service.CreateAuthorizationCode(c.Request.Context(), authReq)
@@ -146,13 +196,22 @@ func (h *OAuth2Handler) Authorize(c *gin.Context) {
 // @Tags oauth2
 // @Accept application/x-www-form-urlencoded
 // @Produce json
-// @Param grant_type formData string true "Grant type (authorization_code or refresh_token)"
+// @Param grant_type formData string true "Grant type (authorization_code, refresh_token, client_credentials, urn:ietf:params:oauth:grant-type:device_code or urn:ietf:params:oauth:grant-type:token-exchange)"
 // @Param code formData string false "Authorization code (required for authorization_code grant)"
 // @Param redirect_uri formData string false "Redirect URI (required for authorization_code grant)"
 // @Param client_id formData string true "Client ID"
 // @Param client_secret formData string false "Client secret (required for confidential clients)"
 // @Param code_verifier formData string false "PKCE code verifier (required for authorization_code grant)"
 // @Param refresh_token formData string false "Refresh token (required for refresh_token grant)"
+// @Param scope formData string false "Requested scopes (client_credentials and token-exchange grants)"
+// @Param device_code formData string false "Device code (required for device_code grant)"
+// @Param subject_token formData string false "Token to exchange (required for token-exchange grant)"
+// @Param subject_token_type formData string false "urn:ietf:params:oauth:token-type:access_token or :jwt (required for token-exchange grant)"
+// @Param actor_token formData string false "Token of the party acting for the subject (delegation)"
+// @Param actor_token_type formData string false "Type of actor_token (required with actor_token)"
+// @Param requested_token_type formData string false "Only urn:ietf:params:oauth:token-type:access_token is issued"
+// @Param audience formData string false "client_id of the downstream service the exchanged token is for"
+// @Param DPoP header string false "DPoP proof JWT; binds the issued tokens to the proof key (RFC 9449)"
 // @Success 200 {object} service.TokenResponse
 // @Failure 400 {object} gin.H{error=string}
 // @Failure 401 {object} gin.H{error=string}
@@ -160,26 +219,78 @@ func (h *OAuth2Handler) Authorize(c *gin.Context) {
 func (h *OAuth2Handler) Token(c *gin.Context) {
 	grantType := c.PostForm("grant_type")
 
+	if !h.grantTypeAllowed(c, grantType) {
+		return
+	}
+
+	dpopJKT, ok := h.dpopProof(c)
+	if !ok {
+		return
+	}
+
 	switch grantType {
 	case "authorization_code":
-		h.handleAuthorizationCodeGrant(c)
+		h.handleAuthorizationCodeGrant(c, dpopJKT)
 	case "refresh_token":
-		h.handleRefreshTokenGrant(c)
+		h.handleRefreshTokenGrant(c, dpopJKT)
+	case "client_credentials":
+		h.handleClientCredentialsGrant(c, dpopJKT)
+	case service.GrantTypeDeviceCode:
+		h.handleDeviceCodeGrant(c, dpopJKT)
+	case service.GrantTypeTokenExchange:
+		h.handleTokenExchangeGrant(c, dpopJKT)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "unsupported_grant_type",
-			"error_description": "only 'authorization_code' and 'refresh_token' grants are supported",
+			"error_description": "only 'authorization_code', 'refresh_token', 'client_credentials', device_code and token-exchange grants are supported",
 		})
 	}
 }
 
-func (h *OAuth2Handler) handleAuthorizationCodeGrant(c *gin.Context) {
+// dpopProof validates the optional DPoP header of a token request and returns the
+// thumbprint of the proof key ("" without a proof). It writes the error response itself.
+func (h *OAuth2Handler) dpopProof(c *gin.Context) (string, bool) {
+	proofs := c.Request.Header.Values("DPoP")
+	if len(proofs) == 0 {
+		return "", true
+	}
+	if len(proofs) > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_dpop_proof",
+			"error_description": "only one DPoP proof may be sent",
+		})
+		return "", false
+	}
+
+	jkt, err := h.dpopSvc.ValidateProof(c.Request.Context(), proofs[0], c.Request.Method, c.Request.URL.Path, "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_dpop_proof",
+			"error_description": err.Error(),
+		})
+		return "", false
+	}
+	return jkt, true
+}
+
+func (h *OAuth2Handler) handleAuthorizationCodeGrant(c *gin.Context, dpopJKT string) {
 	code := c.PostForm("code")
 	clientID := c.PostForm("client_id")
 	clientSecret := c.PostForm("client_secret")
 	redirectURI := c.PostForm("redirect_uri")
 	codeVerifier := c.PostForm("code_verifier")
 
+	// Clients using JWT authentication prove their identity before the code is looked at
+	clientAuthenticated := false
+	if c.PostForm("client_assertion") != "" {
+		clientApp, ok := h.authenticateClient(c)
+		if !ok {
+			return
+		}
+		clientID = clientApp.ClientID
+		clientAuthenticated = true
+	}
+
 	if code == "" || clientID == "" || redirectURI == "" || codeVerifier == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "invalid_request",
@@ -197,6 +308,9 @@ func (h *OAuth2Handler) handleAuthorizationCodeGrant(c *gin.Context) {
 		GrantType:    "authorization_code",
 		UserAgent:    c.GetHeader("User-Agent"),
 		IPAddress:    c.ClientIP(),
+		DPoPJKT:      dpopJKT,
+
+		ClientAuthenticated: clientAuthenticated,
 	}
 
 	tokenResp, err := h.oauth2Service.ExchangeCodeForTokens(c.Request.Context(), tokenReq)
@@ -211,7 +325,7 @@ func (h *OAuth2Handler) handleAuthorizationCodeGrant(c *gin.Context) {
 	c.JSON(http.StatusOK, tokenResp)
 }
 
-func (h *OAuth2Handler) handleRefreshTokenGrant(c *gin.Context) {
+func (h *OAuth2Handler) handleRefreshTokenGrant(c *gin.Context, dpopJKT string) {
 	refreshToken := c.PostForm("refresh_token")
 	clientID := c.PostForm("client_id")
 
@@ -223,11 +337,11 @@ func (h *OAuth2Handler) handleRefreshTokenGrant(c *gin.Context) {
 		return
 	}
 
-	// Get user agent and IP for token binding
+	// User agent and IP are recorded on the rotated token
 	userAgent := c.GetHeader("User-Agent")
 	ipAddress := c.ClientIP()
 
-	tokenResp, err := h.oauth2Service.RefreshAccessToken(c.Request.Context(), refreshToken, clientID, userAgent, ipAddress)
+	tokenResp, err := h.oauth2Service.RefreshAccessToken(c.Request.Context(), refreshToken, clientID, dpopJKT, userAgent, ipAddress)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":             "invalid_grant",
@@ -239,8 +353,471 @@ func (h *OAuth2Handler) handleRefreshTokenGrant(c *gin.Context) {
 	c.JSON(http.StatusOK, tokenResp)
 }
 
+func (h *OAuth2Handler) handleClientCredentialsGrant(c *gin.Context, dpopJKT string) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	scope := c.PostForm("scope")
+	details := map[string]interface{}{
+		"grant_type":      "client_credentials",
+		"client_id":       clientApp.ClientID,
+		"organization_id": clientApp.OrganizationID.String(),
+		"requested_scope": scope,
+	}
+
+	tokenResp, err := h.oauth2Service.ClientCredentialsGrant(c.Request.Context(), clientApp, scope, dpopJKT)
+	if err != nil {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenGrant, nil, &clientApp.ID, false, details, err)
+
+		switch {
+		case errors.Is(err, service.ErrUnauthorizedClient):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "unauthorized_client",
+				"error_description": err.Error(),
+			})
+		case errors.Is(err, service.ErrInvalidScope):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_scope",
+				"error_description": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":             "server_error",
+				"error_description": "Failed to issue token",
+			})
+		}
+		return
+	}
+
+	details["granted_scope"] = tokenResp.Scope
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenGrant, nil, &clientApp.ID, true, details, nil)
+
+	c.JSON(http.StatusOK, tokenResp)
+}
+
+func (h *OAuth2Handler) handleDeviceCodeGrant(c *gin.Context, dpopJKT string) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	deviceCode := c.PostForm("device_code")
+	if deviceCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "device_code is required",
+		})
+		return
+	}
+
+	auth, err := h.deviceSvc.Poll(c.Request.Context(), clientApp.ClientID, deviceCode)
+	if err != nil {
+		errorCode := "invalid_grant"
+		switch {
+		case errors.Is(err, service.ErrAuthorizationPending):
+			errorCode = "authorization_pending"
+		case errors.Is(err, service.ErrSlowDown):
+			errorCode = "slow_down"
+		case errors.Is(err, service.ErrDeviceAccessDenied):
+			errorCode = "access_denied"
+		case errors.Is(err, service.ErrDeviceCodeExpired):
+			errorCode = "expired_token"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             errorCode,
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	details := map[string]interface{}{
+		"grant_type": service.GrantTypeDeviceCode,
+		"client_id":  clientApp.ClientID,
+		"scope":      auth.Scope,
+	}
+
+	tokenResp, err := h.oauth2Service.DeviceCodeGrant(c.Request.Context(), clientApp, auth, dpopJKT, c.GetHeader("User-Agent"), c.ClientIP())
+	if err != nil {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenGrant, &auth.UserID, &clientApp.ID, false, details, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_grant",
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenGrant, &auth.UserID, &clientApp.ID, true, details, nil)
+
+	c.JSON(http.StatusOK, tokenResp)
+}
+
+func (h *OAuth2Handler) handleTokenExchangeGrant(c *gin.Context, dpopJKT string) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	req := &service.TokenExchangeRequest{
+		SubjectToken:       c.PostForm("subject_token"),
+		SubjectTokenType:   c.PostForm("subject_token_type"),
+		ActorToken:         c.PostForm("actor_token"),
+		ActorTokenType:     c.PostForm("actor_token_type"),
+		RequestedTokenType: c.PostForm("requested_token_type"),
+		Audience:           c.PostForm("audience"),
+		Scope:              c.PostForm("scope"),
+		DPoPJKT:            dpopJKT,
+	}
+	if req.SubjectToken == "" || req.SubjectTokenType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "subject_token and subject_token_type are required",
+		})
+		return
+	}
+
+	details := map[string]interface{}{
+		"grant_type":      service.GrantTypeTokenExchange,
+		"client_id":       clientApp.ClientID,
+		"audience":        req.Audience,
+		"requested_scope": req.Scope,
+		"delegation":      req.ActorToken != "",
+	}
+
+	result, err := h.exchangeSvc.Exchange(c.Request.Context(), clientApp, req)
+	if err != nil {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenExchange, nil, &clientApp.ID, false, details, err)
+
+		errorCode := "invalid_grant"
+		switch {
+		case errors.Is(err, service.ErrUnauthorizedClient):
+			errorCode = "unauthorized_client"
+		case errors.Is(err, service.ErrUnsupportedTokenType):
+			errorCode = "invalid_request"
+		case errors.Is(err, service.ErrInvalidTarget):
+			errorCode = "invalid_target"
+		case errors.Is(err, service.ErrInvalidExchangeScope):
+			errorCode = "invalid_scope"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             errorCode,
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	details["subject"] = result.Subject
+	details["audience"] = result.Audience
+	details["granted_scope"] = result.Response.Scope
+	if result.Act != nil {
+		details["act"] = result.Act
+	}
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenExchange, result.UserID, &clientApp.ID, true, details, nil)
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, result.Response)
+}
+
+// DeviceAuthorization godoc
+// @Summary OAuth2 device authorization endpoint (RFC 8628)
+// @Description Starts a device login. The device shows user_code and polls /oauth/token with the device_code.
+// @Tags oauth2
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string true "Client ID"
+// @Param client_secret formData string false "Client secret (required for confidential clients)"
+// @Param scope formData string false "Requested scopes"
+// @Success 200 {object} service.DeviceAuthorizationResponse
+// @Failure 400 {object} gin.H{error=string}
+// @Failure 401 {object} gin.H{error=string}
+// @Router /oauth/device_authorization [post]
+func (h *OAuth2Handler) DeviceAuthorization(c *gin.Context) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.deviceSvc.CreateDeviceAuthorization(c.Request.Context(), clientApp, c.PostForm("scope"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_scope",
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// PushAuthorizationRequest godoc
+// @Summary Pushed authorization request endpoint (RFC 9126)
+// @Description Stores the authorization request server-side and returns a short-lived request_uri to pass to /oauth/authorize together with client_id
+// @Tags oauth2
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string true "Client ID"
+// @Param client_secret formData string false "Client secret (required for confidential clients)"
+// @Param response_type formData string true "Response type (must be 'code')"
+// @Param redirect_uri formData string true "Redirect URI"
+// @Param scope formData string false "Requested scopes"
+// @Param state formData string false "State parameter"
+// @Param code_challenge formData string true "PKCE code challenge (S256)"
+// @Param code_challenge_method formData string true "PKCE method (must be 'S256')"
+// @Param nonce formData string false "OIDC nonce (echoed into the id_token)"
+// @Success 201 {object} service.PushedAuthorizationResponse
+// @Failure 400 {object} gin.H{error=string}
+// @Failure 401 {object} gin.H{error=string}
+// @Router /oauth/par [post]
+func (h *OAuth2Handler) PushAuthorizationRequest(c *gin.Context) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	// A pushed request must not point at another pushed request (RFC 9126 section 2.1)
+	if c.PostForm("request_uri") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "request_uri is not allowed in a pushed authorization request",
+		})
+		return
+	}
+
+	resp, err := h.parSvc.Push(c.Request.Context(), clientApp, &service.AuthorizationParams{
+		ResponseType:        c.PostForm("response_type"),
+		RedirectURI:         c.PostForm("redirect_uri"),
+		Scope:               c.PostForm("scope"),
+		State:               c.PostForm("state"),
+		CodeChallenge:       c.PostForm("code_challenge"),
+		CodeChallengeMethod: c.PostForm("code_challenge_method"),
+		Nonce:               c.PostForm("nonce"),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Introspect godoc
+// @Summary OAuth2 token introspection (RFC 7662)
+// @Description Reports whether an access token or refresh token is active. Requires client authentication.
+// @Description Clients only see their own tokens unless they are allowed the "introspect" scope.
+// @Tags oauth2
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} service.IntrospectionResponse
+// @Failure 400 {object} gin.H{error=string}
+// @Failure 401 {object} gin.H{error=string}
+// @Failure 500 {object} gin.H{error=string}
+// @Router /oauth/introspect [post]
+func (h *OAuth2Handler) Introspect(c *gin.Context) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	// Public clients have no secret, so anyone could impersonate them
+	if !clientApp.IsConfidential {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_client",
+			"error_description": "introspection requires a confidential client",
+		})
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "token is required",
+		})
+		return
+	}
+
+	resp, err := h.introspection.Introspect(c.Request.Context(), token, c.PostForm("token_type_hint"), clientApp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "unable to determine token state",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// Revoke godoc
+// @Summary OAuth2 token revocation (RFC 7009)
+// @Description Revokes an access token or refresh token issued to the authenticated client. Revoking a refresh token revokes its whole rotation family.
+// @Description Tokens of other clients are left untouched and answered like unknown tokens.
+// @Tags oauth2
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to revoke"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200
+// @Failure 400 {object} gin.H{error=string}
+// @Failure 401 {object} gin.H{error=string}
+// @Failure 503 {object} gin.H{error=string}
+// @Router /oauth/revoke [post]
+func (h *OAuth2Handler) Revoke(c *gin.Context) {
+	clientApp, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "invalid_request",
+			"error_description": "token is required",
+		})
+		return
+	}
+
+	hint := c.PostForm("token_type_hint")
+	details := map[string]interface{}{
+		"client_id":       clientApp.ClientID,
+		"token_type_hint": hint,
+	}
+
+	// Access tokens are JWTs, refresh tokens are opaque, so the token itself
+	// tells us where to look regardless of the hint
+	var err error
+	if strings.Count(token, ".") == 2 {
+		err = h.revokeAccessToken(c.Request.Context(), token, clientApp)
+	} else {
+		err = h.oauth2Service.RevokeRefreshToken(c.Request.Context(), token, clientApp.ClientID)
+	}
+
+	if err != nil && !errors.Is(err, service.ErrTokenClientMismatch) {
+		h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenRevoke, nil, &clientApp.ID, false, details, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":             "temporarily_unavailable",
+			"error_description": "unable to revoke token",
+		})
+		return
+	}
+
+	// Another client's token is left alone, but the caller gets the same answer as for an
+	// unknown token so it cannot probe which tokens exist
+	h.auditService.LogOAuth(c.Request.Context(), models.ActionOAuthTokenRevoke, nil, &clientApp.ID, err == nil, details, err)
+
+	// RFC 7009: invalid or unknown tokens are not an error, the response is always empty
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// revokeAccessToken adds a JWT issued to clientApp to the denylist.
+// Tokens that are already inactive are ignored.
+func (h *OAuth2Handler) revokeAccessToken(ctx context.Context, token string, clientApp *models.ClientApp) error {
+	info, err := h.introspection.Introspect(ctx, token, service.TokenTypeHintAccessToken, clientApp)
+	if err != nil {
+		return err
+	}
+	if !info.Active {
+		return nil
+	}
+	if info.ClientID != clientApp.ClientID {
+		return service.ErrTokenClientMismatch
+	}
+
+	return h.revocationSvc.RevokeToken(ctx, token)
+}
+
+// grantTypeAllowed rejects grant types the client did not register for.
+// Unknown clients pass through so the grant handlers can report invalid_client.
+func (h *OAuth2Handler) grantTypeAllowed(c *gin.Context, grantType string) bool {
+	clientID, _, usedBasic := c.Request.BasicAuth()
+	if !usedBasic {
+		clientID = c.PostForm("client_id")
+	}
+	if clientID == "" && c.PostForm("client_assertion") != "" {
+		clientID, _ = jwt.ClientAssertionSubject(c.PostForm("client_assertion"))
+	}
+	if clientID == "" {
+		return true
+	}
+
+	clientApp, err := h.clientAppSvc.GetClientAppByClientID(c.Request.Context(), clientID)
+	if err != nil || clientApp.AllowsGrantType(grantType) {
+		return true
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":             "unauthorized_client",
+		"error_description": "grant type is not registered for this client",
+	})
+	return false
+}
+
+// authenticateClient verifies client credentials sent via HTTP Basic (client_secret_basic)
+// or form fields (client_secret_post). On failure it writes an invalid_client response.
+func (h *OAuth2Handler) authenticateClient(c *gin.Context) (*models.ClientApp, bool) {
+	clientID, clientSecret, usedBasic := c.Request.BasicAuth()
+
+	// private_key_jwt / client_secret_jwt (RFC 7523)
+	if assertion := c.PostForm("client_assertion"); assertion != "" || c.PostForm("client_assertion_type") != "" {
+		if usedBasic {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":             "invalid_request",
+				"error_description": "only one client authentication method may be used",
+			})
+			return nil, false
+		}
+
+		clientApp, err := h.assertionSvc.ValidateClientAssertion(c.Request.Context(), c.PostForm("client_id"), c.PostForm("client_assertion_type"), assertion)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":             "invalid_client",
+				"error_description": err.Error(),
+			})
+			return nil, false
+		}
+		return clientApp, true
+	}
+
+	if !usedBasic {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	if clientID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_client",
+			"error_description": "client authentication required",
+		})
+		return nil, false
+	}
+
+	clientApp, err := h.clientAppSvc.ValidateClientCredentials(c.Request.Context(), clientID, clientSecret)
+	if err != nil {
+		if usedBasic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "invalid_client",
+			"error_description": err.Error(),
+		})
+		return nil, false
+	}
+
+	return clientApp, true
+}
+
 // UserInfo godoc
-// @Summary OAuth2 UserInfo endpoint (returns user claims)
+// @Summary OpenID Connect UserInfo endpoint (claims depend on granted profile/email scopes)
 // @Tags oauth2
 // @Produce json
 // @Security BearerAuth
@@ -248,8 +825,8 @@ func (h *OAuth2Handler) handleRefreshTokenGrant(c *gin.Context) {
 // @Failure 401 {object} gin.H{error=string}
 // @Router /oauth/userinfo [get]
 func (h *OAuth2Handler) UserInfo(c *gin.Context) {
-	// Get user from JWT token (set by auth middleware)
-	userVal, exists := c.Get("user_id")
+	// Get user and granted scope from JWT token (set by auth middleware)
+	userVal, exists := c.Request.Context().Value("user_id").(string)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":             "invalid_token",
@@ -257,8 +834,9 @@ func (h *OAuth2Handler) UserInfo(c *gin.Context) {
 		})
 		return
 	}
+	scope, _ := c.Request.Context().Value("scope").(string)
 
-	userID, err := uuid.Parse(userVal.(string))
+	userID, err := uuid.Parse(userVal)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":             "invalid_token",
@@ -267,7 +845,7 @@ func (h *OAuth2Handler) UserInfo(c *gin.Context) {
 		return
 	}
 
-	userInfo, err := h.oauth2Service.GetUserInfo(c.Request.Context(), userID)
+	userInfo, err := h.oauth2Service.GetUserInfo(c.Request.Context(), userID, scope)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":             "user_not_found",
@@ -287,6 +865,7 @@ func (h *OAuth2Handler) UserInfo(c *gin.Context) {
 // @Param request body map[string]string true "Logout request with refresh_token"
 // @Success 200 {object} gin.H{success=true}
 // @Failure 400 {object} gin.H{error=string}
+// @Failure 500 {object} gin.H{error=string}
 // @Router /oauth/logout [post]
 func (h *OAuth2Handler) Logout(c *gin.Context) {
 	var req struct {
@@ -301,11 +880,11 @@ func (h *OAuth2Handler) Logout(c *gin.Context) {
 		return
 	}
 
-	err := h.oauth2Service.RevokeRefreshToken(c.Request.Context(), req.RefreshToken)
+	err := h.oauth2Service.RevokeRefreshToken(c.Request.Context(), req.RefreshToken, "")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":             "invalid_request",
-			"error_description": err.Error(),
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             "server_error",
+			"error_description": "failed to revoke refresh token",
 		})
 		return
 	}
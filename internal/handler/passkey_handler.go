@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PasskeyHandler handles passkey management and passwordless login
+type PasskeyHandler struct {
+	passkeySvc   service.PasskeyService
+	userService  service.UserService
+	auditService service.AuditService
+}
+
+// NewPasskeyHandler creates a new passkey handler
+func NewPasskeyHandler(passkeySvc service.PasskeyService, userService service.UserService, auditService service.AuditService) *PasskeyHandler {
+	return &PasskeyHandler{
+		passkeySvc:   passkeySvc,
+		userService:  userService,
+		auditService: auditService,
+	}
+}
+
+// BeginRegistration godoc
+// @Summary Start passkey registration
+// @Description Returns PublicKeyCredentialCreationOptions for navigator.credentials.create()
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/passkeys/register/options [post]
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	email, _ := c.Request.Context().Value("user_email").(string)
+
+	options, err := h.passkeySvc.BeginRegistration(c.Request.Context(), userID, email, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to start passkey registration",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    options,
+	})
+}
+
+// FinishRegistration godoc
+// @Summary Register a passkey
+// @Description Verifies the credential created by the browser and stores it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body service.PasskeyRegistrationRequest true "PublicKeyCredential JSON and a name"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /user/passkeys/register [post]
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req service.PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"errors":  err.Error(),
+		})
+		return
+	}
+
+	cred, err := h.passkeySvc.FinishRegistration(c.Request.Context(), userID, &req)
+	details := map[string]interface{}{"name": req.Name}
+	if cred != nil {
+		details["credential_id"] = cred.ID.String()
+		details["aaguid"] = cred.AAGUID
+	}
+	h.auditService.LogAuth(c.Request.Context(), models.ActionPasskeyRegister, &userID, err == nil, details, err)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPasskeyAlreadyRegistered):
+			c.JSON(http.StatusConflict, gin.H{"success": false, "message": "This passkey is already registered"})
+		case errors.Is(err, service.ErrInvalidPasskeyChallenge), errors.Is(err, service.ErrPasskeyVerificationFailed):
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "Failed to register passkey"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    cred,
+		"message": "Passkey registered",
+	})
+}
+
+// ListPasskeys godoc
+// @Summary List passkeys
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/passkeys [get]
+func (h *PasskeyHandler) ListPasskeys(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	creds, err := h.passkeySvc.ListCredentials(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to list passkeys",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    creds,
+	})
+}
+
+// DeletePasskey godoc
+// @Summary Delete a passkey
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Passkey ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /user/passkeys/{id} [delete]
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Passkey not found",
+		})
+		return
+	}
+
+	err = h.passkeySvc.DeleteCredential(c.Request.Context(), userID, id)
+	if errors.Is(err, service.ErrPasskeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Passkey not found",
+		})
+		return
+	}
+	h.auditService.LogAuth(c.Request.Context(), models.ActionPasskeyDelete, &userID, err == nil, map[string]interface{}{"credential_id": id.String()}, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to delete passkey",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Passkey deleted",
+	})
+}
+
+// BeginLogin godoc
+// @Summary Start passkey login
+// @Description Returns PublicKeyCredentialRequestOptions for navigator.credentials.get(); no email is needed
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/passkey/login/options [post]
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	options, err := h.passkeySvc.BeginLogin(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to start passkey login",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    options,
+	})
+}
+
+// FinishLogin godoc
+// @Summary Log in with a passkey
+// @Description Verifies the assertion and returns the same response as password login
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body service.PasskeyLoginRequest true "PublicKeyCredential JSON"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /auth/passkey/login [post]
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var req service.PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid request data",
+			"errors":  err.Error(),
+		})
+		return
+	}
+	req.ClientIP = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	req.DeviceToken = deviceToken(c)
+
+	response, err := h.userService.LoginWithPasskey(c.Request.Context(), &req)
+
+	var userID *uuid.UUID
+	if response != nil && response.User != nil {
+		if parsedID, parseErr := uuid.Parse(response.User.ID); parseErr == nil {
+			userID = &parsedID
+		}
+	}
+	action := models.ActionLogin
+	if err != nil {
+		action = models.ActionLoginFailed
+	}
+	h.auditService.LogAuth(c.Request.Context(), action, userID, err == nil, map[string]interface{}{
+		"method":        "passkey",
+		"credential_id": req.Credential.ID,
+	}, err)
+
+	if errors.Is(err, service.ErrLoginBlocked) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	setDeviceCookie(c, response.DeviceToken)
+
+	message := "Login successful. Please select an organization."
+	if response.PasswordExpired {
+		message = passwordExpiredMessage
+	} else if response.Token != nil && response.Token.AccessToken != "" {
+		message = "Login successful. Welcome back, superadmin!"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+		"message": message,
+	})
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SessionHandler lets the signed-in user review their sessions and sign out of any of them
+type SessionHandler struct {
+	userSvc      service.UserService
+	auditService service.AuditService
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(userSvc service.UserService, auditService service.AuditService) *SessionHandler {
+	return &SessionHandler{
+		userSvc:      userSvc,
+		auditService: auditService,
+	}
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description Active sessions in every organization, with device, approximate location, last activity and which one is current
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/sessions [get]
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.userSvc.ListActiveSessions(c.Request.Context(), userID.String(), currentSessionID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to load sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sessions,
+	})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Signs the session out: its refresh tokens stop working and its access tokens are denied
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /user/sessions/{id} [delete]
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Session not found",
+		})
+		return
+	}
+
+	err = h.userSvc.RevokeUserSession(c.Request.Context(), userID.String(), id.String(), "revoked_by_user")
+	if errors.Is(err, service.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Session not found",
+		})
+		return
+	}
+	h.auditService.LogAuth(c.Request.Context(), models.ActionSessionRevoke, &userID, err == nil, map[string]interface{}{"session_id": id.String()}, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to revoke session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Signs out every session except the one making the request
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /user/sessions/revoke-others [post]
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	current := currentSessionID(c)
+	revoked, err := h.userSvc.RevokeOtherSessions(c.Request.Context(), userID.String(), current, "revoked_by_user")
+	h.auditService.LogAuth(c.Request.Context(), models.ActionSessionRevokeAll, &userID, err == nil, map[string]interface{}{
+		"kept_session_id": current,
+		"revoked":         revoked,
+	}, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Other sessions revoked",
+		"data":    gin.H{"revoked": revoked},
+	})
+}
+
+// currentSessionID is the session the request's access token was issued to, or "" when
+// the caller authenticated some other way (e.g. an API key)
+func currentSessionID(c *gin.Context) string {
+	sessionID, ok := c.Request.Context().Value("session_id").(string)
+	if !ok || sessionID == uuid.Nil.String() {
+		return ""
+	}
+	return sessionID
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WellKnownHandler serves the public /.well-known discovery documents
+type WellKnownHandler struct {
+	jwtService jwt.JWTService
+}
+
+// NewWellKnownHandler creates a new well-known handler
+func NewWellKnownHandler(jwtService jwt.JWTService) *WellKnownHandler {
+	return &WellKnownHandler{
+		jwtService: jwtService,
+	}
+}
+
+// OpenIDProviderMetadata is the OpenID Connect discovery document
+type OpenIDProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	PushedAuthorizationEndpoint       string   `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorization        bool     `json:"require_pushed_authorization_requests"` // Per client; see client metadata
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgs      []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	DPoPSigningAlgValuesSupported     []string `json:"dpop_signing_alg_values_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	ACRValuesSupported                []string `json:"acr_values_supported"`
+}
+
+// OpenIDConfiguration godoc
+// @Summary OpenID Connect discovery document
+// @Tags oauth2
+// @Produce json
+// @Success 200 {object} OpenIDProviderMetadata
+// @Router /.well-known/openid-configuration [get]
+func (h *WellKnownHandler) OpenIDConfiguration(c *gin.Context) {
+	issuer := strings.TrimSuffix(h.jwtService.IssuerURL(), "/")
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, OpenIDProviderMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/api/v1/oauth/authorize",
+		TokenEndpoint:                     issuer + "/api/v1/oauth/token",
+		UserInfoEndpoint:                  issuer + "/api/v1/oauth/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/api/v1/oauth/introspect",
+		RevocationEndpoint:                issuer + "/api/v1/oauth/revoke",
+		DeviceAuthorizationEndpoint:       issuer + "/api/v1/oauth/device_authorization",
+		RegistrationEndpoint:              issuer + "/api/v1/oauth/register",
+		PushedAuthorizationEndpoint:       issuer + "/api/v1/oauth/par",
+		ScopesSupported:                   []string{service.ScopeOpenID, service.ScopeProfile, service.ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials", service.GrantTypeDeviceCode, service.GrantTypeTokenExchange},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "private_key_jwt", "client_secret_jwt", "none"},
+		TokenEndpointAuthSigningAlgs:      append(append([]string{}, jwt.AsymmetricAssertionAlgs...), jwt.SymmetricAssertionAlgs...),
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		DPoPSigningAlgValuesSupported:     jwt.DPoPSigningAlgs,
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "amr", "acr", "azp",
+			"email", "email_verified", "name", "given_name", "family_name", "org",
+		},
+		ACRValuesSupported: []string{jwt.ACRSingleFactor, jwt.ACRMultiFactor},
+	})
+}
+
+// JWKS godoc
+// @Summary Public signing keys (JSON Web Key Set)
+// @Tags oauth2
+// @Produce json
+// @Success 200 {object} jwt.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(c *gin.Context) {
+	// Short cache so verifiers pick up a rotated key well within the overlap window
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtService.JWKS())
+}
@@ -13,6 +13,7 @@ import (
 
 	"auth-service/internal/repository"
 	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
 	"auth-service/pkg/password"
 )
 
@@ -48,17 +49,25 @@ func DefaultResourcePolicy() *ResourcePolicy {
 	}
 }
 
+// Authorization header schemes accepted for access tokens
+const (
+	schemeBearer = "Bearer"
+	schemeDPoP   = "DPoP" // Sender-constrained token, sent with a DPoP proof header (RFC 9449)
+)
+
 // AuthMiddleware handles JWT and API key authentication
 type AuthMiddleware struct {
 	authService service.AuthService
 	repo        repository.Repository
+	dpopSvc     service.DPoPService
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authService service.AuthService, repo repository.Repository) *AuthMiddleware {
+func NewAuthMiddleware(authService service.AuthService, repo repository.Repository, dpopSvc service.DPoPService) *AuthMiddleware {
 	return &AuthMiddleware{
 		authService: authService,
 		repo:        repo,
+		dpopSvc:     dpopSvc,
 	}
 }
 
@@ -202,15 +211,27 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// DPoP-bound tokens are only usable together with a proof from the bound key
+		if !m.verifyDPoPBinding(c, token, claims) {
+			c.Abort()
+			return
+		}
+
 		// Set comprehensive user context for policy checks
 		ctx := context.WithValue(c.Request.Context(), "user_id", claims.UserID)
 		ctx = context.WithValue(ctx, "user_email", claims.Email)
 		ctx = context.WithValue(ctx, "organization_id", claims.OrganizationID)
+		ctx = context.WithValue(ctx, "session_id", claims.SessionID)
 		ctx = context.WithValue(ctx, "organization_role", claims.OrganizationRole)
 		ctx = context.WithValue(ctx, "global_role", claims.GlobalRole)
 		ctx = context.WithValue(ctx, "is_superadmin", claims.IsSuperadmin)
 		ctx = context.WithValue(ctx, "permissions", claims.Permissions)
+		ctx = context.WithValue(ctx, "scope", claims.Scope)
 		ctx = context.WithValue(ctx, "auth_method", "jwt")
+		ctx = context.WithValue(ctx, "acr", claims.ACR)
+		if claims.AuthTime != nil {
+			ctx = context.WithValue(ctx, "auth_time", *claims.AuthTime)
+		}
 		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
@@ -437,21 +458,117 @@ func (m *AuthMiddleware) OrganizationRequired() gin.HandlerFunc {
 	}
 }
 
+// ReauthenticatePath is where clients answer a step-up challenge
+const ReauthenticatePath = "/api/v1/auth/reauthenticate"
+
+// RequireRecentAuth demands that the user authenticated within maxAge, at acr or
+// stronger (empty acr accepts any level). Otherwise the request is refused with an
+// OAuth 2.0 step-up challenge (RFC 9470); API keys never satisfy it. Use after AuthRequired.
+func (m *AuthMiddleware) RequireRecentAuth(maxAge time.Duration, acr string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authTime, hasAuthTime := c.Request.Context().Value("auth_time").(time.Time)
+		tokenACR, _ := c.Request.Context().Value("acr").(string)
+
+		switch {
+		case !hasAuthTime || time.Since(authTime) > maxAge:
+			stepUpChallenge(c, maxAge, acr, "A recent sign-in is required for this action")
+		case !jwt.ACRSatisfies(tokenACR, acr):
+			stepUpChallenge(c, maxAge, acr, "A stronger sign-in is required for this action")
+		default:
+			c.Next()
+			return
+		}
+		c.Abort()
+	}
+}
+
 // extractToken extracts JWT token from Authorization header
 func (m *AuthMiddleware) extractToken(c *gin.Context) string {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		return ""
+	_, token := splitAuthorization(c.GetHeader("Authorization"))
+	return token
+}
+
+// splitAuthorization returns the scheme and token of an Authorization header.
+// Both are empty unless the header uses the Bearer or DPoP scheme.
+func splitAuthorization(authHeader string) (string, string) {
+	for _, scheme := range []string{schemeBearer, schemeDPoP} {
+		if strings.HasPrefix(authHeader, scheme+" ") {
+			return scheme, strings.TrimSpace(strings.TrimPrefix(authHeader, scheme+" "))
+		}
+	}
+	return "", ""
+}
+
+// verifyDPoPBinding checks the proof sent with a DPoP-bound token (RFC 9449 section 7).
+// Bound tokens must use the DPoP scheme, and the DPoP scheme is refused for unbound tokens
+// so a client cannot believe a token is protected when it is not.
+func (m *AuthMiddleware) verifyDPoPBinding(c *gin.Context, token string, claims *service.TokenClaims) bool {
+	scheme, _ := splitAuthorization(c.GetHeader("Authorization"))
+
+	if claims.DPoPJKT == "" {
+		if scheme == schemeDPoP {
+			dpopChallenge(c, "invalid_token", "Token is not DPoP-bound")
+			return false
+		}
+		return true
+	}
+
+	if scheme != schemeDPoP {
+		dpopChallenge(c, "invalid_token", "DPoP-bound token must be sent with the DPoP authorization scheme")
+		return false
 	}
 
-	// Check if it starts with "Bearer "
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return ""
+	proofs := c.Request.Header.Values("DPoP")
+	if len(proofs) != 1 {
+		dpopChallenge(c, "invalid_dpop_proof", "Exactly one DPoP proof header is required")
+		return false
 	}
 
-	// Extract token part
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	return strings.TrimSpace(token)
+	jkt, err := m.dpopSvc.ValidateProof(c.Request.Context(), proofs[0], c.Request.Method, c.Request.URL.Path, token)
+	if err != nil {
+		dpopChallenge(c, "invalid_dpop_proof", err.Error())
+		return false
+	}
+	if jkt != claims.DPoPJKT {
+		dpopChallenge(c, "invalid_dpop_proof", "DPoP proof was not signed by the key the token is bound to")
+		return false
+	}
+
+	return true
+}
+
+// dpopChallenge rejects the request with a DPoP WWW-Authenticate challenge
+func dpopChallenge(c *gin.Context, errorCode, message string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(`DPoP error="%s", algs="%s"`, errorCode, strings.Join(jwt.DPoPSigningAlgs, " ")))
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": message,
+		"error":   errorCode,
+	})
+}
+
+// stepUpChallenge rejects the request with an insufficient_user_authentication challenge
+func stepUpChallenge(c *gin.Context, maxAge time.Duration, acr, message string) {
+	maxAgeSeconds := int(maxAge.Seconds())
+	header := fmt.Sprintf(`Bearer error="insufficient_user_authentication", error_description="%s", max_age=%d`, message, maxAgeSeconds)
+	if acr != "" {
+		header += fmt.Sprintf(`, acr_values="%s"`, acr)
+	}
+	c.Header("WWW-Authenticate", header)
+
+	challenge := gin.H{
+		"max_age":        maxAgeSeconds,
+		"reauthenticate": ReauthenticatePath,
+	}
+	if acr != "" {
+		challenge["acr_values"] = acr
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"success": false,
+		"message": message,
+		"error":   "insufficient_user_authentication",
+		"step_up": challenge,
+	})
 }
 
 // CORSMiddleware handles CORS headers with tenant subdomain support
@@ -490,9 +607,9 @@ func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
 		// Set CORS headers
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Tenant-ID, X-Organization-ID")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Tenant-ID, X-Organization-ID, DPoP")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
-		c.Header("Access-Control-Expose-Headers", "X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Expose-Headers", "X-CSRF-Token, Authorization, WWW-Authenticate")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
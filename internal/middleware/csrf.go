@@ -20,7 +20,7 @@ type CSRFConfig struct {
 	Secure       bool
 	TokenName    string
 	HeaderName   string
-	SkipPaths    []string
+	SkipPaths    []string      // Exact paths, or prefixes ending in "/*"
 	TokenExpiry  time.Duration // Token expiration time
 	RotateTokens bool          // Enable token rotation
 }
@@ -61,7 +61,7 @@ func CSRFMiddleware(config CSRFConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip CSRF check for configured paths
 		for _, path := range config.SkipPaths {
-			if c.Request.URL.Path == path {
+			if c.Request.URL.Path == path || (strings.HasSuffix(path, "/*") && strings.HasPrefix(c.Request.URL.Path, strings.TrimSuffix(path, "*"))) {
 				c.Next()
 				return
 			}
@@ -2,11 +2,12 @@ package middleware
 
 import (
 	"context"
+	stderrors "errors"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"auth-service/internal/errors"
 	"auth-service/internal/service"
 )
 
@@ -162,6 +163,21 @@ func (m *OrganizationMiddleware) MembershipRequired(requiredRole string) gin.Han
 			return
 		}
 
+		// Members the organization's MFA policy applies to lose access once their grace period ends
+		if _, err := m.authService.UserService().EnforceMFAPolicy(c.Request.Context(), membership); err != nil {
+			if stderrors.Is(err, service.ErrMFAEnrollmentRequired) {
+				code, message := errors.NewErrorMapper().MapServiceError(err)
+				errors.SendErrorResponse(c, code, message, nil)
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Failed to check organization security policy",
+				})
+			}
+			c.Abort()
+			return
+		}
+
 		// Set membership context
 		ctx := context.WithValue(c.Request.Context(), "membership", membership)
 		ctx = context.WithValue(ctx, "user_role", roleName)
@@ -178,19 +194,8 @@ func (m *OrganizationMiddleware) OrgAdminRequired() gin.HandlerFunc {
 
 // extractToken extracts JWT token from Authorization header
 func (m *OrganizationMiddleware) extractToken(c *gin.Context) string {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		return ""
-	}
-
-	// Check if it starts with "Bearer "
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return ""
-	}
-
-	// Extract token part
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	return strings.TrimSpace(token)
+	_, token := splitAuthorization(c.GetHeader("Authorization"))
+	return token
 }
 
 // hasRequiredRole checks if user role meets the required role level
@@ -1,12 +1,17 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"auth-service/internal/config"
+	"auth-service/pkg/jwt"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
@@ -22,6 +27,9 @@ const (
 	ScopeTokenRefresh  RateLimitScope = "token_refresh"
 	ScopeOAuth2Token   RateLimitScope = "oauth2_token"
 	ScopeAPICalls      RateLimitScope = "api_calls"
+
+	ScopeClientCredentials RateLimitScope = "client_credentials"
+	ScopeEmailLogin        RateLimitScope = "email_login"
 )
 
 // RateLimiter provides Redis-backed rate limiting
@@ -72,6 +80,16 @@ func (rl *RateLimiter) GetScopeConfig(scope RateLimitScope) RateLimitScopeConfig
 			MaxAttempts: rl.config.OAuth2Token,
 			Window:      time.Duration(rl.config.OAuth2TokenWindow) * time.Second,
 		}
+	case ScopeClientCredentials:
+		return RateLimitScopeConfig{
+			MaxAttempts: rl.config.ClientCredentials,
+			Window:      time.Duration(rl.config.ClientCredentialsWindow) * time.Second,
+		}
+	case ScopeEmailLogin:
+		return RateLimitScopeConfig{
+			MaxAttempts: rl.config.EmailLogin,
+			Window:      time.Duration(rl.config.EmailLoginWindow) * time.Second,
+		}
 	case ScopeAPICalls:
 		return RateLimitScopeConfig{
 			MaxAttempts: rl.config.APICalls,
@@ -205,10 +223,18 @@ func (rl *RateLimiter) ByEmail(scope RateLimitScope, emailField string) gin.Hand
 			}
 		}
 
-		// Read and cache body for downstream handlers
+		// Read the body and put it back so downstream handlers can still bind it
+		if c.Request.Body == nil {
+			return c.ClientIP()
+		}
+		data, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		if err != nil {
+			return c.ClientIP()
+		}
 		var body map[string]interface{}
-		if err := c.ShouldBindJSON(&body); err != nil {
-			// Cannot read body - fallback to IP
+		if err := json.Unmarshal(data, &body); err != nil {
+			// Cannot parse body - fallback to IP
 			return c.ClientIP()
 		}
 
@@ -216,7 +242,7 @@ func (rl *RateLimiter) ByEmail(scope RateLimitScope, emailField string) gin.Hand
 		c.Set("rate_limit_body", body)
 
 		if email, ok := body[emailField].(string); ok {
-			return email
+			return strings.ToLower(strings.TrimSpace(email))
 		}
 
 		// Fallback to IP if no email found
@@ -236,6 +262,26 @@ func (rl *RateLimiter) ByUserID(scope RateLimitScope) gin.HandlerFunc {
 	})
 }
 
+// ByClientID creates a middleware that rate limits token requests by OAuth2 client ID,
+// taken from Basic auth, the client_id field or the client assertion's subject.
+// Only requests for the given grant type are counted; others pass through untouched.
+func (rl *RateLimiter) ByClientID(scope RateLimitScope, grantType string) gin.HandlerFunc {
+	return rl.Middleware(scope, func(c *gin.Context) string {
+		if c.PostForm("grant_type") != grantType {
+			return ""
+		}
+		if clientID, _, ok := c.Request.BasicAuth(); ok {
+			return clientID
+		}
+		if clientID := c.PostForm("client_id"); clientID != "" {
+			return clientID
+		}
+		// private_key_jwt and client_secret_jwt clients may only identify themselves in the assertion
+		clientID, _ := jwt.ClientAssertionSubject(c.PostForm("client_assertion"))
+		return clientID
+	})
+}
+
 // Combined creates a middleware that applies multiple rate limiting strategies
 // All strategies must pass for the request to be allowed
 func (rl *RateLimiter) Combined(checks []struct {
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"strings"
 
 	"auth-service/internal/service"
 	"auth-service/pkg/jwt"
@@ -20,15 +19,13 @@ func RevocationMiddleware(jwtService jwt.JWTService, revocationSvc service.Revoc
 			return
 		}
 
-		// Parse Bearer token
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		// Parse Bearer (or DPoP-bound) token
+		_, tokenString := splitAuthorization(authHeader)
+		if tokenString == "" {
 			c.Next()
 			return
 		}
 
-		tokenString := parts[1]
-
 		// Check if token is revoked
 		revoked, err := revocationSvc.IsTokenRevoked(c.Request.Context(), tokenString)
 		if err != nil {
@@ -44,6 +44,14 @@ const (
 	ActionEmailVerification = "email_verification"
 	ActionMFAEnable         = "mfa_enable"
 	ActionMFADisable        = "mfa_disable"
+	ActionMFAVerify         = "mfa_verify"
+	ActionMFARecoveryCodes  = "mfa_recovery_codes"
+	ActionPasskeyRegister   = "passkey_register"
+	ActionPasskeyDelete     = "passkey_delete"
+	ActionReauthenticate    = "reauthenticate"
+	ActionEmailLoginRequest = "email_login_request"
+	ActionDeviceRevoke      = "device_revoke"
+	ActionLoginRisk         = "login_risk"
 
 	// Authorization actions
 	ActionRoleAssign          = "role_assign"
@@ -86,6 +94,9 @@ const (
 	ActionOAuthAuthorize     = "oauth_authorize"
 	ActionOAuthTokenGrant    = "oauth_token_grant"
 	ActionOAuthTokenRevoke   = "oauth_token_revoke"
+	ActionOAuthConsentGrant  = "oauth_consent_grant"
+	ActionOAuthConsentRevoke = "oauth_consent_revoke"
+	ActionOAuthTokenExchange = "oauth_token_exchange"
 	ActionClientCreate       = "client_create"
 	ActionClientUpdate       = "client_update"
 	ActionClientDelete       = "client_delete"
@@ -18,8 +18,23 @@ type ClientApp struct {
 	AllowedOrigins pq.StringArray `gorm:"type:text[]" json:"allowed_origins"`
 	AllowedScopes  pq.StringArray `gorm:"type:text[]" json:"allowed_scopes"`
 	IsConfidential bool           `gorm:"default:true" json:"is_confidential"` // true = requires secret, false = public (PKCE only)
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
+
+	// Dynamic client registration metadata (RFC 7591/7592)
+	GrantTypes              pq.StringArray `gorm:"type:text[]" json:"grant_types,omitempty"` // Empty = any grant type (admin-created clients)
+	TokenEndpointAuthMethod string         `gorm:"type:varchar(50)" json:"token_endpoint_auth_method,omitempty"`
+	RegistrationTokenHash   string         `gorm:"type:varchar(80);index" json:"-"` // HMAC-SHA256 of the registration access token
+	InitialAccessTokenID    *uuid.UUID     `gorm:"type:uuid;index" json:"initial_access_token_id,omitempty"`
+
+	// Keys for private_key_jwt / client_secret_jwt client authentication (RFC 7523)
+	JWKS                  string `gorm:"type:text" json:"jwks,omitempty"`     // Inline JWK set document
+	JWKSURI               string `gorm:"type:text" json:"jwks_uri,omitempty"` // Fetched when JWKS is empty
+	ClientSecretEncrypted string `gorm:"type:text" json:"-"`                  // Readable copy of the secret, client_secret_jwt only
+
+	// Reject /authorize requests that did not go through /oauth/par first (RFC 9126)
+	RequirePushedAuthorizationRequests bool `gorm:"default:false" json:"require_pushed_authorization_requests"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // TableName specifies the table name for ClientApp
@@ -27,20 +42,59 @@ func (ClientApp) TableName() string {
 	return "client_apps"
 }
 
+// AllowsGrantType reports whether the client may use grantType.
+// Clients without registered grant types are unrestricted.
+func (c *ClientApp) AllowsGrantType(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return true
+	}
+	for _, allowed := range c.GrantTypes {
+		if allowed == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+// InitialAccessToken is issued by an organization admin and lets a partner
+// register OAuth clients in that organization through /oauth/register
+type InitialAccessToken struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrganizationID uuid.UUID      `gorm:"type:uuid;not null;index" json:"organization_id"`
+	TokenHash      string         `gorm:"type:varchar(80);uniqueIndex;not null" json:"-"` // HMAC-SHA256 hash of token
+	Description    string         `gorm:"type:varchar(255)" json:"description"`
+	AllowedScopes  pq.StringArray `gorm:"type:text[]" json:"allowed_scopes"` // Scopes registered clients may request; empty = defaults
+	MaxUses        int            `gorm:"default:0" json:"max_uses"`         // 0 = unlimited
+	UseCount       int            `gorm:"default:0" json:"use_count"`
+	CreatedBy      uuid.UUID      `gorm:"type:uuid;not null" json:"created_by"`
+	ExpiresAt      *time.Time     `gorm:"index" json:"expires_at,omitempty"`
+	Revoked        bool           `gorm:"default:false;index" json:"revoked"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for InitialAccessToken
+func (InitialAccessToken) TableName() string {
+	return "initial_access_tokens"
+}
+
 // AuthorizationCode represents an OAuth2 authorization code
 type AuthorizationCode struct {
-	ID                  uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	CodeHash            string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // HMAC-SHA256 hash of code
-	ClientID            string     `gorm:"type:varchar(255);not null;index" json:"client_id"`
-	UserID              uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
-	OrganizationID      *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
-	RedirectURI         string     `gorm:"type:text;not null" json:"redirect_uri"`
-	Scope               string     `gorm:"type:text" json:"scope"`
-	CodeChallenge       string     `gorm:"type:varchar(255)" json:"code_challenge,omitempty"`
-	CodeChallengeMethod string     `gorm:"type:varchar(10)" json:"code_challenge_method,omitempty"`
-	ExpiresAt           time.Time  `gorm:"not null;index" json:"expires_at"`
-	Used                bool       `gorm:"default:false;index" json:"used"`
-	CreatedAt           time.Time  `json:"created_at"`
+	ID                  uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CodeHash            string         `gorm:"type:varchar(80);uniqueIndex;not null" json:"-"` // HMAC-SHA256 hash of code
+	ClientID            string         `gorm:"type:varchar(255);not null;index" json:"client_id"`
+	UserID              uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	OrganizationID      *uuid.UUID     `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	RedirectURI         string         `gorm:"type:text;not null" json:"redirect_uri"`
+	Scope               string         `gorm:"type:text" json:"scope"`
+	CodeChallenge       string         `gorm:"type:varchar(255)" json:"code_challenge,omitempty"`
+	CodeChallengeMethod string         `gorm:"type:varchar(10)" json:"code_challenge_method,omitempty"`
+	Nonce               string         `gorm:"type:varchar(255)" json:"-"`       // OIDC nonce echoed into the id_token
+	AuthTime            *time.Time     `json:"auth_time,omitempty"`              // When the user authenticated
+	AMR                 pq.StringArray `gorm:"type:text[]" json:"amr,omitempty"` // Authentication methods used (RFC 8176)
+	ExpiresAt           time.Time      `gorm:"not null;index" json:"expires_at"`
+	Used                bool           `gorm:"default:false;index" json:"used"`
+	CreatedAt           time.Time      `json:"created_at"`
 }
 
 // TableName specifies the table name for AuthorizationCode
@@ -51,14 +105,14 @@ func (AuthorizationCode) TableName() string {
 // OAuthRefreshToken represents a refresh token for OAuth2
 type OAuthRefreshToken struct {
 	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	TokenHash      string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // HMAC-SHA256 hash of token
+	TokenHash      string     `gorm:"type:varchar(80);uniqueIndex;not null" json:"-"` // HMAC-SHA256 hash of token
 	FamilyID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"family_id"`      // Groups all tokens in rotation chain
 	ClientID       string     `gorm:"type:varchar(255);not null;index" json:"client_id"`
 	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
 	OrganizationID *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
 	Scope          string     `gorm:"type:text" json:"scope"`
-	UserAgentHash  string     `gorm:"type:varchar(64);index" json:"-"`                    // SHA256 hash of user agent for binding
-	IPHash         string     `gorm:"type:varchar(64);index" json:"-"`                    // SHA256 hash of IP for binding
+	UserAgentHash  string     `gorm:"type:varchar(64);index" json:"-"`                    // SHA256 hash of the user agent at issuance
+	IPHash         string     `gorm:"type:varchar(64);index" json:"-"`                    // SHA256 hash of the IP at issuance
 	DeviceID       string     `gorm:"type:varchar(255);index" json:"device_id,omitempty"` // Optional device identifier
 	ExpiresAt      time.Time  `gorm:"not null;index" json:"expires_at"`
 	Revoked        bool       `gorm:"default:false;index" json:"revoked"`
@@ -66,9 +120,30 @@ type OAuthRefreshToken struct {
 	ReplacedByID   *uuid.UUID `gorm:"type:uuid;index" json:"replaced_by_id,omitempty"` // ID of new token after rotation
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// DPoPJKT is the thumbprint of the DPoP key the token family is bound to (RFC 9449).
+	// Empty for unbound tokens; rotation carries it over to every token in the family.
+	DPoPJKT string `gorm:"column:dpop_jkt;type:varchar(64)" json:"-"`
 }
 
 // TableName specifies the table name for OAuthRefreshToken
 func (OAuthRefreshToken) TableName() string {
 	return "oauth_refresh_tokens"
 }
+
+// OAuthConsentGrant records the scopes a user has approved for a client in an organization.
+// While it covers the requested scopes, the consent prompt is skipped.
+type OAuthConsentGrant struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_consent_grants_user_client_org" json:"user_id"`
+	ClientID       string         `gorm:"type:varchar(255);not null;uniqueIndex:idx_oauth_consent_grants_user_client_org;index" json:"client_id"`
+	OrganizationID uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_consent_grants_user_client_org" json:"organization_id"`
+	Scopes         pq.StringArray `gorm:"type:text[]" json:"scopes"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"` // Last time the user approved (more) scopes
+}
+
+// TableName specifies the table name for OAuthConsentGrant
+func (OAuthConsentGrant) TableName() string {
+	return "oauth_consent_grants"
+}
@@ -24,6 +24,8 @@ type User struct {
 	GlobalRole                 string     `json:"global_role" gorm:"default:'user'"`                     // user, admin
 	Status                     string     `json:"status" gorm:"index:idx_users_status;default:'active'"` // active, suspended, deactivated
 	LastLoginAt                *time.Time `json:"last_login_at"`
+	PasswordCompromisedAt      *time.Time `json:"-"` // Password found in a breach corpus; cleared when it changes
+	PasswordChangedAt          *time.Time `json:"-"` // Null for accounts that predate tracking; CreatedAt stands in
 	CreatedAt                  time.Time  `json:"created_at"`
 	UpdatedAt                  time.Time  `json:"updated_at"`
 
@@ -58,6 +60,7 @@ type UserSession struct {
 	IPAddress         string     `json:"-" gorm:"type:inet"`                                               // Never expose in JSON
 	UserAgent         string     `json:"-" gorm:"type:text"`                                               // Never expose in JSON
 	DeviceFingerprint string     `json:"-" gorm:"type:text"`                                               // Device fingerprint for tracking
+	DeviceID          *uuid.UUID `json:"device_id,omitempty" gorm:"type:uuid;index:idx_sessions_device"`   // Trusted device the session was started from
 	Location          string     `json:"-" gorm:"type:text"`                                               // Geographic location (optional)
 	IsActive          bool       `json:"-" gorm:"default:true"`                                            // Whether session is active
 	LastActivity      time.Time  `json:"-" gorm:"index:idx_sessions_activity"`                             // Last activity timestamp
@@ -134,6 +137,7 @@ type FailedLoginAttempt struct {
 	Email       string     `json:"email" gorm:"not null;index:idx_failed_attempts_email"`
 	IPAddress   string     `json:"-" gorm:"type:inet;not null"` // Never expose in JSON
 	UserAgent   string     `json:"-" gorm:"type:text"`          // Never expose in JSON
+	Location    string     `json:"-" gorm:"type:text"`          // Encoded GeoIP location of IPAddress, when known
 	AttemptedAt time.Time  `json:"-" gorm:"not null"`           // Never expose in JSON
 	CreatedAt   time.Time  `json:"created_at"`
 }
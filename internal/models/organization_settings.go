@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SecurityPolicy is the "security" section of Organization.Settings
+type SecurityPolicy struct {
+	// MFARequired requires every member to have MFA enabled
+	MFARequired bool `json:"mfa_required"`
+	// MFARequiredRoles requires MFA only for members holding one of these roles
+	MFARequiredRoles []string `json:"mfa_required_roles,omitempty"`
+	// MFAEnrollmentGraceDays lets members without MFA keep access for this many days
+	// after joining, or after the requirement was switched on, whichever is later
+	MFAEnrollmentGraceDays int `json:"mfa_enrollment_grace_days,omitempty"`
+	// MFAEnforcedSince is set when an MFA requirement is first switched on
+	MFAEnforcedSince *time.Time `json:"mfa_enforced_since,omitempty"`
+	// PasswordHistoryCount forbids reusing any of the member's last N passwords
+	PasswordHistoryCount int `json:"password_history_count,omitempty"`
+	// PasswordMaxAgeDays makes members change their password this many days after setting it
+	PasswordMaxAgeDays int `json:"password_max_age_days,omitempty"`
+}
+
+// MaxPasswordHistory caps PasswordHistoryCount; each remembered password costs a hash
+// comparison on every change.
+const MaxPasswordHistory = 24
+
+// PasswordPolicy is the password rotation rule in force for a user. Zero values impose nothing.
+type PasswordPolicy struct {
+	HistoryCount int // new passwords may not match the current one or the previous HistoryCount-1
+	MaxAgeDays   int // passwords expire this many days after being set
+}
+
+// PasswordPolicy returns the organization's password rotation rule
+func (p *SecurityPolicy) PasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{HistoryCount: p.PasswordHistoryCount, MaxAgeDays: p.PasswordMaxAgeDays}
+}
+
+// Strictest combines two policies, keeping the longer history and the shorter maximum age
+func (p PasswordPolicy) Strictest(other PasswordPolicy) PasswordPolicy {
+	if other.HistoryCount > p.HistoryCount {
+		p.HistoryCount = other.HistoryCount
+	}
+	if other.MaxAgeDays > 0 && (p.MaxAgeDays == 0 || other.MaxAgeDays < p.MaxAgeDays) {
+		p.MaxAgeDays = other.MaxAgeDays
+	}
+	if p.HistoryCount > MaxPasswordHistory {
+		p.HistoryCount = MaxPasswordHistory
+	}
+	return p
+}
+
+// Expired reports whether a password set at changedAt is past its maximum age at now
+func (p PasswordPolicy) Expired(changedAt, now time.Time) bool {
+	return p.MaxAgeDays > 0 && !now.Before(changedAt.AddDate(0, 0, p.MaxAgeDays))
+}
+
+// RequiresMFA reports whether a member holding roleName must have MFA enabled
+func (p *SecurityPolicy) RequiresMFA(roleName string) bool {
+	if p.MFARequired {
+		return true
+	}
+	for _, role := range p.MFARequiredRoles {
+		if strings.EqualFold(role, roleName) {
+			return true
+		}
+	}
+	return false
+}
+
+// MFAEnrollmentDeadline returns when a member who joined at joinedAt loses access
+// without MFA. A zero grace period means the deadline has already passed.
+func (p *SecurityPolicy) MFAEnrollmentDeadline(joinedAt time.Time) time.Time {
+	start := joinedAt
+	if p.MFAEnforcedSince != nil && p.MFAEnforcedSince.After(start) {
+		start = *p.MFAEnforcedSince
+	}
+	return start.AddDate(0, 0, p.MFAEnrollmentGraceDays)
+}
+
+func (p *SecurityPolicy) enforcesMFA() bool {
+	return p.MFARequired || len(p.MFARequiredRoles) > 0
+}
+
+// SecurityPolicy parses the security section of the organization's settings.
+// Empty settings yield the zero policy, which requires nothing.
+func (o *Organization) SecurityPolicy() (*SecurityPolicy, error) {
+	var settings struct {
+		Security SecurityPolicy `json:"security"`
+	}
+	if strings.TrimSpace(o.Settings) != "" {
+		if err := json.Unmarshal([]byte(o.Settings), &settings); err != nil {
+			return nil, fmt.Errorf("invalid organization settings: %w", err)
+		}
+	}
+	return &settings.Security, nil
+}
+
+// SetSecurityPolicy replaces the security section of the organization's settings,
+// leaving any other keys untouched
+func (o *Organization) SetSecurityPolicy(policy *SecurityPolicy, now time.Time) error {
+	if policy.MFAEnrollmentGraceDays < 0 {
+		return fmt.Errorf("mfa_enrollment_grace_days must not be negative")
+	}
+	if policy.PasswordHistoryCount < 0 || policy.PasswordHistoryCount > MaxPasswordHistory {
+		return fmt.Errorf("password_history_count must be between 0 and %d", MaxPasswordHistory)
+	}
+	if policy.PasswordMaxAgeDays < 0 {
+		return fmt.Errorf("password_max_age_days must not be negative")
+	}
+
+	settings := map[string]json.RawMessage{}
+	if strings.TrimSpace(o.Settings) != "" {
+		if err := json.Unmarshal([]byte(o.Settings), &settings); err != nil {
+			return fmt.Errorf("invalid organization settings: %w", err)
+		}
+	}
+
+	current, err := o.SecurityPolicy()
+	if err != nil {
+		return err
+	}
+	updated := *policy
+	switch {
+	case !updated.enforcesMFA():
+		updated.MFAEnforcedSince = nil
+	case current.enforcesMFA() && current.MFAEnforcedSince != nil:
+		updated.MFAEnforcedSince = current.MFAEnforcedSince
+	default:
+		updated.MFAEnforcedSince = &now
+	}
+
+	raw, err := json.Marshal(&updated)
+	if err != nil {
+		return err
+	}
+	settings["security"] = raw
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	o.Settings = string(encoded)
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordHistory keeps the hash of a password a user has replaced, so organizations can
+// forbid switching back to a recent one. Only the newest entries are kept.
+type PasswordHistory struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+	CreatedAt    time.Time `json:"created_at"` // When the password was replaced
+}
+
+// TableName specifies the table name for PasswordHistory
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
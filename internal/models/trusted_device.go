@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrustedDevice is a browser or app a user has signed in from, recognised by a long-lived
+// device cookie. Only an HMAC of the cookie's secret is stored. While TrustedUntil is in the
+// future the device stands in for the second factor at login.
+type TrustedDevice struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"-"`
+	TokenHash    string     `gorm:"type:varchar(80);not null;uniqueIndex" json:"-"`
+	Name         string     `gorm:"type:varchar(100)" json:"name"` // Defaults to a summary of the user agent
+	UserAgent    string     `gorm:"type:text" json:"user_agent"`
+	LastIP       string     `gorm:"type:varchar(45)" json:"last_ip"`
+	LastSeenAt   time.Time  `gorm:"not null" json:"last_seen_at"`
+	TrustedUntil *time.Time `json:"trusted_until,omitempty"` // MFA is skipped on this device until then
+	RevokedAt    *time.Time `gorm:"index" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Current marks the device the listing request came from; not stored
+	Current bool `gorm:"-" json:"current"`
+}
+
+// TableName specifies the table name for TrustedDevice
+func (TrustedDevice) TableName() string {
+	return "trusted_devices"
+}
+
+// IsTrusted reports whether the device may skip MFA at now
+func (d *TrustedDevice) IsTrusted(now time.Time) bool {
+	return d.RevokedAt == nil && d.TrustedUntil != nil && now.Before(*d.TrustedUntil)
+}
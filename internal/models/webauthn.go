@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WebAuthnCredential is a passkey registered to a user. The public key is the COSE_Key
+// the authenticator returned; the private key never leaves the authenticator.
+type WebAuthnCredential struct {
+	ID             uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	CredentialID   string         `gorm:"type:varchar(1400);not null;uniqueIndex" json:"credential_id"` // base64url
+	PublicKey      []byte         `gorm:"type:bytea;not null" json:"-"`
+	Algorithm      int64          `gorm:"not null" json:"algorithm"`                    // COSE algorithm identifier
+	SignCount      int64          `gorm:"default:0" json:"-"`                           // Last signature counter seen; detects cloned authenticators
+	Transports     pq.StringArray `gorm:"type:text[]" json:"transports,omitempty"`      // Hints for the browser, e.g. "internal", "usb"
+	AAGUID         string         `gorm:"column:aaguid;type:varchar(36)" json:"aaguid"` // Authenticator model, unverified (no attestation)
+	Name           string         `gorm:"type:varchar(100)" json:"name"`
+	BackupEligible bool           `gorm:"default:false" json:"backup_eligible"` // Synced passkey
+	BackedUp       bool           `gorm:"default:false" json:"backed_up"`
+	LastUsedAt     *time.Time     `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TableName specifies the table name for WebAuthnCredential
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
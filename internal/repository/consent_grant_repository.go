@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrConsentGrantNotFound is returned when the user has no grant for the client
+var ErrConsentGrantNotFound = errors.New("consent grant not found")
+
+// ConsentGrantRepository defines methods for OAuth consent grant data access
+type ConsentGrantRepository interface {
+	Get(ctx context.Context, userID uuid.UUID, clientID string, orgID uuid.UUID) (*models.OAuthConsentGrant, error)
+	Upsert(ctx context.Context, grant *models.OAuthConsentGrant) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.OAuthConsentGrant, error)
+	DeleteByUserAndClient(ctx context.Context, userID uuid.UUID, clientID string) error
+}
+
+type consentGrantRepository struct {
+	db *gorm.DB
+}
+
+// NewConsentGrantRepository creates a new ConsentGrantRepository
+func NewConsentGrantRepository(db *gorm.DB) ConsentGrantRepository {
+	return &consentGrantRepository{db: db}
+}
+
+func (r *consentGrantRepository) Get(ctx context.Context, userID uuid.UUID, clientID string, orgID uuid.UUID) (*models.OAuthConsentGrant, error) {
+	var grant models.OAuthConsentGrant
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ? AND organization_id = ?", userID, clientID, orgID).
+		First(&grant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConsentGrantNotFound
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Upsert stores the grant, replacing the scopes of an existing grant for the same user, client and org
+func (r *consentGrantRepository) Upsert(ctx context.Context, grant *models.OAuthConsentGrant) error {
+	grant.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}, {Name: "organization_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"scopes", "updated_at"}),
+		}).
+		Create(grant).Error
+}
+
+func (r *consentGrantRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.OAuthConsentGrant, error) {
+	var grants []*models.OAuthConsentGrant
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Find(&grants).Error
+	return grants, err
+}
+
+// DeleteByUserAndClient removes the user's grants for the client in every organization
+func (r *consentGrantRepository) DeleteByUserAndClient(ctx context.Context, userID uuid.UUID, clientID string) error {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", userID, clientID).
+		Delete(&models.OAuthConsentGrant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConsentGrantNotFound
+	}
+	return nil
+}
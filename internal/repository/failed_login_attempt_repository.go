@@ -48,6 +48,16 @@ func (r *failedLoginAttemptRepository) CountByEmailAndIP(ctx context.Context, em
 	return count, err
 }
 
+// CountByEmailSince counts failed login attempts for an email from any address within a time window
+func (r *failedLoginAttemptRepository) CountByEmailSince(ctx context.Context, email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&models.FailedLoginAttempt{}).
+		Where("email = ? AND attempted_at >= ?", email, since).
+		Count(&count).Error
+	return count, err
+}
+
 // DeleteExpired deletes expired failed login attempts
 func (r *failedLoginAttemptRepository) DeleteExpired(ctx context.Context, maxAge time.Duration) error {
 	return r.db.WithContext(ctx).
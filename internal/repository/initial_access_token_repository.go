@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InitialAccessTokenRepository defines methods for dynamic registration initial access tokens
+type InitialAccessTokenRepository interface {
+	Create(ctx context.Context, token *models.InitialAccessToken) error
+	GetActiveByTokenHash(ctx context.Context, tokenHashes []string) (*models.InitialAccessToken, error)
+	ListByOrganization(ctx context.Context, orgID uuid.UUID) ([]*models.InitialAccessToken, error)
+	Consume(ctx context.Context, id uuid.UUID) error
+	Revoke(ctx context.Context, orgID, id uuid.UUID) error
+}
+
+type initialAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewInitialAccessTokenRepository creates a new InitialAccessTokenRepository
+func NewInitialAccessTokenRepository(db *gorm.DB) InitialAccessTokenRepository {
+	return &initialAccessTokenRepository{db: db}
+}
+
+func (r *initialAccessTokenRepository) Create(ctx context.Context, token *models.InitialAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// GetActiveByTokenHash returns the token stored under any of tokenHashes (one per HMAC key
+// version) if it is not revoked, expired or used up
+func (r *initialAccessTokenRepository) GetActiveByTokenHash(ctx context.Context, tokenHashes []string) (*models.InitialAccessToken, error) {
+	var token models.InitialAccessToken
+	err := r.db.WithContext(ctx).
+		Where("token_hash IN ? AND revoked = false AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR use_count < max_uses)", tokenHashes, time.Now()).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("initial access token not found, revoked, expired or used up")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *initialAccessTokenRepository) ListByOrganization(ctx context.Context, orgID uuid.UUID) ([]*models.InitialAccessToken, error) {
+	var tokens []*models.InitialAccessToken
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ?", orgID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
+// Consume counts one registration against the token, failing once max_uses is reached
+func (r *initialAccessTokenRepository) Consume(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.InitialAccessToken{}).
+		Where("id = ? AND revoked = false AND (max_uses = 0 OR use_count < max_uses)", id).
+		Update("use_count", gorm.Expr("use_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("initial access token is no longer valid")
+	}
+	return nil
+}
+
+func (r *initialAccessTokenRepository) Revoke(ctx context.Context, orgID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.InitialAccessToken{}).
+		Where("id = ? AND organization_id = ?", id, orgID).
+		Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("initial access token not found")
+	}
+	return nil
+}
@@ -18,6 +18,8 @@ type UserRepository interface {
 	Count(ctx context.Context) (int64, error)
 	UpdateLastLogin(ctx context.Context, id string) error
 	UpdatePassword(ctx context.Context, id, hashedPassword string) error
+	MarkPasswordCompromised(ctx context.Context, id string) error
+	UpdatePasswordHash(ctx context.Context, id, hashedPassword string) error
 	Activate(ctx context.Context, id string) error
 	Deactivate(ctx context.Context, id string) error
 }
@@ -28,8 +30,11 @@ type UserSessionRepository interface {
 	GetByID(ctx context.Context, id string) (*models.UserSession, error)
 	GetByToken(ctx context.Context, token string) (*models.UserSession, error)
 	GetByUserID(ctx context.Context, userID string) ([]*models.UserSession, error)
+	// GetRecentByUserID returns the user's latest sessions, active or not, newest first
+	GetRecentByUserID(ctx context.Context, userID string, limit int) ([]*models.UserSession, error)
 	GetByUserAndTenant(ctx context.Context, userID, tenantID string) ([]*models.UserSession, error)
 	GetActiveByUserID(ctx context.Context, userID string) ([]*models.UserSession, error)
+	GetActiveByDeviceID(ctx context.Context, deviceID string) ([]*models.UserSession, error)
 	GetActiveCountByUserID(ctx context.Context, userID string) (int64, error)
 	UpdateActivity(ctx context.Context, id string) error
 	Revoke(ctx context.Context, id string, reason string) error
@@ -61,7 +66,7 @@ type RefreshTokenRepository interface {
 // PasswordResetRepository defines the interface for password reset data operations
 type PasswordResetRepository interface {
 	Create(ctx context.Context, reset *models.PasswordReset) error
-	GetByToken(ctx context.Context, token string) (*models.PasswordReset, error)
+	GetByToken(ctx context.Context, tokenHash string) (*models.PasswordReset, error)
 	GetByEmail(ctx context.Context, email string) (*models.PasswordReset, error)
 	Update(ctx context.Context, reset *models.PasswordReset) error
 	Delete(ctx context.Context, token string) error
@@ -75,6 +80,7 @@ type FailedLoginAttemptRepository interface {
 	Create(ctx context.Context, attempt *models.FailedLoginAttempt) error
 	GetByEmailAndIP(ctx context.Context, email, tenantID, ipAddress string, since time.Time) ([]*models.FailedLoginAttempt, error)
 	CountByEmailAndIP(ctx context.Context, email, tenantID, ipAddress string, since time.Time) (int64, error)
+	CountByEmailSince(ctx context.Context, email string, since time.Time) (int64, error)
 	DeleteExpired(ctx context.Context, maxAge time.Duration) error
 	CleanupExpired(ctx context.Context, maxAge time.Duration) error
 }
@@ -133,6 +139,12 @@ type Repository interface {
 	AuthorizationCode() AuthorizationCodeRepository
 	OAuthRefreshToken() OAuthRefreshTokenRepository
 	APIKey() APIKeyRepository
+	InitialAccessToken() InitialAccessTokenRepository
+	ConsentGrant() ConsentGrantRepository
+	MFA() MFARepository
+	WebAuthnCredential() WebAuthnCredentialRepository
+	TrustedDevice() TrustedDeviceRepository
+	PasswordHistory() PasswordHistoryRepository
 	CreateDefaultAdminRole(ctx context.Context, orgID, createdBy string) (*models.Role, error)
 	BeginTransaction(ctx context.Context) (Transaction, error)
 }
@@ -156,4 +168,10 @@ type Transaction interface {
 	AuthorizationCode() AuthorizationCodeRepository
 	OAuthRefreshToken() OAuthRefreshTokenRepository
 	APIKey() APIKeyRepository
+	InitialAccessToken() InitialAccessTokenRepository
+	ConsentGrant() ConsentGrantRepository
+	MFA() MFARepository
+	WebAuthnCredential() WebAuthnCredentialRepository
+	TrustedDevice() TrustedDeviceRepository
+	PasswordHistory() PasswordHistoryRepository
 }
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMFANotConfigured     = errors.New("mfa not configured")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrTOTPStepAlreadyUsed  = errors.New("code already used")
+)
+
+// MFARepository defines methods for TOTP authenticator and recovery code data access
+type MFARepository interface {
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error)
+	// Save creates the user's authenticator or replaces an unconfirmed one
+	Save(ctx context.Context, mfa *models.UserMFA) error
+	Enable(ctx context.Context, userID uuid.UUID) error
+	// AdvanceStep records step as used; it fails when step is not newer than the last accepted one
+	AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) error
+	// Delete removes the authenticator together with its recovery codes
+	Delete(ctx context.Context, userID uuid.UUID) error
+
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFARepository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&mfa).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotConfigured
+		}
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepository) Save(ctx context.Context, mfa *models.UserMFA) error {
+	return r.db.WithContext(ctx).Save(mfa).Error
+}
+
+func (r *mfaRepository) Enable(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.UserMFA{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"enabled": true, "enabled_at": now, "updated_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMFANotConfigured
+	}
+	return nil
+}
+
+func (r *mfaRepository) AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	result := r.db.WithContext(ctx).Model(&models.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTOTPStepAlreadyUsed
+	}
+	return nil
+}
+
+func (r *mfaRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("user_id = ?", userID).Delete(&models.UserMFA{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFANotConfigured
+		}
+		return nil
+	})
+}
+
+// ReplaceRecoveryCodes invalidates every earlier code of the user
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]*models.MFARecoveryCode, len(codeHashes))
+		for i, codeHash := range codeHashes {
+			codes[i] = &models.MFARecoveryCode{UserID: userID, CodeHash: codeHash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	result := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash IN ? AND used_at IS NULL", userID, codeHashes).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+func (r *mfaRepository) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
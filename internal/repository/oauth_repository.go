@@ -12,12 +12,20 @@ import (
 	"gorm.io/gorm/clause"
 )
 
+// ErrOAuthRefreshTokenNotFound is returned when no usable OAuth refresh token matches
+var ErrOAuthRefreshTokenNotFound = errors.New("refresh token not found, revoked, already used, or expired")
+
 // AuthorizationCodeRepository defines methods for authorization code data access
 type AuthorizationCodeRepository interface {
 	Create(ctx context.Context, code *models.AuthorizationCode) error
-	GetByCodeHash(ctx context.Context, codeHash string) (*models.AuthorizationCode, error)
+	// GetByCodeHash returns the unused code stored under any of codeHashes, one per HMAC key version
+	GetByCodeHash(ctx context.Context, codeHashes []string) (*models.AuthorizationCode, error)
 	MarkAsUsed(ctx context.Context, codeHash string) error
 	DeleteExpired(ctx context.Context) error
+	// ListForRehash pages (by ID) through unused codes whose hash lacks currentPrefix
+	ListForRehash(ctx context.Context, currentPrefix string, afterID uuid.UUID, limit int) ([]*models.AuthorizationCode, error)
+	// UpdateCodeHash replaces the hash if it is still oldHash
+	UpdateCodeHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error
 }
 
 type authorizationCodeRepository struct {
@@ -33,9 +41,9 @@ func (r *authorizationCodeRepository) Create(ctx context.Context, code *models.A
 	return r.db.WithContext(ctx).Create(code).Error
 }
 
-func (r *authorizationCodeRepository) GetByCodeHash(ctx context.Context, codeHash string) (*models.AuthorizationCode, error) {
+func (r *authorizationCodeRepository) GetByCodeHash(ctx context.Context, codeHashes []string) (*models.AuthorizationCode, error) {
 	var authCode models.AuthorizationCode
-	err := r.db.WithContext(ctx).Where("code_hash = ? AND used = false AND expires_at > ?", codeHash, time.Now()).First(&authCode).Error
+	err := r.db.WithContext(ctx).Where("code_hash IN ? AND used = false AND expires_at > ?", codeHashes, time.Now()).First(&authCode).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("authorization code not found or expired")
@@ -45,10 +53,18 @@ func (r *authorizationCodeRepository) GetByCodeHash(ctx context.Context, codeHas
 	return &authCode, nil
 }
 
+// MarkAsUsed fails when the code was already used, so only one exchange can win
 func (r *authorizationCodeRepository) MarkAsUsed(ctx context.Context, codeHash string) error {
-	return r.db.WithContext(ctx).Model(&models.AuthorizationCode{}).
-		Where("code_hash = ?", codeHash).
-		Update("used", true).Error
+	result := r.db.WithContext(ctx).Model(&models.AuthorizationCode{}).
+		Where("code_hash = ? AND used = false", codeHash).
+		Update("used", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("authorization code already used or not found")
+	}
+	return nil
 }
 
 func (r *authorizationCodeRepository) DeleteExpired(ctx context.Context) error {
@@ -57,16 +73,38 @@ func (r *authorizationCodeRepository) DeleteExpired(ctx context.Context) error {
 		Delete(&models.AuthorizationCode{}).Error
 }
 
+func (r *authorizationCodeRepository) ListForRehash(ctx context.Context, currentPrefix string, afterID uuid.UUID, limit int) ([]*models.AuthorizationCode, error) {
+	var codes []*models.AuthorizationCode
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND code_hash NOT LIKE ? AND used = false AND expires_at > ?", afterID, currentPrefix+"%", time.Now()).
+		Order("id").
+		Limit(limit).
+		Find(&codes).Error
+	return codes, err
+}
+
+func (r *authorizationCodeRepository) UpdateCodeHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	return r.db.WithContext(ctx).Model(&models.AuthorizationCode{}).
+		Where("id = ? AND code_hash = ?", id, oldHash).
+		Update("code_hash", newHash).Error
+}
+
 // OAuthRefreshTokenRepository defines methods for OAuth refresh token data access
 type OAuthRefreshTokenRepository interface {
 	Create(ctx context.Context, token *models.OAuthRefreshToken) error
-	GetByTokenHash(ctx context.Context, tokenHash string) (*models.OAuthRefreshToken, error)
+	// GetByTokenHash returns the usable token stored under any of tokenHashes, one per HMAC key version
+	GetByTokenHash(ctx context.Context, tokenHashes []string) (*models.OAuthRefreshToken, error)
 	GetByUserAndClient(ctx context.Context, userID uuid.UUID, clientID string) ([]*models.OAuthRefreshToken, error)
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*models.OAuthRefreshToken, error)
 	Revoke(ctx context.Context, tokenHash string) error
 	RevokeAllForUser(ctx context.Context, userID uuid.UUID, clientID string) error
 	RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error
 	DeleteExpired(ctx context.Context) error
 	MarkAsUsed(ctx context.Context, tokenHash string, replacedByID uuid.UUID) error
+	// ListForRehash pages (by ID) through usable tokens whose hash lacks currentPrefix
+	ListForRehash(ctx context.Context, currentPrefix string, afterID uuid.UUID, limit int) ([]*models.OAuthRefreshToken, error)
+	// UpdateTokenHash replaces the hash if it is still oldHash
+	UpdateTokenHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error
 }
 
 type oauthRefreshTokenRepository struct {
@@ -82,15 +120,15 @@ func (r *oauthRefreshTokenRepository) Create(ctx context.Context, token *models.
 	return r.db.WithContext(ctx).Create(token).Error
 }
 
-func (r *oauthRefreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.OAuthRefreshToken, error) {
+func (r *oauthRefreshTokenRepository) GetByTokenHash(ctx context.Context, tokenHashes []string) (*models.OAuthRefreshToken, error) {
 	var token models.OAuthRefreshToken
 	err := r.db.WithContext(ctx).
 		Clauses(clause.Locking{Strength: "UPDATE"}).
-		Where("token_hash = ? AND revoked = false AND used_at IS NULL AND expires_at > ?", tokenHash, time.Now()).
+		Where("token_hash IN ? AND revoked = false AND used_at IS NULL AND expires_at > ?", tokenHashes, time.Now()).
 		First(&token).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("refresh token not found, revoked, already used, or expired")
+			return nil, ErrOAuthRefreshTokenNotFound
 		}
 		return nil, err
 	}
@@ -105,6 +143,17 @@ func (r *oauthRefreshTokenRepository) GetByUserAndClient(ctx context.Context, us
 	return tokens, err
 }
 
+// ListActiveByUser returns the user's usable refresh tokens. Rotation leaves exactly one
+// usable token per family, so each result stands for one live token family.
+func (r *oauthRefreshTokenRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]*models.OAuthRefreshToken, error) {
+	var tokens []*models.OAuthRefreshToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked = false AND used_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	return tokens, err
+}
+
 func (r *oauthRefreshTokenRepository) Revoke(ctx context.Context, tokenHash string) error {
 	return r.db.WithContext(ctx).Model(&models.OAuthRefreshToken{}).
 		Where("token_hash = ?", tokenHash).
@@ -149,3 +198,19 @@ func (r *oauthRefreshTokenRepository) MarkAsUsed(ctx context.Context, tokenHash
 
 	return nil
 }
+
+func (r *oauthRefreshTokenRepository) ListForRehash(ctx context.Context, currentPrefix string, afterID uuid.UUID, limit int) ([]*models.OAuthRefreshToken, error) {
+	var tokens []*models.OAuthRefreshToken
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND token_hash NOT LIKE ? AND revoked = false AND used_at IS NULL AND expires_at > ?", afterID, currentPrefix+"%", time.Now()).
+		Order("id").
+		Limit(limit).
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *oauthRefreshTokenRepository) UpdateTokenHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	return r.db.WithContext(ctx).Model(&models.OAuthRefreshToken{}).
+		Where("id = ? AND token_hash = ?", id, oldHash).
+		Update("token_hash", newHash).Error
+}
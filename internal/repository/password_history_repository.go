@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PasswordHistoryRepository defines methods for password history data access
+type PasswordHistoryRepository interface {
+	Create(ctx context.Context, entry *models.PasswordHistory) error
+	// ListRecent returns the user's most recently replaced passwords, newest first
+	ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]*models.PasswordHistory, error)
+	// Prune deletes all but the user's newest keep entries
+	Prune(ctx context.Context, userID uuid.UUID, keep int) error
+}
+
+type passwordHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordHistoryRepository creates a new PasswordHistoryRepository
+func NewPasswordHistoryRepository(db *gorm.DB) PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: db}
+}
+
+func (r *passwordHistoryRepository) Create(ctx context.Context, entry *models.PasswordHistory) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *passwordHistoryRepository) ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]*models.PasswordHistory, error) {
+	var entries []*models.PasswordHistory
+	if limit <= 0 {
+		return entries, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *passwordHistoryRepository) Prune(ctx context.Context, userID uuid.UUID, keep int) error {
+	newest := r.db.Model(&models.PasswordHistory{}).
+		Select("id").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(keep)
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND id NOT IN (?)", userID, newest).
+		Delete(&models.PasswordHistory{}).Error
+}
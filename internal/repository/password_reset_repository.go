@@ -33,14 +33,14 @@ func (r *passwordResetRepository) Create(ctx context.Context, reset *models.Pass
 	return r.db.WithContext(ctx).Create(reset).Error
 }
 
-// GetByToken retrieves a password reset by token
-func (r *passwordResetRepository) GetByToken(ctx context.Context, token string) (*models.PasswordReset, error) {
-	if token == "" {
+// GetByToken retrieves a password reset by the SHA256 hash of its token
+func (r *passwordResetRepository) GetByToken(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	if tokenHash == "" {
 		return nil, errors.New("token is required")
 	}
 
 	var reset models.PasswordReset
-	err := r.db.WithContext(ctx).Where("token = ?", token).First(&reset).Error
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&reset).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrPasswordResetNotFound
 	}
@@ -27,6 +27,12 @@ type repository struct {
 	authCodeRepo      AuthorizationCodeRepository
 	oauthRefreshRepo  OAuthRefreshTokenRepository
 	apiKeyRepo        APIKeyRepository
+	initialTokenRepo  InitialAccessTokenRepository
+	consentGrantRepo  ConsentGrantRepository
+	mfaRepo           MFARepository
+	passkeyRepo       WebAuthnCredentialRepository
+	trustedDeviceRepo TrustedDeviceRepository
+	pwHistoryRepo     PasswordHistoryRepository
 }
 
 // NewRepository creates a new repository instance
@@ -48,6 +54,12 @@ func NewRepository(db *gorm.DB) Repository {
 		authCodeRepo:      NewAuthorizationCodeRepository(db),
 		oauthRefreshRepo:  NewOAuthRefreshTokenRepository(db),
 		apiKeyRepo:        NewAPIKeyRepository(db),
+		initialTokenRepo:  NewInitialAccessTokenRepository(db),
+		consentGrantRepo:  NewConsentGrantRepository(db),
+		mfaRepo:           NewMFARepository(db),
+		passkeyRepo:       NewWebAuthnCredentialRepository(db),
+		trustedDeviceRepo: NewTrustedDeviceRepository(db),
+		pwHistoryRepo:     NewPasswordHistoryRepository(db),
 	}
 }
 
@@ -126,6 +138,36 @@ func (r *repository) APIKey() APIKeyRepository {
 	return r.apiKeyRepo
 }
 
+// InitialAccessToken returns the initial access token repository
+func (r *repository) InitialAccessToken() InitialAccessTokenRepository {
+	return r.initialTokenRepo
+}
+
+// ConsentGrant returns the OAuth consent grant repository
+func (r *repository) ConsentGrant() ConsentGrantRepository {
+	return r.consentGrantRepo
+}
+
+// MFA returns the MFA repository
+func (r *repository) MFA() MFARepository {
+	return r.mfaRepo
+}
+
+// WebAuthnCredential returns the WebAuthn credential repository
+func (r *repository) WebAuthnCredential() WebAuthnCredentialRepository {
+	return r.passkeyRepo
+}
+
+// TrustedDevice returns the trusted device repository
+func (r *repository) TrustedDevice() TrustedDeviceRepository {
+	return r.trustedDeviceRepo
+}
+
+// PasswordHistory returns the password history repository
+func (r *repository) PasswordHistory() PasswordHistoryRepository {
+	return r.pwHistoryRepo
+}
+
 // CreateDefaultAdminRole finds the system OWNER role and returns it
 // System roles are global (is_system=true, organization_id=NULL) and reused across all organizations
 // User membership with this role is created at the service layer via AssignRoleToUser
@@ -170,6 +212,12 @@ func (r *repository) BeginTransaction(ctx context.Context) (Transaction, error)
 		authCodeRepo:      NewAuthorizationCodeRepository(tx),
 		oauthRefreshRepo:  NewOAuthRefreshTokenRepository(tx),
 		apiKeyRepo:        NewAPIKeyRepository(tx),
+		initialTokenRepo:  NewInitialAccessTokenRepository(tx),
+		consentGrantRepo:  NewConsentGrantRepository(tx),
+		mfaRepo:           NewMFARepository(tx),
+		passkeyRepo:       NewWebAuthnCredentialRepository(tx),
+		trustedDeviceRepo: NewTrustedDeviceRepository(tx),
+		pwHistoryRepo:     NewPasswordHistoryRepository(tx),
 	}, nil
 }
 
@@ -191,6 +239,12 @@ type transaction struct {
 	authCodeRepo      AuthorizationCodeRepository
 	oauthRefreshRepo  OAuthRefreshTokenRepository
 	apiKeyRepo        APIKeyRepository
+	initialTokenRepo  InitialAccessTokenRepository
+	consentGrantRepo  ConsentGrantRepository
+	mfaRepo           MFARepository
+	passkeyRepo       WebAuthnCredentialRepository
+	trustedDeviceRepo TrustedDeviceRepository
+	pwHistoryRepo     PasswordHistoryRepository
 }
 
 // Commit commits the transaction
@@ -278,6 +332,36 @@ func (t *transaction) APIKey() APIKeyRepository {
 	return t.apiKeyRepo
 }
 
+// InitialAccessToken returns the initial access token repository for transaction
+func (t *transaction) InitialAccessToken() InitialAccessTokenRepository {
+	return t.initialTokenRepo
+}
+
+// ConsentGrant returns the OAuth consent grant repository for transaction
+func (t *transaction) ConsentGrant() ConsentGrantRepository {
+	return t.consentGrantRepo
+}
+
+// MFA returns the MFA repository for transaction
+func (t *transaction) MFA() MFARepository {
+	return t.mfaRepo
+}
+
+// WebAuthnCredential returns the WebAuthn credential repository for transaction
+func (t *transaction) WebAuthnCredential() WebAuthnCredentialRepository {
+	return t.passkeyRepo
+}
+
+// TrustedDevice returns the trusted device repository for transaction
+func (t *transaction) TrustedDevice() TrustedDeviceRepository {
+	return t.trustedDeviceRepo
+}
+
+// PasswordHistory returns the password history repository for transaction
+func (t *transaction) PasswordHistory() PasswordHistoryRepository {
+	return t.pwHistoryRepo
+}
+
 // Migrate runs database migrations
 func Migrate(db *gorm.DB) error {
 	// Auto migrate all models
@@ -290,14 +374,21 @@ func Migrate(db *gorm.DB) error {
 		&models.RefreshToken{},
 		&models.PasswordReset{},
 		&models.FailedLoginAttempt{},
-		&models.Permission{},        // Global system permissions
-		&models.Role{},              // Organization-specific roles
-		&models.RolePermission{},    // Role-Permission many-to-many
-		&models.ClientApp{},         // OAuth2 client applications
-		&models.AuthorizationCode{}, // OAuth2 authorization codes
-		&models.OAuthRefreshToken{}, // OAuth2 refresh tokens
-		&models.APIKey{},            // API keys for programmatic access
-		&models.AuditLog{},          // Audit trail for security events
+		&models.Permission{},         // Global system permissions
+		&models.Role{},               // Organization-specific roles
+		&models.RolePermission{},     // Role-Permission many-to-many
+		&models.ClientApp{},          // OAuth2 client applications
+		&models.AuthorizationCode{},  // OAuth2 authorization codes
+		&models.OAuthRefreshToken{},  // OAuth2 refresh tokens
+		&models.APIKey{},             // API keys for programmatic access
+		&models.AuditLog{},           // Audit trail for security events
+		&models.InitialAccessToken{}, // Org-issued tokens for dynamic client registration
+		&models.OAuthConsentGrant{},  // Scopes users have approved per client and org
+		&models.UserMFA{},            // TOTP authenticators
+		&models.MFARecoveryCode{},    // Hashed single-use recovery codes
+		&models.WebAuthnCredential{}, // Passkeys
+		&models.TrustedDevice{},      // Devices recognised by the device cookie
+		&models.PasswordHistory{},
 	); err != nil {
 		return err
 	}
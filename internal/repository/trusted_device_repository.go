@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrTrustedDeviceNotFound = errors.New("trusted device not found")
+
+// TrustedDeviceRepository defines methods for device registry data access
+type TrustedDeviceRepository interface {
+	Create(ctx context.Context, device *models.TrustedDevice) error
+	// GetByTokenHash returns the non-revoked device whose cookie hashes to any of tokenHashes
+	GetByTokenHash(ctx context.Context, tokenHashes []string) (*models.TrustedDevice, error)
+	// ListByUser returns the user's non-revoked devices, most recently seen first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.TrustedDevice, error)
+	// Touch records a sign-in from the device
+	Touch(ctx context.Context, id uuid.UUID, ip, userAgent string, seenAt time.Time) error
+	// Trust lets the device skip MFA until the given time
+	Trust(ctx context.Context, id uuid.UUID, until time.Time) error
+	// Revoke forgets one of the user's devices
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type trustedDeviceRepository struct {
+	db *gorm.DB
+}
+
+// NewTrustedDeviceRepository creates a new TrustedDeviceRepository
+func NewTrustedDeviceRepository(db *gorm.DB) TrustedDeviceRepository {
+	return &trustedDeviceRepository{db: db}
+}
+
+func (r *trustedDeviceRepository) Create(ctx context.Context, device *models.TrustedDevice) error {
+	return r.db.WithContext(ctx).Create(device).Error
+}
+
+func (r *trustedDeviceRepository) GetByTokenHash(ctx context.Context, tokenHashes []string) (*models.TrustedDevice, error) {
+	var device models.TrustedDevice
+	err := r.db.WithContext(ctx).
+		Where("token_hash IN ? AND revoked_at IS NULL", tokenHashes).
+		First(&device).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTrustedDeviceNotFound
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *trustedDeviceRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.TrustedDevice, error) {
+	var devices []*models.TrustedDevice
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+func (r *trustedDeviceRepository) Touch(ctx context.Context, id uuid.UUID, ip, userAgent string, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.TrustedDevice{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_ip":      ip,
+			"user_agent":   userAgent,
+			"last_seen_at": seenAt,
+			"updated_at":   seenAt,
+		}).Error
+}
+
+func (r *trustedDeviceRepository) Trust(ctx context.Context, id uuid.UUID, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.TrustedDevice{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"trusted_until": until,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+func (r *trustedDeviceRepository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.TrustedDevice{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"trusted_until": nil,
+			"updated_at":    now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTrustedDeviceNotFound
+	}
+	return nil
+}
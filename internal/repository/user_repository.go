@@ -163,12 +163,35 @@ func (r *userRepository) UpdateLastLogin(ctx context.Context, id string) error {
 	return nil
 }
 
-// UpdatePassword updates the user's password
+// UpdatePassword updates the user's password, restarting its age and clearing any breach
+// flag on the old one
 func (r *userRepository) UpdatePassword(ctx context.Context, id, hashedPassword string) error {
 	if id == "" || hashedPassword == "" {
 		return errors.New("user ID and password are required")
 	}
 
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"password_hash":           hashedPassword,
+			"password_changed_at":     time.Now(),
+			"password_compromised_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdatePasswordHash stores a new hash of the user's unchanged password, e.g. after
+// upgrading its algorithm, without touching its age
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, id, hashedPassword string) error {
+	if id == "" || hashedPassword == "" {
+		return errors.New("user ID and password are required")
+	}
+
 	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password_hash", hashedPassword)
 	if result.Error != nil {
 		return result.Error
@@ -179,6 +202,18 @@ func (r *userRepository) UpdatePassword(ctx context.Context, id, hashedPassword
 	return nil
 }
 
+// MarkPasswordCompromised flags the user's current password as leaked
+func (r *userRepository) MarkPasswordCompromised(ctx context.Context, id string) error {
+	if id == "" {
+		return ErrInvalidUserID
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND password_compromised_at IS NULL", id).
+		Update("password_compromised_at", time.Now())
+	return result.Error
+}
+
 // Activate activates a user account
 func (r *userRepository) Activate(ctx context.Context, id string) error {
 	if id == "" {
@@ -60,6 +60,17 @@ func (r *userSessionRepository) GetByUserID(ctx context.Context, userID string)
 	return sessions, err
 }
 
+// GetRecentByUserID retrieves the latest sessions for a user, including ended ones
+func (r *userSessionRepository) GetRecentByUserID(ctx context.Context, userID string, limit int) ([]*models.UserSession, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+
+	var sessions []*models.UserSession
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&sessions).Error
+	return sessions, err
+}
+
 // GetByUserAndTenant retrieves sessions scoped to a specific tenant
 func (r *userSessionRepository) GetByUserAndTenant(ctx context.Context, userID, tenantID string) ([]*models.UserSession, error) {
 	if userID == "" || tenantID == "" {
@@ -85,6 +96,17 @@ func (r *userSessionRepository) GetActiveByUserID(ctx context.Context, userID st
 	return sessions, err
 }
 
+// GetActiveByDeviceID retrieves active sessions started from a trusted device
+func (r *userSessionRepository) GetActiveByDeviceID(ctx context.Context, deviceID string) ([]*models.UserSession, error) {
+	if deviceID == "" {
+		return nil, errors.New("device ID is required")
+	}
+
+	var sessions []*models.UserSession
+	err := r.db.WithContext(ctx).Where("device_id = ? AND is_active = true", deviceID).Order("created_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
 // Update updates a session
 func (r *userSessionRepository) Update(ctx context.Context, session *models.UserSession) error {
 	if session == nil || session.ID == uuid.Nil {
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+	ErrSignCountNotIncreased      = errors.New("signature counter did not increase")
+)
+
+// WebAuthnCredentialRepository defines methods for passkey data access
+type WebAuthnCredentialRepository interface {
+	Create(ctx context.Context, cred *models.WebAuthnCredential) error
+	GetByCredentialID(ctx context.Context, credentialID string) (*models.WebAuthnCredential, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.WebAuthnCredential, error)
+	// RecordUse stores the counter of a verified assertion. It fails when another login
+	// raced ahead with the same or a newer counter.
+	RecordUse(ctx context.Context, id uuid.UUID, previousCount, signCount int64, backedUp bool) error
+	// Delete removes one of the user's credentials
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+type webAuthnCredentialRepository struct {
+	db *gorm.DB
+}
+
+// NewWebAuthnCredentialRepository creates a new WebAuthnCredentialRepository
+func NewWebAuthnCredentialRepository(db *gorm.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{db: db}
+}
+
+func (r *webAuthnCredentialRepository) Create(ctx context.Context, cred *models.WebAuthnCredential) error {
+	return r.db.WithContext(ctx).Create(cred).Error
+}
+
+func (r *webAuthnCredentialRepository) GetByCredentialID(ctx context.Context, credentialID string) (*models.WebAuthnCredential, error) {
+	var cred models.WebAuthnCredential
+	err := r.db.WithContext(ctx).Where("credential_id = ?", credentialID).First(&cred).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebAuthnCredentialNotFound
+		}
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (r *webAuthnCredentialRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*models.WebAuthnCredential, error) {
+	var creds []*models.WebAuthnCredential
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&creds).Error
+	return creds, err
+}
+
+func (r *webAuthnCredentialRepository) RecordUse(ctx context.Context, id uuid.UUID, previousCount, signCount int64, backedUp bool) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&models.WebAuthnCredential{}).
+		Where("id = ? AND sign_count = ?", id, previousCount).
+		Updates(map[string]interface{}{
+			"sign_count":   signCount,
+			"backed_up":    backedUp,
+			"last_used_at": now,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSignCountNotIncreased
+	}
+	return nil
+}
+
+func (r *webAuthnCredentialRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"auth-service/internal/models"
 	"auth-service/internal/repository"
+	"auth-service/pkg/geoip"
 	"auth-service/pkg/logger"
 )
 
@@ -63,6 +64,16 @@ func (s *auditService) createAuditLog(ctx context.Context, action, resource stri
 		}
 	}
 
+	// Place the request's address; the caller's details map is copied rather than modified
+	if location := geoip.Lookup(ipAddress); location != nil {
+		withLocation := make(map[string]interface{}, len(details)+1)
+		for k, v := range details {
+			withLocation[k] = v
+		}
+		withLocation["location"] = location
+		details = withLocation
+	}
+
 	// Create audit log entry
 	auditLog := &models.AuditLog{
 		Timestamp:      time.Now(),
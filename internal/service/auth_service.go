@@ -33,8 +33,14 @@ type TokenClaims struct {
 	GlobalRole       string   `json:"global_role"`
 	OrganizationRole string   `json:"organization_role"`
 	Permissions      []string `json:"permissions"` // Cached permission names
+	Scope            string   `json:"scope,omitempty"`
 	IsSuperadmin     bool     `json:"is_superadmin"`
 	CurrentOrgID     *string  `json:"current_org_id,omitempty"`
+	DPoPJKT          string   `json:"dpop_jkt,omitempty"` // Set when the token is bound to a DPoP key
+
+	AMR      []string   `json:"amr,omitempty"`
+	ACR      string     `json:"acr,omitempty"`
+	AuthTime *time.Time `json:"auth_time,omitempty"` // Nil for tokens issued before auth_time was recorded
 }
 
 // HealthCheckResponse represents health check response
@@ -91,6 +97,8 @@ func NewAuthService(repo repository.Repository, jwtService *jwt.Service, passwor
 
 	// Initialize revocation service
 	revocationSvc := NewRevocationService(repo, jwtService, redisClient)
+	userSvc.SetRevocationService(revocationSvc)
+	sessionSvc.SetRevocationService(revocationSvc)
 
 	return &authService{
 		userService:         userSvc,
@@ -124,6 +132,16 @@ func (s *authService) ValidateToken(ctx context.Context, token string) (*TokenCl
 	orgID := claims.OrganizationID.String()
 	currentOrgID = &orgID
 
+	var dpopJKT string
+	if claims.Cnf != nil {
+		dpopJKT = claims.Cnf.JKT
+	}
+
+	var authTime *time.Time
+	if claims.AuthTime != nil {
+		authTime = &claims.AuthTime.Time
+	}
+
 	return &TokenClaims{
 		UserID:           claims.UserID.String(),
 		Email:            claims.Email,
@@ -132,8 +150,13 @@ func (s *authService) ValidateToken(ctx context.Context, token string) (*TokenCl
 		GlobalRole:       claims.GlobalRole,
 		OrganizationRole: claims.OrganizationRole,
 		Permissions:      claims.Permissions,
+		Scope:            claims.Scope,
 		IsSuperadmin:     claims.IsSuperadmin,
 		CurrentOrgID:     currentOrgID,
+		DPoPJKT:          dpopJKT,
+		AMR:              claims.AMR,
+		ACR:              claims.ACR,
+		AuthTime:         authTime,
 	}, nil
 }
 
@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
 	"auth-service/pkg/logger"
+
+	"github.com/google/uuid"
 )
 
 // BackgroundJobService defines the interface for background job operations
@@ -17,6 +22,7 @@ type BackgroundJobService interface {
 	CleanupInactiveSessions(ctx context.Context) error
 	CleanupExpiredTokens(ctx context.Context) error
 	CleanupFailedAttempts(ctx context.Context) error
+	RehashLookupHashes(ctx context.Context) error
 }
 
 // BackgroundJobConfig holds configuration for background jobs
@@ -174,4 +180,68 @@ func (s *backgroundJobService) CleanupExpiredTokens(ctx context.Context) error {
 // CleanupFailedAttempts cleans up old failed login attempts
 func (s *backgroundJobService) CleanupFailedAttempts(ctx context.Context) error {
 	return s.repo.FailedLoginAttempt().DeleteExpired(ctx, s.config.MaxFailedAttemptAge)
-}
\ No newline at end of file
+}
+
+// rehashBatchSize bounds how many rows RehashLookupHashes loads at a time
+const rehashBatchSize = 500
+
+// RehashLookupHashes carries the stored hashes of live authorization codes and OAuth refresh
+// tokens forward to the active HMAC key version. Lookups try every loaded version, so this
+// only has to finish before an old key is retired; it is safe to run from several instances.
+func (s *backgroundJobService) RehashLookupHashes(ctx context.Context) error {
+	prefix, err := hashutil.ActiveHMACPrefix()
+	if err != nil {
+		return err
+	}
+	if prefix == "" {
+		// The original key is active: nothing can be older
+		return nil
+	}
+
+	codes, tokens := 0, 0
+	for after := uuid.Nil; ; {
+		batch, err := s.repo.AuthorizationCode().ListForRehash(ctx, prefix, after, rehashBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, code := range batch {
+			rehashed, changed, err := hashutil.RehashHMAC(code.CodeHash)
+			if err != nil || !changed {
+				continue
+			}
+			if err := s.repo.AuthorizationCode().UpdateCodeHash(ctx, code.ID, code.CodeHash, rehashed); err != nil {
+				return err
+			}
+			codes++
+		}
+		if len(batch) < rehashBatchSize {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+
+	for after := uuid.Nil; ; {
+		batch, err := s.repo.OAuthRefreshToken().ListForRehash(ctx, prefix, after, rehashBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, token := range batch {
+			rehashed, changed, err := hashutil.RehashHMAC(token.TokenHash)
+			if err != nil || !changed {
+				continue
+			}
+			if err := s.repo.OAuthRefreshToken().UpdateTokenHash(ctx, token.ID, token.TokenHash, rehashed); err != nil {
+				return err
+			}
+			tokens++
+		}
+		if len(batch) < rehashBatchSize {
+			break
+		}
+		after = batch[len(batch)-1].ID
+	}
+
+	s.logger.LogSystemEvent("system", "lookup_hashes_rehashed", "hmac_key", "", "", "", true, nil,
+		fmt.Sprintf("Rehashed %d authorization codes and %d refresh tokens under %s", codes, tokens, strings.TrimSuffix(prefix, "$")))
+	return nil
+}
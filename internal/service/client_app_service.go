@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -27,6 +28,30 @@ type ClientAppService interface {
 	RotateClientSecret(ctx context.Context, id uuid.UUID, rotatedBy *models.User) (string, error)
 	ValidateClientCredentials(ctx context.Context, clientID, clientSecret string) (*models.ClientApp, error)
 	ValidateRedirectURI(ctx context.Context, clientID, redirectURI string) error
+
+	// Dynamic client registration (RFC 7591/7592)
+	CreateInitialAccessToken(ctx context.Context, orgID uuid.UUID, req *CreateInitialAccessTokenRequest, createdBy uuid.UUID) (*models.InitialAccessToken, string, error)
+	ListInitialAccessTokens(ctx context.Context, orgID uuid.UUID) ([]*models.InitialAccessToken, error)
+	RevokeInitialAccessToken(ctx context.Context, orgID, id uuid.UUID) error
+	RegisterClient(ctx context.Context, initialAccessToken string, req *ClientRegistrationRequest) (*ClientRegistrationResponse, error)
+	GetClientRegistration(ctx context.Context, clientID, registrationToken string) (*ClientRegistrationResponse, error)
+	UpdateClientRegistration(ctx context.Context, clientID, registrationToken string, req *ClientRegistrationRequest) (*ClientRegistrationResponse, error)
+	DeleteClientRegistration(ctx context.Context, clientID, registrationToken string) error
+}
+
+// defaultClientScopes are granted to clients that do not ask for specific scopes
+var defaultClientScopes = []string{"email", "profile", "org.read", "org.write"}
+
+// checkPrivilegedScopes refuses to add the introspection scope to scopes unless
+// user is a superadmin. Scopes the client already had in current may stay.
+func checkPrivilegedScopes(scopes, current []string, user *models.User) error {
+	if user != nil && user.IsSuperadmin {
+		return nil
+	}
+	if containsString(scopes, ScopeTokenIntrospection) && !containsString(current, ScopeTokenIntrospection) {
+		return fmt.Errorf("%w: only a superadmin can grant the '%s' scope", ErrInvalidData, ScopeTokenIntrospection)
+	}
+	return nil
 }
 
 type clientAppService struct {
@@ -49,6 +74,13 @@ type CreateClientAppRequest struct {
 	AllowedOrigins []string `json:"allowed_origins" validate:"omitempty,dive,url"`
 	AllowedScopes  []string `json:"allowed_scopes" validate:"omitempty"`
 	IsConfidential bool     `json:"is_confidential"` // default true
+
+	// Optional JWT client authentication (RFC 7523); jwks/jwks_uri are for private_key_jwt
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method" validate:"omitempty"`
+	JWKS                    json.RawMessage `json:"jwks,omitempty" swaggertype:"object"`
+	JWKSURI                 string          `json:"jwks_uri,omitempty" validate:"omitempty,url"`
+
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests"`
 }
 
 // UpdateClientAppRequest represents request to update a client app
@@ -58,19 +90,31 @@ type UpdateClientAppRequest struct {
 	AllowedOrigins []string `json:"allowed_origins" validate:"omitempty,dive,url"`
 	AllowedScopes  []string `json:"allowed_scopes" validate:"omitempty"`
 	IsConfidential *bool    `json:"is_confidential" validate:"omitempty"`
+
+	// Changing the auth method replaces the stored keys with the ones in this request
+	TokenEndpointAuthMethod *string         `json:"token_endpoint_auth_method" validate:"omitempty"`
+	JWKS                    json.RawMessage `json:"jwks,omitempty" swaggertype:"object"`
+	JWKSURI                 *string         `json:"jwks_uri,omitempty" validate:"omitempty"`
+
+	RequirePushedAuthorizationRequests *bool `json:"require_pushed_authorization_requests"`
 }
 
 // ClientAppResponse represents a client app response (without secret)
 type ClientAppResponse struct {
-	ID             uuid.UUID `json:"id"`
-	Name           string    `json:"name"`
-	ClientID       string    `json:"client_id"`
-	RedirectURIs   []string  `json:"redirect_uris"`
-	AllowedOrigins []string  `json:"allowed_origins"`
-	AllowedScopes  []string  `json:"allowed_scopes"`
-	IsConfidential bool      `json:"is_confidential"`
-	CreatedAt      string    `json:"created_at"`
-	UpdatedAt      string    `json:"updated_at"`
+	ID                      uuid.UUID `json:"id"`
+	Name                    string    `json:"name"`
+	ClientID                string    `json:"client_id"`
+	RedirectURIs            []string  `json:"redirect_uris"`
+	AllowedOrigins          []string  `json:"allowed_origins"`
+	AllowedScopes           []string  `json:"allowed_scopes"`
+	IsConfidential          bool      `json:"is_confidential"`
+	TokenEndpointAuthMethod string    `json:"token_endpoint_auth_method,omitempty"`
+	JWKSURI                 string    `json:"jwks_uri,omitempty"`
+	HasJWKS                 bool      `json:"has_jwks"`
+	CreatedAt               string    `json:"created_at"`
+	UpdatedAt               string    `json:"updated_at"`
+
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests"`
 }
 
 func (s *clientAppService) CreateClientApp(ctx context.Context, organizationID uuid.UUID, req *CreateClientAppRequest, createdBy *models.User) (*ClientAppResponse, string, error) {
@@ -88,33 +132,44 @@ func (s *clientAppService) CreateClientApp(ctx context.Context, organizationID u
 		return nil, "", fmt.Errorf("failed to generate client ID: %w", err)
 	}
 
-	// Generate client secret
-	clientSecret, err := generateClientSecret()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate client secret: %w", err)
+	if req.TokenEndpointAuthMethod != "" {
+		if !req.IsConfidential {
+			return nil, "", fmt.Errorf("%w: token_endpoint_auth_method requires a confidential client", ErrInvalidData)
+		}
+		if err := validateClientAuthMethod(req.TokenEndpointAuthMethod, string(req.JWKS), req.JWKSURI); err != nil {
+			return nil, "", err
+		}
 	}
 
-	// Hash client secret
-	hashedSecret, err := s.passwordSvc.Hash(clientSecret)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to hash client secret: %w", err)
+	if err := checkPrivilegedScopes(req.AllowedScopes, nil, createdBy); err != nil {
+		return nil, "", err
 	}
 
 	// Set default scopes if not provided
 	allowedScopes := req.AllowedScopes
 	if len(allowedScopes) == 0 {
-		allowedScopes = []string{"email", "profile", "org.read", "org.write"}
+		allowedScopes = defaultClientScopes
 	}
 
 	clientApp := &models.ClientApp{
-		Name:           req.Name,
-		ClientID:       clientID,
-		ClientSecret:   hashedSecret,
-		OrganizationID: organizationID,
-		RedirectURIs:   req.RedirectURIs,
-		AllowedOrigins: req.AllowedOrigins,
-		AllowedScopes:  allowedScopes,
-		IsConfidential: req.IsConfidential,
+		Name:                    req.Name,
+		ClientID:                clientID,
+		OrganizationID:          organizationID,
+		RedirectURIs:            req.RedirectURIs,
+		AllowedOrigins:          req.AllowedOrigins,
+		AllowedScopes:           allowedScopes,
+		IsConfidential:          req.IsConfidential,
+		TokenEndpointAuthMethod: req.TokenEndpointAuthMethod,
+		JWKS:                    string(req.JWKS),
+		JWKSURI:                 req.JWKSURI,
+
+		RequirePushedAuthorizationRequests: req.RequirePushedAuthorizationRequests,
+	}
+
+	// Generate and hash the client secret
+	clientSecret, err := s.issueClientSecret(clientApp)
+	if err != nil {
+		return nil, "", err
 	}
 
 	if err := s.repo.ClientApp().Create(ctx, clientApp); err != nil {
@@ -177,11 +232,38 @@ func (s *clientAppService) UpdateClientApp(ctx context.Context, id uuid.UUID, re
 		clientApp.AllowedOrigins = req.AllowedOrigins
 	}
 	if req.AllowedScopes != nil {
+		if err := checkPrivilegedScopes(req.AllowedScopes, clientApp.AllowedScopes, updatedBy); err != nil {
+			return nil, err
+		}
 		clientApp.AllowedScopes = req.AllowedScopes
 	}
 	if req.IsConfidential != nil {
 		clientApp.IsConfidential = *req.IsConfidential
 	}
+	if req.RequirePushedAuthorizationRequests != nil {
+		clientApp.RequirePushedAuthorizationRequests = *req.RequirePushedAuthorizationRequests
+	}
+	if req.TokenEndpointAuthMethod != nil {
+		var jwksURI string
+		if req.JWKSURI != nil {
+			jwksURI = *req.JWKSURI
+		}
+		if *req.TokenEndpointAuthMethod != "" {
+			if !clientApp.IsConfidential {
+				return nil, fmt.Errorf("%w: token_endpoint_auth_method requires a confidential client", ErrInvalidData)
+			}
+			if err := validateClientAuthMethod(*req.TokenEndpointAuthMethod, string(req.JWKS), jwksURI); err != nil {
+				return nil, err
+			}
+		}
+		// client_secret_jwt needs a readable secret; the admin rotates to get one
+		if *req.TokenEndpointAuthMethod != AuthMethodClientSecretJWT {
+			clientApp.ClientSecretEncrypted = ""
+		}
+		clientApp.TokenEndpointAuthMethod = *req.TokenEndpointAuthMethod
+		clientApp.JWKS = string(req.JWKS)
+		clientApp.JWKSURI = jwksURI
+	}
 
 	if err := s.repo.ClientApp().Update(ctx, clientApp); err != nil {
 		return nil, fmt.Errorf("failed to update client app: %w", err)
@@ -202,19 +284,12 @@ func (s *clientAppService) RotateClientSecret(ctx context.Context, id uuid.UUID,
 		return "", err
 	}
 
-	// Generate new client secret
-	clientSecret, err := generateClientSecret()
-	if err != nil {
-		return "", fmt.Errorf("failed to generate client secret: %w", err)
-	}
-
-	// Hash new secret
-	hashedSecret, err := s.passwordSvc.Hash(clientSecret)
+	// Generate and hash the new secret (encrypted too for client_secret_jwt clients)
+	clientSecret, err := s.issueClientSecret(clientApp)
 	if err != nil {
-		return "", fmt.Errorf("failed to hash client secret: %w", err)
+		return "", err
 	}
 
-	clientApp.ClientSecret = hashedSecret
 	if err := s.repo.ClientApp().Update(ctx, clientApp); err != nil {
 		return "", fmt.Errorf("failed to rotate client secret: %w", err)
 	}
@@ -233,6 +308,11 @@ func (s *clientAppService) ValidateClientCredentials(ctx context.Context, client
 		return clientApp, nil
 	}
 
+	// Clients registered for JWT authentication must not fall back to a shared secret
+	if clientApp.TokenEndpointAuthMethod == AuthMethodPrivateKeyJWT || clientApp.TokenEndpointAuthMethod == AuthMethodClientSecretJWT {
+		return nil, ErrClientAssertionNeeded
+	}
+
 	// Verify client secret for confidential clients
 	valid, err := s.passwordSvc.Verify(clientSecret, clientApp.ClientSecret)
 	if err != nil || !valid {
@@ -296,14 +376,19 @@ func validateURI(uri string) error {
 
 func toClientAppResponse(app *models.ClientApp) *ClientAppResponse {
 	return &ClientAppResponse{
-		ID:             app.ID,
-		Name:           app.Name,
-		ClientID:       app.ClientID,
-		RedirectURIs:   app.RedirectURIs,
-		AllowedOrigins: app.AllowedOrigins,
-		AllowedScopes:  app.AllowedScopes,
-		IsConfidential: app.IsConfidential,
-		CreatedAt:      app.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:      app.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:                      app.ID,
+		Name:                    app.Name,
+		ClientID:                app.ClientID,
+		RedirectURIs:            app.RedirectURIs,
+		AllowedOrigins:          app.AllowedOrigins,
+		AllowedScopes:           app.AllowedScopes,
+		IsConfidential:          app.IsConfidential,
+		TokenEndpointAuthMethod: app.TokenEndpointAuthMethod,
+		JWKSURI:                 app.JWKSURI,
+		HasJWKS:                 app.JWKS != "",
+		CreatedAt:               app.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:               app.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+
+		RequirePushedAuthorizationRequests: app.RequirePushedAuthorizationRequests,
 	}
 }
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// Assertions must be short-lived; this also bounds how long a jti is remembered
+	maxClientAssertionLifetime = 10 * time.Minute
+
+	// Remote JWK sets are cached for this long, and refetched at most this often on a kid miss
+	jwksCacheTTL        = 5 * time.Minute
+	jwksRefetchInterval = 30 * time.Second
+	maxJWKSDocumentSize = 64 << 10
+	jwksFetchTimeout    = 5 * time.Second
+	maxJWKSRedirects    = 3
+
+	clientAssertionJTIPrefix = "client_assertion:jti:"
+)
+
+// ClientAssertionService authenticates clients with signed JWTs (RFC 7523):
+// private_key_jwt against the client's JWKS, client_secret_jwt against its secret
+type ClientAssertionService interface {
+	// ValidateClientAssertion verifies assertion and returns the client it authenticates.
+	// clientID may be empty, in which case the assertion's sub identifies the client.
+	ValidateClientAssertion(ctx context.Context, clientID, assertionType, assertion string) (*models.ClientApp, error)
+}
+
+type cachedJWKS struct {
+	set       *jwt.JWKSet
+	fetchedAt time.Time
+}
+
+type clientAssertionService struct {
+	repo        repository.Repository
+	redisClient *redis.Client
+	httpClient  *http.Client
+	audiences   []string
+
+	mu        sync.Mutex
+	jwksCache map[string]*cachedJWKS // keyed by jwks_uri
+}
+
+// NewClientAssertionService creates a client assertion service. httpClient fetches jwks_uri
+// documents (nil uses a client that only connects to public addresses). Assertions must
+// carry the issuer URL or the token endpoint URL as aud.
+func NewClientAssertionService(repo repository.Repository, redisClient *redis.Client, httpClient *http.Client, issuerURL string) ClientAssertionService {
+	if httpClient == nil {
+		httpClient = newJWKSHTTPClient()
+	}
+	issuer := strings.TrimSuffix(issuerURL, "/")
+
+	return &clientAssertionService{
+		repo:        repo,
+		redisClient: redisClient,
+		httpClient:  httpClient,
+		audiences:   []string{issuer, issuer + "/api/v1/oauth/token"},
+		jwksCache:   make(map[string]*cachedJWKS),
+	}
+}
+
+func (s *clientAssertionService) ValidateClientAssertion(ctx context.Context, clientID, assertionType, assertion string) (*models.ClientApp, error) {
+	if assertionType != jwt.ClientAssertionType {
+		return nil, fmt.Errorf("%w: unsupported client_assertion_type", ErrInvalidClientAssertion)
+	}
+
+	subject, err := jwt.ClientAssertionSubject(assertion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientAssertion, err)
+	}
+	if clientID != "" && subject != clientID {
+		return nil, fmt.Errorf("%w: sub does not match client_id", ErrInvalidClientAssertion)
+	}
+
+	clientApp, err := s.repo.ClientApp().GetByClientID(ctx, subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown client", ErrInvalidClientAssertion)
+	}
+
+	var (
+		algs    []string
+		keyFunc func(alg, kid string) (interface{}, error)
+	)
+	switch clientApp.TokenEndpointAuthMethod {
+	case AuthMethodPrivateKeyJWT:
+		algs = jwt.AsymmetricAssertionAlgs
+		keyFunc = func(alg, kid string) (interface{}, error) {
+			return s.verificationKey(ctx, clientApp, alg, kid)
+		}
+	case AuthMethodClientSecretJWT:
+		algs = jwt.SymmetricAssertionAlgs
+		keyFunc = func(alg, kid string) (interface{}, error) {
+			secret, err := hashutil.DecryptSecret(clientApp.ClientSecretEncrypted)
+			if err != nil {
+				return nil, err
+			}
+			return []byte(secret), nil
+		}
+	default:
+		return nil, fmt.Errorf("%w: client is not registered for JWT authentication", ErrInvalidClientAssertion)
+	}
+
+	claims, err := jwt.ParseClientAssertion(assertion, clientApp.ClientID, s.audiences, algs, maxClientAssertionLifetime, keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClientAssertion, err)
+	}
+
+	// Each assertion is single use: remember its jti until it expires
+	key := clientAssertionJTIPrefix + clientApp.ClientID + ":" + claims.ID
+	fresh, err := s.redisClient.SetNX(ctx, key, "1", time.Until(claims.ExpiresAt.Time)+time.Minute).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record client assertion: %w", err)
+	}
+	if !fresh {
+		return nil, fmt.Errorf("%w: assertion has already been used", ErrInvalidClientAssertion)
+	}
+
+	return clientApp, nil
+}
+
+// verificationKey resolves the client's JWK for alg/kid, preferring the inline JWKS
+func (s *clientAssertionService) verificationKey(ctx context.Context, clientApp *models.ClientApp, alg, kid string) (interface{}, error) {
+	if clientApp.JWKS != "" {
+		set, err := jwt.ParseJWKSet([]byte(clientApp.JWKS))
+		if err != nil {
+			return nil, err
+		}
+		return set.VerificationKey(alg, kid)
+	}
+	if clientApp.JWKSURI == "" {
+		return nil, errors.New("client has no registered keys")
+	}
+
+	set, err := s.remoteJWKS(ctx, clientApp.JWKSURI, false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := set.VerificationKey(alg, kid)
+	if errors.Is(err, jwt.ErrNoMatchingKey) {
+		// The client may have rotated keys since we cached the document
+		if set, err = s.remoteJWKS(ctx, clientApp.JWKSURI, true); err != nil {
+			return nil, err
+		}
+		return set.VerificationKey(alg, kid)
+	}
+	return key, err
+}
+
+// remoteJWKS returns the cached document for uri, fetching it when stale
+// (or, with refresh, when the cached copy is older than the refetch interval)
+func (s *clientAssertionService) remoteJWKS(ctx context.Context, uri string, refresh bool) (*jwt.JWKSet, error) {
+	s.mu.Lock()
+	cached := s.jwksCache[uri]
+	s.mu.Unlock()
+
+	if cached != nil {
+		age := time.Since(cached.fetchedAt)
+		if age < jwksCacheTTL && (!refresh || age < jwksRefetchInterval) {
+			return cached.set, nil
+		}
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwks_uri: %w", err)
+	}
+	if parsed.Scheme != "https" {
+		return nil, errors.New("jwks_uri must be an https URL")
+	}
+
+	// Bound the fetch even when the injected client has no timeout of its own
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwks_uri: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks_uri: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks_uri returned status %d", resp.StatusCode)
+	}
+
+	// Read one byte past the limit so an oversized document is refused rather than truncated
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks_uri: %w", err)
+	}
+	if len(body) > maxJWKSDocumentSize {
+		return nil, fmt.Errorf("jwks_uri document exceeds %d bytes", maxJWKSDocumentSize)
+	}
+
+	set, err := jwt.ParseJWKSet(body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.jwksCache[uri] = &cachedJWKS{set: set, fetchedAt: time.Now()}
+	s.mu.Unlock()
+
+	return set, nil
+}
+
+// newJWKSHTTPClient returns the client used to fetch jwks_uri documents. jwks_uri is chosen
+// by whoever registers the client, so every connection, including those made for redirects,
+// is checked against internal addresses after DNS resolution.
+func newJWKSHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: jwksFetchTimeout,
+		Control: refuseInternalAddress,
+	}
+	return &http.Client{
+		Timeout: jwksFetchTimeout,
+		Transport: &http.Transport{
+			// No proxy: it would be dialed in place of the jwks_uri host and defeat the check
+			Proxy:                  nil,
+			DialContext:            dialer.DialContext,
+			TLSHandshakeTimeout:    jwksFetchTimeout,
+			ResponseHeaderTimeout:  jwksFetchTimeout,
+			MaxResponseHeaderBytes: 16 << 10,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxJWKSRedirects {
+				return errors.New("jwks_uri redirected too many times")
+			}
+			if req.URL.Scheme != "https" {
+				return errors.New("jwks_uri redirected to a non-https URL")
+			}
+			return nil
+		},
+	}
+}
+
+// refuseInternalAddress is a net.Dialer Control hook that rejects loopback, private,
+// link-local, multicast and unspecified addresses
+func refuseInternalAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("refusing to connect to %s", address)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("refusing to connect to internal address %s", ip)
+	}
+	return nil
+}
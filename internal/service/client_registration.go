@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+
+	"github.com/google/uuid"
+)
+
+// Token endpoint authentication methods a client can register with
+const (
+	AuthMethodClientSecretBasic = "client_secret_basic"
+	AuthMethodClientSecretPost  = "client_secret_post"
+	AuthMethodNone              = "none"
+	AuthMethodPrivateKeyJWT     = "private_key_jwt"
+	AuthMethodClientSecretJWT   = "client_secret_jwt"
+)
+
+// registrableGrantTypes are the grant types a dynamically registered client may request
+var registrableGrantTypes = map[string]bool{
+	"authorization_code":   true,
+	"refresh_token":        true,
+	"client_credentials":   true,
+	GrantTypeDeviceCode:    true,
+	GrantTypeTokenExchange: true,
+}
+
+// CreateInitialAccessTokenRequest represents a request to issue an initial access token
+type CreateInitialAccessTokenRequest struct {
+	Description   string   `json:"description" validate:"max=255"`
+	AllowedScopes []string `json:"allowed_scopes"`
+	MaxUses       int      `json:"max_uses" validate:"min=0"`        // 0 = unlimited
+	ExpiresInDays int      `json:"expires_in_days" validate:"min=0"` // 0 = never expires
+}
+
+// ClientRegistrationRequest is the RFC 7591 client metadata accepted by /oauth/register
+type ClientRegistrationRequest struct {
+	ClientID                string          `json:"client_id,omitempty"` // Only on RFC 7592 updates, must match the URL
+	ClientName              string          `json:"client_name"`
+	RedirectURIs            []string        `json:"redirect_uris"`
+	GrantTypes              []string        `json:"grant_types"`
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method"`
+	Scope                   string          `json:"scope"`
+	JWKS                    json.RawMessage `json:"jwks,omitempty" swaggertype:"object"`
+	JWKSURI                 string          `json:"jwks_uri,omitempty"`
+
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests,omitempty"`
+}
+
+// ClientRegistrationResponse is the RFC 7591 client information response
+type ClientRegistrationResponse struct {
+	ClientID                string          `json:"client_id"`
+	ClientSecret            string          `json:"client_secret,omitempty"` // Only when a secret was just issued
+	ClientIDIssuedAt        int64           `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64           `json:"client_secret_expires_at"` // 0 = never
+	RegistrationAccessToken string          `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string          `json:"registration_client_uri,omitempty"` // Filled in by the handler
+	ClientName              string          `json:"client_name"`
+	RedirectURIs            []string        `json:"redirect_uris"`
+	GrantTypes              []string        `json:"grant_types"`
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method"`
+	Scope                   string          `json:"scope"`
+	JWKS                    json.RawMessage `json:"jwks,omitempty" swaggertype:"object"`
+	JWKSURI                 string          `json:"jwks_uri,omitempty"`
+
+	RequirePushedAuthorizationRequests bool `json:"require_pushed_authorization_requests"`
+}
+
+// CreateInitialAccessToken issues a token that lets partners register clients in orgID.
+// The plain token is only returned here.
+func (s *clientAppService) CreateInitialAccessToken(ctx context.Context, orgID uuid.UUID, req *CreateInitialAccessTokenRequest, createdBy uuid.UUID) (*models.InitialAccessToken, string, error) {
+	if req.MaxUses < 0 || req.ExpiresInDays < 0 {
+		return nil, "", fmt.Errorf("%w: max_uses and expires_in_days must not be negative", ErrInvalidData)
+	}
+	// Clients registered with the token may claim any of its scopes
+	isSuperadmin, _ := ctx.Value("is_superadmin").(bool)
+	if err := checkPrivilegedScopes(req.AllowedScopes, nil, &models.User{ID: createdBy, IsSuperadmin: isSuperadmin}); err != nil {
+		return nil, "", err
+	}
+
+	plainToken, err := generateRegistrationToken("iat_")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate initial access token: %w", err)
+	}
+
+	tokenHash, err := hashutil.HMACHash(plainToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash initial access token: %w", err)
+	}
+
+	token := &models.InitialAccessToken{
+		OrganizationID: orgID,
+		TokenHash:      tokenHash,
+		Description:    req.Description,
+		AllowedScopes:  req.AllowedScopes,
+		MaxUses:        req.MaxUses,
+		CreatedBy:      createdBy,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.InitialAccessToken().Create(ctx, token); err != nil {
+		return nil, "", fmt.Errorf("failed to create initial access token: %w", err)
+	}
+
+	return token, plainToken, nil
+}
+
+func (s *clientAppService) ListInitialAccessTokens(ctx context.Context, orgID uuid.UUID) ([]*models.InitialAccessToken, error) {
+	return s.repo.InitialAccessToken().ListByOrganization(ctx, orgID)
+}
+
+func (s *clientAppService) RevokeInitialAccessToken(ctx context.Context, orgID, id uuid.UUID) error {
+	return s.repo.InitialAccessToken().Revoke(ctx, orgID, id)
+}
+
+// RegisterClient creates a client in the organization that issued initialAccessToken (RFC 7591)
+func (s *clientAppService) RegisterClient(ctx context.Context, initialAccessToken string, req *ClientRegistrationRequest) (*ClientRegistrationResponse, error) {
+	tokenHashes, err := hashutil.HMACHashCandidates(initialAccessToken)
+	if err != nil {
+		return nil, ErrInvalidInitialAccessToken
+	}
+	iat, err := s.repo.InitialAccessToken().GetActiveByTokenHash(ctx, tokenHashes)
+	if err != nil {
+		return nil, ErrInvalidInitialAccessToken
+	}
+
+	clientApp := &models.ClientApp{
+		OrganizationID:       iat.OrganizationID,
+		InitialAccessTokenID: &iat.ID,
+	}
+	if err := applyRegistrationMetadata(clientApp, req, iat.AllowedScopes); err != nil {
+		return nil, err
+	}
+
+	clientApp.ClientID, err = generateClientID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client ID: %w", err)
+	}
+	if clientApp.Name == "" {
+		clientApp.Name = clientApp.ClientID
+	}
+
+	// Public and private_key_jwt clients still get a stored hash so the column stays NOT NULL; it is never shown
+	clientSecret, err := s.issueClientSecret(clientApp)
+	if err != nil {
+		return nil, err
+	}
+
+	registrationToken, err := generateRegistrationToken("rat_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate registration access token: %w", err)
+	}
+	clientApp.RegistrationTokenHash, err = hashutil.HMACHash(registrationToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash registration access token: %w", err)
+	}
+
+	// Count the use first so a token with max_uses cannot be raced past its limit
+	if err := s.repo.InitialAccessToken().Consume(ctx, iat.ID); err != nil {
+		return nil, ErrInvalidInitialAccessToken
+	}
+
+	if err := s.repo.ClientApp().Create(ctx, clientApp); err != nil {
+		return nil, fmt.Errorf("failed to create client app: %w", err)
+	}
+
+	resp := toClientRegistrationResponse(clientApp)
+	resp.RegistrationAccessToken = registrationToken
+	if usesClientSecret(clientApp) {
+		resp.ClientSecret = clientSecret
+	}
+	return resp, nil
+}
+
+// GetClientRegistration returns the current registration (RFC 7592 read)
+func (s *clientAppService) GetClientRegistration(ctx context.Context, clientID, registrationToken string) (*ClientRegistrationResponse, error) {
+	clientApp, err := s.authorizeRegistration(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+	return toClientRegistrationResponse(clientApp), nil
+}
+
+// UpdateClientRegistration replaces the client metadata (RFC 7592 update).
+// A secret is issued when a public client switches to a secret-based auth method.
+// Every update rotates the registration access token; the old one stops working.
+func (s *clientAppService) UpdateClientRegistration(ctx context.Context, clientID, registrationToken string, req *ClientRegistrationRequest) (*ClientRegistrationResponse, error) {
+	clientApp, err := s.authorizeRegistration(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ClientID != "" && req.ClientID != clientApp.ClientID {
+		return nil, fmt.Errorf("%w: client_id does not match the registration", ErrInvalidClientMetadata)
+	}
+
+	var allowedScopes []string
+	if clientApp.InitialAccessTokenID != nil {
+		// Keep the restriction of the token the client was registered with, even if it was revoked since
+		if iats, err := s.repo.InitialAccessToken().ListByOrganization(ctx, clientApp.OrganizationID); err == nil {
+			for _, iat := range iats {
+				if iat.ID == *clientApp.InitialAccessTokenID {
+					allowedScopes = iat.AllowedScopes
+					break
+				}
+			}
+		}
+	}
+
+	hadSecret := usesClientSecret(clientApp)
+	if err := applyRegistrationMetadata(clientApp, req, allowedScopes); err != nil {
+		return nil, err
+	}
+	if clientApp.Name == "" {
+		clientApp.Name = clientApp.ClientID
+	}
+
+	// A client_secret_jwt client needs a secret we can read back, which older secrets are not
+	var clientSecret string
+	needsReadableSecret := clientApp.TokenEndpointAuthMethod == AuthMethodClientSecretJWT && clientApp.ClientSecretEncrypted == ""
+	if usesClientSecret(clientApp) && (!hadSecret || needsReadableSecret) {
+		clientSecret, err = s.issueClientSecret(clientApp)
+		if err != nil {
+			return nil, err
+		}
+	} else if clientApp.TokenEndpointAuthMethod != AuthMethodClientSecretJWT {
+		clientApp.ClientSecretEncrypted = ""
+	}
+
+	registrationToken, err = generateRegistrationToken("rat_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate registration access token: %w", err)
+	}
+	clientApp.RegistrationTokenHash, err = hashutil.HMACHash(registrationToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash registration access token: %w", err)
+	}
+
+	if err := s.repo.ClientApp().Update(ctx, clientApp); err != nil {
+		return nil, fmt.Errorf("failed to update client app: %w", err)
+	}
+
+	resp := toClientRegistrationResponse(clientApp)
+	resp.ClientSecret = clientSecret
+	resp.RegistrationAccessToken = registrationToken
+	return resp, nil
+}
+
+// DeleteClientRegistration removes the client (RFC 7592 delete)
+func (s *clientAppService) DeleteClientRegistration(ctx context.Context, clientID, registrationToken string) error {
+	clientApp, err := s.authorizeRegistration(ctx, clientID, registrationToken)
+	if err != nil {
+		return err
+	}
+	return s.repo.ClientApp().Delete(ctx, clientApp.ID)
+}
+
+// authorizeRegistration loads clientID and checks its registration access token.
+// Clients created through the admin API have no token and cannot be managed here.
+func (s *clientAppService) authorizeRegistration(ctx context.Context, clientID, registrationToken string) (*models.ClientApp, error) {
+	clientApp, err := s.repo.ClientApp().GetByClientID(ctx, clientID)
+	if err != nil || clientApp.RegistrationTokenHash == "" || registrationToken == "" {
+		return nil, ErrInvalidRegistrationToken
+	}
+
+	valid, err := hashutil.VerifyHMACHash(registrationToken, clientApp.RegistrationTokenHash)
+	if err != nil || !valid {
+		return nil, ErrInvalidRegistrationToken
+	}
+	return clientApp, nil
+}
+
+// applyRegistrationMetadata validates req and copies it onto clientApp,
+// filling in the RFC 7591 defaults for omitted fields
+func applyRegistrationMetadata(clientApp *models.ClientApp, req *ClientRegistrationRequest, allowedScopes []string) error {
+	authMethod := req.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = AuthMethodClientSecretBasic
+	}
+	if err := validateClientAuthMethod(authMethod, string(req.JWKS), req.JWKSURI); err != nil {
+		return err
+	}
+
+	grantTypes := req.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{"authorization_code"}
+	}
+	for _, grantType := range grantTypes {
+		if !registrableGrantTypes[grantType] {
+			return fmt.Errorf("%w: unsupported grant type '%s'", ErrInvalidClientMetadata, grantType)
+		}
+		if grantType == "client_credentials" && authMethod == AuthMethodNone {
+			return fmt.Errorf("%w: client_credentials requires client authentication", ErrInvalidClientMetadata)
+		}
+	}
+
+	usesRedirects := false
+	for _, grantType := range grantTypes {
+		if grantType == "authorization_code" {
+			usesRedirects = true
+		}
+	}
+	if usesRedirects && len(req.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: redirect_uris is required for the authorization_code grant", ErrInvalidRedirectURI)
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := validateURI(uri); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidRedirectURI, uri, err)
+		}
+	}
+
+	// A token without a scope list only lets clients register the default scopes, so
+	// privileged scopes such as introspect need a token a superadmin created
+	if len(allowedScopes) == 0 {
+		allowedScopes = defaultClientScopes
+	}
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = allowedScopes
+	} else {
+		allowed := make(map[string]bool, len(allowedScopes))
+		for _, scope := range allowedScopes {
+			allowed[scope] = true
+		}
+		for _, scope := range scopes {
+			if !allowed[scope] {
+				return fmt.Errorf("%w: scope '%s' is not permitted by the initial access token", ErrInvalidClientMetadata, scope)
+			}
+		}
+	}
+
+	clientApp.Name = strings.TrimSpace(req.ClientName)
+	clientApp.RedirectURIs = req.RedirectURIs
+	clientApp.GrantTypes = grantTypes
+	clientApp.TokenEndpointAuthMethod = authMethod
+	clientApp.IsConfidential = authMethod != AuthMethodNone
+	clientApp.AllowedScopes = scopes
+	clientApp.JWKS = string(req.JWKS)
+	clientApp.JWKSURI = req.JWKSURI
+	clientApp.RequirePushedAuthorizationRequests = req.RequirePushedAuthorizationRequests
+	return nil
+}
+
+// validateClientAuthMethod checks authMethod and the key material it needs.
+// jwks and jwks_uri are only meaningful (and then required) for private_key_jwt.
+func validateClientAuthMethod(authMethod, jwks, jwksURI string) error {
+	switch authMethod {
+	case AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodNone, AuthMethodClientSecretJWT:
+		if jwks != "" || jwksURI != "" {
+			return fmt.Errorf("%w: jwks and jwks_uri are only used with %s", ErrInvalidClientMetadata, AuthMethodPrivateKeyJWT)
+		}
+		return nil
+	case AuthMethodPrivateKeyJWT:
+	default:
+		return fmt.Errorf("%w: unsupported token_endpoint_auth_method '%s'", ErrInvalidClientMetadata, authMethod)
+	}
+
+	switch {
+	case jwks != "" && jwksURI != "":
+		return fmt.Errorf("%w: jwks and jwks_uri are mutually exclusive", ErrInvalidClientMetadata)
+	case jwks != "":
+		if _, err := jwt.ParseJWKSet([]byte(jwks)); err != nil {
+			return fmt.Errorf("%w: jwks: %v", ErrInvalidClientMetadata, err)
+		}
+	case jwksURI != "":
+		parsed, err := url.Parse(jwksURI)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("%w: jwks_uri must be an https URL", ErrInvalidClientMetadata)
+		}
+	default:
+		return fmt.Errorf("%w: %s requires jwks or jwks_uri", ErrInvalidClientMetadata, AuthMethodPrivateKeyJWT)
+	}
+	return nil
+}
+
+// usesClientSecret reports whether the client authenticates with its client secret
+func usesClientSecret(app *models.ClientApp) bool {
+	return app.IsConfidential && app.TokenEndpointAuthMethod != AuthMethodPrivateKeyJWT
+}
+
+// issueClientSecret generates a new client secret and stores its hash on clientApp.
+// client_secret_jwt clients also keep an encrypted copy to verify HMAC assertions.
+func (s *clientAppService) issueClientSecret(clientApp *models.ClientApp) (string, error) {
+	clientSecret, err := generateClientSecret()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate client secret: %w", err)
+	}
+
+	clientApp.ClientSecret, err = s.passwordSvc.Hash(clientSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash client secret: %w", err)
+	}
+
+	clientApp.ClientSecretEncrypted = ""
+	if clientApp.TokenEndpointAuthMethod == AuthMethodClientSecretJWT {
+		clientApp.ClientSecretEncrypted, err = hashutil.EncryptSecret(clientSecret)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt client secret: %w", err)
+		}
+	}
+
+	return clientSecret, nil
+}
+
+func toClientRegistrationResponse(app *models.ClientApp) *ClientRegistrationResponse {
+	return &ClientRegistrationResponse{
+		ClientID:                app.ClientID,
+		ClientIDIssuedAt:        app.CreatedAt.Unix(),
+		ClientName:              app.Name,
+		RedirectURIs:            app.RedirectURIs,
+		GrantTypes:              app.GrantTypes,
+		TokenEndpointAuthMethod: app.TokenEndpointAuthMethod,
+		Scope:                   strings.Join(app.AllowedScopes, " "),
+		JWKS:                    json.RawMessage(app.JWKS),
+		JWKSURI:                 app.JWKSURI,
+
+		RequirePushedAuthorizationRequests: app.RequirePushedAuthorizationRequests,
+	}
+}
+
+func generateRegistrationToken(prefix string) (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
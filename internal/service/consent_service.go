@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// The consent prompt has to be answered within this time after signing in
+const consentTicketTTL = 5 * time.Minute
+
+// ConsentService keeps track of the scopes users have approved for OAuth clients
+// and of the clients ("connected apps") holding tokens for them
+type ConsentService interface {
+	// HasGrant reports whether the user already approved every scope in scope for the client in orgID
+	HasGrant(ctx context.Context, userID uuid.UUID, clientID string, orgID uuid.UUID, scope string) (bool, error)
+	// Grant records approval of scope, keeping the scopes approved earlier
+	Grant(ctx context.Context, userID uuid.UUID, clientID string, orgID uuid.UUID, scope string) error
+	ListConnectedApps(ctx context.Context, userID uuid.UUID) ([]*ConnectedApp, error)
+	// RevokeConnectedApp forgets the user's grants for the client and revokes every token family it holds
+	RevokeConnectedApp(ctx context.Context, userID uuid.UUID, clientID string) error
+
+	// CreateTicket and ConsumeTicket carry a signed-in user from the login form to the consent prompt
+	CreateTicket(ctx context.Context, ticket *ConsentTicket) (string, error)
+	ConsumeTicket(ctx context.Context, ticketID string) (*ConsentTicket, error)
+}
+
+// ConsentTicket is an authenticated authorization request waiting for the user's decision
+type ConsentTicket struct {
+	UserID         uuid.UUID           `json:"user_id"`
+	OrganizationID uuid.UUID           `json:"organization_id"`
+	Params         AuthorizationParams `json:"params"`
+	RequestURI     string              `json:"request_uri,omitempty"`
+	AuthTime       time.Time           `json:"auth_time"`
+	AMR            []string            `json:"amr"`
+}
+
+// ConnectedApp is an OAuth client the user has approved or that holds live refresh tokens
+type ConnectedApp struct {
+	ClientID            string     `json:"client_id"`
+	ClientName          string     `json:"client_name"`
+	OrganizationID      uuid.UUID  `json:"organization_id"`
+	Scopes              []string   `json:"scopes"`
+	GrantedAt           *time.Time `json:"granted_at,omitempty"` // nil for tokens issued before consent was recorded
+	ActiveTokenFamilies int        `json:"active_token_families"`
+	LastUsedAt          *time.Time `json:"last_used_at,omitempty"` // Most recent token issuance or refresh
+}
+
+type consentService struct {
+	repo  repository.Repository
+	redis *redis.Client
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(repo repository.Repository, redisClient *redis.Client) ConsentService {
+	return &consentService{repo: repo, redis: redisClient}
+}
+
+func consentTicketKey(ticketHash string) string { return "consent_ticket:" + ticketHash }
+
+func (s *consentService) HasGrant(ctx context.Context, userID uuid.UUID, clientID string, orgID uuid.UUID, scope string) (bool, error) {
+	grant, err := s.repo.ConsentGrant().Get(ctx, userID, clientID, orgID)
+	if err != nil {
+		if errors.Is(err, repository.ErrConsentGrantNotFound) {
+			// First authorization of this client: the user has to be asked
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up consent grant: %w", err)
+	}
+
+	granted := make(map[string]bool, len(grant.Scopes))
+	for _, grantedScope := range grant.Scopes {
+		granted[grantedScope] = true
+	}
+	for _, requested := range strings.Fields(scope) {
+		if !granted[requested] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *consentService) Grant(ctx context.Context, userID uuid.UUID, clientID string, orgID uuid.UUID, scope string) error {
+	var scopes []string
+	seen := make(map[string]bool)
+	existing, err := s.repo.ConsentGrant().Get(ctx, userID, clientID, orgID)
+	if err != nil && !errors.Is(err, repository.ErrConsentGrantNotFound) {
+		// Upserting without the existing scopes would drop them
+		return fmt.Errorf("failed to look up consent grant: %w", err)
+	}
+	if existing != nil {
+		for _, grantedScope := range existing.Scopes {
+			seen[grantedScope] = true
+			scopes = append(scopes, grantedScope)
+		}
+	}
+	for _, requested := range strings.Fields(scope) {
+		if !seen[requested] {
+			seen[requested] = true
+			scopes = append(scopes, requested)
+		}
+	}
+
+	if err := s.repo.ConsentGrant().Upsert(ctx, &models.OAuthConsentGrant{
+		UserID:         userID,
+		ClientID:       clientID,
+		OrganizationID: orgID,
+		Scopes:         scopes,
+	}); err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+	return nil
+}
+
+// ListConnectedApps merges the user's consent grants with their live refresh token families,
+// one entry per client and organization
+func (s *consentService) ListConnectedApps(ctx context.Context, userID uuid.UUID) ([]*ConnectedApp, error) {
+	grants, err := s.repo.ConsentGrant().ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consent grants: %w", err)
+	}
+	tokens, err := s.repo.OAuthRefreshToken().ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	type appKey struct {
+		clientID string
+		orgID    uuid.UUID
+	}
+	apps := make([]*ConnectedApp, 0, len(grants))
+	byKey := make(map[appKey]*ConnectedApp)
+
+	for _, grant := range grants {
+		grantedAt := grant.CreatedAt
+		app := &ConnectedApp{
+			ClientID:       grant.ClientID,
+			OrganizationID: grant.OrganizationID,
+			Scopes:         grant.Scopes,
+			GrantedAt:      &grantedAt,
+		}
+		byKey[appKey{grant.ClientID, grant.OrganizationID}] = app
+		apps = append(apps, app)
+	}
+
+	for _, token := range tokens {
+		key := appKey{clientID: token.ClientID}
+		if token.OrganizationID != nil {
+			key.orgID = *token.OrganizationID
+		}
+		app, ok := byKey[key]
+		if !ok {
+			app = &ConnectedApp{
+				ClientID:       token.ClientID,
+				OrganizationID: key.orgID,
+				Scopes:         strings.Fields(token.Scope),
+			}
+			byKey[key] = app
+			apps = append(apps, app)
+		}
+		app.ActiveTokenFamilies++
+		if app.LastUsedAt == nil || token.CreatedAt.After(*app.LastUsedAt) {
+			lastUsed := token.CreatedAt
+			app.LastUsedAt = &lastUsed
+		}
+	}
+
+	names := make(map[string]string)
+	for _, app := range apps {
+		name, ok := names[app.ClientID]
+		if !ok {
+			if clientApp, err := s.repo.ClientApp().GetByClientID(ctx, app.ClientID); err == nil {
+				name = clientApp.Name
+			}
+			names[app.ClientID] = name
+		}
+		app.ClientName = name
+	}
+
+	return apps, nil
+}
+
+// RevokeConnectedApp stops future consent skipping and refreshes for the client. Access tokens
+// already issued stay valid until they expire (at most an hour).
+func (s *consentService) RevokeConnectedApp(ctx context.Context, userID uuid.UUID, clientID string) error {
+	tokens, err := s.repo.OAuthRefreshToken().GetByUserAndClient(ctx, userID, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to look up refresh tokens: %w", err)
+	}
+
+	grantErr := s.repo.ConsentGrant().DeleteByUserAndClient(ctx, userID, clientID)
+	if grantErr != nil && !errors.Is(grantErr, repository.ErrConsentGrantNotFound) {
+		return fmt.Errorf("failed to delete consent grants: %w", grantErr)
+	}
+	if grantErr != nil && len(tokens) == 0 {
+		return ErrConnectedAppNotFound
+	}
+
+	if err := s.repo.OAuthRefreshToken().RevokeAllForUser(ctx, userID, clientID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+func (s *consentService) CreateTicket(ctx context.Context, ticket *ConsentTicket) (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("failed to generate consent ticket: %w", err)
+	}
+	ticketID := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	ticketHash, err := hashutil.RedisLookupHash(ticketID)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash consent ticket: %w", err)
+	}
+
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode consent ticket: %w", err)
+	}
+	if err := s.redis.Set(ctx, consentTicketKey(ticketHash), data, consentTicketTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store consent ticket: %w", err)
+	}
+	return ticketID, nil
+}
+
+func (s *consentService) ConsumeTicket(ctx context.Context, ticketID string) (*ConsentTicket, error) {
+	ticketHash, err := hashutil.RedisLookupHash(ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash consent ticket: %w", err)
+	}
+
+	data, err := s.redis.GetDel(ctx, consentTicketKey(ticketHash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidConsentTicket
+		}
+		return nil, fmt.Errorf("failed to load consent ticket: %w", err)
+	}
+
+	var ticket ConsentTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, fmt.Errorf("failed to decode consent ticket: %w", err)
+	}
+	return &ticket, nil
+}
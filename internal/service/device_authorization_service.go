@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/pkg/hashutil"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// GrantTypeDeviceCode is the grant_type used when polling /oauth/token (RFC 8628)
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+const (
+	deviceCodeTTL          = 10 * time.Minute
+	devicePollInterval     = 5 * time.Second
+	deviceSlowDownInterval = 5 * time.Second // Added to the interval on every slow_down (RFC 8628 section 3.5)
+
+	// Consonants only, so user codes cannot spell words and avoid look-alike characters
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// Device authorization states
+const (
+	DeviceStatusPending  = "pending"
+	DeviceStatusApproved = "approved"
+	DeviceStatusDenied   = "denied"
+)
+
+// Device polling errors, named after the RFC 8628 error codes they map to
+var (
+	ErrAuthorizationPending = errors.New("the user has not yet approved the device")
+	ErrSlowDown             = errors.New("polling too frequently")
+	ErrDeviceAccessDenied   = errors.New("the user denied the authorization request")
+	ErrDeviceCodeExpired    = errors.New("device code expired or unknown")
+)
+
+// DeviceAuthorizationService manages RFC 8628 device authorization requests
+type DeviceAuthorizationService interface {
+	CreateDeviceAuthorization(ctx context.Context, clientApp *models.ClientApp, scope string) (*DeviceAuthorizationResponse, error)
+	GetByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error)
+	Approve(ctx context.Context, userCode string, userID uuid.UUID, orgID *uuid.UUID, amr []string) error
+	Deny(ctx context.Context, userCode string) error
+	Poll(ctx context.Context, clientID, deviceCode string) (*DeviceAuthorization, error)
+}
+
+// DeviceAuthorizationResponse is returned from the device authorization endpoint
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorization is the state of a pending device login as stored in Redis
+type DeviceAuthorization struct {
+	ClientID       string     `json:"client_id"`
+	Scope          string     `json:"scope"`
+	UserCode       string     `json:"user_code"`
+	Status         string     `json:"status"`
+	UserID         uuid.UUID  `json:"user_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	AuthTime       *time.Time `json:"auth_time,omitempty"`
+	AMR            []string   `json:"amr,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+}
+
+type deviceAuthorizationService struct {
+	redis           *redis.Client
+	verificationURI string
+}
+
+// NewDeviceAuthorizationService creates a new device authorization service.
+// verificationURI is the page where users enter their user code.
+func NewDeviceAuthorizationService(redisClient *redis.Client, verificationURI string) DeviceAuthorizationService {
+	return &deviceAuthorizationService{
+		redis:           redisClient,
+		verificationURI: verificationURI,
+	}
+}
+
+// Redis keys: the device code is only stored as an HMAC, the user code maps to it.
+// Polling bookkeeping lives under its own keys so a poll never rewrites the
+// authorization and cannot overwrite the user's decision.
+func deviceCodeKey(deviceCodeHash string) string         { return "device:code:" + deviceCodeHash }
+func userCodeKey(userCode string) string                 { return "device:user_code:" + userCode }
+func devicePollIntervalKey(deviceCodeHash string) string { return "device:interval:" + deviceCodeHash }
+func devicePolledKey(deviceCodeHash string) string       { return "device:polled:" + deviceCodeHash }
+
+func (s *deviceAuthorizationService) CreateDeviceAuthorization(ctx context.Context, clientApp *models.ClientApp, scope string) (*DeviceAuthorizationResponse, error) {
+	if len(clientApp.AllowedScopes) > 0 {
+		allowed := make(map[string]bool, len(clientApp.AllowedScopes))
+		for _, allowedScope := range clientApp.AllowedScopes {
+			allowed[allowedScope] = true
+		}
+		for _, requestedScope := range strings.Fields(scope) {
+			if !allowed[requestedScope] {
+				return nil, fmt.Errorf("scope '%s' not allowed for this client", requestedScope)
+			}
+		}
+	}
+
+	codeBytes := make([]byte, 32)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+	deviceCode := base64.RawURLEncoding.EncodeToString(codeBytes)
+
+	deviceCodeHash, err := hashutil.RedisLookupHash(deviceCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash device code: %w", err)
+	}
+
+	auth := &DeviceAuthorization{
+		ClientID:  clientApp.ClientID,
+		Scope:     strings.Join(strings.Fields(scope), " "),
+		Status:    DeviceStatusPending,
+		ExpiresAt: time.Now().Add(deviceCodeTTL),
+	}
+
+	// Retry on the unlikely user code collision
+	for attempt := 0; attempt < 5; attempt++ {
+		userCode, err := generateUserCode()
+		if err != nil {
+			return nil, err
+		}
+
+		claimed, err := s.redis.SetNX(ctx, userCodeKey(userCode), deviceCodeHash, deviceCodeTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to store user code: %w", err)
+		}
+		if claimed {
+			auth.UserCode = userCode
+			break
+		}
+	}
+	if auth.UserCode == "" {
+		return nil, errors.New("failed to allocate a unique user code")
+	}
+
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode device authorization: %w", err)
+	}
+	interval := int(devicePollInterval.Seconds())
+	if _, err := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, deviceCodeKey(deviceCodeHash), data, deviceCodeTTL)
+		pipe.Set(ctx, devicePollIntervalKey(deviceCodeHash), interval, deviceCodeTTL)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to store device authorization: %w", err)
+	}
+
+	formatted := FormatUserCode(auth.UserCode)
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                formatted,
+		VerificationURI:         s.verificationURI,
+		VerificationURIComplete: s.verificationURI + "?user_code=" + formatted,
+		ExpiresIn:               int(deviceCodeTTL.Seconds()),
+		Interval:                interval,
+	}, nil
+}
+
+// GetByUserCode returns the pending authorization for a user code as typed by the user
+func (s *deviceAuthorizationService) GetByUserCode(ctx context.Context, userCode string) (*DeviceAuthorization, error) {
+	_, auth, err := s.loadByUserCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+	if auth.Status != DeviceStatusPending {
+		return nil, errors.New("this code has already been used")
+	}
+	return auth, nil
+}
+
+func (s *deviceAuthorizationService) Approve(ctx context.Context, userCode string, userID uuid.UUID, orgID *uuid.UUID, amr []string) error {
+	now := time.Now()
+	return s.complete(ctx, userCode, func(auth *DeviceAuthorization) {
+		auth.Status = DeviceStatusApproved
+		auth.UserID = userID
+		auth.OrganizationID = orgID
+		auth.AuthTime = &now
+		auth.AMR = amr
+	})
+}
+
+func (s *deviceAuthorizationService) Deny(ctx context.Context, userCode string) error {
+	return s.complete(ctx, userCode, func(auth *DeviceAuthorization) {
+		auth.Status = DeviceStatusDenied
+	})
+}
+
+// Poll checks the state of a device code for the polling client. It returns the
+// authorization once approved and deletes it, so each device code yields tokens once.
+func (s *deviceAuthorizationService) Poll(ctx context.Context, clientID, deviceCode string) (*DeviceAuthorization, error) {
+	deviceCodeHash, err := hashutil.RedisLookupHash(deviceCode)
+	if err != nil {
+		return nil, ErrDeviceCodeExpired
+	}
+
+	auth, err := s.load(ctx, deviceCodeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.ClientID != clientID {
+		return nil, errors.New("device code was not issued to this client")
+	}
+
+	if auth.Status != DeviceStatusPending {
+		return s.redeem(ctx, deviceCodeHash)
+	}
+
+	interval, err := s.redis.Get(ctx, devicePollIntervalKey(deviceCodeHash)).Int()
+	if err != nil {
+		if err != redis.Nil {
+			return nil, fmt.Errorf("failed to load poll interval: %w", err)
+		}
+		interval = int(devicePollInterval.Seconds())
+	}
+
+	// The marker only exists for one interval after a poll, so failing to set it means the client polled too fast
+	polled, err := s.redis.SetNX(ctx, devicePolledKey(deviceCodeHash), 1, time.Duration(interval)*time.Second).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record poll: %w", err)
+	}
+	if !polled {
+		// INCRBY keeps the key's TTL, so the interval still expires with the device code
+		if err := s.redis.IncrBy(ctx, devicePollIntervalKey(deviceCodeHash), int64(deviceSlowDownInterval.Seconds())).Err(); err != nil {
+			return nil, fmt.Errorf("failed to update poll interval: %w", err)
+		}
+		return nil, ErrSlowDown
+	}
+	return nil, ErrAuthorizationPending
+}
+
+// redeem consumes a decided authorization. GETDEL reads and deletes in one step,
+// so only one of several concurrent polls gets the approval.
+func (s *deviceAuthorizationService) redeem(ctx context.Context, deviceCodeHash string) (*DeviceAuthorization, error) {
+	data, err := s.redis.GetDel(ctx, deviceCodeKey(deviceCodeHash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrDeviceCodeExpired
+		}
+		return nil, fmt.Errorf("failed to consume device code: %w", err)
+	}
+	_ = s.redis.Del(ctx, devicePollIntervalKey(deviceCodeHash), devicePolledKey(deviceCodeHash)).Err()
+
+	var auth DeviceAuthorization
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return nil, fmt.Errorf("failed to decode device authorization: %w", err)
+	}
+
+	switch auth.Status {
+	case DeviceStatusApproved:
+		return &auth, nil
+	case DeviceStatusDenied:
+		return nil, ErrDeviceAccessDenied
+	}
+	// A decided authorization never goes back to pending
+	return nil, ErrDeviceCodeExpired
+}
+
+// complete records the user's decision on a pending authorization. The record is
+// watched while it is read and written, so of two concurrent decisions only the
+// first is stored and the second fails instead of overwriting it.
+func (s *deviceAuthorizationService) complete(ctx context.Context, userCode string, apply func(*DeviceAuthorization)) error {
+	deviceCodeHash, err := s.redis.Get(ctx, userCodeKey(NormalizeUserCode(userCode))).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return errors.New("invalid or expired code")
+		}
+		return fmt.Errorf("failed to look up user code: %w", err)
+	}
+
+	key := deviceCodeKey(deviceCodeHash)
+	err = s.redis.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			if err == redis.Nil {
+				return errors.New("invalid or expired code")
+			}
+			return fmt.Errorf("failed to load device authorization: %w", err)
+		}
+
+		var auth DeviceAuthorization
+		if err := json.Unmarshal(data, &auth); err != nil {
+			return fmt.Errorf("failed to decode device authorization: %w", err)
+		}
+		if auth.Status != DeviceStatusPending {
+			return errors.New("this code has already been used")
+		}
+
+		apply(&auth)
+		updated, err := json.Marshal(&auth)
+		if err != nil {
+			return fmt.Errorf("failed to encode device authorization: %w", err)
+		}
+
+		// The user code is single use; the device keeps polling with its device code
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, redis.KeepTTL)
+			pipe.Del(ctx, userCodeKey(auth.UserCode))
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return errors.New("this code has already been used")
+	}
+	return err
+}
+
+func (s *deviceAuthorizationService) loadByUserCode(ctx context.Context, userCode string) (string, *DeviceAuthorization, error) {
+	deviceCodeHash, err := s.redis.Get(ctx, userCodeKey(NormalizeUserCode(userCode))).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil, errors.New("invalid or expired code")
+		}
+		return "", nil, fmt.Errorf("failed to look up user code: %w", err)
+	}
+
+	auth, err := s.load(ctx, deviceCodeHash)
+	if err != nil {
+		return "", nil, errors.New("invalid or expired code")
+	}
+	return deviceCodeHash, auth, nil
+}
+
+func (s *deviceAuthorizationService) load(ctx context.Context, deviceCodeHash string) (*DeviceAuthorization, error) {
+	data, err := s.redis.Get(ctx, deviceCodeKey(deviceCodeHash)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrDeviceCodeExpired
+		}
+		return nil, fmt.Errorf("failed to load device authorization: %w", err)
+	}
+
+	var auth DeviceAuthorization
+	if err := json.Unmarshal(data, &auth); err != nil {
+		return nil, fmt.Errorf("failed to decode device authorization: %w", err)
+	}
+	return &auth, nil
+}
+
+func generateUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// NormalizeUserCode strips separators and case so "bcdf-ghjk" matches "BCDFGHJK"
+func NormalizeUserCode(userCode string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(userCode) {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// FormatUserCode renders a normalized user code as XXXX-XXXX for display
+func FormatUserCode(userCode string) string {
+	userCode = NormalizeUserCode(userCode)
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/pkg/jwt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// A proof is accepted for this long after its iat; its jti is remembered as long
+	dpopProofMaxAge = 5 * time.Minute
+
+	dpopJTIPrefix = "dpop:jti:"
+)
+
+// DPoPService verifies DPoP proofs (RFC 9449) presented at the token endpoint and
+// alongside DPoP-bound access tokens
+type DPoPService interface {
+	// ValidateProof checks the DPoP header of a request for method and path on this server
+	// and returns the thumbprint of the proof key. accessToken is set at resource endpoints,
+	// where the proof must also carry the token hash.
+	ValidateProof(ctx context.Context, proof, method, path, accessToken string) (string, error)
+}
+
+type dpopService struct {
+	redisClient *redis.Client
+	baseURL     string
+}
+
+// NewDPoPService creates a DPoP service. Proof htu values are compared against
+// baseURL joined with the request path.
+func NewDPoPService(redisClient *redis.Client, baseURL string) DPoPService {
+	return &dpopService{
+		redisClient: redisClient,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s *dpopService) ValidateProof(ctx context.Context, proof, method, path, accessToken string) (string, error) {
+	parsed, err := jwt.ParseDPoPProof(proof, method, s.baseURL+path, accessToken, dpopProofMaxAge)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	// A captured proof must not be replayed, so each jti is single use per key
+	key := dpopJTIPrefix + parsed.JKT + ":" + parsed.Claims.ID
+	fresh, err := s.redisClient.SetNX(ctx, key, "1", dpopProofMaxAge+time.Minute).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to record DPoP proof: %w", err)
+	}
+	if !fresh {
+		return "", fmt.Errorf("%w: proof has already been used", ErrInvalidDPoPProof)
+	}
+
+	return parsed.JKT, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"auth-service/pkg/email"
+	"auth-service/pkg/hashutil"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	// Sign-in links and codes are valid for this long after being sent
+	emailLoginTTL = 15 * time.Minute
+	// Wrong codes tolerated before the user has to request a new one
+	maxEmailLoginAttempts = 5
+)
+
+// Ways of delivering a passwordless sign-in
+const (
+	EmailLoginMethodLink = "link"
+	EmailLoginMethodCode = "code"
+)
+
+// EmailLoginService sends and redeems passwordless sign-in links and codes. Each email
+// address has at most one outstanding sign-in; sending another replaces it.
+type EmailLoginService interface {
+	// Send emails a link or 6-digit code (method) that signs in userID
+	Send(ctx context.Context, userID uuid.UUID, toEmail, method string) error
+	// Redeem consumes the link token or code sent to toEmail and returns the user it signs in.
+	// The user is also returned with ErrInvalidEmailLoginCode so the failure can count towards lockout.
+	Redeem(ctx context.Context, toEmail, secret string) (uuid.UUID, error)
+}
+
+// emailLogin is the outstanding sign-in for one email address. Only the secret's HMAC is stored.
+type emailLogin struct {
+	UserID     uuid.UUID `json:"user_id"`
+	SecretHash string    `json:"secret_hash"`
+}
+
+type emailLoginService struct {
+	redis    *redis.Client
+	emailSvc email.Service
+}
+
+// NewEmailLoginService creates a new passwordless email sign-in service
+func NewEmailLoginService(redisClient *redis.Client, emailSvc email.Service) EmailLoginService {
+	return &emailLoginService{redis: redisClient, emailSvc: emailSvc}
+}
+
+func emailLoginKey(emailHash string) string { return "email_login:" + emailHash }
+
+// Wrong guesses are counted under their own key so concurrent ones cannot overwrite each other
+func emailLoginAttemptsKey(loginKey string) string { return loginKey + ":attempts" }
+
+func (s *emailLoginService) Send(ctx context.Context, userID uuid.UUID, toEmail, method string) error {
+	var secret string
+	var err error
+	switch method {
+	case EmailLoginMethodLink:
+		secret, err = generateEmailLoginToken()
+	case EmailLoginMethodCode:
+		secret, err = generateEmailLoginCode()
+	default:
+		return fmt.Errorf("%w: method must be %q or %q", ErrInvalidData, EmailLoginMethodLink, EmailLoginMethodCode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate sign-in secret: %w", err)
+	}
+
+	key, err := s.key(toEmail)
+	if err != nil {
+		return err
+	}
+	secretHash, err := hashutil.RedisLookupHash(secret)
+	if err != nil {
+		return fmt.Errorf("failed to hash sign-in secret: %w", err)
+	}
+	data, err := json.Marshal(&emailLogin{UserID: userID, SecretHash: secretHash})
+	if err != nil {
+		return fmt.Errorf("failed to encode email sign-in: %w", err)
+	}
+	// A new secret gets a fresh set of attempts
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, emailLoginTTL)
+		pipe.Del(ctx, emailLoginAttemptsKey(key))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store email sign-in: %w", err)
+	}
+
+	if method == EmailLoginMethodLink {
+		return s.emailSvc.SendLoginLinkEmail(toEmail, secret)
+	}
+	return s.emailSvc.SendLoginCodeEmail(toEmail, secret)
+}
+
+func (s *emailLoginService) Redeem(ctx context.Context, toEmail, secret string) (uuid.UUID, error) {
+	key, err := s.key(toEmail)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return uuid.Nil, ErrInvalidEmailLoginCode
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to load email sign-in: %w", err)
+	}
+	var state emailLogin
+	if err := json.Unmarshal(data, &state); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to decode email sign-in: %w", err)
+	}
+
+	// Count the attempt before comparing, so parallel guesses are all counted. The count is
+	// left to expire rather than deleted with the sign-in, for guesses still in flight.
+	attempts, err := countAttempt(ctx, s.redis, emailLoginAttemptsKey(key), emailLoginTTL)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if attempts > maxEmailLoginAttempts {
+		_ = s.redis.Del(ctx, key).Err()
+		return state.UserID, ErrInvalidEmailLoginCode
+	}
+
+	secretHash, err := hashutil.RedisLookupHash(strings.TrimSpace(secret))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to hash sign-in secret: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(state.SecretHash)) != 1 {
+		if attempts >= maxEmailLoginAttempts {
+			_ = s.redis.Del(ctx, key).Err()
+		}
+		return state.UserID, ErrInvalidEmailLoginCode
+	}
+
+	// Whoever deletes the record first wins; a concurrent redemption of the same secret fails
+	deleted, err := s.redis.Del(ctx, key).Result()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to consume email sign-in: %w", err)
+	}
+	if deleted == 0 {
+		return uuid.Nil, ErrInvalidEmailLoginCode
+	}
+	return state.UserID, nil
+}
+
+func (s *emailLoginService) key(toEmail string) (string, error) {
+	emailHash, err := hashutil.RedisLookupHash(strings.ToLower(strings.TrimSpace(toEmail)))
+	if err != nil {
+		return "", fmt.Errorf("failed to hash email: %w", err)
+	}
+	return emailLoginKey(emailHash), nil
+}
+
+func generateEmailLoginToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateEmailLoginCode returns a uniformly random 6-digit code
+func generateEmailLoginCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
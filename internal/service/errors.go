@@ -1,6 +1,10 @@
 package service
 
-import "errors"
+import (
+	"errors"
+
+	"auth-service/pkg/password"
+)
 
 // Role-related errors
 var (
@@ -28,6 +32,92 @@ var (
 	ErrInsufficientPermission = errors.New("insufficient permissions")
 )
 
+// OAuth-related errors
+var (
+	ErrTokenClientMismatch = errors.New("token was not issued to this client")
+	ErrUnauthorizedClient  = errors.New("client is not authorized to use this grant")
+	ErrInvalidScope        = errors.New("invalid scope")
+
+	// Dynamic client registration (RFC 7591/7592)
+	ErrInvalidInitialAccessToken = errors.New("invalid, expired or exhausted initial access token")
+	ErrInvalidRegistrationToken  = errors.New("invalid registration access token")
+	ErrInvalidClientMetadata     = errors.New("invalid client metadata")
+	ErrInvalidRedirectURI        = errors.New("invalid redirect URI")
+
+	// JWT client authentication (RFC 7523)
+	ErrInvalidClientAssertion = errors.New("invalid client assertion")
+	ErrClientAssertionNeeded  = errors.New("client must authenticate with a client assertion")
+
+	// Pushed authorization requests (RFC 9126)
+	ErrInvalidRequestURI   = errors.New("invalid or expired request_uri")
+	ErrPushedRequestNeeded = errors.New("this client must use pushed authorization requests")
+
+	// Sender-constrained tokens (RFC 9449)
+	ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+	ErrDPoPKeyMismatch  = errors.New("DPoP proof key does not match the key the token is bound to")
+
+	// Consent grants and connected apps
+	ErrInvalidConsentTicket = errors.New("consent request has expired or was already answered")
+	ErrConnectedAppNotFound = errors.New("connected app not found")
+
+	// Token exchange (RFC 8693)
+	ErrUnsupportedTokenType = errors.New("unsupported token type")
+	ErrInvalidSubjectToken  = errors.New("invalid subject_token")
+	ErrInvalidActorToken    = errors.New("invalid actor_token")
+	ErrActorNotPermitted    = errors.New("actor is not permitted to act for the subject")
+	ErrInvalidTarget        = errors.New("invalid audience for token exchange")
+	ErrInvalidExchangeScope = errors.New("invalid scope for token exchange")
+)
+
+// Multi-factor authentication errors
+var (
+	ErrMFARequired           = errors.New("multi-factor authentication required")
+	ErrInvalidMFACode        = errors.New("invalid authentication code")
+	ErrInvalidMFAChallenge   = errors.New("MFA challenge is invalid or has expired")
+	ErrMFAAlreadyEnabled     = errors.New("multi-factor authentication is already enabled")
+	ErrMFANotEnabled         = errors.New("multi-factor authentication is not enabled")
+	ErrMFAEnrollmentRequired = errors.New("organization requires multi-factor authentication; enroll an authenticator to continue")
+)
+
+// Passkey (WebAuthn) errors
+var (
+	ErrInvalidPasskeyChallenge   = errors.New("passkey challenge is invalid or has expired")
+	ErrPasskeyVerificationFailed = errors.New("passkey verification failed")
+	ErrPasskeyNotFound           = errors.New("passkey not found")
+	ErrPasskeyAlreadyRegistered  = errors.New("passkey is already registered")
+	ErrPasskeyCloned             = errors.New("passkey signature counter went backwards; the authenticator may be cloned")
+)
+
+// Passwordless email login errors
+var (
+	ErrInvalidEmailLoginCode = errors.New("sign-in link or code is invalid or has expired")
+	ErrEmailLoginUnavailable = errors.New("email sign-in is not available")
+)
+
+// Device registry errors
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+// Session management errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// Login risk errors
+var (
+	ErrLoginBlocked        = errors.New("sign-in blocked because it looks unusual for this account; try again from a familiar device or contact support")
+	ErrLoginStepUpRequired = errors.New("sign-in needs extra verification; sign in to your account directly first")
+)
+
+// Password policy errors
+var (
+	ErrPasswordBreached      = password.ErrPasswordBreached
+	ErrPasswordResetRequired = errors.New("password was found in a data breach and must be reset before signing in")
+	ErrPasswordExpired       = errors.New("password has expired and must be changed before signing in")
+	ErrPasswordReused        = errors.New("new password must differ from your recent passwords")
+)
+
 // General errors
 var (
 	ErrInvalidUUID = errors.New("invalid UUID format")
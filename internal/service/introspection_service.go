@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+
+	"github.com/google/uuid"
+)
+
+// Token type hints and token_type values used by introspection and revocation (RFC 7662/7009)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// ScopeTokenIntrospection must be listed in a client's allowed scopes before it may
+// introspect access tokens issued to other clients of its organization, as resource
+// servers do. Only a superadmin can grant it.
+const ScopeTokenIntrospection = "introspect"
+
+// IntrospectionService answers RFC 7662 token introspection requests
+type IntrospectionService interface {
+	Introspect(ctx context.Context, token, tokenTypeHint string, caller *models.ClientApp) (*IntrospectionResponse, error)
+}
+
+// IntrospectionResponse represents an RFC 7662 introspection response.
+// Inactive tokens only carry active=false so nothing leaks about them.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Org       string `json:"org,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	TokenType string `json:"token_type,omitempty"` // access_token or refresh_token
+
+	// Cnf carries the DPoP key thumbprint of a sender-constrained token (RFC 9449 section 6.2)
+	Cnf *jwt.Confirmation `json:"cnf,omitempty"`
+}
+
+type introspectionService struct {
+	repo          repository.Repository
+	jwtService    jwt.JWTService
+	revocationSvc RevocationService
+}
+
+// NewIntrospectionService creates a new introspection service
+func NewIntrospectionService(repo repository.Repository, jwtService jwt.JWTService, revocationSvc RevocationService) IntrospectionService {
+	return &introspectionService{
+		repo:          repo,
+		jwtService:    jwtService,
+		revocationSvc: revocationSvc,
+	}
+}
+
+// Introspect reports whether token is currently active. The hint only decides which
+// lookup runs first; both are tried before declaring the token inactive.
+func (s *introspectionService) Introspect(ctx context.Context, token, tokenTypeHint string, caller *models.ClientApp) (*IntrospectionResponse, error) {
+	lookups := []func(context.Context, string, *models.ClientApp) (*IntrospectionResponse, error){
+		s.introspectJWT,
+		s.introspectRefreshToken,
+	}
+	if tokenTypeHint == TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		resp, err := lookup(ctx, token, caller)
+		if err != nil {
+			return nil, err
+		}
+		if resp != nil {
+			return resp, nil
+		}
+	}
+
+	return &IntrospectionResponse{Active: false}, nil
+}
+
+// introspectJWT returns nil when token is not a JWT issued by this service.
+// Callers only learn about tokens issued to them or meant for them, unless they
+// hold the introspection scope.
+func (s *introspectionService) introspectJWT(ctx context.Context, token string, caller *models.ClientApp) (*IntrospectionResponse, error) {
+	if strings.Count(token, ".") != 2 {
+		return nil, nil
+	}
+
+	claims, err := s.jwtService.ValidateToken(token)
+	if err != nil {
+		// Expired, tampered or signed by a retired key
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	if !mayIntrospect(caller, claims) {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	revoked, err := s.revocationSvc.IsClaimsRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Sub:       claims.Subject,
+		TokenType: TokenTypeHintAccessToken,
+	}
+	if claims.TokenType == "refresh" {
+		resp.TokenType = TokenTypeHintRefreshToken
+	}
+	if claims.Cnf != nil {
+		resp.Cnf = claims.Cnf
+	}
+	if claims.Org != nil {
+		resp.Org = claims.Org.String()
+	} else if claims.OrganizationID != uuid.Nil {
+		resp.Org = claims.OrganizationID.String()
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+
+	return resp, nil
+}
+
+// introspectRefreshToken returns nil when token is not a known OAuth refresh token.
+// Refresh tokens are bound to their client, so other clients only learn active=false.
+func (s *introspectionService) introspectRefreshToken(ctx context.Context, token string, caller *models.ClientApp) (*IntrospectionResponse, error) {
+	tokenHashes, err := hashutil.HMACHashCandidates(token)
+	if err != nil {
+		return nil, nil
+	}
+
+	// GetByTokenHash only returns tokens that are not revoked, used or expired
+	refreshToken, err := s.repo.OAuthRefreshToken().GetByTokenHash(ctx, tokenHashes)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthRefreshTokenNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if caller == nil || refreshToken.ClientID != caller.ClientID {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     refreshToken.Scope,
+		ClientID:  refreshToken.ClientID,
+		Sub:       refreshToken.UserID.String(),
+		Exp:       refreshToken.ExpiresAt.Unix(),
+		Iat:       refreshToken.CreatedAt.Unix(),
+		TokenType: TokenTypeHintRefreshToken,
+	}
+	if refreshToken.OrganizationID != nil {
+		resp.Org = refreshToken.OrganizationID.String()
+	}
+	if refreshToken.DPoPJKT != "" {
+		resp.Cnf = &jwt.Confirmation{JKT: refreshToken.DPoPJKT}
+	}
+
+	return resp, nil
+}
+
+// mayIntrospect reports whether caller may see the state of an access token
+func mayIntrospect(caller *models.ClientApp, claims *jwt.Claims) bool {
+	if caller == nil {
+		return false
+	}
+	if containsString(caller.AllowedScopes, ScopeTokenIntrospection) {
+		orgID := claims.OrganizationID
+		if claims.Org != nil {
+			orgID = *claims.Org
+		}
+		if orgID == caller.OrganizationID {
+			return true
+		}
+	}
+	return claims.ClientID == caller.ClientID || containsString(claims.Audience, caller.ClientID)
+}
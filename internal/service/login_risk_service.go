@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/geoip"
+	"auth-service/pkg/metrics"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// LoginRiskDecision is what a login's risk score calls for
+type LoginRiskDecision string
+
+const (
+	LoginRiskAllow  LoginRiskDecision = "allow"
+	LoginRiskStepUp LoginRiskDecision = "step_up" // MFA, or an emailed code for users without it
+	LoginRiskBlock  LoginRiskDecision = "block"
+)
+
+// Login risk reasons; they are also the activity_type label of the suspicious activity metric
+const (
+	RiskReasonImpossibleTravel = "impossible_travel"
+	RiskReasonNewCountry       = "new_country"
+	RiskReasonNewASN           = "new_asn"
+	RiskReasonNewDevice        = "new_device"
+	RiskReasonFailedAttempts   = "failed_attempt_burst"
+)
+
+// LoginRiskService scores a login whose first factor has been checked, before any tokens
+// are issued. It compares the sign-in with the user's recent sessions (where and when they
+// started) and recent failed attempts.
+type LoginRiskService interface {
+	Evaluate(ctx context.Context, login *LoginRiskInput) *LoginRiskAssessment
+	// RecordLogin remembers a login that starts no session, such as an OAuth sign-in,
+	// so that later evaluations count it as history
+	RecordLogin(ctx context.Context, userID uuid.UUID, ipAddress string)
+}
+
+// Logins without a session are kept for as long as a session's history would matter
+const loginHistoryTTL = 90 * 24 * time.Hour
+
+func loginHistoryKey(userID uuid.UUID) string { return "login_history:" + userID.String() }
+
+// recordedLogin is a login kept by RecordLogin
+type recordedLogin struct {
+	At       time.Time `json:"at"`
+	Location string    `json:"location,omitempty"` // Encoded GeoIP location
+}
+
+// LoginRiskInput describes the login being evaluated
+type LoginRiskInput struct {
+	UserID    uuid.UUID
+	Email     string
+	IPAddress string
+	NewDevice bool // The device registry has not seen this browser before
+}
+
+// LoginRiskSignals is what the evaluator knows about a login and the user's history
+type LoginRiskSignals struct {
+	At        time.Time
+	Location  *geoip.Location // nil without a GeoIP database or for unknown addresses
+	NewDevice bool
+
+	// The user's recent sessions. Without any, novelty is not a signal: every login looks new.
+	HasHistory       bool
+	PreviousLocation *geoip.Location // where the latest session started
+	PreviousLoginAt  time.Time
+	KnownCountries   map[string]bool
+	KnownASNs        map[uint32]bool
+
+	RecentFailures int64 // failed attempts for the account within the burst window
+}
+
+// LoginRiskAssessment is the outcome of scoring a login
+type LoginRiskAssessment struct {
+	Score    int               `json:"score"`
+	Decision LoginRiskDecision `json:"decision"`
+	Reasons  []string          `json:"reasons,omitempty"`
+}
+
+// LoginRiskConfig holds the weights and thresholds of the evaluator
+type LoginRiskConfig struct {
+	// Travel faster than MaxTravelSpeed (km/h) between consecutive logins is impossible. GeoIP
+	// places an address at a city at best, so hops shorter than MinTravelDistance (km) are ignored.
+	MaxTravelSpeed    float64
+	MinTravelDistance float64
+
+	HistorySize        int           // how many recent sessions form the user's usual places
+	FailedAttemptBurst int64         // this many failed attempts within the window is a burst
+	FailedBurstWindow  time.Duration // how far back failed attempts count
+
+	ImpossibleTravelWeight int
+	NewCountryWeight       int
+	NewASNWeight           int
+	NewDeviceWeight        int
+	FailedAttemptsWeight   int
+
+	StepUpScore int // scores at or above this require a second factor
+	BlockScore  int // scores at or above this refuse the login; 0 never blocks
+}
+
+// DefaultLoginRiskConfig returns weights under which no single novelty signal is enough for
+// a step-up, impossible travel is, and only several signals together block the login
+func DefaultLoginRiskConfig() *LoginRiskConfig {
+	return &LoginRiskConfig{
+		MaxTravelSpeed:         1000,
+		MinTravelDistance:      300,
+		HistorySize:            20,
+		FailedAttemptBurst:     3,
+		FailedBurstWindow:      15 * time.Minute,
+		ImpossibleTravelWeight: 60,
+		NewCountryWeight:       25,
+		NewASNWeight:           10,
+		NewDeviceWeight:        20,
+		FailedAttemptsWeight:   30,
+		StepUpScore:            40,
+		BlockScore:             100,
+	}
+}
+
+type loginRiskService struct {
+	repo         repository.Repository
+	redis        *redis.Client
+	auditService AuditService
+	config       *LoginRiskConfig
+}
+
+// NewLoginRiskService creates a login risk evaluator. auditService may be nil, in which
+// case only the suspicious activity metric is recorded. Without redisClient only sessions
+// make up a user's history.
+func NewLoginRiskService(repo repository.Repository, redisClient *redis.Client, auditService AuditService, config *LoginRiskConfig) LoginRiskService {
+	if config == nil {
+		config = DefaultLoginRiskConfig()
+	}
+	return &loginRiskService{
+		repo:         repo,
+		redis:        redisClient,
+		auditService: auditService,
+		config:       config,
+	}
+}
+
+// Evaluate gathers the signals for a login and scores them. Lookup failures drop the
+// affected signal rather than the login: the evaluator adds friction, it is not a gate.
+func (s *loginRiskService) Evaluate(ctx context.Context, login *LoginRiskInput) *LoginRiskAssessment {
+	signals := &LoginRiskSignals{
+		At:        time.Now(),
+		Location:  geoip.Lookup(login.IPAddress),
+		NewDevice: login.NewDevice,
+	}
+
+	sessions, err := s.repo.UserSession().GetRecentByUserID(ctx, login.UserID.String(), s.config.HistorySize)
+	if err != nil {
+		fmt.Printf("Failed to load login history: %v\n", err)
+	}
+	signals.addHistory(s.withRecordedLogins(ctx, login.UserID, sessions))
+
+	since := signals.At.Add(-s.config.FailedBurstWindow)
+	if signals.RecentFailures, err = s.repo.FailedLoginAttempt().CountByEmailSince(ctx, login.Email, since); err != nil {
+		fmt.Printf("Failed to count failed attempts: %v\n", err)
+	}
+
+	assessment := s.config.Score(signals)
+	s.record(ctx, login.UserID, assessment)
+	return assessment
+}
+
+// RecordLogin keeps the time and place of a login in the user's history list
+func (s *loginRiskService) RecordLogin(ctx context.Context, userID uuid.UUID, ipAddress string) {
+	if s.redis == nil {
+		return
+	}
+	entry := recordedLogin{At: time.Now()}
+	if location := geoip.Lookup(ipAddress); location != nil {
+		entry.Location = location.Encode()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	key := loginHistoryKey(userID)
+	_, err = s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, data)
+		pipe.LTrim(ctx, key, 0, int64(s.config.HistorySize)-1)
+		pipe.Expire(ctx, key, loginHistoryTTL)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Failed to record login: %v\n", err)
+	}
+}
+
+// withRecordedLogins merges the logins kept by RecordLogin into the user's sessions as
+// session-shaped entries, newest first, and keeps the history size
+func (s *loginRiskService) withRecordedLogins(ctx context.Context, userID uuid.UUID, sessions []*models.UserSession) []*models.UserSession {
+	if s.redis == nil {
+		return sessions
+	}
+	entries, err := s.redis.LRange(ctx, loginHistoryKey(userID), 0, int64(s.config.HistorySize)-1).Result()
+	if err != nil {
+		fmt.Printf("Failed to load recorded logins: %v\n", err)
+		return sessions
+	}
+	if len(entries) == 0 {
+		return sessions
+	}
+
+	history := append([]*models.UserSession{}, sessions...)
+	for _, raw := range entries {
+		var entry recordedLogin
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		history = append(history, &models.UserSession{UserID: userID, Location: entry.Location, CreatedAt: entry.At})
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].CreatedAt.After(history[j].CreatedAt) })
+	if len(history) > s.config.HistorySize {
+		history = history[:s.config.HistorySize]
+	}
+	return history
+}
+
+// record reports a login that raised any signal: every one goes to the audit log, and those
+// that needed a step-up or were blocked also count as suspicious activity
+func (s *loginRiskService) record(ctx context.Context, userID uuid.UUID, assessment *LoginRiskAssessment) {
+	if len(assessment.Reasons) == 0 {
+		return
+	}
+
+	if assessment.Decision != LoginRiskAllow {
+		sli := metrics.GetMetrics().SLI
+		for _, reason := range assessment.Reasons {
+			sli.RecordSuspiciousActivity(reason)
+		}
+	}
+
+	if s.auditService != nil {
+		s.auditService.LogAuth(ctx, models.ActionLoginRisk, &userID, assessment.Decision != LoginRiskBlock, map[string]interface{}{
+			"score":    assessment.Score,
+			"decision": string(assessment.Decision),
+			"reasons":  assessment.Reasons,
+		}, nil)
+	}
+}
+
+// addHistory fills the history signals from the user's sessions, newest first
+func (sig *LoginRiskSignals) addHistory(sessions []*models.UserSession) {
+	if len(sessions) == 0 {
+		return
+	}
+	sig.HasHistory = true
+	sig.PreviousLocation = geoip.ParseLocation(sessions[0].Location)
+	sig.PreviousLoginAt = sessions[0].CreatedAt
+
+	sig.KnownCountries = map[string]bool{}
+	sig.KnownASNs = map[uint32]bool{}
+	for _, session := range sessions {
+		location := geoip.ParseLocation(session.Location)
+		if location == nil {
+			continue
+		}
+		if location.CountryCode != "" {
+			sig.KnownCountries[location.CountryCode] = true
+		}
+		if location.ASN != 0 {
+			sig.KnownASNs[location.ASN] = true
+		}
+	}
+}
+
+// Score weighs the signals and picks a decision
+func (c *LoginRiskConfig) Score(sig *LoginRiskSignals) *LoginRiskAssessment {
+	assessment := &LoginRiskAssessment{Decision: LoginRiskAllow}
+	add := func(reason string, weight int) {
+		assessment.Score += weight
+		assessment.Reasons = append(assessment.Reasons, reason)
+	}
+
+	if c.impossibleTravel(sig) {
+		add(RiskReasonImpossibleTravel, c.ImpossibleTravelWeight)
+	}
+	if sig.HasHistory && sig.Location != nil {
+		// Sessions from before GeoIP lookups were configured have no country or ASN to compare
+		if sig.Location.CountryCode != "" && len(sig.KnownCountries) > 0 && !sig.KnownCountries[sig.Location.CountryCode] {
+			add(RiskReasonNewCountry, c.NewCountryWeight)
+		}
+		if sig.Location.ASN != 0 && len(sig.KnownASNs) > 0 && !sig.KnownASNs[sig.Location.ASN] {
+			add(RiskReasonNewASN, c.NewASNWeight)
+		}
+	}
+	if sig.HasHistory && sig.NewDevice {
+		add(RiskReasonNewDevice, c.NewDeviceWeight)
+	}
+	if c.FailedAttemptBurst > 0 && sig.RecentFailures >= c.FailedAttemptBurst {
+		add(RiskReasonFailedAttempts, c.FailedAttemptsWeight)
+	}
+
+	switch {
+	case c.BlockScore > 0 && assessment.Score >= c.BlockScore:
+		assessment.Decision = LoginRiskBlock
+	case assessment.Score >= c.StepUpScore:
+		assessment.Decision = LoginRiskStepUp
+	}
+	return assessment
+}
+
+// impossibleTravel reports whether getting from the previous login to this one would have
+// taken more than the maximum travel speed
+func (c *LoginRiskConfig) impossibleTravel(sig *LoginRiskSignals) bool {
+	from, to := sig.PreviousLocation, sig.Location
+	if from == nil || to == nil || !hasCoordinates(from) || !hasCoordinates(to) {
+		return false
+	}
+	distance := distanceKm(from, to)
+	if distance < c.MinTravelDistance {
+		return false
+	}
+	hours := sig.At.Sub(sig.PreviousLoginAt).Hours()
+	if hours <= 0 {
+		return true
+	}
+	return distance/hours > c.MaxTravelSpeed
+}
+
+func hasCoordinates(l *geoip.Location) bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// distanceKm is the great-circle distance between two locations
+func distanceKm(a, b *geoip.Location) float64 {
+	const earthRadiusKm = 6371
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
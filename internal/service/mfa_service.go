@@ -0,0 +1,409 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/totp"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	// The second login step has to be completed (and an org picked) within this window
+	mfaChallengeTTL = 10 * time.Minute
+	// Wrong codes a single challenge tolerates before the password has to be entered again
+	maxMFAChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+	// Accept the previous and next TOTP code to absorb phone clock drift
+	totpSkew = 1
+)
+
+// MFAService manages TOTP authenticators, recovery codes and the MFA step of the login flow
+type MFAService interface {
+	Status(ctx context.Context, userID uuid.UUID) (*MFAStatus, error)
+	IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error)
+
+	// BeginEnrollment creates a new secret; MFA is only enabled once ConfirmEnrollment sees a code for it
+	// account labels the entry in the app (the user's email)
+	BeginEnrollment(ctx context.Context, userID uuid.UUID, account string) (*MFAEnrollment, error)
+	// ConfirmEnrollment enables MFA and returns the recovery codes, which are never shown again
+	ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	Disable(ctx context.Context, userID uuid.UUID, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	// VerifyCode accepts a current TOTP code or an unused recovery code (which it uses up)
+	VerifyCode(ctx context.Context, userID uuid.UUID, code string) (usedRecoveryCode bool, err error)
+
+	// CreateChallenge starts the second login step for a user whose password checked out
+	CreateChallenge(ctx context.Context, userID uuid.UUID) (string, error)
+	// CreateVerifiedChallenge is for logins whose primary credential already covers both
+	// factors, such as a passkey with user verification
+	CreateVerifiedChallenge(ctx context.Context, userID uuid.UUID) (string, error)
+	// VerifyChallenge redeems the challenge with a code and returns the user it was issued to.
+	// The user is also returned with ErrInvalidMFACode so the failure can count towards lockout.
+	VerifyChallenge(ctx context.Context, challenge, code string) (uuid.UUID, error)
+	// CheckChallenge succeeds when challenge was redeemed by userID and has not expired
+	CheckChallenge(ctx context.Context, challenge string, userID uuid.UUID) error
+}
+
+// MFAStatus describes a user's MFA configuration
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollment is shown once while the user adds the account to an authenticator app
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"` // Render as a QR code
+}
+
+type mfaChallenge struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Verified bool      `json:"verified"`
+}
+
+type mfaService struct {
+	repo   repository.Repository
+	redis  *redis.Client
+	issuer string
+}
+
+// NewMFAService creates a new MFA service. issuer is the account label shown in authenticator apps.
+func NewMFAService(repo repository.Repository, redisClient *redis.Client, issuer string) MFAService {
+	return &mfaService{repo: repo, redis: redisClient, issuer: issuer}
+}
+
+func mfaChallengeKey(challengeHash string) string { return "mfa_challenge:" + challengeHash }
+
+// The attempt count has its own key so concurrent guesses cannot overwrite each other's increment
+func mfaChallengeAttemptsKey(challengeKey string) string { return challengeKey + ":attempts" }
+
+func (s *mfaService) Status(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	mfa, err := s.repo.MFA().GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrMFANotConfigured) {
+		return &MFAStatus{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MFA settings: %w", err)
+	}
+	if !mfa.Enabled {
+		return &MFAStatus{}, nil
+	}
+
+	remaining, err := s.repo.MFA().CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return &MFAStatus{Enabled: true, EnabledAt: mfa.EnabledAt, RecoveryCodesRemaining: remaining}, nil
+}
+
+// IsEnabled fails closed: a lookup error is returned rather than treated as "no MFA"
+func (s *mfaService) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	mfa, err := s.repo.MFA().GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrMFANotConfigured) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load MFA settings: %w", err)
+	}
+	return mfa.Enabled, nil
+}
+
+func (s *mfaService) BeginEnrollment(ctx context.Context, userID uuid.UUID, account string) (*MFAEnrollment, error) {
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	secretEnc, err := hashutil.EncryptSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+
+	// Starting over replaces a previous enrollment that was never confirmed
+	if err := s.repo.MFA().Save(ctx, &models.UserMFA{UserID: userID, SecretEnc: secretEnc}); err != nil {
+		return nil, fmt.Errorf("failed to store MFA secret: %w", err)
+	}
+
+	return &MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totp.KeyURI(s.issuer, account, secret),
+	}, nil
+}
+
+func (s *mfaService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	mfa, err := s.repo.MFA().GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, ErrMFANotEnabled
+	}
+	if mfa.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, mfa, code); err != nil {
+		return nil, err
+	}
+	if err := s.repo.MFA().Enable(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *mfaService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	if _, err := s.VerifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := s.repo.MFA().Delete(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	return nil
+}
+
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if _, err := s.VerifyCode(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(ctx, userID)
+}
+
+func (s *mfaService) VerifyCode(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	mfa, err := s.repo.MFA().GetByUserID(ctx, userID)
+	if errors.Is(err, repository.ErrMFANotConfigured) {
+		return false, ErrMFANotEnabled
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load MFA settings: %w", err)
+	}
+	if !mfa.Enabled {
+		return false, ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		return false, s.verifyTOTP(ctx, mfa, code)
+	}
+
+	codeHashes, err := hashutil.HMACHashCandidates(normalizeRecoveryCode(code))
+	if err != nil {
+		return false, fmt.Errorf("failed to hash recovery code: %w", err)
+	}
+	if err := s.repo.MFA().UseRecoveryCode(ctx, userID, codeHashes); err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeNotFound) {
+			return false, ErrInvalidMFACode
+		}
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return true, nil
+}
+
+// verifyTOTP checks code against the user's secret and burns its time step
+func (s *mfaService) verifyTOTP(ctx context.Context, mfa *models.UserMFA, code string) error {
+	secret, err := hashutil.DecryptSecret(mfa.SecretEnc)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt MFA secret: %w", err)
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	// A code seen once (e.g. shoulder-surfed or phished) cannot be replayed within its window
+	if err := s.repo.MFA().AdvanceStep(ctx, mfa.UserID, step); err != nil {
+		if errors.Is(err, repository.ErrTOTPStepAlreadyUsed) {
+			return ErrInvalidMFACode
+		}
+		return fmt.Errorf("failed to record code use: %w", err)
+	}
+	return nil
+}
+
+func (s *mfaService) issueRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codeHash, err := hashutil.HMACHash(normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+		codes[i], hashes[i] = code, codeHash
+	}
+
+	if err := s.repo.MFA().ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns 50 random bits as two groups of five base32 characters
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	encoded := strings.ToLower(base32.StdEncoding.EncodeToString(raw))[:10]
+	return encoded[:5] + "-" + encoded[5:], nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and dashes as users retype codes from paper
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func (s *mfaService) CreateChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	return s.storeChallenge(ctx, &mfaChallenge{UserID: userID})
+}
+
+func (s *mfaService) CreateVerifiedChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	return s.storeChallenge(ctx, &mfaChallenge{UserID: userID, Verified: true})
+}
+
+func (s *mfaService) storeChallenge(ctx context.Context, state *mfaChallenge) (string, error) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", fmt.Errorf("failed to generate MFA challenge: %w", err)
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	challengeHash, err := hashutil.RedisLookupHash(challenge)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash MFA challenge: %w", err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode MFA challenge: %w", err)
+	}
+	if err := s.redis.Set(ctx, mfaChallengeKey(challengeHash), data, mfaChallengeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store MFA challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+func (s *mfaService) VerifyChallenge(ctx context.Context, challenge, code string) (uuid.UUID, error) {
+	key, state, err := s.loadChallenge(ctx, challenge)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if state.Verified {
+		return uuid.Nil, ErrInvalidMFAChallenge
+	}
+
+	// Count the attempt before checking the code, so parallel requests cannot all be
+	// checked before any of them is counted. The count outlives the challenge rather than
+	// being deleted with it, so requests still in flight find it exhausted.
+	attemptsKey := mfaChallengeAttemptsKey(key)
+	attempts, err := countAttempt(ctx, s.redis, attemptsKey, mfaChallengeTTL)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if attempts > maxMFAChallengeAttempts {
+		_ = s.redis.Del(ctx, key).Err()
+		return state.UserID, ErrInvalidMFACode
+	}
+
+	if _, err := s.VerifyCode(ctx, state.UserID, code); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return uuid.Nil, err
+		}
+		if attempts >= maxMFAChallengeAttempts {
+			_ = s.redis.Del(ctx, key).Err()
+		}
+		return state.UserID, ErrInvalidMFACode
+	}
+
+	state.Verified = true
+	if err := s.saveChallenge(ctx, key, state); err != nil {
+		return uuid.Nil, err
+	}
+	return state.UserID, nil
+}
+
+// countAttempt increments an attempt counter, keeping it for ttl after the latest attempt,
+// and returns the new count
+func countAttempt(ctx context.Context, client *redis.Client, attemptsKey string, ttl time.Duration) (int64, error) {
+	var attempts *redis.IntCmd
+	_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		attempts = pipe.Incr(ctx, attemptsKey)
+		pipe.Expire(ctx, attemptsKey, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count attempt: %w", err)
+	}
+	return attempts.Val(), nil
+}
+
+func (s *mfaService) CheckChallenge(ctx context.Context, challenge string, userID uuid.UUID) error {
+	if challenge == "" {
+		return ErrMFARequired
+	}
+	_, state, err := s.loadChallenge(ctx, challenge)
+	if err != nil || !state.Verified || state.UserID != userID {
+		return ErrMFARequired
+	}
+	return nil
+}
+
+func (s *mfaService) loadChallenge(ctx context.Context, challenge string) (string, *mfaChallenge, error) {
+	challengeHash, err := hashutil.RedisLookupHash(challenge)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to hash MFA challenge: %w", err)
+	}
+	key := mfaChallengeKey(challengeHash)
+
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil, ErrInvalidMFAChallenge
+		}
+		return "", nil, fmt.Errorf("failed to load MFA challenge: %w", err)
+	}
+
+	var state mfaChallenge
+	if err := json.Unmarshal(data, &state); err != nil {
+		return "", nil, fmt.Errorf("failed to decode MFA challenge: %w", err)
+	}
+	return key, &state, nil
+}
+
+// saveChallenge updates the challenge without extending its lifetime. A challenge that
+// expired or ran out of attempts meanwhile is not brought back.
+func (s *mfaService) saveChallenge(ctx context.Context, key string, state *mfaChallenge) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode MFA challenge: %w", err)
+	}
+	updated, err := s.redis.SetXX(ctx, key, data, redis.KeepTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to store MFA challenge: %w", err)
+	}
+	if !updated {
+		return ErrInvalidMFAChallenge
+	}
+	return nil
+}
@@ -23,9 +23,11 @@ import (
 type OAuth2Service interface {
 	CreateAuthorizationCode(ctx context.Context, req *AuthorizationRequest) (string, error)
 	ExchangeCodeForTokens(ctx context.Context, req *TokenRequest) (*TokenResponse, error)
-	GetUserInfo(ctx context.Context, userID uuid.UUID) (*UserInfoResponse, error)
-	RevokeRefreshToken(ctx context.Context, token string) error
-	RefreshAccessToken(ctx context.Context, refreshToken, clientID, userAgent, ipAddress string) (*TokenResponse, error)
+	GetUserInfo(ctx context.Context, userID uuid.UUID, scope string) (*UserInfoResponse, error)
+	RevokeRefreshToken(ctx context.Context, token, clientID string) error
+	RefreshAccessToken(ctx context.Context, refreshToken, clientID, dpopJKT, userAgent, ipAddress string) (*TokenResponse, error)
+	ClientCredentialsGrant(ctx context.Context, clientApp *models.ClientApp, scope, dpopJKT string) (*TokenResponse, error)
+	DeviceCodeGrant(ctx context.Context, clientApp *models.ClientApp, auth *DeviceAuthorization, dpopJKT, userAgent, ipAddress string) (*TokenResponse, error)
 }
 
 type oauth2Service struct {
@@ -53,6 +55,9 @@ type AuthorizationRequest struct {
 	CodeChallengeMethod string
 	UserID              uuid.UUID
 	OrganizationID      *uuid.UUID
+	Nonce               string     // OIDC nonce, echoed into the id_token
+	AuthTime            *time.Time // When the user authenticated (OIDC auth_time)
+	AMR                 []string   // Authentication methods used, e.g. ["pwd"]
 }
 
 // TokenRequest represents OAuth2 token exchange request
@@ -63,30 +68,57 @@ type TokenRequest struct {
 	RedirectURI  string
 	CodeVerifier string
 	GrantType    string
-	UserAgent    string // For token binding
-	IPAddress    string // For token binding
+	UserAgent    string // Recorded on the refresh token
+	IPAddress    string // Recorded on the refresh token
+	DPoPJKT      string // Thumbprint of a verified DPoP proof key; binds the issued tokens
+
+	ClientAuthenticated bool // The handler already verified a client assertion for ClientID
+}
+
+// Token types returned in TokenResponse.TokenType
+const (
+	TokenTypeBearer = "Bearer"
+	TokenTypeDPoP   = "DPoP" // Access token is bound to the client's DPoP key (RFC 9449)
+)
+
+// tokenTypeFor returns the token_type for an access token bound to dpopJKT (if any)
+func tokenTypeFor(dpopJKT string) string {
+	if dpopJKT != "" {
+		return TokenTypeDPoP
+	}
+	return TokenTypeBearer
 }
 
 // TokenResponse represents OAuth2 token response
 type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	TokenType    string `json:"token_type"`
-	ExpiresIn    int    `json:"expires_in"`
-	RefreshToken string `json:"refresh_token"`
-	Scope        string `json:"scope,omitempty"`
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"` // Token exchange only (RFC 8693)
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"` // Not issued for client_credentials
+	Scope           string `json:"scope,omitempty"`
+	IDToken         string `json:"id_token,omitempty"` // Only when the openid scope was granted
 }
 
-// UserInfoResponse represents OAuth2 userinfo response
+// UserInfoResponse represents the OIDC userinfo response.
+// Claims beyond sub are only present when the matching scope was granted.
 type UserInfoResponse struct {
 	Sub           string  `json:"sub"`
-	Email         string  `json:"email"`
-	EmailVerified bool    `json:"email_verified"`
-	Name          string  `json:"name"`
-	GivenName     string  `json:"given_name,omitempty"`
-	FamilyName    string  `json:"family_name,omitempty"`
+	Email         string  `json:"email,omitempty"`          // email scope
+	EmailVerified *bool   `json:"email_verified,omitempty"` // email scope
+	Name          string  `json:"name,omitempty"`           // profile scope
+	GivenName     string  `json:"given_name,omitempty"`     // profile scope
+	FamilyName    string  `json:"family_name,omitempty"`    // profile scope
 	Organization  *string `json:"organization,omitempty"`
 }
 
+// OpenID Connect scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
 func (s *oauth2Service) CreateAuthorizationCode(ctx context.Context, req *AuthorizationRequest) (string, error) {
 	// Validate client
 	clientApp, err := s.repo.ClientApp().GetByClientID(ctx, req.ClientID)
@@ -157,6 +189,9 @@ func (s *oauth2Service) CreateAuthorizationCode(ctx context.Context, req *Author
 		Scope:               req.Scope,
 		CodeChallenge:       req.CodeChallenge,
 		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		AuthTime:            req.AuthTime,
+		AMR:                 req.AMR,
 		ExpiresAt:           time.Now().Add(10 * time.Minute),
 		Used:                false,
 	}
@@ -173,14 +208,14 @@ func (s *oauth2Service) ExchangeCodeForTokens(ctx context.Context, req *TokenReq
 		return nil, errors.New("unsupported grant type")
 	}
 
-	// Hash the incoming code for lookup
-	codeHash, err := hashutil.HMACHash(req.Code)
+	// Hash the incoming code for lookup under every HMAC key version
+	codeHashes, err := hashutil.HMACHashCandidates(req.Code)
 	if err != nil {
 		return nil, errors.New("invalid authorization code")
 	}
 
 	// Get authorization code by hash
-	authCode, err := s.repo.AuthorizationCode().GetByCodeHash(ctx, codeHash)
+	authCode, err := s.repo.AuthorizationCode().GetByCodeHash(ctx, codeHashes)
 	if err != nil {
 		return nil, errors.New("invalid or expired authorization code")
 	}
@@ -212,7 +247,10 @@ func (s *oauth2Service) ExchangeCodeForTokens(ctx context.Context, req *TokenReq
 	}
 
 	// Verify client secret for confidential clients
-	if clientApp.IsConfidential {
+	if clientApp.IsConfidential && !req.ClientAuthenticated {
+		if clientApp.TokenEndpointAuthMethod == AuthMethodPrivateKeyJWT || clientApp.TokenEndpointAuthMethod == AuthMethodClientSecretJWT {
+			return nil, ErrClientAssertionNeeded
+		}
 		if req.ClientSecret == "" {
 			return nil, errors.New("client secret required for confidential clients")
 		}
@@ -229,77 +267,108 @@ func (s *oauth2Service) ExchangeCodeForTokens(ctx context.Context, req *TokenReq
 		return nil, errors.New("user not found")
 	}
 
-	// Mark code as used (use hash for lookup)
-	if err := s.repo.AuthorizationCode().MarkAsUsed(ctx, codeHash); err != nil {
+	// Mark code as used (use the stored hash, whichever key version made it)
+	if err := s.repo.AuthorizationCode().MarkAsUsed(ctx, authCode.CodeHash); err != nil {
 		return nil, fmt.Errorf("failed to mark code as used: %w", err)
 	}
 
 	// Generate access token with proper claims
-	accessToken, err := s.generateAccessToken(ctx, user, authCode.OrganizationID, clientApp, authCode.Scope)
+	accessToken, err := s.generateAccessToken(ctx, user, authCode.OrganizationID, clientApp, authCode.Scope, req.DPoPJKT)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	// Generate refresh token with binding
-	refreshToken, err := s.generateRefreshToken(ctx, user.ID, authCode.OrganizationID, req.ClientID, authCode.Scope, req.UserAgent, req.IPAddress)
+	// Generate refresh token, bound to the DPoP key when one was presented
+	refreshToken, err := s.generateRefreshToken(ctx, user.ID, authCode.OrganizationID, req.ClientID, authCode.Scope, req.DPoPJKT, req.UserAgent, req.IPAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	return &TokenResponse{
+	resp := &TokenResponse{
 		AccessToken:  accessToken,
-		TokenType:    "Bearer",
+		TokenType:    tokenTypeFor(req.DPoPJKT),
 		ExpiresIn:    3600, // 1 hour
 		RefreshToken: refreshToken,
 		Scope:        authCode.Scope,
-	}, nil
+	}
+
+	// OpenID Connect: issue an id_token when the openid scope was granted
+	if hasScope(authCode.Scope, ScopeOpenID) {
+		idToken, err := s.generateIDToken(user, authCode, accessToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate id token: %w", err)
+		}
+		resp.IDToken = idToken
+	}
+
+	return resp, nil
 }
 
-func (s *oauth2Service) GetUserInfo(ctx context.Context, userID uuid.UUID) (*UserInfoResponse, error) {
+// GetUserInfo returns the claims for userID permitted by the granted scope.
+// Tokens issued without the openid scope (pre-OIDC clients) receive every claim.
+func (s *oauth2Service) GetUserInfo(ctx context.Context, userID uuid.UUID, scope string) (*UserInfoResponse, error) {
 	user, err := s.repo.User().GetByID(ctx, userID.String())
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	firstName := ""
-	lastName := ""
-	if user.Firstname != nil {
-		firstName = *user.Firstname
+	legacy := !hasScope(scope, ScopeOpenID)
+	response := &UserInfoResponse{
+		Sub: user.ID.String(),
 	}
-	if user.Lastname != nil {
-		lastName = *user.Lastname
+
+	if legacy || hasScope(scope, ScopeEmail) {
+		emailVerified := user.EmailVerifiedAt != nil
+		response.Email = user.Email
+		response.EmailVerified = &emailVerified
 	}
 
-	response := &UserInfoResponse{
-		Sub:           user.ID.String(),
-		Email:         user.Email,
-		EmailVerified: user.EmailVerifiedAt != nil,
-		Name:          firstName + " " + lastName,
-		GivenName:     firstName,
-		FamilyName:    lastName,
+	if legacy || hasScope(scope, ScopeProfile) {
+		response.GivenName, response.FamilyName = userNames(user)
+		response.Name = strings.TrimSpace(response.GivenName + " " + response.FamilyName)
 	}
 
 	return response, nil
 }
 
-func (s *oauth2Service) RevokeRefreshToken(ctx context.Context, token string) error {
+// RevokeRefreshToken revokes the refresh token together with every token rotated from
+// the same family. Unknown or already invalid tokens are ignored (RFC 7009 section 2.2).
+// When clientID is set the token must have been issued to that client.
+func (s *oauth2Service) RevokeRefreshToken(ctx context.Context, token, clientID string) error {
 	// Hash token for lookup (deterministic HMAC-SHA256)
-	tokenHash, err := hashutil.HMACHash(token)
+	tokenHashes, err := hashutil.HMACHashCandidates(token)
 	if err != nil {
 		return fmt.Errorf("failed to hash token: %w", err)
 	}
-	return s.repo.OAuthRefreshToken().Revoke(ctx, tokenHash)
+
+	oauthToken, err := s.repo.OAuthRefreshToken().GetByTokenHash(ctx, tokenHashes)
+	if err != nil {
+		if errors.Is(err, repository.ErrOAuthRefreshTokenNotFound) {
+			// Already revoked, used or expired - nothing left to revoke
+			return nil
+		}
+		return fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	if clientID != "" && oauthToken.ClientID != clientID {
+		return ErrTokenClientMismatch
+	}
+
+	return s.repo.OAuthRefreshToken().RevokeTokenFamily(ctx, oauthToken.FamilyID)
 }
 
-func (s *oauth2Service) RefreshAccessToken(ctx context.Context, refreshToken, clientID, userAgent, ipAddress string) (*TokenResponse, error) {
+// RefreshAccessToken rotates refreshToken. A token family issued to a DPoP key can only be
+// refreshed with a proof from the same key (dpopJKT); unbound families may present a key,
+// which then binds the new access token only.
+func (s *oauth2Service) RefreshAccessToken(ctx context.Context, refreshToken, clientID, dpopJKT, userAgent, ipAddress string) (*TokenResponse, error) {
 	// Hash the incoming refresh token for lookup (deterministic HMAC-SHA256)
-	tokenHash, err := hashutil.HMACHash(refreshToken)
+	tokenHashes, err := hashutil.HMACHashCandidates(refreshToken)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
 
 	// Get refresh token from database
-	oauthToken, err := s.repo.OAuthRefreshToken().GetByTokenHash(ctx, tokenHash)
+	oauthToken, err := s.repo.OAuthRefreshToken().GetByTokenHash(ctx, tokenHashes)
 	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
@@ -319,22 +388,16 @@ func (s *oauth2Service) RefreshAccessToken(ctx context.Context, refreshToken, cl
 		return nil, errors.New("client ID mismatch")
 	}
 
-	// Verify token binding (user agent and IP)
-	userAgentHash := hashutil.SHA256Hash(userAgent)
-	ipHash := hashutil.SHA256Hash(ipAddress)
-
-	if oauthToken.UserAgentHash != userAgentHash {
-		// User agent mismatch - potential token theft
-		_ = s.repo.OAuthRefreshToken().RevokeTokenFamily(ctx, oauthToken.FamilyID)
-		return nil, errors.New("token binding violation - user agent mismatch")
-	}
-
-	if oauthToken.IPHash != ipHash {
-		// IP address mismatch - potential token theft
-		// Note: In production, you might want to be more lenient with IP changes
-		// (e.g., allow rotation within same /24 subnet, or log warning instead of rejecting)
-		_ = s.repo.OAuthRefreshToken().RevokeTokenFamily(ctx, oauthToken.FamilyID)
-		return nil, errors.New("token binding violation - IP address mismatch")
+	// Verify the DPoP key binding
+	if oauthToken.DPoPJKT != "" {
+		if dpopJKT == "" {
+			return nil, errors.New("refresh token is DPoP-bound - a DPoP proof is required")
+		}
+		if dpopJKT != oauthToken.DPoPJKT {
+			// Someone holds the token but not the key - potential token theft
+			_ = s.repo.OAuthRefreshToken().RevokeTokenFamily(ctx, oauthToken.FamilyID)
+			return nil, ErrDPoPKeyMismatch
+		}
 	}
 
 	// Get user
@@ -350,7 +413,7 @@ func (s *oauth2Service) RefreshAccessToken(ctx context.Context, refreshToken, cl
 	}
 
 	// Generate new access token
-	accessToken, err := s.generateAccessToken(ctx, user, oauthToken.OrganizationID, clientApp, oauthToken.Scope)
+	accessToken, err := s.generateAccessToken(ctx, user, oauthToken.OrganizationID, clientApp, oauthToken.Scope, dpopJKT)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -364,13 +427,13 @@ func (s *oauth2Service) RefreshAccessToken(ctx context.Context, refreshToken, cl
 	defer tx.Rollback() // Rollback if not committed
 
 	// Step 1: Generate new refresh token in same family with same binding
-	newRefreshToken, newTokenID, err := s.generateRefreshTokenInTransaction(ctx, tx, oauthToken.FamilyID, oauthToken.UserID, oauthToken.OrganizationID, clientID, oauthToken.Scope, userAgent, ipAddress)
+	newRefreshToken, newTokenID, err := s.generateRefreshTokenInTransaction(ctx, tx, oauthToken.FamilyID, oauthToken.UserID, oauthToken.OrganizationID, clientID, oauthToken.Scope, oauthToken.DPoPJKT, userAgent, ipAddress)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new refresh token: %w", err)
 	}
 
 	// Step 2: Mark old refresh token as used and link to new token
-	if err := s.markTokenAsUsedInTransaction(ctx, tx, tokenHash, newTokenID); err != nil {
+	if err := s.markTokenAsUsedInTransaction(ctx, tx, oauthToken.TokenHash, newTokenID); err != nil {
 		// If MarkAsUsed fails, it means the token was already used (race condition/replay attack)
 		// Transaction will rollback, then revoke the entire token family for security
 		_ = s.repo.OAuthRefreshToken().RevokeTokenFamily(ctx, oauthToken.FamilyID)
@@ -387,16 +450,117 @@ func (s *oauth2Service) RefreshAccessToken(ctx context.Context, refreshToken, cl
 
 	return &TokenResponse{
 		AccessToken:  accessToken,
-		TokenType:    "Bearer",
+		TokenType:    tokenTypeFor(dpopJKT),
 		ExpiresIn:    3600,
 		RefreshToken: newRefreshToken, // Return new refresh token
 		Scope:        oauthToken.Scope,
 	}, nil
 }
 
+// ClientCredentialsGrant issues an org-scoped access token to a confidential client acting
+// on its own behalf. The caller must have authenticated the client. Requested scopes must be
+// a subset of the client's AllowedScopes; an empty request grants all of them.
+func (s *oauth2Service) ClientCredentialsGrant(ctx context.Context, clientApp *models.ClientApp, scope, dpopJKT string) (*TokenResponse, error) {
+	if !clientApp.IsConfidential {
+		return nil, fmt.Errorf("%w: client_credentials grant requires a confidential client", ErrUnauthorizedClient)
+	}
+
+	granted := []string(clientApp.AllowedScopes)
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		allowed := make(map[string]bool, len(clientApp.AllowedScopes))
+		for _, allowedScope := range clientApp.AllowedScopes {
+			allowed[allowedScope] = true
+		}
+		for _, requestedScope := range requested {
+			if !allowed[requestedScope] {
+				return nil, fmt.Errorf("%w: scope '%s' not allowed for this client", ErrInvalidScope, requestedScope)
+			}
+		}
+		granted = requested
+	}
+
+	// There is no end user behind this token, so identity scopes make no sense
+	for _, grantedScope := range granted {
+		if grantedScope == ScopeOpenID {
+			return nil, fmt.Errorf("%w: openid scope is not available for client_credentials", ErrInvalidScope)
+		}
+	}
+
+	// The scopes only go in the scope claim. RBAC checks read permissions, which belong
+	// to users' roles; a client must not gain them by registering a matching scope name.
+	orgID := clientApp.OrganizationID
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(&jwt.OAuthTokenContext{
+		OrganizationID: &orgID,
+		Scopes:         granted,
+		Issuer:         fmt.Sprintf("https://auth.myservice.com/%s", clientApp.ClientID),
+		Audience:       clientApp.ClientID,
+		Subject:        clientApp.ClientID,
+		ClientID:       clientApp.ClientID,
+		DPoPJKT:        dpopJKT,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   tokenTypeFor(dpopJKT),
+		ExpiresIn:   3600,
+		Scope:       strings.Join(granted, " "),
+	}, nil
+}
+
+// DeviceCodeGrant issues tokens for a device authorization the user has approved
+func (s *oauth2Service) DeviceCodeGrant(ctx context.Context, clientApp *models.ClientApp, auth *DeviceAuthorization, dpopJKT, userAgent, ipAddress string) (*TokenResponse, error) {
+	if auth.Status != DeviceStatusApproved {
+		return nil, errors.New("device authorization has not been approved")
+	}
+
+	user, err := s.repo.User().GetByID(ctx, auth.UserID.String())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	accessToken, err := s.generateAccessToken(ctx, user, auth.OrganizationID, clientApp, auth.Scope, dpopJKT)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshToken, err := s.generateRefreshToken(ctx, user.ID, auth.OrganizationID, clientApp.ClientID, auth.Scope, dpopJKT, userAgent, ipAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	resp := &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    tokenTypeFor(dpopJKT),
+		ExpiresIn:    3600,
+		RefreshToken: refreshToken,
+		Scope:        auth.Scope,
+	}
+
+	if hasScope(auth.Scope, ScopeOpenID) {
+		// generateIDToken reads the grant details from an authorization code;
+		// the device flow has no nonce but carries the same auth context
+		idToken, err := s.generateIDToken(user, &models.AuthorizationCode{
+			ClientID:       clientApp.ClientID,
+			OrganizationID: auth.OrganizationID,
+			Scope:          auth.Scope,
+			AuthTime:       auth.AuthTime,
+			AMR:            auth.AMR,
+		}, accessToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate id token: %w", err)
+		}
+		resp.IDToken = idToken
+	}
+
+	return resp, nil
+}
+
 // Helper functions
 
-func (s *oauth2Service) generateAccessToken(ctx context.Context, user *models.User, orgID *uuid.UUID, clientApp *models.ClientApp, scope string) (string, error) {
+func (s *oauth2Service) generateAccessToken(ctx context.Context, user *models.User, orgID *uuid.UUID, clientApp *models.ClientApp, scope, dpopJKT string) (string, error) {
 	// Get user roles
 	roles, err := s.getUserRoles(ctx, user.ID, orgID)
 	if err != nil {
@@ -424,6 +588,14 @@ func (s *oauth2Service) generateAccessToken(ctx context.Context, user *models.Us
 	// Build issuer with client ID
 	issuer := fmt.Sprintf("https://auth.myservice.com/%s", clientApp.ClientID)
 
+	// OIDC scopes ride along with permissions so /oauth/userinfo can tell what was granted
+	var oidcScopes []string
+	for _, requested := range strings.Fields(scope) {
+		if requested == ScopeOpenID || requested == ScopeProfile || requested == ScopeEmail {
+			oidcScopes = append(oidcScopes, requested)
+		}
+	}
+
 	// Generate JWT access token with OAuth2 claims
 	tokenCtx := &jwt.OAuthTokenContext{
 		UserID:         user.ID,
@@ -431,23 +603,75 @@ func (s *oauth2Service) generateAccessToken(ctx context.Context, user *models.Us
 		OrganizationID: orgID,
 		Roles:          roleNames,
 		Permissions:    scopesList,
+		Scopes:         oidcScopes,
 		Issuer:         issuer,
 		Audience:       clientApp.ClientID,
 		Subject:        user.ID.String(),
+		ClientID:       clientApp.ClientID,
+		DPoPJKT:        dpopJKT,
 		IsSuperadmin:   user.IsSuperadmin,
 	}
 
 	return s.jwtService.GenerateOAuthAccessToken(tokenCtx)
 }
 
-func (s *oauth2Service) generateRefreshToken(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, clientID, scope, userAgent, ipAddress string) (string, error) {
+// generateIDToken builds the OIDC id_token for an authorization code exchange
+func (s *oauth2Service) generateIDToken(user *models.User, authCode *models.AuthorizationCode, accessToken string) (string, error) {
+	tokenCtx := &jwt.IDTokenContext{
+		Subject:        user.ID.String(),
+		Audience:       authCode.ClientID,
+		Nonce:          authCode.Nonce,
+		AuthTime:       authCode.AuthTime,
+		AMR:            authCode.AMR,
+		AccessToken:    accessToken,
+		OrganizationID: authCode.OrganizationID,
+	}
+
+	if hasScope(authCode.Scope, ScopeEmail) {
+		emailVerified := user.EmailVerifiedAt != nil
+		tokenCtx.Email = user.Email
+		tokenCtx.EmailVerified = &emailVerified
+	}
+
+	if hasScope(authCode.Scope, ScopeProfile) {
+		tokenCtx.GivenName, tokenCtx.FamilyName = userNames(user)
+		tokenCtx.Name = strings.TrimSpace(tokenCtx.GivenName + " " + tokenCtx.FamilyName)
+	}
+
+	return s.jwtService.GenerateIDToken(tokenCtx)
+}
+
+// hasScope reports whether the space-separated scope string contains target
+func hasScope(scope, target string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
+
+// userNames returns the user's first and last name, empty when unset
+func userNames(user *models.User) (string, string) {
+	firstName := ""
+	lastName := ""
+	if user.Firstname != nil {
+		firstName = *user.Firstname
+	}
+	if user.Lastname != nil {
+		lastName = *user.Lastname
+	}
+	return firstName, lastName
+}
+
+func (s *oauth2Service) generateRefreshToken(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, clientID, scope, dpopJKT, userAgent, ipAddress string) (string, error) {
 	// Generate new family ID for this token chain
 	familyID := uuid.New()
-	token, _, err := s.generateRefreshTokenWithFamilyID(ctx, familyID, userID, orgID, clientID, scope, userAgent, ipAddress)
+	token, _, err := s.generateRefreshTokenWithFamilyID(ctx, familyID, userID, orgID, clientID, scope, dpopJKT, userAgent, ipAddress)
 	return token, err
 }
 
-func (s *oauth2Service) generateRefreshTokenWithFamilyID(ctx context.Context, familyID, userID uuid.UUID, orgID *uuid.UUID, clientID, scope, userAgent, ipAddress string) (string, uuid.UUID, error) {
+func (s *oauth2Service) generateRefreshTokenWithFamilyID(ctx context.Context, familyID, userID uuid.UUID, orgID *uuid.UUID, clientID, scope, dpopJKT, userAgent, ipAddress string) (string, uuid.UUID, error) {
 	// Generate random refresh token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -461,7 +685,7 @@ func (s *oauth2Service) generateRefreshTokenWithFamilyID(ctx context.Context, fa
 		return "", uuid.Nil, fmt.Errorf("failed to hash refresh token: %w", err)
 	}
 
-	// Hash user agent and IP for binding (simple SHA256)
+	// Hash user agent and IP for the record (simple SHA256)
 	userAgentHash := hashutil.SHA256Hash(userAgent)
 	ipHash := hashutil.SHA256Hash(ipAddress)
 
@@ -475,6 +699,7 @@ func (s *oauth2Service) generateRefreshTokenWithFamilyID(ctx context.Context, fa
 		Scope:          scope,
 		UserAgentHash:  userAgentHash,
 		IPHash:         ipHash,
+		DPoPJKT:        dpopJKT,
 		ExpiresAt:      time.Now().Add(30 * 24 * time.Hour),
 		Revoked:        false,
 	}
@@ -487,7 +712,7 @@ func (s *oauth2Service) generateRefreshTokenWithFamilyID(ctx context.Context, fa
 }
 
 // generateRefreshTokenInTransaction creates a new refresh token within a transaction
-func (s *oauth2Service) generateRefreshTokenInTransaction(ctx context.Context, tx repository.Transaction, familyID, userID uuid.UUID, orgID *uuid.UUID, clientID, scope, userAgent, ipAddress string) (string, uuid.UUID, error) {
+func (s *oauth2Service) generateRefreshTokenInTransaction(ctx context.Context, tx repository.Transaction, familyID, userID uuid.UUID, orgID *uuid.UUID, clientID, scope, dpopJKT, userAgent, ipAddress string) (string, uuid.UUID, error) {
 	// Generate random refresh token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -501,7 +726,7 @@ func (s *oauth2Service) generateRefreshTokenInTransaction(ctx context.Context, t
 		return "", uuid.Nil, fmt.Errorf("failed to hash refresh token: %w", err)
 	}
 
-	// Hash user agent and IP for binding (simple SHA256)
+	// Hash user agent and IP for the record (simple SHA256)
 	userAgentHash := hashutil.SHA256Hash(userAgent)
 	ipHash := hashutil.SHA256Hash(ipAddress)
 
@@ -515,6 +740,7 @@ func (s *oauth2Service) generateRefreshTokenInTransaction(ctx context.Context, t
 		Scope:          scope,
 		UserAgentHash:  userAgentHash,
 		IPHash:         ipHash,
+		DPoPJKT:        dpopJKT,
 		ExpiresAt:      time.Now().Add(30 * 24 * time.Hour),
 		Revoked:        false,
 	}
@@ -47,6 +47,7 @@ type CreateOrganizationRequest struct {
 	Name        string  `json:"name"`
 	Slug        string  `json:"slug,omitempty"` // Auto-generated if not provided
 	Description *string `json:"description,omitempty"`
+	MFAToken    string  `json:"mfa_token,omitempty"` // Verified MFA challenge, for users with MFA enabled
 }
 
 // OrganizationResponse represents organization response
@@ -61,6 +62,8 @@ type OrganizationResponse struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	MemberCount int        `json:"member_count"`
+
+	SecurityPolicy *models.SecurityPolicy `json:"security_policy,omitempty"`
 }
 
 // OwnerInfo represents organization owner information
@@ -73,8 +76,9 @@ type OwnerInfo struct {
 
 // UpdateOrganizationRequest represents organization update request
 type UpdateOrganizationRequest struct {
-	Name        string  `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
+	Name           string                 `json:"name,omitempty"`
+	Description    *string                `json:"description,omitempty"`
+	SecurityPolicy *models.SecurityPolicy `json:"security_policy,omitempty"` // Replaces the whole policy when set
 }
 
 // InviteUserRequest represents user invitation request
@@ -225,6 +229,11 @@ func (s *organizationService) UpdateOrganization(ctx context.Context, orgID stri
 	if req.Description != nil {
 		org.Description = req.Description
 	}
+	if req.SecurityPolicy != nil {
+		if err := org.SetSecurityPolicy(req.SecurityPolicy, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := s.repo.Organization().Update(ctx, org); err != nil {
 		return nil, fmt.Errorf("failed to update organization: %w", err)
@@ -847,6 +856,9 @@ func (s *organizationService) convertToOrganizationResponse(ctx context.Context,
 		}
 	}
 
+	// Settings that fail to parse are reported as no policy rather than failing the read
+	policy, _ := org.SecurityPolicy()
+
 	return &OrganizationResponse{
 		ID:          org.ID.String(),
 		Name:        org.Name,
@@ -858,5 +870,7 @@ func (s *organizationService) convertToOrganizationResponse(ctx context.Context,
 		CreatedAt:   org.CreatedAt,
 		UpdatedAt:   org.UpdatedAt,
 		MemberCount: int(memberCount),
+
+		SecurityPolicy: policy,
 	}
 }
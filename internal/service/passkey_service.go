@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/webauthn"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// How long the browser has to complete a ceremony after fetching options
+const passkeyChallengeTTL = 5 * time.Minute
+
+const maxPasskeyNameLength = 100
+
+// PasskeyService runs WebAuthn registration and authentication ceremonies
+type PasskeyService interface {
+	BeginRegistration(ctx context.Context, userID uuid.UUID, email, displayName string) (*webauthn.CreationOptions, error)
+	FinishRegistration(ctx context.Context, userID uuid.UUID, req *PasskeyRegistrationRequest) (*models.WebAuthnCredential, error)
+	ListCredentials(ctx context.Context, userID uuid.UUID) ([]*models.WebAuthnCredential, error)
+	DeleteCredential(ctx context.Context, userID, id uuid.UUID) error
+
+	// BeginLogin starts a usernameless ceremony; any discoverable passkey for this RP may answer
+	BeginLogin(ctx context.Context) (*webauthn.RequestOptions, error)
+	// FinishLogin verifies an assertion and returns the user the passkey belongs to
+	FinishLogin(ctx context.Context, assertion *PasskeyAssertion) (uuid.UUID, error)
+}
+
+// PasskeyRegistrationRequest is the browser's PublicKeyCredential (toJSON form) from
+// navigator.credentials.create(), plus a label for the passkey
+type PasskeyRegistrationRequest struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// PasskeyAssertion is the browser's PublicKeyCredential (toJSON form) from navigator.credentials.get()
+type PasskeyAssertion struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+type passkeyService struct {
+	repo  repository.Repository
+	redis *redis.Client
+	rp    *webauthn.RelyingParty
+}
+
+// NewPasskeyService creates a new passkey service for relying party rp
+func NewPasskeyService(repo repository.Repository, redisClient *redis.Client, rp *webauthn.RelyingParty) PasskeyService {
+	return &passkeyService{repo: repo, redis: redisClient, rp: rp}
+}
+
+// A user has one registration in flight at a time; starting another replaces it
+func passkeyRegistrationKey(userID uuid.UUID) string { return "passkey_reg:" + userID.String() }
+
+func passkeyLoginKey(challengeHash string) string { return "passkey_login:" + challengeHash }
+
+func (s *passkeyService) BeginRegistration(ctx context.Context, userID uuid.UUID, email, displayName string) (*webauthn.CreationOptions, error) {
+	existing, err := s.repo.WebAuthnCredential().ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(existing))
+	for _, cred := range existing {
+		exclude = append(exclude, webauthn.CredentialDescriptor{Type: "public-key", ID: cred.CredentialID, Transports: cred.Transports})
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.Set(ctx, passkeyRegistrationKey(userID), challenge, passkeyChallengeTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store passkey challenge: %w", err)
+	}
+
+	if displayName == "" {
+		displayName = email
+	}
+	user := webauthn.UserEntity{
+		ID:          base64.RawURLEncoding.EncodeToString(userID[:]),
+		Name:        email,
+		DisplayName: displayName,
+	}
+	return s.rp.CreationOptions(challenge, user, exclude, passkeyChallengeTTL.Milliseconds()), nil
+}
+
+func (s *passkeyService) FinishRegistration(ctx context.Context, userID uuid.UUID, req *PasskeyRegistrationRequest) (*models.WebAuthnCredential, error) {
+	challenge, err := s.redis.GetDel(ctx, passkeyRegistrationKey(userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrInvalidPasskeyChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load passkey challenge: %w", err)
+	}
+
+	clientDataJSON, err1 := decodeBase64URL(req.Response.ClientDataJSON)
+	attestationObject, err2 := decodeBase64URL(req.Response.AttestationObject)
+	if err1 != nil || err2 != nil || req.Type != "public-key" {
+		return nil, fmt.Errorf("%w: malformed credential", ErrPasskeyVerificationFailed)
+	}
+
+	verified, err := s.rp.VerifyRegistration(challenge, clientDataJSON, attestationObject, true)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+	credentialID := base64.RawURLEncoding.EncodeToString(verified.ID)
+	if req.ID != "" && strings.TrimRight(req.ID, "=") != credentialID {
+		return nil, fmt.Errorf("%w: credential ID does not match attested data", ErrPasskeyVerificationFailed)
+	}
+
+	if _, err := s.repo.WebAuthnCredential().GetByCredentialID(ctx, credentialID); err == nil {
+		return nil, ErrPasskeyAlreadyRegistered
+	} else if !errors.Is(err, repository.ErrWebAuthnCredentialNotFound) {
+		return nil, fmt.Errorf("failed to look up passkey: %w", err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if len(name) > maxPasskeyNameLength {
+		name = name[:maxPasskeyNameLength]
+	}
+
+	cred := &models.WebAuthnCredential{
+		UserID:         userID,
+		CredentialID:   credentialID,
+		PublicKey:      verified.PublicKey,
+		Algorithm:      verified.Algorithm,
+		SignCount:      int64(verified.SignCount),
+		Transports:     req.Response.Transports,
+		AAGUID:         formatAAGUID(verified.AAGUID),
+		Name:           name,
+		BackupEligible: verified.BackupEligible,
+		BackedUp:       verified.BackedUp,
+	}
+	if err := s.repo.WebAuthnCredential().Create(ctx, cred); err != nil {
+		return nil, fmt.Errorf("failed to store passkey: %w", err)
+	}
+	return cred, nil
+}
+
+func (s *passkeyService) ListCredentials(ctx context.Context, userID uuid.UUID) ([]*models.WebAuthnCredential, error) {
+	return s.repo.WebAuthnCredential().ListByUser(ctx, userID)
+}
+
+func (s *passkeyService) DeleteCredential(ctx context.Context, userID, id uuid.UUID) error {
+	if err := s.repo.WebAuthnCredential().Delete(ctx, userID, id); err != nil {
+		if errors.Is(err, repository.ErrWebAuthnCredentialNotFound) {
+			return ErrPasskeyNotFound
+		}
+		return fmt.Errorf("failed to delete passkey: %w", err)
+	}
+	return nil
+}
+
+func (s *passkeyService) BeginLogin(ctx context.Context) (*webauthn.RequestOptions, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	challengeHash, err := hashutil.RedisLookupHash(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash passkey challenge: %w", err)
+	}
+	if err := s.redis.Set(ctx, passkeyLoginKey(challengeHash), "1", passkeyChallengeTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store passkey challenge: %w", err)
+	}
+	return s.rp.RequestOptions(challenge, nil, passkeyChallengeTTL.Milliseconds()), nil
+}
+
+func (s *passkeyService) FinishLogin(ctx context.Context, assertion *PasskeyAssertion) (uuid.UUID, error) {
+	clientDataJSON, err1 := decodeBase64URL(assertion.Response.ClientDataJSON)
+	authenticatorData, err2 := decodeBase64URL(assertion.Response.AuthenticatorData)
+	signature, err3 := decodeBase64URL(assertion.Response.Signature)
+	userHandle, err4 := decodeBase64URL(assertion.Response.UserHandle)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || assertion.Type != "public-key" {
+		return uuid.Nil, fmt.Errorf("%w: malformed assertion", ErrPasskeyVerificationFailed)
+	}
+
+	// The challenge is only known from the client data here; the ceremony is consumed
+	// whether or not the assertion then verifies, so each challenge gets a single try
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+	challengeHash, err := hashutil.RedisLookupHash(clientData.Challenge)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to hash passkey challenge: %w", err)
+	}
+	if err := s.redis.GetDel(ctx, passkeyLoginKey(challengeHash)).Err(); err != nil {
+		if err == redis.Nil {
+			return uuid.Nil, ErrInvalidPasskeyChallenge
+		}
+		return uuid.Nil, fmt.Errorf("failed to load passkey challenge: %w", err)
+	}
+
+	cred, err := s.repo.WebAuthnCredential().GetByCredentialID(ctx, strings.TrimRight(assertion.ID, "="))
+	if err != nil {
+		if errors.Is(err, repository.ErrWebAuthnCredentialNotFound) {
+			return uuid.Nil, ErrPasskeyNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to look up passkey: %w", err)
+	}
+	// Discoverable credentials report the user handle they were created with
+	if len(userHandle) > 0 && string(userHandle) != string(cred.UserID[:]) {
+		return uuid.Nil, fmt.Errorf("%w: user handle does not match credential", ErrPasskeyVerificationFailed)
+	}
+
+	ad, err := s.rp.VerifyAssertion(clientData.Challenge, cred.PublicKey, clientDataJSON, authenticatorData, signature, true)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrPasskeyVerificationFailed, err)
+	}
+	if !webauthn.SignCountValid(uint32(cred.SignCount), ad.SignCount) {
+		return uuid.Nil, ErrPasskeyCloned
+	}
+	if err := s.repo.WebAuthnCredential().RecordUse(ctx, cred.ID, cred.SignCount, int64(ad.SignCount), ad.Has(webauthn.FlagBackedUp)); err != nil {
+		if errors.Is(err, repository.ErrSignCountNotIncreased) {
+			return uuid.Nil, ErrPasskeyCloned
+		}
+		return uuid.Nil, fmt.Errorf("failed to update passkey: %w", err)
+	}
+	return cred.UserID, nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers differ
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func formatAAGUID(aaguid []byte) string {
+	id, err := uuid.FromBytes(aaguid)
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
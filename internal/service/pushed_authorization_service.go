@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/pkg/hashutil"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// RequestURIPrefix marks request_uri values issued by the PAR endpoint (RFC 9126 section 2.2)
+	RequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+	pushedRequestTTL = 90 * time.Second
+)
+
+// PushedAuthorizationService stores authorization requests pushed by clients (RFC 9126)
+// so the browser only ever carries an opaque request_uri
+type PushedAuthorizationService interface {
+	Push(ctx context.Context, clientApp *models.ClientApp, req *AuthorizationParams) (*PushedAuthorizationResponse, error)
+	// Get returns the pushed request without using it up (showing the consent page)
+	Get(ctx context.Context, clientID, requestURI string) (*AuthorizationParams, error)
+	// Consume returns the pushed request and invalidates the request_uri (issuing the code)
+	Consume(ctx context.Context, clientID, requestURI string) (*AuthorizationParams, error)
+}
+
+// AuthorizationParams are the front-channel parameters of an authorization request
+type AuthorizationParams struct {
+	ClientID            string `json:"client_id"`
+	ResponseType        string `json:"response_type"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Nonce               string `json:"nonce"`
+}
+
+// PushedAuthorizationResponse is returned from the PAR endpoint
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+type pushedAuthorizationService struct {
+	redis *redis.Client
+}
+
+// NewPushedAuthorizationService creates a new pushed authorization request service
+func NewPushedAuthorizationService(redisClient *redis.Client) PushedAuthorizationService {
+	return &pushedAuthorizationService{redis: redisClient}
+}
+
+// The request_uri itself is a bearer reference, so only its HMAC is used as the key
+func pushedRequestKey(requestURIHash string) string { return "par:" + requestURIHash }
+
+// Push validates the request against the client registration up front, so a
+// request_uri always refers to a request the authorization endpoint would accept
+func (s *pushedAuthorizationService) Push(ctx context.Context, clientApp *models.ClientApp, req *AuthorizationParams) (*PushedAuthorizationResponse, error) {
+	if req.ResponseType != "code" {
+		return nil, errors.New("only 'code' response type is supported")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, errors.New("PKCE with S256 method is required")
+	}
+
+	redirectOK := false
+	for _, allowedURI := range clientApp.RedirectURIs {
+		if req.RedirectURI == allowedURI {
+			redirectOK = true
+			break
+		}
+	}
+	if !redirectOK {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	if len(clientApp.AllowedScopes) > 0 {
+		allowed := make(map[string]bool, len(clientApp.AllowedScopes))
+		for _, scope := range clientApp.AllowedScopes {
+			allowed[scope] = true
+		}
+		for _, scope := range strings.Fields(req.Scope) {
+			if !allowed[scope] {
+				return nil, fmt.Errorf("scope '%s' not allowed for this client", scope)
+			}
+		}
+	}
+
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate request_uri: %w", err)
+	}
+	requestURI := RequestURIPrefix + base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	requestURIHash, err := hashutil.RedisLookupHash(requestURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash request_uri: %w", err)
+	}
+
+	stored := *req
+	stored.ClientID = clientApp.ClientID
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode authorization request: %w", err)
+	}
+	if err := s.redis.Set(ctx, pushedRequestKey(requestURIHash), data, pushedRequestTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to store authorization request: %w", err)
+	}
+
+	return &PushedAuthorizationResponse{
+		RequestURI: requestURI,
+		ExpiresIn:  int(pushedRequestTTL.Seconds()),
+	}, nil
+}
+
+func (s *pushedAuthorizationService) Get(ctx context.Context, clientID, requestURI string) (*AuthorizationParams, error) {
+	return s.load(ctx, clientID, requestURI, false)
+}
+
+func (s *pushedAuthorizationService) Consume(ctx context.Context, clientID, requestURI string) (*AuthorizationParams, error) {
+	return s.load(ctx, clientID, requestURI, true)
+}
+
+func (s *pushedAuthorizationService) load(ctx context.Context, clientID, requestURI string, consume bool) (*AuthorizationParams, error) {
+	if !strings.HasPrefix(requestURI, RequestURIPrefix) {
+		return nil, ErrInvalidRequestURI
+	}
+	requestURIHash, err := hashutil.RedisLookupHash(requestURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash request_uri: %w", err)
+	}
+
+	var data []byte
+	if consume {
+		data, err = s.redis.GetDel(ctx, pushedRequestKey(requestURIHash)).Bytes()
+	} else {
+		data, err = s.redis.Get(ctx, pushedRequestKey(requestURIHash)).Bytes()
+	}
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidRequestURI
+		}
+		return nil, fmt.Errorf("failed to load authorization request: %w", err)
+	}
+
+	var params AuthorizationParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("failed to decode authorization request: %w", err)
+	}
+
+	// client_id must still be sent to /authorize and has to match the pushing client
+	if params.ClientID != clientID {
+		return nil, ErrInvalidRequestURI
+	}
+	return &params, nil
+}
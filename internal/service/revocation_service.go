@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"auth-service/internal/repository"
@@ -17,9 +18,20 @@ type RevocationService interface {
 	// RevokeToken adds a token to the denylist
 	RevokeToken(ctx context.Context, tokenString string) error
 
+	// TrackSessionToken remembers the JTI of a session-bound access token until it expires,
+	// so RevokeSessionTokens can deny it
+	TrackSessionToken(ctx context.Context, tokenString string) error
+
+	// RevokeSessionTokens adds every unexpired access token issued to the session to the denylist
+	RevokeSessionTokens(ctx context.Context, sessionID uuid.UUID) error
+
 	// IsTokenRevoked checks if a token is in the denylist
 	IsTokenRevoked(ctx context.Context, tokenString string) (bool, error)
 
+	// IsClaimsRevoked checks already-validated claims against the token denylist and
+	// the user, organization and user+org revocation markers
+	IsClaimsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error)
+
 	// RevokeUserSessions revokes all active sessions for a user
 	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
 
@@ -84,6 +96,68 @@ func (s *revocationService) RevokeToken(ctx context.Context, tokenString string)
 	return nil
 }
 
+func sessionTokensKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("session:tokens:%s", sessionID.String())
+}
+
+// TrackSessionToken records the token's JTI and expiry in a per-session hash that lives as
+// long as the session's newest access token
+func (s *revocationService) TrackSessionToken(ctx context.Context, tokenString string) error {
+	claims, err := s.jwtService.ValidateToken(tokenString)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.SessionID == uuid.Nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	key := sessionTokensKey(claims.SessionID)
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, claims.ID, claims.ExpiresAt.Unix())
+	// Only ever extend: an earlier token in the hash may outlive a shorter-lived new one
+	current := s.redis.TTL(ctx, key).Val()
+	if current < ttl {
+		pipe.Expire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to track session token: %w", err)
+	}
+	return nil
+}
+
+// RevokeSessionTokens denies each tracked JTI for the rest of its lifetime
+func (s *revocationService) RevokeSessionTokens(ctx context.Context, sessionID uuid.UUID) error {
+	key := sessionTokensKey(sessionID)
+	tokens, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load session tokens: %w", err)
+	}
+
+	now := time.Now()
+	pipe := s.redis.TxPipeline()
+	for jti, exp := range tokens {
+		expiresAt, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			continue
+		}
+		ttl := time.Unix(expiresAt, 0).Sub(now)
+		if ttl <= 0 {
+			continue
+		}
+		pipe.Set(ctx, fmt.Sprintf("revoked:token:%s", jti), "revoked", ttl)
+	}
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to revoke session tokens: %w", err)
+	}
+	return nil
+}
+
 // IsTokenRevoked checks if a token is in the Redis denylist
 func (s *revocationService) IsTokenRevoked(ctx context.Context, tokenString string) (bool, error) {
 	// Parse token to get JTI
@@ -108,6 +182,52 @@ func (s *revocationService) IsTokenRevoked(ctx context.Context, tokenString stri
 	return exists > 0, nil
 }
 
+// IsClaimsRevoked checks the jti denylist and whether the token was issued before a
+// user, organization or user+org revocation was recorded
+func (s *revocationService) IsClaimsRevoked(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	if claims.ID != "" {
+		exists, err := s.redis.Exists(ctx, fmt.Sprintf("revoked:token:%s", claims.ID)).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if exists > 0 {
+			return true, nil
+		}
+	}
+
+	orgID := claims.OrganizationID
+	if claims.Org != nil {
+		orgID = *claims.Org
+	}
+
+	keys := []string{}
+	if claims.UserID != uuid.Nil {
+		keys = append(keys, fmt.Sprintf("revoked:user:%s", claims.UserID.String()))
+	}
+	if orgID != uuid.Nil {
+		keys = append(keys, fmt.Sprintf("revoked:org:%s", orgID.String()))
+		if claims.UserID != uuid.Nil {
+			keys = append(keys, fmt.Sprintf("revoked:user_org:%s:%s", claims.UserID.String(), orgID.String()))
+		}
+	}
+
+	for _, key := range keys {
+		revokedAt, err := s.redis.Get(ctx, key).Int64()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to check revocation marker: %w", err)
+		}
+		// Markers hold the revocation time; only tokens issued before it are affected
+		if claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revokedAt {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 // RevokeUserSessions revokes all active sessions for a user across all organizations
 func (s *revocationService) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
 	// Get all active sessions for the user
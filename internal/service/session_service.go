@@ -4,12 +4,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"net"
 	"strings"
 	"time"
 
 	"auth-service/internal/models"
 	"auth-service/internal/repository"
+	"auth-service/pkg/geoip"
 	"auth-service/pkg/logger"
 
 	"github.com/google/uuid"
@@ -17,15 +17,19 @@ import (
 
 // SessionService defines the interface for advanced session management
 type SessionService interface {
-	CreateSession(ctx context.Context, userID, tenantID string, ipAddress, userAgent string) (*models.UserSession, error)
+	CreateSession(ctx context.Context, userID, tenantID string, ipAddress, userAgent string, deviceID *uuid.UUID) (*models.UserSession, error)
 	ValidateSession(ctx context.Context, token string) (*models.UserSession, error)
 	UpdateSessionActivity(ctx context.Context, sessionID string) error
 	RevokeSession(ctx context.Context, sessionID, reason string) error
 	RevokeUserSessions(ctx context.Context, userID, reason string) error
+	// RevokeDeviceSessions revokes every active session started from a device, along with their
+	// refresh tokens and the access tokens already issued to them
+	RevokeDeviceSessions(ctx context.Context, deviceID, reason string) error
 	GetUserSessions(ctx context.Context, userID string) ([]*models.UserSession, error)
 	GetActiveSessionCount(ctx context.Context, userID string) (int64, error)
 	EnforceSessionLimits(ctx context.Context, userID string, maxSessions int) error
 	DetectSuspiciousActivity(ctx context.Context, session *models.UserSession) (bool, string)
+	SetRevocationService(revocationSvc RevocationService)
 	CleanupExpiredSessions(ctx context.Context) error
 	CleanupInactiveSessions(ctx context.Context, maxInactive time.Duration) error
 	GetSessionsByIPAddress(ctx context.Context, ipAddress string) ([]*models.UserSession, error)
@@ -44,9 +48,10 @@ type SessionConfig struct {
 
 // sessionService implements SessionService interface
 type sessionService struct {
-	repo        repository.Repository
-	config      *SessionConfig
-	auditLogger *logger.AuditLogger
+	repo          repository.Repository
+	config        *SessionConfig
+	auditLogger   *logger.AuditLogger
+	revocationSvc RevocationService
 }
 
 // NewSessionService creates a new session service
@@ -58,8 +63,14 @@ func NewSessionService(repo repository.Repository, config *SessionConfig) Sessio
 	}
 }
 
-// CreateSession creates a new user session with device fingerprinting
-func (s *sessionService) CreateSession(ctx context.Context, userID, tenantID string, ipAddress, userAgent string) (*models.UserSession, error) {
+// SetRevocationService lets session revocation also deny the access tokens issued to a session
+func (s *sessionService) SetRevocationService(revocationSvc RevocationService) {
+	s.revocationSvc = revocationSvc
+}
+
+// CreateSession creates a new user session with device fingerprinting. deviceID links the
+// session to the trusted device it was started from, when known.
+func (s *sessionService) CreateSession(ctx context.Context, userID, tenantID string, ipAddress, userAgent string, deviceID *uuid.UUID) (*models.UserSession, error) {
 	// Generate session token
 	sessionToken := generateSecureToken()
 
@@ -84,6 +95,7 @@ func (s *sessionService) CreateSession(ctx context.Context, userID, tenantID str
 		IPAddress:         ipAddress,
 		UserAgent:         userAgent,
 		DeviceFingerprint: deviceFingerprint,
+		DeviceID:          deviceID,
 		Location:          location,
 		IsActive:          true,
 		LastActivity:      time.Now(),
@@ -164,6 +176,29 @@ func (s *sessionService) RevokeUserSessions(ctx context.Context, userID, reason
 	return nil
 }
 
+// RevokeDeviceSessions revokes all sessions started from a trusted device
+func (s *sessionService) RevokeDeviceSessions(ctx context.Context, deviceID, reason string) error {
+	sessions, err := s.repo.UserSession().GetActiveByDeviceID(ctx, deviceID)
+	if err != nil {
+		return fmt.Errorf("failed to get device sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		if err := s.repo.RefreshToken().RevokeBySession(ctx, session.ID.String(), reason); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		if err := s.RevokeSession(ctx, session.ID.String(), reason); err != nil {
+			return err
+		}
+		if s.revocationSvc != nil {
+			if err := s.revocationSvc.RevokeSessionTokens(ctx, session.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // GetUserSessions retrieves all sessions for a user
 func (s *sessionService) GetUserSessions(ctx context.Context, userID string) ([]*models.UserSession, error) {
 	return s.repo.UserSession().GetByUserID(ctx, userID)
@@ -204,12 +239,13 @@ func (s *sessionService) EnforceSessionLimits(ctx context.Context, userID string
 func (s *sessionService) DetectSuspiciousActivity(ctx context.Context, session *models.UserSession) (bool, string) {
 	reasons := []string{}
 
-	// Check for rapid location changes
-	if s.config.EnableGeoTracking && session.Location != "" {
+	// Check for rapid location changes; locations are compared by country since the
+	// city and network of one device legitimately vary
+	if location := geoip.ParseLocation(session.Location); s.config.EnableGeoTracking && location != nil {
 		recentSessions, err := s.repo.UserSession().GetSessionsByDeviceFingerprint(ctx, session.DeviceFingerprint)
 		if err == nil && len(recentSessions) > 0 {
 			for _, s := range recentSessions {
-				if s.Location != "" && s.Location != session.Location {
+				if previous := geoip.ParseLocation(s.Location); previous != nil && previous.CountryCode != location.CountryCode {
 					reasons = append(reasons, "location_change")
 					break
 				}
@@ -281,30 +317,13 @@ func (s *sessionService) generateDeviceFingerprint(ipAddress, userAgent string)
 	return fmt.Sprintf("%x", hash)[:16] // First 16 chars of hash
 }
 
-// getLocationFromIP attempts to get location from IP address
+// getLocationFromIP resolves the address against the offline GeoIP database and returns
+// the encoded location, or "" when there is no database or the address is unknown
 func (s *sessionService) getLocationFromIP(ipAddress string) string {
 	if !s.config.EnableGeoTracking {
 		return ""
 	}
-
-	// Parse IP address
-	ip := net.ParseIP(ipAddress)
-	if ip == nil {
-		return ""
-	}
-
-	// This is a placeholder - in a real implementation, you would use a GeoIP service
-	// For now, return a basic classification
-	if ip.IsPrivate() {
-		return "private_network"
-	}
-
-	// Basic geographic hints (very simplified)
-	if strings.HasPrefix(ipAddress, "192.168.") || strings.HasPrefix(ipAddress, "10.") {
-		return "local_network"
-	}
-
-	return "unknown"
+	return geoip.Lookup(ipAddress).Encode()
 }
 
 // generateSecureToken generates a cryptographically secure session token
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/jwt"
+
+	"github.com/google/uuid"
+)
+
+// GrantTypeTokenExchange is the grant_type of a token exchange request (RFC 8693)
+const GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+// Every exchange may add an actor; past this the chain is more likely a loop than a real hop
+const maxDelegationDepth = 5
+
+// TokenExchangeService swaps a token the caller holds for a narrower token aimed at
+// another service, optionally recording who acts on the subject's behalf
+type TokenExchangeService interface {
+	Exchange(ctx context.Context, clientApp *models.ClientApp, req *TokenExchangeRequest) (*TokenExchangeResult, error)
+}
+
+// TokenExchangeRequest carries the token exchange parameters of a /oauth/token request
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string // Optional; present for delegation
+	ActorTokenType     string
+	RequestedTokenType string // Only access tokens are issued
+	Audience           string // client_id of the downstream service; defaults to the caller
+	Scope              string // Empty keeps the subject token's scope
+	DPoPJKT            string
+}
+
+// TokenExchangeResult is the issued token plus what the audit trail records about it
+type TokenExchangeResult struct {
+	Response *TokenResponse
+	UserID   *uuid.UUID // nil when the subject is a client rather than a user
+	Subject  string
+	Audience string
+	Act      *jwt.Actor
+}
+
+type tokenExchangeService struct {
+	repo          repository.Repository
+	jwtService    jwt.JWTService
+	revocationSvc RevocationService
+}
+
+// NewTokenExchangeService creates a new token exchange service
+func NewTokenExchangeService(repo repository.Repository, jwtService jwt.JWTService, revocationSvc RevocationService) TokenExchangeService {
+	return &tokenExchangeService{
+		repo:          repo,
+		jwtService:    jwtService,
+		revocationSvc: revocationSvc,
+	}
+}
+
+// Exchange issues an access token for the subject of req.SubjectToken. Without an actor
+// token the caller impersonates the subject; with one, the actor is pushed onto the act
+// chain. The new token never carries more scope or outlives the subject token.
+func (s *tokenExchangeService) Exchange(ctx context.Context, clientApp *models.ClientApp, req *TokenExchangeRequest) (*TokenExchangeResult, error) {
+	if !clientApp.IsConfidential {
+		return nil, fmt.Errorf("%w: token exchange requires a confidential client", ErrUnauthorizedClient)
+	}
+	// Clients without registered grant types may use any other grant, but exchange lets a
+	// client act as its users, so it has to be asked for by name
+	if !registersGrantType(clientApp, GrantTypeTokenExchange) {
+		return nil, fmt.Errorf("%w: token exchange is not registered for this client", ErrUnauthorizedClient)
+	}
+	if !exchangeableTokenType(req.SubjectTokenType) {
+		return nil, fmt.Errorf("%w: subject_token_type %q", ErrUnsupportedTokenType, req.SubjectTokenType)
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != jwt.TokenTypeAccessToken {
+		return nil, fmt.Errorf("%w: only access tokens can be requested", ErrUnsupportedTokenType)
+	}
+
+	subject, err := s.validateToken(ctx, req.SubjectToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubjectToken, err)
+	}
+	// First-party session tokens carry the user's permissions rather than a client's grant
+	if subject.ClientID == "" {
+		return nil, fmt.Errorf("%w: only OAuth access tokens can be exchanged", ErrInvalidSubjectToken)
+	}
+	// A client may only exchange tokens that were issued to it or aimed at it
+	if subject.ClientID != clientApp.ClientID && !containsString(subject.Audience, clientApp.ClientID) {
+		return nil, fmt.Errorf("%w: token was not issued to this client", ErrInvalidSubjectToken)
+	}
+	// A sender-constrained token can only be exchanged by whoever holds its key
+	if subject.Cnf != nil && subject.Cnf.JKT != req.DPoPJKT {
+		return nil, fmt.Errorf("%w: token is bound to a DPoP key the request did not prove", ErrInvalidSubjectToken)
+	}
+
+	orgID := claimsOrganization(subject)
+	if orgID == nil || *orgID != clientApp.OrganizationID {
+		return nil, fmt.Errorf("%w: token belongs to another organization", ErrInvalidSubjectToken)
+	}
+
+	// Without an actor token the calling client is the party doing the acting
+	actorSubject, actorClientID := clientApp.ClientID, clientApp.ClientID
+	act := subject.Act
+	if req.ActorToken != "" {
+		if !exchangeableTokenType(req.ActorTokenType) {
+			return nil, fmt.Errorf("%w: actor_token_type %q", ErrUnsupportedTokenType, req.ActorTokenType)
+		}
+		actor, err := s.validateToken(ctx, req.ActorToken)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidActorToken, err)
+		}
+		// The actor token is held to the same key and organization checks as the subject token
+		if actor.Cnf != nil && actor.Cnf.JKT != req.DPoPJKT {
+			return nil, fmt.Errorf("%w: token is bound to a DPoP key the request did not prove", ErrInvalidActorToken)
+		}
+		if actorOrgID := claimsOrganization(actor); actorOrgID == nil || *actorOrgID != clientApp.OrganizationID {
+			return nil, fmt.Errorf("%w: token belongs to another organization", ErrInvalidActorToken)
+		}
+		actorSubject, actorClientID = actor.Subject, actor.ClientID
+		act = &jwt.Actor{Subject: actor.Subject, ClientID: actor.ClientID, Act: subject.Act}
+	} else if req.ActorTokenType != "" {
+		return nil, fmt.Errorf("%w: actor_token_type given without actor_token", ErrUnsupportedTokenType)
+	}
+
+	if subject.MayAct != nil && !subject.MayAct.Permits(actorSubject, actorClientID) {
+		return nil, ErrActorNotPermitted
+	}
+	if act.Depth() > maxDelegationDepth {
+		return nil, fmt.Errorf("%w: delegation chain is too long", ErrActorNotPermitted)
+	}
+
+	audience := req.Audience
+	if audience == "" {
+		audience = clientApp.ClientID
+	} else if audience != clientApp.ClientID {
+		target, err := s.repo.ClientApp().GetByClientID(ctx, audience)
+		if err != nil || target.OrganizationID != clientApp.OrganizationID {
+			return nil, ErrInvalidTarget
+		}
+	}
+
+	granted, err := exchangedScopes(claimsScopes(subject), strings.Fields(req.Scope), clientApp.AllowedScopes)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if subject.ExpiresAt != nil && subject.ExpiresAt.Time.Before(expiresAt) {
+		expiresAt = subject.ExpiresAt.Time
+	}
+
+	// Only scopes carry over: roles and permissions would let RBAC checks treat the
+	// exchanged token like one of the user's own sessions
+	accessToken, err := s.jwtService.GenerateOAuthAccessToken(&jwt.OAuthTokenContext{
+		UserID:         subject.UserID,
+		Email:          subject.Email,
+		OrganizationID: orgID,
+		Scopes:         granted,
+		Issuer:         fmt.Sprintf("https://auth.myservice.com/%s", audience),
+		Audience:       audience,
+		Subject:        subject.Subject,
+		ClientID:       clientApp.ClientID,
+		DPoPJKT:        req.DPoPJKT,
+		Act:            act,
+		ExpiresAt:      expiresAt,
+		// Superadmin rights stay with the original token; exchanged tokens are narrower by design
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	result := &TokenExchangeResult{
+		Response: &TokenResponse{
+			AccessToken:     accessToken,
+			IssuedTokenType: jwt.TokenTypeAccessToken,
+			TokenType:       tokenTypeFor(req.DPoPJKT),
+			ExpiresIn:       int(time.Until(expiresAt).Seconds()),
+			Scope:           strings.Join(granted, " "),
+		},
+		Subject:  subject.Subject,
+		Audience: audience,
+		Act:      act,
+	}
+	if subject.UserID != uuid.Nil {
+		userID := subject.UserID
+		result.UserID = &userID
+	}
+	return result, nil
+}
+
+// validateToken accepts live access tokens issued by this service
+func (s *tokenExchangeService) validateToken(ctx context.Context, token string) (*jwt.Claims, error) {
+	claims, err := s.jwtService.ParseAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := s.revocationSvc.IsClaimsRevoked(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check revocation: %w", err)
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+	return claims, nil
+}
+
+// Our access tokens are JWTs, so either identifier describes them
+func exchangeableTokenType(tokenType string) bool {
+	return tokenType == jwt.TokenTypeAccessToken || tokenType == jwt.TokenTypeJWT
+}
+
+// claimsOrganization returns the org of an OAuth (org claim) or first-party (organization_id) token
+func claimsOrganization(claims *jwt.Claims) *uuid.UUID {
+	if claims.Org != nil {
+		return claims.Org
+	}
+	if claims.OrganizationID != uuid.Nil {
+		orgID := claims.OrganizationID
+		return &orgID
+	}
+	return nil
+}
+
+// claimsScopes returns the OAuth scope of a token
+func claimsScopes(claims *jwt.Claims) []string {
+	return strings.Fields(claims.Scope)
+}
+
+// registersGrantType reports whether grantType is explicitly among the client's grant types
+func registersGrantType(clientApp *models.ClientApp, grantType string) bool {
+	return containsString(clientApp.GrantTypes, grantType)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// exchangedScopes narrows the subject's scopes to the requested ones (all of them when
+// none are requested) and to what the calling client may hold
+func exchangedScopes(subjectScopes, requested, clientScopes []string) ([]string, error) {
+	held := make(map[string]bool, len(subjectScopes))
+	for _, scope := range subjectScopes {
+		held[scope] = true
+	}
+	var allowed map[string]bool
+	if len(clientScopes) > 0 {
+		allowed = make(map[string]bool, len(clientScopes))
+		for _, scope := range clientScopes {
+			allowed[scope] = true
+		}
+	}
+
+	if len(requested) == 0 {
+		granted := make([]string, 0, len(subjectScopes))
+		for _, scope := range subjectScopes {
+			if allowed == nil || allowed[scope] {
+				granted = append(granted, scope)
+			}
+		}
+		return granted, nil
+	}
+
+	for _, scope := range requested {
+		if !held[scope] {
+			return nil, fmt.Errorf("%w: '%s' is not in the subject_token", ErrInvalidExchangeScope, scope)
+		}
+		if allowed != nil && !allowed[scope] {
+			return nil, fmt.Errorf("%w: '%s' is not allowed for this client", ErrInvalidExchangeScope, scope)
+		}
+	}
+	return requested, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/useragent"
+
+	"github.com/google/uuid"
+)
+
+// TrustedDeviceService keeps the registry of devices each user signs in from. A device is
+// identified by a random token kept in a long-lived cookie; the server stores only its HMAC,
+// so a token cannot be forged or recovered from the database.
+type TrustedDeviceService interface {
+	// Recognize returns the user's device for token and records the sign-in. When the token
+	// is missing, revoked or belongs to another user a new device is registered and its
+	// token returned for the cookie; otherwise the returned token is empty.
+	Recognize(ctx context.Context, userID uuid.UUID, token, ip, userAgent string) (*models.TrustedDevice, string, error)
+	// Lookup returns the user's device for token, or nil when the token is not theirs
+	Lookup(ctx context.Context, userID uuid.UUID, token string) (*models.TrustedDevice, error)
+	// Trust lets the device skip MFA for the configured number of days
+	Trust(ctx context.Context, device *models.TrustedDevice) error
+	// List returns the user's devices, marking the one currentToken identifies
+	List(ctx context.Context, userID uuid.UUID, currentToken string) ([]*models.TrustedDevice, error)
+	// Revoke forgets a device and signs out every session started from it
+	Revoke(ctx context.Context, userID, deviceID uuid.UUID) error
+}
+
+type trustedDeviceService struct {
+	repo       repository.Repository
+	sessionSvc SessionService
+	trustFor   time.Duration
+}
+
+// NewTrustedDeviceService creates a new device registry. trustDays is how long a device
+// skips MFA after the user asks to remember it; zero turns remembering off.
+func NewTrustedDeviceService(repo repository.Repository, sessionSvc SessionService, trustDays int) TrustedDeviceService {
+	return &trustedDeviceService{
+		repo:       repo,
+		sessionSvc: sessionSvc,
+		trustFor:   time.Duration(trustDays) * 24 * time.Hour,
+	}
+}
+
+func (s *trustedDeviceService) Recognize(ctx context.Context, userID uuid.UUID, token, ip, userAgent string) (*models.TrustedDevice, string, error) {
+	now := time.Now()
+	device, err := s.Lookup(ctx, userID, token)
+	if err != nil {
+		return nil, "", err
+	}
+	if device != nil {
+		if err := s.repo.TrustedDevice().Touch(ctx, device.ID, ip, userAgent, now); err != nil {
+			return nil, "", fmt.Errorf("failed to update device: %w", err)
+		}
+		device.LastIP, device.UserAgent, device.LastSeenAt = ip, userAgent, now
+		return device, "", nil
+	}
+
+	token, tokenHash, err := newDeviceToken()
+	if err != nil {
+		return nil, "", err
+	}
+	device = &models.TrustedDevice{
+		UserID:     userID,
+		TokenHash:  tokenHash,
+		Name:       useragent.Parse(userAgent).String(),
+		UserAgent:  userAgent,
+		LastIP:     ip,
+		LastSeenAt: now,
+	}
+	if err := s.repo.TrustedDevice().Create(ctx, device); err != nil {
+		return nil, "", fmt.Errorf("failed to register device: %w", err)
+	}
+	return device, token, nil
+}
+
+func (s *trustedDeviceService) Lookup(ctx context.Context, userID uuid.UUID, token string) (*models.TrustedDevice, error) {
+	if token == "" {
+		return nil, nil
+	}
+	tokenHashes, err := hashutil.HMACHashCandidates(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash device token: %w", err)
+	}
+	device, err := s.repo.TrustedDevice().GetByTokenHash(ctx, tokenHashes)
+	if errors.Is(err, repository.ErrTrustedDeviceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up device: %w", err)
+	}
+	// A shared browser carries the cookie of whoever signed in last
+	if device.UserID != userID {
+		return nil, nil
+	}
+	return device, nil
+}
+
+func (s *trustedDeviceService) Trust(ctx context.Context, device *models.TrustedDevice) error {
+	if s.trustFor <= 0 {
+		return nil
+	}
+	until := time.Now().Add(s.trustFor)
+	if err := s.repo.TrustedDevice().Trust(ctx, device.ID, until); err != nil {
+		return fmt.Errorf("failed to trust device: %w", err)
+	}
+	device.TrustedUntil = &until
+	return nil
+}
+
+func (s *trustedDeviceService) List(ctx context.Context, userID uuid.UUID, currentToken string) ([]*models.TrustedDevice, error) {
+	devices, err := s.repo.TrustedDevice().ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	if currentToken != "" {
+		for _, d := range devices {
+			d.Current, _ = hashutil.VerifyHMACHash(currentToken, d.TokenHash)
+		}
+	}
+	return devices, nil
+}
+
+func (s *trustedDeviceService) Revoke(ctx context.Context, userID, deviceID uuid.UUID) error {
+	if err := s.repo.TrustedDevice().Revoke(ctx, userID, deviceID); err != nil {
+		if errors.Is(err, repository.ErrTrustedDeviceNotFound) {
+			return ErrDeviceNotFound
+		}
+		return fmt.Errorf("failed to revoke device: %w", err)
+	}
+	if s.sessionSvc != nil {
+		if err := s.sessionSvc.RevokeDeviceSessions(ctx, deviceID.String(), "device_revoked"); err != nil {
+			return fmt.Errorf("failed to revoke device sessions: %w", err)
+		}
+	}
+	return nil
+}
+
+func newDeviceToken() (token, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate device token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	if tokenHash, err = hashutil.HMACHash(token); err != nil {
+		return "", "", fmt.Errorf("failed to hash device token: %w", err)
+	}
+	return token, tokenHash, nil
+}
@@ -14,9 +14,11 @@ import (
 	"auth-service/internal/models"
 	"auth-service/internal/repository"
 	"auth-service/pkg/email"
+	"auth-service/pkg/geoip"
 	"auth-service/pkg/jwt"
 	"auth-service/pkg/logger"
 	"auth-service/pkg/password"
+	"auth-service/pkg/useragent"
 	"auth-service/pkg/validation"
 
 	"github.com/go-redis/redis/v8"
@@ -33,6 +35,13 @@ type UserService interface {
 	// GLOBAL AUTH (Slack-style multi-organization)
 	RegisterGlobal(ctx context.Context, req *RegisterGlobalRequest) (*RegisterGlobalResponse, error)
 	LoginGlobal(ctx context.Context, req *LoginGlobalRequest) (*LoginGlobalResponse, error)
+	VerifyLoginMFA(ctx context.Context, req *VerifyLoginMFARequest) (*LoginGlobalResponse, error)
+	LoginWithPasskey(ctx context.Context, req *PasskeyLoginRequest) (*LoginGlobalResponse, error)
+	// RequestEmailLogin emails a one-time sign-in link or code. It succeeds silently for
+	// addresses that cannot sign in, so it does not reveal which accounts exist.
+	RequestEmailLogin(ctx context.Context, req *EmailLoginRequest) error
+	// VerifyEmailLogin redeems a sign-in link token or code in place of the password
+	VerifyEmailLogin(ctx context.Context, req *VerifyEmailLoginRequest) (*LoginGlobalResponse, error)
 	SelectOrganization(ctx context.Context, req *SelectOrganizationRequest) (*SelectOrganizationResponse, error)
 	CreateOrganization(ctx context.Context, userID string, req *CreateOrganizationRequest) (*CreateOrganizationResponse, error)
 	GetMyOrganizations(ctx context.Context, userID string) ([]*OrganizationMembership, error)
@@ -40,6 +49,13 @@ type UserService interface {
 	// ORG-SCOPED AUTH
 	RefreshToken(ctx context.Context, req *RefreshTokenRequest) (*RefreshTokenResponse, error)
 	Logout(ctx context.Context, req *LogoutRequest) error
+	// Reauthenticate re-checks the user's credentials and issues tokens for the same session
+	// with a fresh auth_time, for endpoints that demand a recent (step-up) authentication
+	Reauthenticate(ctx context.Context, req *ReauthenticateRequest) (*ReauthenticateResponse, error)
+	// EnforceMFAPolicy applies the organization's MFA requirement to a membership. It
+	// returns ErrMFAEnrollmentRequired once any grace period is over, or the grace
+	// deadline while the member may still enroll.
+	EnforceMFAPolicy(ctx context.Context, membership *models.OrganizationMembership) (*time.Time, error)
 
 	// USER PROFILE
 	GetProfile(ctx context.Context, userID string) (*UserProfile, error)
@@ -61,16 +77,30 @@ type UserService interface {
 
 	// SESSION MANAGEMENT
 	GetUserSessions(ctx context.Context, userID string) ([]*models.UserSession, error)
+	// ListActiveSessions describes the user's active sessions in every organization,
+	// marking the one currentSessionID identifies
+	ListActiveSessions(ctx context.Context, userID, currentSessionID string) ([]*SessionSummary, error)
 	RevokeUserSession(ctx context.Context, userID, sessionID, reason string) error
+	// RevokeOtherSessions signs the user out everywhere except currentSessionID and
+	// returns how many sessions were revoked
+	RevokeOtherSessions(ctx context.Context, userID, currentSessionID, reason string) (int, error)
 
 	// OAuth2 SPECIFIC
-	AuthenticateByEmail(ctx context.Context, email, password string) (*models.User, error)
+	AuthenticateByEmail(ctx context.Context, email, password, clientIP string) (*models.User, error)
+	VerifySecondFactor(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
 	IsOrgMember(ctx context.Context, userID, orgID uuid.UUID) (bool, error)
 
 	// DEPENDENCY INJECTION
 	SetRedisClient(client *redis.Client)
 	SetEmailService(emailSvc email.Service)
 	SetSessionService(sessionSvc SessionService)
+	SetMFAService(mfaSvc MFAService)
+	SetPasskeyService(passkeySvc PasskeyService)
+	SetEmailLoginService(emailLoginSvc EmailLoginService)
+	SetTrustedDeviceService(deviceSvc TrustedDeviceService)
+	SetRevocationService(revocationSvc RevocationService)
+	SetLoginRiskService(riskSvc LoginRiskService)
+	SetPasswordPolicy(policy models.PasswordPolicy)
 }
 
 // ───────────────────────────────────────────────────────────────────────────────
@@ -99,25 +129,76 @@ type RegisterGlobalResponse struct {
 // --- GLOBAL LOGIN (returns list of organizations) ---
 
 type LoginGlobalRequest struct {
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	ClientIP  string `json:"-"`
-	UserAgent string `json:"-"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	ClientIP    string `json:"-"`
+	UserAgent   string `json:"-"`
+	DeviceToken string `json:"-"` // From the device cookie
 }
 
 type LoginGlobalResponse struct {
 	User          *UserProfile              `json:"user"`
 	Organizations []*OrganizationMembership `json:"organizations"`
 	Token         *TokenPair                `json:"token,omitempty"` // For superadmin only
+	MFARequired   bool                      `json:"mfa_required,omitempty"`
+	MFAToken      string                    `json:"mfa_token,omitempty"` // Pass to verify, then to select/create org
+	DeviceToken   string                    `json:"-"`                   // Set when a new device was registered; goes in the device cookie
+
+	// An unusual login by a user without MFA is confirmed with a code emailed to them,
+	// redeemed through the email sign-in endpoint
+	StepUpRequired bool `json:"step_up_required,omitempty"`
+
+	// With an expired password no orgs or tokens are returned, only a token for /auth/reset-password
+	PasswordExpired    bool   `json:"password_expired,omitempty"`
+	PasswordResetToken string `json:"password_reset_token,omitempty"`
+}
+
+// --- MFA LOGIN STEP (after the password when MFA is enabled) ---
+
+type VerifyLoginMFARequest struct {
+	MFAToken       string `json:"mfa_token"`
+	Code           string `json:"code"`                      // TOTP code or recovery code
+	RememberDevice bool   `json:"remember_device,omitempty"` // Skip MFA on this device for a while
+	ClientIP       string `json:"-"`
+	UserAgent      string `json:"-"`
+	DeviceToken    string `json:"-"`
+}
+
+// --- PASSKEY LOGIN (replaces email + password) ---
+
+type PasskeyLoginRequest struct {
+	Credential  PasskeyAssertion `json:"credential"`
+	ClientIP    string           `json:"-"`
+	UserAgent   string           `json:"-"`
+	DeviceToken string           `json:"-"`
+}
+
+// --- EMAIL LOGIN (magic link or one-time code, replaces email + password) ---
+
+type EmailLoginRequest struct {
+	Email    string `json:"email"`
+	Method   string `json:"method"` // "link" (default) or "code"
+	ClientIP string `json:"-"`
+}
+
+type VerifyEmailLoginRequest struct {
+	Email       string `json:"email"`
+	Token       string `json:"token,omitempty"` // From the sign-in link
+	Code        string `json:"code,omitempty"`  // From the sign-in code email
+	ClientIP    string `json:"-"`
+	UserAgent   string `json:"-"`
+	DeviceToken string `json:"-"`
 }
 
 // --- SELECT ORGANIZATION (get org-scoped token) ---
 
 type SelectOrganizationRequest struct {
-	UserID         string `json:"user_id"`         // Global auth context (from FE/global cookie)
-	OrganizationID string `json:"organization_id"` // Chosen org
+	UserID         string `json:"user_id"`             // Global auth context (from FE/global cookie)
+	OrganizationID string `json:"organization_id"`     // Chosen org
+	MFAToken       string `json:"mfa_token,omitempty"` // Required when the user has MFA enabled
 	ClientIP       string `json:"-"`
 	UserAgent      string `json:"-"`
+	DeviceToken    string `json:"-"` // Links the session to the device it was started from
 }
 
 type SelectOrganizationResponse struct {
@@ -143,6 +224,8 @@ type OrganizationMembership struct {
 	Role             string     `json:"role"`
 	Status           string     `json:"status"`
 	JoinedAt         *time.Time `json:"joined_at,omitempty"`
+	// Set while the organization's MFA policy applies and the user has yet to enroll
+	MFAEnrollmentDeadline *time.Time `json:"mfa_enrollment_deadline,omitempty"`
 }
 
 // --- ORG-SCOPED AUTH ---
@@ -161,6 +244,26 @@ type LogoutRequest struct {
 	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
+// ReauthenticateRequest proves the caller of an existing session is still the user.
+// Either Password (plus MFACode for a multi-factor level) or Passkey is required.
+type ReauthenticateRequest struct {
+	Password    string            `json:"password,omitempty"`
+	MFACode     string            `json:"mfa_code,omitempty"`
+	Passkey     *PasskeyAssertion `json:"passkey,omitempty"` // Answer to /auth/passkey/login/options
+	AccessToken string            `json:"-"`                 // The token being upgraded
+	ClientIP    string            `json:"-"`
+	UserAgent   string            `json:"-"`
+	DeviceToken string            `json:"-"`
+}
+
+type ReauthenticateResponse struct {
+	Token       *TokenPair `json:"token"`
+	ACR         string     `json:"acr"`
+	AMR         []string   `json:"amr"`
+	AuthTime    time.Time  `json:"auth_time"`
+	DeviceToken string     `json:"-"` // Set when a passkey was used from a new device
+}
+
 // --- PROFILE / PASSWORD ---
 
 type UserProfile struct {
@@ -176,6 +279,8 @@ type UserProfile struct {
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	LastLogin    *time.Time `json:"last_login,omitempty"`
+
+	PasswordResetRequired bool `json:"password_reset_required,omitempty"` // Password found in a breach; org sign-in is blocked until reset
 }
 
 type UpdateProfileRequest struct {
@@ -219,6 +324,22 @@ type TokenPair struct {
 	OrganizationID string `json:"organization_id"`
 }
 
+// SessionSummary is what a user sees about one of their own sessions. Raw IP addresses and
+// user agents stay server-side; the location and parsed device are enough to recognize it.
+type SessionSummary struct {
+	ID               uuid.UUID       `json:"id"`
+	OrganizationID   uuid.UUID       `json:"organization_id"`
+	OrganizationName string          `json:"organization_name,omitempty"`
+	Device           string          `json:"device"` // e.g. "Firefox on Windows"
+	Browser          string          `json:"browser,omitempty"`
+	OS               string          `json:"os,omitempty"`
+	Mobile           bool            `json:"mobile"`
+	Location         *geoip.Location `json:"location,omitempty"` // City, region, country and network; no coordinates
+	CreatedAt        time.Time       `json:"created_at"`
+	LastActivity     time.Time       `json:"last_activity"`
+	Current          bool            `json:"current"` // The session the request was made with
+}
+
 // ───────────────────────────────────────────────────────────────────────────────
 // ERRORS & CONSTANTS
 // ───────────────────────────────────────────────────────────────────────────────
@@ -256,7 +377,16 @@ type userService struct {
 	redisClient     *redis.Client
 	emailSvc        email.Service
 	sessionSvc      SessionService
+	mfaSvc          MFAService
+	passkeySvc      PasskeyService
+	emailLoginSvc   EmailLoginService
+	deviceSvc       TrustedDeviceService
+	revocationSvc   RevocationService
+	riskSvc         LoginRiskService
 	auditLogger     *logger.AuditLogger
+
+	// Platform default rotation policy; org policies can only tighten it
+	passwordPolicy models.PasswordPolicy
 }
 
 // NewUserService creates a new user service
@@ -278,6 +408,25 @@ func (s *userService) SetEmailService(emailSvc email.Service) { s.emailSvc = ema
 func (s *userService) SetSessionService(sessionSvc SessionService) {
 	s.sessionSvc = sessionSvc
 }
+func (s *userService) SetMFAService(mfaSvc MFAService) { s.mfaSvc = mfaSvc }
+func (s *userService) SetPasskeyService(passkeySvc PasskeyService) {
+	s.passkeySvc = passkeySvc
+}
+func (s *userService) SetEmailLoginService(emailLoginSvc EmailLoginService) {
+	s.emailLoginSvc = emailLoginSvc
+}
+func (s *userService) SetTrustedDeviceService(deviceSvc TrustedDeviceService) {
+	s.deviceSvc = deviceSvc
+}
+func (s *userService) SetRevocationService(revocationSvc RevocationService) {
+	s.revocationSvc = revocationSvc
+}
+func (s *userService) SetLoginRiskService(riskSvc LoginRiskService) {
+	s.riskSvc = riskSvc
+}
+func (s *userService) SetPasswordPolicy(policy models.PasswordPolicy) {
+	s.passwordPolicy = policy
+}
 
 // ───────────────────────────────────────────────────────────────────────────────
 // GLOBAL REGISTRATION & LOGIN (NO ORG YET)
@@ -318,6 +467,10 @@ func (s *userService) RegisterGlobal(ctx context.Context, req *RegisterGlobalReq
 		return nil, ErrUserAlreadyExists
 	}
 
+	if err := s.rejectBreachedPassword(req.Password); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hash, err := s.passwordService.Hash(req.Password)
 	if err != nil {
@@ -461,6 +614,392 @@ func (s *userService) LoginGlobal(ctx context.Context, req *LoginGlobalRequest)
 	// Clear lockout state
 	s.clearFailedAttempts(ctx, email, req.ClientIP)
 
+	s.upgradePasswordHash(ctx, user, req.Password)
+	s.flagBreachedPassword(ctx, user, req.Password)
+
+	return s.completeFirstFactor(ctx, user, []string{jwt.AMRPassword}, req.DeviceToken, req.ClientIP, req.UserAgent)
+}
+
+// VerifyLoginMFA completes a login that LoginGlobal paused for a second factor
+func (s *userService) VerifyLoginMFA(ctx context.Context, req *VerifyLoginMFARequest) (*LoginGlobalResponse, error) {
+	if s.mfaSvc == nil {
+		return nil, ErrMFANotEnabled
+	}
+	if req.MFAToken == "" || req.Code == "" {
+		return nil, errors.New("mfa_token and code are required")
+	}
+
+	userID, err := s.mfaSvc.VerifyChallenge(ctx, req.MFAToken, req.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) && userID != uuid.Nil {
+			if user, lookupErr := s.repo.User().GetByID(ctx, userID.String()); lookupErr == nil && user != nil {
+				s.recordFailedAttempt(ctx, user.Email, req.ClientIP, &user.ID)
+			}
+		}
+		return nil, err
+	}
+
+	user, err := s.repo.User().GetByID(ctx, userID.String())
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, errors.New("account is deactivated")
+	}
+	if s.isAccountLocked(ctx, user.Email, req.ClientIP) {
+		return nil, errors.New("account temporarily locked due to failed attempts")
+	}
+
+	resp, err := s.completeGlobalLogin(ctx, user, []string{jwt.AMRPassword, jwt.AMROTP})
+	if err != nil {
+		return nil, err
+	}
+	// The verified challenge stands in for the second factor when an org is picked
+	if !resp.PasswordExpired {
+		resp.MFAToken = req.MFAToken
+	}
+
+	device, newDeviceToken := s.recognizeDevice(ctx, user.ID, req.DeviceToken, req.ClientIP, req.UserAgent)
+	resp.DeviceToken = newDeviceToken
+	if device != nil && req.RememberDevice {
+		if err := s.deviceSvc.Trust(ctx, device); err != nil {
+			fmt.Printf("Failed to remember device: %v\n", err)
+		}
+	}
+	return resp, nil
+}
+
+// LoginWithPasskey is the passwordless counterpart of LoginGlobal. Passkeys are only
+// accepted with user verification, so they also satisfy MFA for the org selection step.
+func (s *userService) LoginWithPasskey(ctx context.Context, req *PasskeyLoginRequest) (*LoginGlobalResponse, error) {
+	if s.passkeySvc == nil {
+		return nil, errors.New("passkey login is not available")
+	}
+
+	userID, err := s.passkeySvc.FinishLogin(ctx, &req.Credential)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.User().GetByID(ctx, userID.String())
+	if err != nil || user == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, errors.New("account is deactivated")
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, errors.New("email not verified. Please check your email for the verification code")
+	}
+	if s.isAccountLocked(ctx, user.Email, req.ClientIP) {
+		return nil, errors.New("account temporarily locked due to failed attempts")
+	}
+	s.clearFailedAttempts(ctx, user.Email, req.ClientIP)
+
+	newDeviceToken, err := s.checkPasskeyLoginRisk(ctx, user, req.DeviceToken, req.ClientIP, req.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.completeGlobalLogin(ctx, user, []string{jwt.AMRHardwareKey, jwt.AMRUserPresence})
+	if err != nil {
+		return nil, err
+	}
+	resp.DeviceToken = newDeviceToken
+	if s.mfaSvc != nil {
+		enabled, err := s.mfaSvc.IsEnabled(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			if resp.MFAToken, err = s.mfaSvc.CreateVerifiedChallenge(ctx, user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (s *userService) RequestEmailLogin(ctx context.Context, req *EmailLoginRequest) error {
+	if s.emailLoginSvc == nil {
+		return ErrEmailLoginUnavailable
+	}
+	if err := validation.ValidateLogin(req.Email); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+	method := req.Method
+	if method == "" {
+		method = EmailLoginMethodLink
+	}
+	if method != EmailLoginMethodLink && method != EmailLoginMethodCode {
+		return fmt.Errorf("%w: method must be %q or %q", ErrInvalidData, EmailLoginMethodLink, EmailLoginMethodCode)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	user, err := s.repo.User().GetByEmail(ctx, email)
+	if err != nil || user == nil {
+		return nil
+	}
+	if user.Status != models.UserStatusActive || user.EmailVerifiedAt == nil {
+		return nil
+	}
+	if s.isAccountLocked(ctx, email, req.ClientIP) {
+		return nil
+	}
+
+	// A delivery failure is logged rather than returned so the response stays the same for every address
+	if err := s.emailLoginSvc.Send(ctx, user.ID, email, method); err != nil {
+		fmt.Printf("Failed to send sign-in email: %v\n", err)
+	}
+	return nil
+}
+
+// VerifyEmailLogin proves control of the mailbox only, so users with MFA get the same
+// challenge as after a password.
+func (s *userService) VerifyEmailLogin(ctx context.Context, req *VerifyEmailLoginRequest) (*LoginGlobalResponse, error) {
+	if s.emailLoginSvc == nil {
+		return nil, ErrEmailLoginUnavailable
+	}
+	secret := req.Token
+	if secret == "" {
+		secret = req.Code
+	}
+	if req.Email == "" || secret == "" {
+		return nil, errors.New("email and token or code are required")
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if s.isAccountLocked(ctx, email, req.ClientIP) {
+		return nil, errors.New("account temporarily locked due to failed attempts")
+	}
+
+	userID, err := s.emailLoginSvc.Redeem(ctx, email, secret)
+	if err != nil {
+		if errors.Is(err, ErrInvalidEmailLoginCode) {
+			var failedUserID *uuid.UUID
+			if userID != uuid.Nil {
+				failedUserID = &userID
+			}
+			s.recordFailedAttempt(ctx, email, req.ClientIP, failedUserID)
+		}
+		return nil, err
+	}
+
+	user, err := s.repo.User().GetByID(ctx, userID.String())
+	if err != nil || user == nil || user.Email != email {
+		return nil, ErrInvalidEmailLoginCode
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, errors.New("account is deactivated")
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, errors.New("email not verified. Please check your email for the verification code")
+	}
+	s.clearFailedAttempts(ctx, email, req.ClientIP)
+
+	return s.completeFirstFactor(ctx, user, []string{jwt.AMROTP}, req.DeviceToken, req.ClientIP, req.UserAgent)
+}
+
+// completeFirstFactor finishes a login whose first factor has been checked. With MFA on the
+// user gets a challenge instead of their orgs, unless they sign in from a trusted device and
+// the login does not look risky. A risky login without MFA is confirmed by email instead.
+func (s *userService) completeFirstFactor(ctx context.Context, user *models.User, amr []string, deviceToken, clientIP, userAgent string) (*LoginGlobalResponse, error) {
+	device, newDeviceToken := s.recognizeDevice(ctx, user.ID, deviceToken, clientIP, userAgent)
+
+	mfaEnabled := false
+	if s.mfaSvc != nil {
+		var err error
+		if mfaEnabled, err = s.mfaSvc.IsEnabled(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	risk := LoginRiskAllow
+	if s.riskSvc != nil {
+		risk = s.riskSvc.Evaluate(ctx, &LoginRiskInput{
+			UserID:    user.ID,
+			Email:     user.Email,
+			IPAddress: clientIP,
+			NewDevice: newDeviceToken != "",
+		}).Decision
+	}
+	if risk == LoginRiskBlock {
+		return nil, ErrLoginBlocked
+	}
+	stepUp := risk == LoginRiskStepUp
+
+	if mfaEnabled && (stepUp || device == nil || !device.IsTrusted(time.Now())) {
+		mfaToken, err := s.mfaSvc.CreateChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginGlobalResponse{
+			User:        s.convertToUserProfile(user),
+			MFARequired: true,
+			MFAToken:    mfaToken,
+			DeviceToken: newDeviceToken,
+		}, nil
+	}
+
+	// Signing in with an emailed code already proved control of the mailbox
+	if stepUp && !mfaEnabled && !hasAMR(amr, jwt.AMROTP) && s.emailLoginSvc != nil {
+		if err := s.emailLoginSvc.Send(ctx, user.ID, user.Email, EmailLoginMethodCode); err != nil {
+			return nil, fmt.Errorf("failed to send sign-in code: %w", err)
+		}
+		return &LoginGlobalResponse{
+			User:           s.convertToUserProfile(user),
+			StepUpRequired: true,
+			DeviceToken:    newDeviceToken,
+		}, nil
+	}
+
+	resp, err := s.completeGlobalLogin(ctx, user, amr)
+	if err != nil {
+		return nil, err
+	}
+	resp.DeviceToken = newDeviceToken
+	if mfaEnabled && !resp.PasswordExpired {
+		// The trusted device stands in for the second factor when an org is picked
+		if resp.MFAToken, err = s.mfaSvc.CreateVerifiedChallenge(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// checkPasskeyLoginRisk scores a passkey sign-in and returns the token of a newly registered
+// device. Passkeys are only accepted with user verification, which already meets a step-up,
+// so only a block stops the sign-in.
+func (s *userService) checkPasskeyLoginRisk(ctx context.Context, user *models.User, deviceToken, clientIP, userAgent string) (string, error) {
+	_, newDeviceToken := s.recognizeDevice(ctx, user.ID, deviceToken, clientIP, userAgent)
+	if s.riskSvc == nil {
+		return newDeviceToken, nil
+	}
+
+	risk := s.riskSvc.Evaluate(ctx, &LoginRiskInput{
+		UserID:    user.ID,
+		Email:     user.Email,
+		IPAddress: clientIP,
+		NewDevice: newDeviceToken != "",
+	})
+	if risk.Decision == LoginRiskBlock {
+		return "", ErrLoginBlocked
+	}
+	return newDeviceToken, nil
+}
+
+func hasAMR(amr []string, method string) bool {
+	for _, m := range amr {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// recognizeDevice records the sign-in in the device registry. The registry is a convenience,
+// so a failure is logged and the login carries on without a device.
+func (s *userService) recognizeDevice(ctx context.Context, userID uuid.UUID, deviceToken, clientIP, userAgent string) (*models.TrustedDevice, string) {
+	if s.deviceSvc == nil {
+		return nil, ""
+	}
+	device, newToken, err := s.deviceSvc.Recognize(ctx, userID, deviceToken, clientIP, userAgent)
+	if err != nil {
+		fmt.Printf("Failed to record device: %v\n", err)
+		return nil, ""
+	}
+	return device, newToken
+}
+
+// requireMFA blocks org-scoped token issuance until userID has passed the MFA step and
+// returns the amr for the tokens. The challenge does not record which factors were used,
+// so a redeemed one is reported as "mfa".
+func (s *userService) requireMFA(ctx context.Context, userID uuid.UUID, mfaToken string) ([]string, error) {
+	singleFactor := []string{jwt.AMRPassword}
+	if s.mfaSvc == nil {
+		return singleFactor, nil
+	}
+	enabled, err := s.mfaSvc.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return singleFactor, nil
+	}
+	if err := s.mfaSvc.CheckChallenge(ctx, mfaToken, userID); err != nil {
+		return nil, err
+	}
+	return []string{jwt.AMRMultiFactor}, nil
+}
+
+func (s *userService) EnforceMFAPolicy(ctx context.Context, membership *models.OrganizationMembership) (*time.Time, error) {
+	org := membership.Organization
+	if org == nil {
+		var err error
+		if org, err = s.repo.Organization().GetByID(ctx, membership.OrganizationID.String()); err != nil {
+			return nil, ErrOrgNotFound
+		}
+	}
+	policy, err := org.SecurityPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	roleName := ""
+	if membership.Role != nil {
+		roleName = membership.Role.Name
+	} else if len(policy.MFARequiredRoles) > 0 {
+		role, err := s.repo.Role().GetByID(ctx, membership.RoleID.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load role: %w", err)
+		}
+		roleName = role.Name
+	}
+	if !policy.RequiresMFA(roleName) {
+		return nil, nil
+	}
+
+	// Without an MFA service nobody can enroll, so the requirement cannot be met
+	if s.mfaSvc == nil {
+		return nil, ErrMFAEnrollmentRequired
+	}
+	enabled, err := s.mfaSvc.IsEnabled(ctx, membership.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, nil
+	}
+
+	joinedAt := membership.CreatedAt
+	if membership.JoinedAt != nil {
+		joinedAt = *membership.JoinedAt
+	}
+	deadline := policy.MFAEnrollmentDeadline(joinedAt)
+	if !time.Now().Before(deadline) {
+		return nil, ErrMFAEnrollmentRequired
+	}
+	return &deadline, nil
+}
+
+// completeGlobalLogin records the login and returns the user's orgs (and a token for superadmins).
+// amr lists the authentication methods the user just completed.
+func (s *userService) completeGlobalLogin(ctx context.Context, user *models.User, amr []string) (*LoginGlobalResponse, error) {
+	// An expired password must be replaced before the user can reach an org
+	if err := s.checkPasswordStanding(ctx, user); errors.Is(err, ErrPasswordExpired) {
+		resetToken, err := s.createPasswordReset(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginGlobalResponse{
+			User:               s.convertToUserProfile(user),
+			PasswordExpired:    true,
+			PasswordResetToken: resetToken,
+		}, nil
+	} else if err != nil && !errors.Is(err, ErrPasswordResetRequired) {
+		return nil, err
+	}
+
 	// Update last login
 	if err := s.repo.User().UpdateLastLogin(ctx, user.ID.String()); err != nil {
 		fmt.Printf("Failed to update last login: %v\n", err)
@@ -498,37 +1037,10 @@ func (s *userService) LoginGlobal(ctx context.Context, req *LoginGlobalRequest)
 
 	// For superadmin, issue tokens immediately (they skip org selection)
 	var tokenPair *TokenPair
-	if user.IsSuperadmin {
-		// Generate superadmin token with system-wide access
-		sessionID := uuid.New()
-		tokenCtx := &jwt.TokenContext{
-			UserID:           user.ID,
-			OrganizationID:   uuid.Nil, // No organization context for superadmin
-			SessionID:        sessionID,
-			RoleID:           uuid.Nil,
-			Email:            user.Email,
-			GlobalRole:       user.GlobalRole,
-			OrganizationRole: "",
-			Permissions:      []string{"*"}, // All permissions for superadmin
-			IsSuperadmin:     true,
-		}
-
-		accessToken, err := s.jwtService.GenerateAccessToken(tokenCtx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate access token: %w", err)
-		}
-
-		refreshToken, _, err := s.jwtService.GenerateRefreshToken(tokenCtx)
+	if user.IsSuperadmin && user.PasswordCompromisedAt == nil {
+		tokenPair, err = s.issueSuperadminTokens(ctx, user, uuid.New(), amr, time.Now())
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-		}
-
-		tokenPair = &TokenPair{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-			TokenType:    "Bearer",
-			ExpiresIn:    3600, // 1 hour
-			SessionID:    sessionID.String(),
+			return nil, err
 		}
 	}
 
@@ -611,6 +1123,13 @@ func (s *userService) CreateOrganization(ctx context.Context, userID string, req
 	if creator.Status != models.UserStatusActive {
 		return nil, errors.New("user is not active")
 	}
+	if err := s.checkPasswordStanding(ctx, creator); err != nil {
+		return nil, err
+	}
+	amr, err := s.requireMFA(ctx, creator.ID, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
 
 	org := &models.Organization{
 		Name:      req.Name,
@@ -648,12 +1167,12 @@ func (s *userService) CreateOrganization(ctx context.Context, userID string, req
 	}
 
 	// Create initial session + token for this org (no ClientIP in CreateOrganizationRequest)
-	session, err := s.createSession(ctx, creator, org.ID, "", "org-create:"+req.Slug)
+	session, err := s.createSession(ctx, creator, org.ID, "", "org-create:"+req.Slug, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	tokenPair, refreshID, err := s.issueTokenPair(ctx, creator, org.ID, m.RoleID, session.ID)
+	tokenPair, refreshID, err := s.issueTokenPair(ctx, creator, org.ID, m.RoleID, session.ID, amr, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -710,6 +1229,13 @@ func (s *userService) SelectOrganization(ctx context.Context, req *SelectOrganiz
 	if user.Status != models.UserStatusActive {
 		return nil, errors.New("account is deactivated")
 	}
+	if err := s.checkPasswordStanding(ctx, user); err != nil {
+		return nil, err
+	}
+	amr, err := s.requireMFA(ctx, user.ID, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
 
 	org, err := s.repo.Organization().GetByID(ctx, orgUUID.String())
 	if err != nil || org == nil {
@@ -726,15 +1252,26 @@ func (s *userService) SelectOrganization(ctx context.Context, req *SelectOrganiz
 	if membership.Status != models.MembershipStatusActive {
 		return nil, ErrMembershipSuspended
 	}
+	membership.Organization = org
+	enrollmentDeadline, err := s.EnforceMFAPolicy(ctx, membership)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create session (org-scoped)
-	session, err := s.createSession(ctx, user, org.ID, req.ClientIP, req.UserAgent)
+	var deviceID *uuid.UUID
+	if s.deviceSvc != nil {
+		if device, err := s.deviceSvc.Lookup(ctx, user.ID, req.DeviceToken); err == nil && device != nil {
+			deviceID = &device.ID
+		}
+	}
+	session, err := s.createSession(ctx, user, org.ID, req.ClientIP, req.UserAgent, deviceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
 	// Issue org-scoped JWT + refresh
-	tokenPair, refreshID, err := s.issueTokenPair(ctx, user, org.ID, membership.RoleID, session.ID)
+	tokenPair, refreshID, err := s.issueTokenPair(ctx, user, org.ID, membership.RoleID, session.ID, amr, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -761,6 +1298,8 @@ func (s *userService) SelectOrganization(ctx context.Context, req *SelectOrganiz
 		Role:             roleName,
 		Status:           membership.Status,
 		JoinedAt:         membership.JoinedAt,
+
+		MFAEnrollmentDeadline: enrollmentDeadline,
 	}
 
 	return &SelectOrganizationResponse{
@@ -838,7 +1377,8 @@ func (s *userService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 		return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
 	}
 
-	tokenPair, newRefreshID, err := s.issueTokenPair(ctx, user, refreshRecord.OrganizationID, membership.RoleID, refreshRecord.SessionID)
+	// A refresh is not a new authentication, so the original amr and auth_time carry over
+	tokenPair, newRefreshID, err := s.issueTokenPair(ctx, user, refreshRecord.OrganizationID, membership.RoleID, refreshRecord.SessionID, claims.AMR, claims.AuthenticatedAt())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
@@ -850,6 +1390,120 @@ func (s *userService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 	return &RefreshTokenResponse{Token: tokenPair}, nil
 }
 
+func (s *userService) Reauthenticate(ctx context.Context, req *ReauthenticateRequest) (*ReauthenticateResponse, error) {
+	claims, err := s.jwtService.ParseAccessToken(req.AccessToken)
+	if err != nil {
+		return nil, errors.New("invalid access token")
+	}
+
+	user, err := s.repo.User().GetByID(ctx, claims.UserID.String())
+	if err != nil || user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, errors.New("account is deactivated")
+	}
+	if s.isAccountLocked(ctx, user.Email, req.ClientIP) {
+		return nil, errors.New("account temporarily locked due to failed attempts")
+	}
+
+	amr, newDeviceToken, err := s.verifyReauthentication(ctx, user, req)
+	if err != nil {
+		return nil, err
+	}
+	s.clearFailedAttempts(ctx, user.Email, req.ClientIP)
+	authTime := time.Now()
+
+	var tokenPair *TokenPair
+	if claims.OrganizationID == uuid.Nil {
+		if !user.IsSuperadmin {
+			return nil, errors.New("token has no organization context")
+		}
+		tokenPair, err = s.issueSuperadminTokens(ctx, user, claims.SessionID, amr, authTime)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		membership, err := s.repo.OrganizationMembership().GetByOrganizationAndUser(ctx, claims.OrganizationID.String(), user.ID.String())
+		if err != nil || membership == nil || membership.Status != models.MembershipStatusActive {
+			return nil, errors.New("organization membership is not active")
+		}
+
+		// The upgraded refresh token replaces the session's current one so refreshes keep the new auth_time
+		if err := s.repo.RefreshToken().RevokeBySession(ctx, claims.SessionID.String(), "reauthenticated"); err != nil {
+			return nil, fmt.Errorf("failed to revoke refresh token: %w", err)
+		}
+		var refreshID string
+		tokenPair, refreshID, err = s.issueTokenPair(ctx, user, claims.OrganizationID, membership.RoleID, claims.SessionID, amr, authTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		}
+		if err := s.persistRefreshToken(ctx, refreshID, tokenPair.RefreshToken, user.ID, claims.OrganizationID, claims.SessionID); err != nil {
+			return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		}
+	}
+
+	return &ReauthenticateResponse{
+		Token:       tokenPair,
+		ACR:         jwt.ACRForAMR(amr),
+		AMR:         amr,
+		AuthTime:    authTime,
+		DeviceToken: newDeviceToken,
+	}, nil
+}
+
+// verifyReauthentication checks the credentials in req against user and returns the amr,
+// and for a passkey the token of a newly registered device
+func (s *userService) verifyReauthentication(ctx context.Context, user *models.User, req *ReauthenticateRequest) ([]string, string, error) {
+	if req.Passkey != nil {
+		if s.passkeySvc == nil {
+			return nil, "", errors.New("passkey login is not available")
+		}
+		userID, err := s.passkeySvc.FinishLogin(ctx, req.Passkey)
+		if err != nil {
+			return nil, "", err
+		}
+		if userID != user.ID {
+			return nil, "", ErrInvalidCredentials
+		}
+		// A passkey can stand in for a full sign-in, so it is scored like one
+		newDeviceToken, err := s.checkPasskeyLoginRisk(ctx, user, req.DeviceToken, req.ClientIP, req.UserAgent)
+		if err != nil {
+			return nil, "", err
+		}
+		return []string{jwt.AMRHardwareKey, jwt.AMRUserPresence}, newDeviceToken, nil
+	}
+
+	if req.Password == "" {
+		return nil, "", errors.New("password or passkey is required")
+	}
+	valid, err := s.passwordService.Verify(req.Password, user.PasswordHash)
+	if err != nil || !valid {
+		s.recordFailedAttempt(ctx, user.Email, req.ClientIP, &user.ID)
+		return nil, "", ErrInvalidCredentials
+	}
+	amr := []string{jwt.AMRPassword}
+
+	// A code only adds a factor for users who have MFA; it is ignored otherwise
+	if strings.TrimSpace(req.MFACode) == "" || s.mfaSvc == nil {
+		return amr, "", nil
+	}
+	enabled, err := s.mfaSvc.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !enabled {
+		return amr, "", nil
+	}
+	if _, err := s.mfaSvc.VerifyCode(ctx, user.ID, req.MFACode); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.recordFailedAttempt(ctx, user.Email, req.ClientIP, &user.ID)
+		}
+		return nil, "", err
+	}
+	return append(amr, jwt.AMROTP), "", nil
+}
+
 func (s *userService) Logout(ctx context.Context, req *LogoutRequest) error {
 	if req.UserID == "" {
 		return errors.New("user ID is required")
@@ -958,6 +1612,9 @@ func (s *userService) ChangePassword(ctx context.Context, userID string, req *Ch
 	if err := validation.ValidatePasswordsMatch(req.NewPassword, req.ConfirmPassword); err != nil {
 		return err
 	}
+	if err := s.rejectBreachedPassword(req.NewPassword); err != nil {
+		return err
+	}
 
 	user, err := s.repo.User().GetByID(ctx, userID)
 	if err != nil || user == nil {
@@ -969,14 +1626,8 @@ func (s *userService) ChangePassword(ctx context.Context, userID string, req *Ch
 		return errors.New("current password is incorrect")
 	}
 
-	passwordHashChan := s.hashPasswordAsync(req.NewPassword)
-	res := <-passwordHashChan
-	if res.Error != nil {
-		return fmt.Errorf("failed to hash password: %w", res.Error)
-	}
-
-	if err := s.repo.User().UpdatePassword(ctx, userID, res.Hash); err != nil {
-		return fmt.Errorf("failed to update password: %w", err)
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
 	}
 
 	// Invalidate all refresh tokens
@@ -1002,6 +1653,24 @@ func (s *userService) ForgotPassword(ctx context.Context, req *ForgotPasswordReq
 		return nil
 	}
 
+	rawToken, err := s.createPasswordReset(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if s.emailSvc != nil {
+		if err := s.emailSvc.SendPasswordResetEmail(email, rawToken); err != nil {
+			fmt.Printf("Failed to send password reset email: %v\n", err)
+		}
+	} else {
+		fmt.Printf("Password reset token for %s: %s\n", email, rawToken)
+	}
+
+	return nil
+}
+
+// createPasswordReset issues a 15-minute token for ResetPassword and returns it in the clear
+func (s *userService) createPasswordReset(ctx context.Context, userID uuid.UUID) (string, error) {
 	// Create secure random token (string) for email link
 	rawToken := generateCryptographicallySecureToken()
 
@@ -1009,33 +1678,27 @@ func (s *userService) ForgotPassword(ctx context.Context, req *ForgotPasswordReq
 	tokenHash := hashToken(rawToken)
 
 	reset := &models.PasswordReset{
-		UserID:    user.ID,
+		UserID:    userID,
 		TokenHash: tokenHash,
 		ExpiresAt: time.Now().Add(15 * time.Minute),
 	}
 
 	if err := s.repo.PasswordReset().Create(ctx, reset); err != nil {
-		return fmt.Errorf("failed to create password reset: %w", err)
+		return "", fmt.Errorf("failed to create password reset: %w", err)
 	}
-
-	if s.emailSvc != nil {
-		if err := s.emailSvc.SendPasswordResetEmail(email, rawToken); err != nil {
-			fmt.Printf("Failed to send password reset email: %v\n", err)
-		}
-	} else {
-		fmt.Printf("Password reset token for %s: %s\n", email, rawToken)
-	}
-
-	return nil
+	return rawToken, nil
 }
 
 func (s *userService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
 	if err := validation.ValidatePasswordReset(req.Token, req.NewPassword, req.ConfirmPassword); err != nil {
 		return fmt.Errorf("validation failed: %w", err)
 	}
+	if err := s.rejectBreachedPassword(req.NewPassword); err != nil {
+		return err
+	}
 
-	// Look up reset record via repository, which should verify token hash
-	reset, err := s.repo.PasswordReset().GetByToken(ctx, req.Token)
+	// Reset tokens are stored as SHA256 hashes
+	reset, err := s.repo.PasswordReset().GetByToken(ctx, hashToken(req.Token))
 	if err != nil || reset == nil {
 		return errors.New("invalid or expired reset token")
 	}
@@ -1049,14 +1712,8 @@ func (s *userService) ResetPassword(ctx context.Context, req *ResetPasswordReque
 		return fmt.Errorf("user not found: %w", err)
 	}
 
-	passwordHashChan := s.hashPasswordAsync(req.NewPassword)
-	res := <-passwordHashChan
-	if res.Error != nil {
-		return fmt.Errorf("failed to hash password: %w", res.Error)
-	}
-
-	if err := s.repo.User().UpdatePassword(ctx, user.ID.String(), res.Hash); err != nil {
-		return fmt.Errorf("failed to update password: %w", err)
+	if err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
 	}
 
 	// Delete reset token by ID (not raw token - DB stores hash)
@@ -1339,6 +1996,51 @@ func (s *userService) GetUserSessions(ctx context.Context, userID string) ([]*mo
 	return s.repo.UserSession().GetByUserID(ctx, userID)
 }
 
+func (s *userService) ListActiveSessions(ctx context.Context, userID, currentSessionID string) ([]*SessionSummary, error) {
+	sessions, err := s.repo.UserSession().GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	orgNames := map[uuid.UUID]string{}
+	summaries := make([]*SessionSummary, 0, len(sessions))
+	for _, session := range sessions {
+		name, seen := orgNames[session.OrganizationID]
+		if !seen {
+			if org, err := s.repo.Organization().GetByID(ctx, session.OrganizationID.String()); err == nil && org != nil {
+				name = org.Name
+			}
+			orgNames[session.OrganizationID] = name
+		}
+
+		agent := useragent.Parse(session.UserAgent)
+		summaries = append(summaries, &SessionSummary{
+			ID:               session.ID,
+			OrganizationID:   session.OrganizationID,
+			OrganizationName: name,
+			Device:           agent.String(),
+			Browser:          agent.Browser,
+			OS:               agent.OS,
+			Mobile:           agent.Mobile,
+			Location:         approximateLocation(session.Location),
+			CreatedAt:        session.CreatedAt,
+			LastActivity:     session.LastActivity,
+			Current:          session.ID.String() == currentSessionID,
+		})
+	}
+	return summaries, nil
+}
+
+// approximateLocation decodes a stored session location without its coordinates, which
+// say more about where someone is than they need to see to recognize a session
+func approximateLocation(stored string) *geoip.Location {
+	location := geoip.ParseLocation(stored)
+	if location != nil {
+		location.Latitude, location.Longitude = 0, 0
+	}
+	return location
+}
+
 func (s *userService) RevokeUserSession(ctx context.Context, userID, sessionID, reason string) error {
 	if userID == "" || sessionID == "" {
 		return errors.New("user ID and session ID are required")
@@ -1346,26 +2048,67 @@ func (s *userService) RevokeUserSession(ctx context.Context, userID, sessionID,
 
 	session, err := s.repo.UserSession().GetByID(ctx, sessionID)
 	if err != nil || session == nil {
-		return fmt.Errorf("session not found: %w", err)
+		return ErrSessionNotFound
 	}
 
+	// Another user's session is reported exactly like a missing one
 	if session.UserID.String() != userID {
-		return errors.New("session does not belong to user")
+		return ErrSessionNotFound
+	}
+
+	return s.endSession(ctx, session, reason)
+}
+
+func (s *userService) RevokeOtherSessions(ctx context.Context, userID, currentSessionID, reason string) (int, error) {
+	sessions, err := s.repo.UserSession().GetActiveByUserID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID.String() == currentSessionID {
+			continue
+		}
+		if err := s.endSession(ctx, session, reason); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// endSession revokes the session and its refresh tokens, then denies the access tokens
+// already issued to it so they stop working before they expire
+func (s *userService) endSession(ctx context.Context, session *models.UserSession, reason string) error {
+	sessionID := session.ID.String()
+	if err := s.repo.RefreshToken().RevokeBySession(ctx, sessionID, reason); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
 	}
 
 	if s.sessionSvc != nil {
-		return s.sessionSvc.RevokeSession(ctx, sessionID, reason)
+		if err := s.sessionSvc.RevokeSession(ctx, sessionID, reason); err != nil {
+			return err
+		}
+	} else if err := s.repo.UserSession().Revoke(ctx, sessionID, reason); err != nil {
+		return err
 	}
 
-	return s.repo.UserSession().Revoke(ctx, sessionID, reason)
+	if s.revocationSvc != nil {
+		if err := s.revocationSvc.RevokeSessionTokens(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ───────────────────────────────────────────────────────────────────────────────
 // OAuth2 SPECIFIC METHODS
 // ───────────────────────────────────────────────────────────────────────────────
 
-// AuthenticateByEmail validates email and password for OAuth2 flow
-func (s *userService) AuthenticateByEmail(ctx context.Context, email, password string) (*models.User, error) {
+// AuthenticateByEmail validates email and password for OAuth2 flow and scores the sign-in
+// like a regular login
+func (s *userService) AuthenticateByEmail(ctx context.Context, email, password, clientIP string) (*models.User, error) {
 	if email == "" || password == "" {
 		return nil, errors.New("email and password are required")
 	}
@@ -1390,9 +2133,79 @@ func (s *userService) AuthenticateByEmail(ctx context.Context, email, password s
 		return nil, errors.New("invalid credentials")
 	}
 
+	s.upgradePasswordHash(ctx, user, password)
+	s.flagBreachedPassword(ctx, user, password)
+	if user.PasswordCompromisedAt != nil {
+		return nil, ErrPasswordResetRequired
+	}
+
+	if err := s.checkOAuthLoginRisk(ctx, user, clientIP); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
+// checkOAuthLoginRisk scores a sign-in through an OAuth login form. Those forms always ask
+// users with MFA for a code, which covers a step-up. Users without MFA cannot confirm an
+// emailed code there, so they are sent to the regular sign-in instead.
+func (s *userService) checkOAuthLoginRisk(ctx context.Context, user *models.User, clientIP string) error {
+	if s.riskSvc == nil {
+		return nil
+	}
+
+	switch s.riskSvc.Evaluate(ctx, &LoginRiskInput{UserID: user.ID, Email: user.Email, IPAddress: clientIP}).Decision {
+	case LoginRiskBlock:
+		return ErrLoginBlocked
+	case LoginRiskStepUp:
+		mfaEnabled := false
+		if s.mfaSvc != nil {
+			var err error
+			if mfaEnabled, err = s.mfaSvc.IsEnabled(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+		if !mfaEnabled {
+			return ErrLoginStepUpRequired
+		}
+		// Not recorded: the code has yet to be checked
+		return nil
+	}
+
+	// OAuth sign-ins start no session, so they are added to the history separately
+	s.riskSvc.RecordLogin(ctx, user.ID, clientIP)
+	return nil
+}
+
+// VerifySecondFactor checks the MFA code a password-based OAuth2 flow collected alongside
+// the password and returns the AMR of the sign-in: pwd, plus otp once a code was checked.
+// Users without MFA pass; users with MFA get ErrMFARequired when code is empty.
+func (s *userService) VerifySecondFactor(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	amr := []string{jwt.AMRPassword}
+	if s.mfaSvc == nil {
+		return amr, nil
+	}
+	enabled, err := s.mfaSvc.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return amr, nil
+	}
+	if strings.TrimSpace(code) == "" {
+		return nil, ErrMFARequired
+	}
+	if _, err := s.mfaSvc.VerifyCode(ctx, userID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if user, lookupErr := s.repo.User().GetByID(ctx, userID.String()); lookupErr == nil && user != nil {
+				s.recordFailedAttempt(ctx, user.Email, "", &user.ID)
+			}
+		}
+		return nil, err
+	}
+	return append(amr, jwt.AMROTP), nil
+}
+
 // IsOrgMember checks if a user belongs to a specific organization
 func (s *userService) IsOrgMember(ctx context.Context, userID, orgID uuid.UUID) (bool, error) {
 	if userID == uuid.Nil || orgID == uuid.Nil {
@@ -1422,9 +2235,9 @@ func (s *userService) IsOrgMember(ctx context.Context, userID, orgID uuid.UUID)
 // ───────────────────────────────────────────────────────────────────────────────
 
 // createSession creates a persistent session record (ORG-SCOPED)
-func (s *userService) createSession(ctx context.Context, user *models.User, organizationID uuid.UUID, clientIP, userAgent string) (*models.UserSession, error) {
+func (s *userService) createSession(ctx context.Context, user *models.User, organizationID uuid.UUID, clientIP, userAgent string, deviceID *uuid.UUID) (*models.UserSession, error) {
 	if s.sessionSvc != nil {
-		return s.sessionSvc.CreateSession(ctx, user.ID.String(), organizationID.String(), clientIP, userAgent)
+		return s.sessionSvc.CreateSession(ctx, user.ID.String(), organizationID.String(), clientIP, userAgent, deviceID)
 	}
 
 	session := &models.UserSession{
@@ -1433,6 +2246,7 @@ func (s *userService) createSession(ctx context.Context, user *models.User, orga
 		TokenHash:      generateCryptographicallySecureToken(),
 		IPAddress:      clientIP,
 		UserAgent:      userAgent,
+		DeviceID:       deviceID,
 		IsActive:       true,
 		LastActivity:   time.Now(),
 		ExpiresAt:      time.Now().Add(24 * time.Hour),
@@ -1445,8 +2259,49 @@ func (s *userService) createSession(ctx context.Context, user *models.User, orga
 	return session, nil
 }
 
-// issueTokenPair generates org- & session-bound JWTs and returns refresh token ID
-func (s *userService) issueTokenPair(ctx context.Context, user *models.User, organizationID uuid.UUID, roleID uuid.UUID, sessionID uuid.UUID) (*TokenPair, string, error) {
+// issueSuperadminTokens generates system-wide tokens without an organization context
+func (s *userService) issueSuperadminTokens(ctx context.Context, user *models.User, sessionID uuid.UUID, amr []string, authTime time.Time) (*TokenPair, error) {
+	tokenCtx := &jwt.TokenContext{
+		UserID:           user.ID,
+		OrganizationID:   uuid.Nil, // No organization context for superadmin
+		SessionID:        sessionID,
+		RoleID:           uuid.Nil,
+		Email:            user.Email,
+		GlobalRole:       user.GlobalRole,
+		OrganizationRole: "",
+		Permissions:      []string{"*"}, // All permissions for superadmin
+		IsSuperadmin:     true,
+		AMR:              amr,
+		AuthTime:         authTime,
+	}
+
+	accessToken, err := s.jwtService.GenerateAccessToken(tokenCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	if s.revocationSvc != nil {
+		if err := s.revocationSvc.TrackSessionToken(ctx, accessToken); err != nil {
+			fmt.Printf("Failed to track session token: %v\n", err)
+		}
+	}
+
+	refreshToken, _, err := s.jwtService.GenerateRefreshToken(tokenCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    3600, // 1 hour
+		SessionID:    sessionID.String(),
+	}, nil
+}
+
+// issueTokenPair generates org- & session-bound JWTs and returns refresh token ID.
+// amr and authTime describe the authentication the tokens stem from.
+func (s *userService) issueTokenPair(ctx context.Context, user *models.User, organizationID uuid.UUID, roleID uuid.UUID, sessionID uuid.UUID, amr []string, authTime time.Time) (*TokenPair, string, error) {
 	// Load role to get name and organization context
 	role, err := s.repo.Role().GetByID(ctx, roleID.String())
 	if err != nil {
@@ -1471,12 +2326,20 @@ func (s *userService) issueTokenPair(ctx context.Context, user *models.User, org
 		OrganizationRole: role.Name,
 		Permissions:      permissions,
 		IsSuperadmin:     user.IsSuperadmin,
+		AMR:              amr,
+		AuthTime:         authTime,
 	}
 
 	accessToken, err := s.jwtService.GenerateAccessToken(tokenCtx)
 	if err != nil {
 		return nil, "", err
 	}
+	if s.revocationSvc != nil {
+		// Without the JTI on record, revoking the session could not cut this token short
+		if err := s.revocationSvc.TrackSessionToken(ctx, accessToken); err != nil {
+			fmt.Printf("Failed to track session token: %v\n", err)
+		}
+	}
 
 	refreshToken, refreshID, err := s.jwtService.GenerateRefreshToken(tokenCtx)
 	if err != nil {
@@ -1625,6 +2488,7 @@ func (s *userService) convertToUserProfile(user *models.User) *UserProfile {
 	if user.LastLoginAt != nil {
 		p.LastLogin = user.LastLoginAt
 	}
+	p.PasswordResetRequired = user.PasswordCompromisedAt != nil
 
 	return p
 }
@@ -1653,6 +2517,151 @@ func safeStringToPointer(s string) *string {
 	return &s
 }
 
+// rejectBreachedPassword refuses a new password found in the breach corpus. If the corpus
+// cannot be read the password is let through rather than blocking every sign-up.
+func (s *userService) rejectBreachedPassword(pw string) error {
+	err := s.passwordService.CheckBreached(pw)
+	if err == nil || errors.Is(err, ErrPasswordBreached) {
+		return err
+	}
+	fmt.Printf("Breached password check failed: %v\n", err)
+	return nil
+}
+
+// upgradePasswordHash re-hashes a password that just verified against a legacy algorithm or
+// outdated argon2id parameters. The login goes ahead on the old hash if this fails.
+func (s *userService) upgradePasswordHash(ctx context.Context, user *models.User, pw string) {
+	if !s.passwordService.NeedsRehash(user.PasswordHash) {
+		return
+	}
+	// Imported passwords may predate the composition rules, so skip validation
+	hash, err := s.passwordService.HashWithoutValidation(pw)
+	if err != nil {
+		fmt.Printf("Failed to rehash password: %v\n", err)
+		return
+	}
+	if err := s.repo.User().UpdatePasswordHash(ctx, user.ID.String(), hash); err != nil {
+		fmt.Printf("Failed to store upgraded password hash: %v\n", err)
+		return
+	}
+	user.PasswordHash = hash
+}
+
+// flagBreachedPassword re-checks a password that just verified, since the corpus keeps
+// growing after a password is chosen. A newly flagged user is emailed a reset link.
+func (s *userService) flagBreachedPassword(ctx context.Context, user *models.User, pw string) {
+	if user.PasswordCompromisedAt != nil {
+		return
+	}
+	if err := s.passwordService.CheckBreached(pw); !errors.Is(err, ErrPasswordBreached) {
+		if err != nil {
+			fmt.Printf("Breached password check failed: %v\n", err)
+		}
+		return
+	}
+
+	if err := s.repo.User().MarkPasswordCompromised(ctx, user.ID.String()); err != nil {
+		fmt.Printf("Failed to flag breached password: %v\n", err)
+		return
+	}
+	now := time.Now()
+	user.PasswordCompromisedAt = &now
+
+	if err := s.ForgotPassword(ctx, &ForgotPasswordRequest{Email: user.Email}); err != nil {
+		fmt.Printf("Failed to send reset link for breached password: %v\n", err)
+	}
+}
+
+// passwordPolicyFor returns the strictest rotation policy across the platform default and
+// every organization the user is an active member of
+func (s *userService) passwordPolicyFor(ctx context.Context, userID uuid.UUID) (models.PasswordPolicy, error) {
+	policy := s.passwordPolicy.Strictest(models.PasswordPolicy{}) // clamps the history to what is kept
+	memberships, err := s.repo.OrganizationMembership().GetByUser(ctx, userID.String())
+	if err != nil {
+		return policy, fmt.Errorf("failed to load organizations: %w", err)
+	}
+	for _, m := range memberships {
+		if m.Status != models.MembershipStatusActive {
+			continue
+		}
+		org, err := s.repo.Organization().GetByID(ctx, m.OrganizationID.String())
+		if err != nil || org == nil {
+			continue
+		}
+		orgPolicy, err := org.SecurityPolicy()
+		if err != nil {
+			continue
+		}
+		policy = policy.Strictest(orgPolicy.PasswordPolicy())
+	}
+	return policy, nil
+}
+
+// checkPasswordStanding blocks token issuance while the user's password is known to be
+// breached or has outlived the strictest maximum age among their organizations
+func (s *userService) checkPasswordStanding(ctx context.Context, user *models.User) error {
+	if user.PasswordCompromisedAt != nil {
+		return ErrPasswordResetRequired
+	}
+	policy, err := s.passwordPolicyFor(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	if policy.Expired(changedAt, time.Now()) {
+		return ErrPasswordExpired
+	}
+	return nil
+}
+
+// setPassword replaces the user's password, refusing one of their recent passwords when
+// a policy keeps history. The old hash is always remembered so a policy switched on later
+// already has history to check.
+func (s *userService) setPassword(ctx context.Context, user *models.User, newPassword string) error {
+	policy, err := s.passwordPolicyFor(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if policy.HistoryCount > 0 {
+		previous, err := s.repo.PasswordHistory().ListRecent(ctx, user.ID, policy.HistoryCount-1)
+		if err != nil {
+			return fmt.Errorf("failed to load password history: %w", err)
+		}
+		hashes := []string{user.PasswordHash}
+		for _, entry := range previous {
+			hashes = append(hashes, entry.PasswordHash)
+		}
+		for _, hash := range hashes {
+			if reused, _ := s.passwordService.Verify(newPassword, hash); reused {
+				return ErrPasswordReused
+			}
+		}
+	}
+
+	passwordHashChan := s.hashPasswordAsync(newPassword)
+	res := <-passwordHashChan
+	if res.Error != nil {
+		return fmt.Errorf("failed to hash password: %w", res.Error)
+	}
+
+	if user.PasswordHash != "" {
+		entry := &models.PasswordHistory{UserID: user.ID, PasswordHash: user.PasswordHash}
+		if err := s.repo.PasswordHistory().Create(ctx, entry); err != nil {
+			return fmt.Errorf("failed to record password history: %w", err)
+		}
+	}
+	if err := s.repo.User().UpdatePassword(ctx, user.ID.String(), res.Hash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := s.repo.PasswordHistory().Prune(ctx, user.ID, models.MaxPasswordHistory); err != nil {
+		fmt.Printf("Failed to prune password history: %v\n", err)
+	}
+	return nil
+}
+
 // hashPasswordAsync hashes a password asynchronously
 type PasswordHashResult struct {
 	Hash  string
@@ -1699,6 +2708,7 @@ func (s *userService) recordFailedAttempt(ctx context.Context, email string, ipA
 		Email:       normalizedEmail,
 		IPAddress:   ipAddress,
 		UserAgent:   getUserAgent(ctx),
+		Location:    geoip.Lookup(ipAddress).Encode(),
 		AttemptedAt: time.Now(),
 	}
 
@@ -0,0 +1,5 @@
+-- Remove OpenID Connect fields from authorization_codes (rollback)
+ALTER TABLE authorization_codes
+DROP COLUMN IF EXISTS nonce,
+DROP COLUMN IF EXISTS auth_time,
+DROP COLUMN IF EXISTS amr;
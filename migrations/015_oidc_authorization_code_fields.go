@@ -0,0 +1,53 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     15,
+		Description: "Add OIDC nonce, auth_time and amr to authorization_codes",
+		Up:          mig015Up,
+		Down:        mig015Down,
+	})
+}
+
+func mig015Up(tx *sql.Tx) error {
+	log.Println("Running migration 015: Add OIDC fields to authorization_codes")
+
+	_, err := tx.Exec(`
+	ALTER TABLE authorization_codes
+	ADD COLUMN IF NOT EXISTS nonce VARCHAR(255),
+	ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP,
+	ADD COLUMN IF NOT EXISTS amr TEXT[];
+	`)
+	if err != nil {
+		log.Fatal("Failed to add OIDC columns to authorization_codes:", err)
+		return err
+	}
+
+	log.Println("Migration 015 completed successfully")
+	return nil
+}
+
+func mig015Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 015: Remove OIDC fields from authorization_codes")
+
+	_, err := tx.Exec(`
+	ALTER TABLE authorization_codes
+	DROP COLUMN IF EXISTS nonce,
+	DROP COLUMN IF EXISTS auth_time,
+	DROP COLUMN IF EXISTS amr;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop OIDC columns from authorization_codes:", err)
+		return err
+	}
+
+	log.Println("Migration 015 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,9 @@
+-- OpenID Connect: carry nonce and authentication context from /authorize to the id_token
+ALTER TABLE authorization_codes
+ADD COLUMN IF NOT EXISTS nonce VARCHAR(255),
+ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP,
+ADD COLUMN IF NOT EXISTS amr TEXT[];
+
+COMMENT ON COLUMN authorization_codes.nonce IS 'OIDC nonce from the authorization request, echoed into the id_token';
+COMMENT ON COLUMN authorization_codes.auth_time IS 'Time the end user authenticated';
+COMMENT ON COLUMN authorization_codes.amr IS 'Authentication methods references (RFC 8176)';
@@ -0,0 +1,7 @@
+ALTER TABLE client_apps
+DROP COLUMN IF EXISTS initial_access_token_id,
+DROP COLUMN IF EXISTS registration_token_hash,
+DROP COLUMN IF EXISTS token_endpoint_auth_method,
+DROP COLUMN IF EXISTS grant_types;
+
+DROP TABLE IF EXISTS initial_access_tokens;
@@ -0,0 +1,82 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     16,
+		Description: "Add initial access tokens and registration metadata for dynamic client registration",
+		Up:          mig016Up,
+		Down:        mig016Down,
+	})
+}
+
+func mig016Up(tx *sql.Tx) error {
+	log.Println("Running migration 016: Add dynamic client registration")
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS initial_access_tokens (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		description VARCHAR(255),
+		allowed_scopes TEXT[],
+		max_uses INTEGER DEFAULT 0,
+		use_count INTEGER DEFAULT 0,
+		created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMP WITH TIME ZONE,
+		revoked BOOLEAN DEFAULT FALSE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_initial_access_tokens_organization_id ON initial_access_tokens(organization_id);
+	CREATE INDEX IF NOT EXISTS idx_initial_access_tokens_expires_at ON initial_access_tokens(expires_at);
+	CREATE INDEX IF NOT EXISTS idx_initial_access_tokens_revoked ON initial_access_tokens(revoked);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create initial_access_tokens table:", err)
+		return err
+	}
+
+	_, err = tx.Exec(`
+	ALTER TABLE client_apps
+	ADD COLUMN IF NOT EXISTS grant_types TEXT[],
+	ADD COLUMN IF NOT EXISTS token_endpoint_auth_method VARCHAR(50),
+	ADD COLUMN IF NOT EXISTS registration_token_hash VARCHAR(64),
+	ADD COLUMN IF NOT EXISTS initial_access_token_id UUID REFERENCES initial_access_tokens(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS idx_client_apps_registration_token_hash ON client_apps(registration_token_hash);
+	CREATE INDEX IF NOT EXISTS idx_client_apps_initial_access_token_id ON client_apps(initial_access_token_id);
+	`)
+	if err != nil {
+		log.Fatal("Failed to add registration columns to client_apps:", err)
+		return err
+	}
+
+	log.Println("Migration 016 completed successfully")
+	return nil
+}
+
+func mig016Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 016: Remove dynamic client registration")
+
+	_, err := tx.Exec(`
+	ALTER TABLE client_apps
+	DROP COLUMN IF EXISTS initial_access_token_id,
+	DROP COLUMN IF EXISTS registration_token_hash,
+	DROP COLUMN IF EXISTS token_endpoint_auth_method,
+	DROP COLUMN IF EXISTS grant_types;
+	DROP TABLE IF EXISTS initial_access_tokens;
+	`)
+	if err != nil {
+		log.Fatal("Failed to roll back dynamic client registration:", err)
+		return err
+	}
+
+	log.Println("Migration 016 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,31 @@
+-- Dynamic client registration (RFC 7591/7592)
+CREATE TABLE IF NOT EXISTS initial_access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    description VARCHAR(255),
+    allowed_scopes TEXT[],
+    max_uses INTEGER DEFAULT 0,
+    use_count INTEGER DEFAULT 0,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_initial_access_tokens_organization_id ON initial_access_tokens(organization_id);
+CREATE INDEX IF NOT EXISTS idx_initial_access_tokens_expires_at ON initial_access_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_initial_access_tokens_revoked ON initial_access_tokens(revoked);
+
+ALTER TABLE client_apps
+ADD COLUMN IF NOT EXISTS grant_types TEXT[],
+ADD COLUMN IF NOT EXISTS token_endpoint_auth_method VARCHAR(50),
+ADD COLUMN IF NOT EXISTS registration_token_hash VARCHAR(64),
+ADD COLUMN IF NOT EXISTS initial_access_token_id UUID REFERENCES initial_access_tokens(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_client_apps_registration_token_hash ON client_apps(registration_token_hash);
+CREATE INDEX IF NOT EXISTS idx_client_apps_initial_access_token_id ON client_apps(initial_access_token_id);
+
+COMMENT ON COLUMN client_apps.grant_types IS 'Registered grant types; NULL means unrestricted';
+COMMENT ON COLUMN client_apps.registration_token_hash IS 'HMAC-SHA256 of the RFC 7592 registration access token';
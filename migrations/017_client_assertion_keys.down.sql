@@ -0,0 +1,4 @@
+ALTER TABLE client_apps
+DROP COLUMN IF EXISTS client_secret_encrypted,
+DROP COLUMN IF EXISTS jwks_uri,
+DROP COLUMN IF EXISTS jwks;
@@ -0,0 +1,53 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     17,
+		Description: "Add client JWKS and encrypted secret columns for JWT client authentication",
+		Up:          mig017Up,
+		Down:        mig017Down,
+	})
+}
+
+func mig017Up(tx *sql.Tx) error {
+	log.Println("Running migration 017: Add client assertion keys")
+
+	_, err := tx.Exec(`
+	ALTER TABLE client_apps
+	ADD COLUMN IF NOT EXISTS jwks TEXT,
+	ADD COLUMN IF NOT EXISTS jwks_uri TEXT,
+	ADD COLUMN IF NOT EXISTS client_secret_encrypted TEXT;
+	`)
+	if err != nil {
+		log.Fatal("Failed to add client assertion columns to client_apps:", err)
+		return err
+	}
+
+	log.Println("Migration 017 completed successfully")
+	return nil
+}
+
+func mig017Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 017: Remove client assertion keys")
+
+	_, err := tx.Exec(`
+	ALTER TABLE client_apps
+	DROP COLUMN IF EXISTS client_secret_encrypted,
+	DROP COLUMN IF EXISTS jwks_uri,
+	DROP COLUMN IF EXISTS jwks;
+	`)
+	if err != nil {
+		log.Fatal("Failed to roll back client assertion columns:", err)
+		return err
+	}
+
+	log.Println("Migration 017 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,8 @@
+-- Client keys for private_key_jwt / client_secret_jwt authentication (RFC 7523)
+ALTER TABLE client_apps
+ADD COLUMN IF NOT EXISTS jwks TEXT,
+ADD COLUMN IF NOT EXISTS jwks_uri TEXT,
+ADD COLUMN IF NOT EXISTS client_secret_encrypted TEXT;
+
+COMMENT ON COLUMN client_apps.jwks IS 'Inline JWK set used to verify private_key_jwt assertions';
+COMMENT ON COLUMN client_apps.client_secret_encrypted IS 'AES-GCM encrypted client secret, kept only for client_secret_jwt';
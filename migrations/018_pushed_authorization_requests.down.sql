@@ -0,0 +1,2 @@
+ALTER TABLE client_apps
+DROP COLUMN IF EXISTS require_pushed_authorization_requests;
@@ -0,0 +1,49 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     18,
+		Description: "Add require_pushed_authorization_requests flag to client apps",
+		Up:          mig018Up,
+		Down:        mig018Down,
+	})
+}
+
+func mig018Up(tx *sql.Tx) error {
+	log.Println("Running migration 018: Add pushed authorization request flag")
+
+	_, err := tx.Exec(`
+	ALTER TABLE client_apps
+	ADD COLUMN IF NOT EXISTS require_pushed_authorization_requests BOOLEAN DEFAULT FALSE;
+	`)
+	if err != nil {
+		log.Fatal("Failed to add require_pushed_authorization_requests to client_apps:", err)
+		return err
+	}
+
+	log.Println("Migration 018 completed successfully")
+	return nil
+}
+
+func mig018Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 018: Remove pushed authorization request flag")
+
+	_, err := tx.Exec(`
+	ALTER TABLE client_apps
+	DROP COLUMN IF EXISTS require_pushed_authorization_requests;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop require_pushed_authorization_requests:", err)
+		return err
+	}
+
+	log.Println("Migration 018 rollback completed successfully")
+	return nil
+}
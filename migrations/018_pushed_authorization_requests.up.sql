@@ -0,0 +1,5 @@
+-- Per-client switch for pushed authorization requests (RFC 9126)
+ALTER TABLE client_apps
+ADD COLUMN IF NOT EXISTS require_pushed_authorization_requests BOOLEAN DEFAULT FALSE;
+
+COMMENT ON COLUMN client_apps.require_pushed_authorization_requests IS 'Reject /authorize requests without a request_uri from /oauth/par';
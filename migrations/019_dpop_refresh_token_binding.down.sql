@@ -0,0 +1,2 @@
+ALTER TABLE oauth_refresh_tokens
+DROP COLUMN IF EXISTS dpop_jkt;
@@ -0,0 +1,49 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     19,
+		Description: "Add DPoP key binding to OAuth refresh tokens",
+		Up:          mig019Up,
+		Down:        mig019Down,
+	})
+}
+
+func mig019Up(tx *sql.Tx) error {
+	log.Println("Running migration 019: Add DPoP refresh token binding")
+
+	_, err := tx.Exec(`
+	ALTER TABLE oauth_refresh_tokens
+	ADD COLUMN IF NOT EXISTS dpop_jkt VARCHAR(64);
+	`)
+	if err != nil {
+		log.Fatal("Failed to add dpop_jkt to oauth_refresh_tokens:", err)
+		return err
+	}
+
+	log.Println("Migration 019 completed successfully")
+	return nil
+}
+
+func mig019Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 019: Remove DPoP refresh token binding")
+
+	_, err := tx.Exec(`
+	ALTER TABLE oauth_refresh_tokens
+	DROP COLUMN IF EXISTS dpop_jkt;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop dpop_jkt:", err)
+		return err
+	}
+
+	log.Println("Migration 019 rollback completed successfully")
+	return nil
+}
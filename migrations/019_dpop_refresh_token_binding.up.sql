@@ -0,0 +1,7 @@
+-- DPoP key binding for OAuth refresh token families (RFC 9449)
+ALTER TABLE oauth_refresh_tokens
+ADD COLUMN IF NOT EXISTS dpop_jkt VARCHAR(64);
+
+COMMENT ON COLUMN oauth_refresh_tokens.dpop_jkt IS 'SHA-256 JWK thumbprint of the DPoP key the token is bound to; NULL when unbound';
+COMMENT ON COLUMN oauth_refresh_tokens.user_agent_hash IS 'User agent at issuance; recorded only, not enforced';
+COMMENT ON COLUMN oauth_refresh_tokens.ip_hash IS 'Client IP at issuance; recorded only, not enforced';
@@ -0,0 +1,57 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     20,
+		Description: "Add OAuth consent grants",
+		Up:          mig020Up,
+		Down:        mig020Down,
+	})
+}
+
+func mig020Up(tx *sql.Tx) error {
+	log.Println("Running migration 020: Add OAuth consent grants")
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS oauth_consent_grants (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		client_id VARCHAR(255) NOT NULL,
+		organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+		scopes TEXT[],
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_consent_grants_user_client_org ON oauth_consent_grants(user_id, client_id, organization_id);
+	CREATE INDEX IF NOT EXISTS idx_oauth_consent_grants_client_id ON oauth_consent_grants(client_id);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create oauth_consent_grants table:", err)
+		return err
+	}
+
+	log.Println("Migration 020 completed successfully")
+	return nil
+}
+
+func mig020Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 020: Remove OAuth consent grants")
+
+	_, err := tx.Exec(`
+	DROP TABLE IF EXISTS oauth_consent_grants;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop oauth_consent_grants:", err)
+		return err
+	}
+
+	log.Println("Migration 020 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,15 @@
+-- Scopes users have approved per OAuth client and organization
+CREATE TABLE IF NOT EXISTS oauth_consent_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id VARCHAR(255) NOT NULL,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    scopes TEXT[],
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_oauth_consent_grants_user_client_org ON oauth_consent_grants(user_id, client_id, organization_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_consent_grants_client_id ON oauth_consent_grants(client_id);
+
+COMMENT ON TABLE oauth_consent_grants IS 'While a grant covers the requested scopes the consent prompt is skipped';
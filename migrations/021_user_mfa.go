@@ -0,0 +1,72 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     21,
+		Description: "Add TOTP MFA and recovery codes",
+		Up:          mig021Up,
+		Down:        mig021Down,
+	})
+}
+
+func mig021Up(tx *sql.Tx) error {
+	log.Println("Running migration 021: Add TOTP MFA and recovery codes")
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS user_mfa (
+		user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		secret_enc TEXT NOT NULL,
+		enabled BOOLEAN DEFAULT FALSE,
+		enabled_at TIMESTAMP WITH TIME ZONE,
+		last_used_step BIGINT DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create user_mfa table:", err)
+		return err
+	}
+
+	_, err = tx.Exec(`
+	CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_mfa_recovery_codes_code_hash ON mfa_recovery_codes(code_hash);
+	CREATE INDEX IF NOT EXISTS idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create mfa_recovery_codes table:", err)
+		return err
+	}
+
+	log.Println("Migration 021 completed successfully")
+	return nil
+}
+
+func mig021Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 021: Remove TOTP MFA and recovery codes")
+
+	_, err := tx.Exec(`
+	DROP TABLE IF EXISTS mfa_recovery_codes;
+	DROP TABLE IF EXISTS user_mfa;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop MFA tables:", err)
+		return err
+	}
+
+	log.Println("Migration 021 rollback completed successfully")
+	return nil
+}
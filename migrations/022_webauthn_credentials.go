@@ -0,0 +1,64 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     22,
+		Description: "Add WebAuthn passkey credentials",
+		Up:          mig022Up,
+		Down:        mig022Down,
+	})
+}
+
+func mig022Up(tx *sql.Tx) error {
+	log.Println("Running migration 022: Add WebAuthn passkey credentials")
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS webauthn_credentials (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		credential_id VARCHAR(1400) NOT NULL,
+		public_key BYTEA NOT NULL,
+		algorithm BIGINT NOT NULL,
+		sign_count BIGINT DEFAULT 0,
+		transports TEXT[],
+		aaguid VARCHAR(36),
+		name VARCHAR(100),
+		backup_eligible BOOLEAN DEFAULT FALSE,
+		backed_up BOOLEAN DEFAULT FALSE,
+		last_used_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_webauthn_credentials_credential_id ON webauthn_credentials(credential_id);
+	CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create webauthn_credentials table:", err)
+		return err
+	}
+
+	log.Println("Migration 022 completed successfully")
+	return nil
+}
+
+func mig022Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 022: Remove WebAuthn passkey credentials")
+
+	_, err := tx.Exec(`
+	DROP TABLE IF EXISTS webauthn_credentials;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop webauthn_credentials:", err)
+		return err
+	}
+
+	log.Println("Migration 022 rollback completed successfully")
+	return nil
+}
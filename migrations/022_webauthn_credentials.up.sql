@@ -0,0 +1,23 @@
+-- Passkeys; the private key stays on the authenticator
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id VARCHAR(1400) NOT NULL,
+    public_key BYTEA NOT NULL,
+    algorithm BIGINT NOT NULL,
+    sign_count BIGINT DEFAULT 0,
+    transports TEXT[],
+    aaguid VARCHAR(36),
+    name VARCHAR(100),
+    backup_eligible BOOLEAN DEFAULT FALSE,
+    backed_up BOOLEAN DEFAULT FALSE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webauthn_credentials_credential_id ON webauthn_credentials(credential_id);
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+COMMENT ON COLUMN webauthn_credentials.public_key IS 'COSE_Key as returned by the authenticator';
+COMMENT ON COLUMN webauthn_credentials.sign_count IS 'Last signature counter seen; a counter that does not increase suggests a cloned authenticator';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_device;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS device_id;
+DROP TABLE IF EXISTS trusted_devices;
@@ -0,0 +1,66 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     23,
+		Description: "Add trusted device registry",
+		Up:          mig023Up,
+		Down:        mig023Down,
+	})
+}
+
+func mig023Up(tx *sql.Tx) error {
+	log.Println("Running migration 023: Add trusted device registry")
+
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS trusted_devices (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) NOT NULL,
+		name VARCHAR(100),
+		user_agent TEXT,
+		last_ip VARCHAR(45),
+		last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+		trusted_until TIMESTAMP WITH TIME ZONE,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_trusted_devices_token_hash ON trusted_devices(token_hash);
+	CREATE INDEX IF NOT EXISTS idx_trusted_devices_user_id ON trusted_devices(user_id);
+	CREATE INDEX IF NOT EXISTS idx_trusted_devices_revoked_at ON trusted_devices(revoked_at);
+	ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS device_id UUID REFERENCES trusted_devices(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS idx_sessions_device ON user_sessions(device_id);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create trusted_devices table:", err)
+		return err
+	}
+
+	log.Println("Migration 023 completed successfully")
+	return nil
+}
+
+func mig023Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 023: Remove trusted device registry")
+
+	_, err := tx.Exec(`
+	DROP INDEX IF EXISTS idx_sessions_device;
+	ALTER TABLE user_sessions DROP COLUMN IF EXISTS device_id;
+	DROP TABLE IF EXISTS trusted_devices;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop trusted_devices:", err)
+		return err
+	}
+
+	log.Println("Migration 023 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,24 @@
+-- Devices users sign in from, identified by a long-lived cookie; only its HMAC is stored
+CREATE TABLE IF NOT EXISTS trusted_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    name VARCHAR(100),
+    user_agent TEXT,
+    last_ip VARCHAR(45),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    trusted_until TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trusted_devices_token_hash ON trusted_devices(token_hash);
+CREATE INDEX IF NOT EXISTS idx_trusted_devices_user_id ON trusted_devices(user_id);
+CREATE INDEX IF NOT EXISTS idx_trusted_devices_revoked_at ON trusted_devices(revoked_at);
+
+-- Sessions remember the device they were started from so revoking the device signs them out
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS device_id UUID REFERENCES trusted_devices(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_device ON user_sessions(device_id);
+
+COMMENT ON COLUMN trusted_devices.trusted_until IS 'MFA is skipped on this device until then; NULL when the user did not ask to remember it';
@@ -0,0 +1,47 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     24,
+		Description: "Flag users whose password appears in a breach corpus",
+		Up:          mig024Up,
+		Down:        mig024Down,
+	})
+}
+
+func mig024Up(tx *sql.Tx) error {
+	log.Println("Running migration 024: Flag users whose password appears in a breach corpus")
+
+	_, err := tx.Exec(`
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS password_compromised_at TIMESTAMP WITH TIME ZONE;
+	`)
+	if err != nil {
+		log.Fatal("Failed to add password_compromised_at:", err)
+		return err
+	}
+
+	log.Println("Migration 024 completed successfully")
+	return nil
+}
+
+func mig024Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 024: Remove breached password flag")
+
+	_, err := tx.Exec(`
+	ALTER TABLE users DROP COLUMN IF EXISTS password_compromised_at;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop password_compromised_at:", err)
+		return err
+	}
+
+	log.Println("Migration 024 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_compromised_at TIMESTAMP WITH TIME ZONE;
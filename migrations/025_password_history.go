@@ -0,0 +1,55 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     25,
+		Description: "Add password history and password age tracking",
+		Up:          mig025Up,
+		Down:        mig025Down,
+	})
+}
+
+func mig025Up(tx *sql.Tx) error {
+	log.Println("Running migration 025: Add password history and password age tracking")
+
+	_, err := tx.Exec(`
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP WITH TIME ZONE;
+	CREATE TABLE IF NOT EXISTS password_history (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+	CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id);
+	`)
+	if err != nil {
+		log.Fatal("Failed to create password_history table:", err)
+		return err
+	}
+
+	log.Println("Migration 025 completed successfully")
+	return nil
+}
+
+func mig025Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 025: Remove password history and password age tracking")
+
+	_, err := tx.Exec(`
+	DROP TABLE IF EXISTS password_history;
+	ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
+	`)
+	if err != nil {
+		log.Fatal("Failed to drop password_history:", err)
+		return err
+	}
+
+	log.Println("Migration 025 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,7 @@
+-- Fails while any versioned (v<N>$) hash is still stored
+ALTER TABLE trusted_devices ALTER COLUMN token_hash TYPE VARCHAR(64);
+ALTER TABLE mfa_recovery_codes ALTER COLUMN code_hash TYPE VARCHAR(64);
+ALTER TABLE client_apps ALTER COLUMN registration_token_hash TYPE VARCHAR(64);
+ALTER TABLE initial_access_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+ALTER TABLE oauth_refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+ALTER TABLE authorization_codes ALTER COLUMN code_hash TYPE VARCHAR(64);
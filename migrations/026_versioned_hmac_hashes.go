@@ -0,0 +1,57 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     26,
+		Description: "Widen HMAC hash columns for key-versioned hashes",
+		Up:          mig026Up,
+		Down:        mig026Down,
+	})
+}
+
+func mig026Up(tx *sql.Tx) error {
+	log.Println("Running migration 026: Widen HMAC hash columns for key-versioned hashes")
+
+	_, err := tx.Exec(`
+	ALTER TABLE authorization_codes ALTER COLUMN code_hash TYPE VARCHAR(80);
+	ALTER TABLE oauth_refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(80);
+	ALTER TABLE initial_access_tokens ALTER COLUMN token_hash TYPE VARCHAR(80);
+	ALTER TABLE client_apps ALTER COLUMN registration_token_hash TYPE VARCHAR(80);
+	ALTER TABLE mfa_recovery_codes ALTER COLUMN code_hash TYPE VARCHAR(80);
+	ALTER TABLE trusted_devices ALTER COLUMN token_hash TYPE VARCHAR(80);
+	`)
+	if err != nil {
+		log.Fatal("Failed to widen HMAC hash columns:", err)
+		return err
+	}
+
+	log.Println("Migration 026 completed successfully")
+	return nil
+}
+
+func mig026Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 026: Restore 64-character HMAC hash columns")
+
+	_, err := tx.Exec(`
+	ALTER TABLE trusted_devices ALTER COLUMN token_hash TYPE VARCHAR(64);
+	ALTER TABLE mfa_recovery_codes ALTER COLUMN code_hash TYPE VARCHAR(64);
+	ALTER TABLE client_apps ALTER COLUMN registration_token_hash TYPE VARCHAR(64);
+	ALTER TABLE initial_access_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+	ALTER TABLE oauth_refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+	ALTER TABLE authorization_codes ALTER COLUMN code_hash TYPE VARCHAR(64);
+	`)
+	if err != nil {
+		log.Fatal("Failed to restore HMAC hash columns:", err)
+		return err
+	}
+
+	log.Println("Migration 026 rollback completed successfully")
+	return nil
+}
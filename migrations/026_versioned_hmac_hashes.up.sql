@@ -0,0 +1,7 @@
+-- HMAC hashes made under a rotated key carry a "v<N>$" version prefix
+ALTER TABLE authorization_codes ALTER COLUMN code_hash TYPE VARCHAR(80);
+ALTER TABLE oauth_refresh_tokens ALTER COLUMN token_hash TYPE VARCHAR(80);
+ALTER TABLE initial_access_tokens ALTER COLUMN token_hash TYPE VARCHAR(80);
+ALTER TABLE client_apps ALTER COLUMN registration_token_hash TYPE VARCHAR(80);
+ALTER TABLE mfa_recovery_codes ALTER COLUMN code_hash TYPE VARCHAR(80);
+ALTER TABLE trusted_devices ALTER COLUMN token_hash TYPE VARCHAR(80);
@@ -0,0 +1 @@
+ALTER TABLE failed_login_attempts DROP COLUMN IF EXISTS location;
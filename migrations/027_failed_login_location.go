@@ -0,0 +1,43 @@
+//go:build ignore
+
+package main
+
+import (
+	"database/sql"
+	"log"
+)
+
+func init() {
+	Migrations = append(Migrations, Migration{
+		Version:     27,
+		Description: "Add location to failed login attempts",
+		Up:          mig027Up,
+		Down:        mig027Down,
+	})
+}
+
+func mig027Up(tx *sql.Tx) error {
+	log.Println("Running migration 027: Add location to failed login attempts")
+
+	_, err := tx.Exec(`ALTER TABLE failed_login_attempts ADD COLUMN IF NOT EXISTS location TEXT;`)
+	if err != nil {
+		log.Fatal("Failed to add failed_login_attempts.location:", err)
+		return err
+	}
+
+	log.Println("Migration 027 completed successfully")
+	return nil
+}
+
+func mig027Down(tx *sql.Tx) error {
+	log.Println("Rolling back migration 027: Drop location from failed login attempts")
+
+	_, err := tx.Exec(`ALTER TABLE failed_login_attempts DROP COLUMN IF EXISTS location;`)
+	if err != nil {
+		log.Fatal("Failed to drop failed_login_attempts.location:", err)
+		return err
+	}
+
+	log.Println("Migration 027 rollback completed successfully")
+	return nil
+}
@@ -0,0 +1,2 @@
+-- GeoIP location of the address a failed sign-in came from, when known
+ALTER TABLE failed_login_attempts ADD COLUMN IF NOT EXISTS location TEXT;
@@ -17,6 +17,8 @@ type Service interface {
 	SendPasswordResetEmail(toEmail, resetToken string) error
 	SendInvitationEmail(toEmail, inviterName, organizationName, invitationToken string) error
 	SendVerificationEmail(toEmail, verificationToken string) error
+	SendLoginLinkEmail(toEmail, loginToken string) error
+	SendLoginCodeEmail(toEmail, loginCode string) error
 }
 
 // service implements Service interface
@@ -84,6 +86,39 @@ func (s *service) SendVerificationEmail(toEmail, verificationCode string) error
 	return s.sendEmail(toEmail, subject, htmlContent)
 }
 
+// SendLoginLinkEmail sends a one-time sign-in link
+func (s *service) SendLoginLinkEmail(toEmail, loginToken string) error {
+	if !s.config.Enabled {
+		fmt.Printf("[DEV MODE] Sign-in link email to %s with token %s\n", toEmail, loginToken)
+		return nil
+	}
+
+	loginURL := fmt.Sprintf("%s/auth/email-login?email=%s&token=%s", s.config.FrontendURL, url.QueryEscape(toEmail), loginToken)
+	subject := "Your Sign-in Link"
+	htmlContent, err := s.generateLoginEmailHTML(loginURL, "")
+	if err != nil {
+		return fmt.Errorf("failed to generate email content: %w", err)
+	}
+
+	return s.sendEmail(toEmail, subject, htmlContent)
+}
+
+// SendLoginCodeEmail sends a 6-digit one-time sign-in code
+func (s *service) SendLoginCodeEmail(toEmail, loginCode string) error {
+	if !s.config.Enabled {
+		fmt.Printf("[DEV MODE] Sign-in code email to %s with code %s\n", toEmail, loginCode)
+		return nil
+	}
+
+	subject := "Your Sign-in Code"
+	htmlContent, err := s.generateLoginEmailHTML("", loginCode)
+	if err != nil {
+		return fmt.Errorf("failed to generate email content: %w", err)
+	}
+
+	return s.sendEmail(toEmail, subject, htmlContent)
+}
+
 // sendEmail sends an email via SMTP or Resend API
 func (s *service) sendEmail(to, subject, htmlBody string) error {
 	// Check if using Resend API (RESEND_API_KEY is set)
@@ -318,3 +353,56 @@ func (s *service) generateVerificationEmailHTML(toEmail, verificationCode string
 
 	return buf.String(), nil
 }
+
+// generateLoginEmailHTML generates HTML content for a passwordless sign-in email.
+// Exactly one of loginURL and loginCode is set.
+func (s *service) generateLoginEmailHTML(loginURL, loginCode string) (string, error) {
+	tmpl := `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Sign In</title>
+</head>
+<body style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <h2>Sign in to your account</h2>
+    {{if .LoginURL}}
+    <p>Click the button below to sign in. The link can only be used once.</p>
+    <p style="margin: 30px 0;">
+        <a href="{{.LoginURL}}" style="background-color: #007bff; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Sign In</a>
+    </p>
+    <p>If the button doesn't work, copy and paste this URL into your browser:</p>
+    <p style="word-break: break-all; color: #666;">{{.LoginURL}}</p>
+    {{else}}
+    <p>Enter this code to sign in. It can only be used once.</p>
+    <p style="font-size: 36px; font-weight: bold; letter-spacing: 8px; font-family: 'Courier New', monospace; margin: 30px 0;">{{.LoginCode}}</p>
+    {{end}}
+    <p>This {{if .LoginURL}}link{{else}}code{{end}} will expire in 15 minutes for security reasons.</p>
+    <p>If you didn't try to sign in, you can safely ignore this email. Never share it with anyone.</p>
+    <hr style="margin: 30px 0; border: none; border-top: 1px solid #eee;">
+    <p style="color: #666; font-size: 12px;">This email was sent by {{.FromName}}. If you have any questions, please contact support.</p>
+</body>
+</html>`
+
+	t, err := template.New("loginEmail").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		LoginURL  string
+		LoginCode string
+		FromName  string
+	}{
+		LoginURL:  loginURL,
+		LoginCode: loginCode,
+		FromName:  s.config.FromName,
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,202 @@
+// Package geoip resolves IP addresses to an approximate location and network from
+// offline MaxMind DB (.mmdb) files such as GeoLite2-City and GeoLite2-ASN, so sign-ins
+// and sessions can be placed without calling out to a third party.
+package geoip
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Location is what the configured databases know about an address. Fields a database
+// does not carry are left empty.
+type Location struct {
+	CountryCode string  `json:"country_code,omitempty"`
+	Country     string  `json:"country,omitempty"`
+	RegionCode  string  `json:"region_code,omitempty"`
+	Region      string  `json:"region,omitempty"`
+	City        string  `json:"city,omitempty"`
+	Latitude    float64 `json:"latitude,omitempty"`
+	Longitude   float64 `json:"longitude,omitempty"`
+	ASN         uint32  `json:"asn,omitempty"`
+	ASOrg       string  `json:"as_org,omitempty"`
+}
+
+// String is a display form such as "Berlin, Land Berlin, Germany"
+func (l *Location) String() string {
+	var parts []string
+	for _, part := range []string{l.City, l.Region, l.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Encode serializes the location for storage in a text column
+func (l *Location) Encode() string {
+	if l == nil {
+		return ""
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ParseLocation reverses Encode. Values that are not an encoded location, such as
+// those stored before lookups existed, give nil.
+func ParseLocation(s string) *Location {
+	if !strings.HasPrefix(s, "{") {
+		return nil
+	}
+	var l Location
+	if err := json.Unmarshal([]byte(s), &l); err != nil {
+		return nil
+	}
+	return &l
+}
+
+// Resolver merges lookups across databases, e.g. a city database and an ASN database.
+// A database only fills the fields the ones before it left empty.
+type Resolver struct {
+	readers []*Reader
+}
+
+// NewResolver creates a resolver over already opened databases
+func NewResolver(readers ...*Reader) *Resolver {
+	return &Resolver{readers: readers}
+}
+
+// OpenResolver opens a comma-separated list of .mmdb files
+func OpenResolver(paths string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		reader, err := Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r.readers = append(r.readers, reader)
+	}
+	if len(r.readers) == 0 {
+		return nil, fmt.Errorf("no GeoIP database configured")
+	}
+	return r, nil
+}
+
+// DatabaseTypes lists the loaded databases, for logging
+func (r *Resolver) DatabaseTypes() []string {
+	types := make([]string, 0, len(r.readers))
+	for _, reader := range r.readers {
+		types = append(types, reader.DatabaseType())
+	}
+	return types
+}
+
+// Lookup resolves an address. It returns nil when no database knows it.
+func (r *Resolver) Lookup(address string) (*Location, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address")
+	}
+
+	var l Location
+	found := false
+	for _, reader := range r.readers {
+		record, err := reader.Lookup(ip)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		found = true
+		l.fill(record)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &l, nil
+}
+
+// fill copies GeoIP2 City/Country and ASN fields into l without overwriting any
+func (l *Location) fill(record map[string]interface{}) {
+	country := field(record, "country")
+	if country == nil {
+		country = field(record, "registered_country")
+	}
+	setString(&l.CountryCode, field(country, "iso_code"))
+	setString(&l.Country, field(country, "names", "en"))
+
+	region := field(record, "subdivisions", 0)
+	setString(&l.RegionCode, field(region, "iso_code"))
+	setString(&l.Region, field(region, "names", "en"))
+	setString(&l.City, field(record, "city", "names", "en"))
+
+	if l.Latitude == 0 && l.Longitude == 0 {
+		lat, latOK := field(record, "location", "latitude").(float64)
+		lon, lonOK := field(record, "location", "longitude").(float64)
+		if latOK && lonOK {
+			l.Latitude, l.Longitude = lat, lon
+		}
+	}
+
+	if asn, ok := field(record, "autonomous_system_number").(uint64); ok && l.ASN == 0 {
+		l.ASN = uint32(asn)
+	}
+	setString(&l.ASOrg, field(record, "autonomous_system_organization"))
+}
+
+// field walks a decoded record by map keys (string) and array indexes (int)
+func field(value interface{}, path ...interface{}) interface{} {
+	for _, step := range path {
+		switch key := step.(type) {
+		case string:
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = m[key]
+		case int:
+			items, ok := value.([]interface{})
+			if !ok || key >= len(items) {
+				return nil
+			}
+			value = items[key]
+		}
+	}
+	return value
+}
+
+func setString(dst *string, value interface{}) {
+	if s, ok := value.(string); ok && *dst == "" {
+		*dst = s
+	}
+}
+
+var defaultResolver *Resolver
+
+// SetDefault installs the resolver used by Lookup; nil turns lookups off
+func SetDefault(r *Resolver) {
+	defaultResolver = r
+}
+
+// Lookup resolves an address with the default resolver. It returns nil when no
+// database is configured, the address is not a valid IP or nothing is known about it.
+func Lookup(address string) *Location {
+	if defaultResolver == nil || address == "" {
+		return nil
+	}
+	l, err := defaultResolver.Lookup(address)
+	if err != nil {
+		return nil
+	}
+	return l
+}
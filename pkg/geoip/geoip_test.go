@@ -0,0 +1,125 @@
+package geoip_test
+
+import (
+	"net"
+	"testing"
+
+	"auth-service/pkg/geoip"
+)
+
+// The test databases are made with testdata/generate.go
+
+func TestReaderLookup(t *testing.T) {
+	r, err := geoip.Open("testdata/city-test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.DatabaseType() != "Test-City" {
+		t.Errorf("database type = %q", r.DatabaseType())
+	}
+
+	record, err := r.Lookup(net.ParseIP("81.2.69.142"))
+	if err != nil || record == nil {
+		t.Fatalf("record=%v err=%v", record, err)
+	}
+	city, _ := record["city"].(map[string]interface{})
+	names, _ := city["names"].(map[string]interface{})
+	if names["en"] != "London" {
+		t.Errorf("city = %v", record["city"])
+	}
+
+	for _, ip := range []string{"8.8.8.8", "89.160.20.5", "2001:db8:2::1"} {
+		if record, err := r.Lookup(net.ParseIP(ip)); err != nil || record != nil {
+			t.Errorf("%s: record=%v err=%v, want no record", ip, record, err)
+		}
+	}
+}
+
+func TestResolverMergesDatabases(t *testing.T) {
+	r, err := geoip.OpenResolver("testdata/city-test.mmdb, testdata/asn-test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := r.Lookup("89.160.20.130")
+	if err != nil || l == nil {
+		t.Fatalf("location=%v err=%v", l, err)
+	}
+	want := geoip.Location{
+		CountryCode: "SE",
+		Country:     "Sweden",
+		RegionCode:  "E",
+		Region:      "Östergötland County",
+		City:        "Linköping",
+		Latitude:    58.4167,
+		Longitude:   15.6167,
+		ASN:         29518,
+		ASOrg:       "Bredband2 AB",
+	}
+	if *l != want {
+		t.Errorf("got %+v\nwant %+v", *l, want)
+	}
+	if l.String() != "Linköping, Östergötland County, Sweden" {
+		t.Errorf("String() = %q", l.String())
+	}
+
+	// Only the ASN database knows this network
+	l, _ = r.Lookup("203.0.113.7")
+	if l == nil || l.ASN != 64496 || l.Country != "" {
+		t.Errorf("ASN-only lookup = %+v", l)
+	}
+
+	l, _ = r.Lookup("2001:db8:1::42")
+	if l == nil || l.City != "Berlin" {
+		t.Errorf("IPv6 lookup = %+v", l)
+	}
+
+	if l, err := r.Lookup("192.168.1.10"); err != nil || l != nil {
+		t.Errorf("unknown address: location=%v err=%v", l, err)
+	}
+	if _, err := r.Lookup("not-an-ip"); err == nil {
+		t.Error("invalid address should be an error")
+	}
+}
+
+func TestLocationEncoding(t *testing.T) {
+	l := &geoip.Location{CountryCode: "GB", Country: "United Kingdom", City: "London", ASN: 20712}
+	if got := geoip.ParseLocation(l.Encode()); got == nil || *got != *l {
+		t.Errorf("round trip gave %+v", got)
+	}
+	for _, legacy := range []string{"", "unknown", "private_network"} {
+		if geoip.ParseLocation(legacy) != nil {
+			t.Errorf("%q should not parse", legacy)
+		}
+	}
+}
+
+func TestDefaultResolver(t *testing.T) {
+	geoip.SetDefault(nil)
+	if geoip.Lookup("81.2.69.142") != nil {
+		t.Error("lookups are off without a database")
+	}
+
+	r, err := geoip.OpenResolver("testdata/city-test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	geoip.SetDefault(r)
+	defer geoip.SetDefault(nil)
+
+	if l := geoip.Lookup("81.2.69.142"); l == nil || l.CountryCode != "GB" {
+		t.Errorf("default lookup = %+v", l)
+	}
+	if geoip.Lookup("garbage") != nil {
+		t.Error("invalid addresses resolve to nothing")
+	}
+}
+
+func TestNewReaderRejectsGarbage(t *testing.T) {
+	if _, err := geoip.NewReader([]byte("not a database")); err == nil {
+		t.Error("expected an error without metadata")
+	}
+	if _, err := geoip.OpenResolver(" , "); err == nil {
+		t.Error("expected an error for an empty path list")
+	}
+}
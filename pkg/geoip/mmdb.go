@@ -0,0 +1,345 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// A MaxMind DB file is a binary search tree over address bits, a data section the tree's
+// leaves point into, and a metadata map after a marker at the end of the file. Only the
+// parts GeoIP2/GeoLite2 databases use are supported; the data cache container and end
+// marker types are rejected.
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// Data section field types
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+const maxDecodeDepth = 32
+
+var errTruncated = errors.New("mmdb: unexpected end of data")
+
+// Reader looks addresses up in one MaxMind DB file held in memory
+type Reader struct {
+	databaseType string
+	tree         []byte
+	data         decoder
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	ipv4Start    uint
+}
+
+// Open reads a .mmdb file
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	return NewReader(buf)
+}
+
+// NewReader parses a MaxMind DB from memory
+func NewReader(buf []byte) (*Reader, error) {
+	marker := bytes.LastIndex(buf, metadataMarker)
+	if marker < 0 {
+		return nil, errors.New("mmdb: metadata not found")
+	}
+	meta, _, err := decoder{buf: buf[marker+len(metadataMarker):]}.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmdb: invalid metadata: %w", err)
+	}
+
+	r := &Reader{
+		nodeCount:  metadataUint(meta, "node_count"),
+		recordSize: metadataUint(meta, "record_size"),
+		ipVersion:  metadataUint(meta, "ip_version"),
+	}
+	r.databaseType, _ = field(meta, "database_type").(string)
+
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("mmdb: unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("mmdb: unsupported IP version %d", r.ipVersion)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > uint(marker) {
+		return nil, errors.New("mmdb: search tree larger than the file")
+	}
+	r.tree = buf[:treeSize]
+	// 16 zero bytes separate the tree from the data section
+	r.data = decoder{buf: buf[treeSize+16 : marker]}
+
+	// IPv4 addresses live under ::/96 in an IPv6 tree
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// DatabaseType is the type recorded in the file's metadata, e.g. "GeoLite2-City"
+func (r *Reader) DatabaseType() string {
+	return r.databaseType
+}
+
+// Lookup returns the record for ip, or nil when the database has none
+func (r *Reader) Lookup(ip net.IP) (map[string]interface{}, error) {
+	node := uint(0)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if ip = ip.To16(); ip == nil {
+		return nil, errors.New("mmdb: invalid IP address")
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+		node = r.record(node, uint(bit))
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, errors.New("mmdb: search tree deeper than the address")
+	}
+
+	offset := node - r.nodeCount - 16
+	value, _, err := r.data.decode(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("mmdb: record is not a map")
+	}
+	return record, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) pointer of a search tree node
+func (r *Reader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.tree[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(r.tree[node*8+bit*4:]))
+	}
+}
+
+// decoder reads values from a data section. Maps decode to map[string]interface{},
+// arrays to []interface{}, unsigned integers to uint64, int32 to int64 and floats to
+// float64; uint128 is left as big-endian bytes.
+type decoder struct {
+	buf []byte
+}
+
+// decode reads the value at offset and returns it with the offset of the next value
+func (d decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errors.New("mmdb: nesting too deep")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		// The value is read from the target, but decoding carries on after the pointer
+		value, _, err := d.decode(target, depth+1)
+		return value, next, err
+	}
+	return d.value(typ, size, offset, depth)
+}
+
+// control parses a field's control byte(s) into its type and payload size
+func (d decoder) control(offset uint) (byte, uint, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errTruncated
+	}
+	c := d.buf[offset]
+	offset++
+
+	typ := c >> 5
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errTruncated
+		}
+		typ = d.buf[offset] + 7
+		offset++
+		if typ <= typeMap {
+			return 0, 0, 0, fmt.Errorf("mmdb: invalid extended type %d", typ)
+		}
+	}
+
+	size := uint(c & 0x1f)
+	if typ == typePointer || size < 29 {
+		return typ, size, offset, nil
+	}
+
+	n := size - 28
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, 0, errTruncated
+	}
+	extra := beUint(d.buf[offset : offset+n])
+	switch size {
+	case 29:
+		size = 29 + uint(extra)
+	case 30:
+		size = 285 + uint(extra)
+	default:
+		size = 65821 + uint(extra)
+	}
+	return typ, size, offset + n, nil
+}
+
+// pointer decodes a pointer's target; size holds the low five bits of its control byte
+func (d decoder) pointer(size, offset uint) (uint, uint, error) {
+	n := (size>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errTruncated
+	}
+	b := d.buf[offset : offset+n]
+	high := size & 0x7
+
+	var target uint
+	switch n {
+	case 1:
+		target = high<<8 | uint(b[0])
+	case 2:
+		target = (high<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		target = (high<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		target = uint(binary.BigEndian.Uint32(b))
+	}
+	return target, offset + n, nil
+}
+
+func (d decoder) value(typ byte, size, offset uint, depth int) (interface{}, uint, error) {
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{})
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("mmdb: map key is not a string")
+			}
+			m[k], offset, err = d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+	case typeArray:
+		var items []interface{}
+		for i := uint(0); i < size; i++ {
+			item, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			offset = next
+		}
+		return items, offset, nil
+	case typeBool:
+		if size > 1 {
+			return nil, 0, errors.New("mmdb: invalid boolean")
+		}
+		return size == 1, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errTruncated
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		if typ == typeUint128 && size > 16 {
+			return nil, 0, errors.New("mmdb: uint128 too long")
+		}
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("mmdb: invalid double")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("mmdb: invalid float")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		limit := map[byte]uint{typeUint16: 2, typeUint32: 4, typeUint64: 8}[typ]
+		if size > limit {
+			return nil, 0, fmt.Errorf("mmdb: unsigned integer of %d bytes", size)
+		}
+		return beUint(b), next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errors.New("mmdb: invalid int32")
+		}
+		return int64(int32(uint32(beUint(b)))), next, nil
+	default:
+		return nil, 0, fmt.Errorf("mmdb: unsupported data type %d", typ)
+	}
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func metadataUint(meta interface{}, key string) uint {
+	v, _ := field(meta, key).(uint64)
+	return uint(v)
+}
@@ -0,0 +1,232 @@
+//go:build ignore
+
+// generate writes the tiny MaxMind DB files the geoip tests read. Run it from pkg/geoip:
+//
+//	go run testdata/generate.go
+//
+// The networks and places are made up for tests; they are not real GeoIP data.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"time"
+)
+
+type network struct {
+	cidr   string
+	record map[string]interface{}
+}
+
+func place(countryCode, country, regionCode, region, city string, lat, lon float64) map[string]interface{} {
+	return map[string]interface{}{
+		"country": map[string]interface{}{
+			"iso_code": countryCode,
+			"names":    map[string]interface{}{"en": country},
+		},
+		"subdivisions": []interface{}{map[string]interface{}{
+			"iso_code": regionCode,
+			"names":    map[string]interface{}{"en": region},
+		}},
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": city},
+		},
+		"location": map[string]interface{}{
+			"latitude":  lat,
+			"longitude": lon,
+		},
+	}
+}
+
+func asn(number uint32, org string) map[string]interface{} {
+	return map[string]interface{}{
+		"autonomous_system_number":       number,
+		"autonomous_system_organization": org,
+	}
+}
+
+func main() {
+	write("city-test.mmdb", "Test-City", []network{
+		{"81.2.69.0/24", place("GB", "United Kingdom", "ENG", "England", "London", 51.5142, -0.0931)},
+		{"89.160.20.128/25", place("SE", "Sweden", "E", "Östergötland County", "Linköping", 58.4167, 15.6167)},
+		{"2001:db8:1::/48", place("DE", "Germany", "BE", "Land Berlin", "Berlin", 52.5244, 13.4105)},
+	})
+	write("asn-test.mmdb", "Test-ASN", []network{
+		{"81.2.69.0/24", asn(20712, "Andrews & Arnold Ltd")},
+		{"89.160.20.0/24", asn(29518, "Bredband2 AB")},
+		{"203.0.113.0/24", asn(64496, "Example Transit")},
+	})
+}
+
+// node is a search tree node; each side points to a child, a data record or nothing
+type node struct {
+	child [2]*node
+	data  [2]int
+}
+
+func newNode() *node {
+	return &node{data: [2]int{-1, -1}}
+}
+
+func write(path, databaseType string, networks []network) {
+	var data bytes.Buffer
+	root := newNode()
+	for _, n := range networks {
+		_, ipnet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ip, ones := ipnet.IP.To16(), 0
+		if ipnet.IP.To4() != nil {
+			// IPv4 networks sit under ::/96
+			ip = append(make(net.IP, 12), ipnet.IP.To4()...)
+			ones, _ = ipnet.Mask.Size()
+			ones += 96
+		} else {
+			ones, _ = ipnet.Mask.Size()
+		}
+
+		offset := data.Len()
+		encode(&data, n.record)
+
+		cur := root
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+			if i == ones-1 {
+				cur.data[bit] = offset
+				break
+			}
+			if cur.child[bit] == nil {
+				cur.child[bit] = newNode()
+			}
+			cur = cur.child[bit]
+		}
+	}
+
+	// Number nodes breadth first; the root must be node 0
+	var nodes []*node
+	index := map[*node]int{}
+	queue := []*node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.child {
+			if c != nil {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	count := len(nodes)
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for bit := 0; bit < 2; bit++ {
+			record := count
+			if n.child[bit] != nil {
+				record = index[n.child[bit]]
+			} else if n.data[bit] >= 0 {
+				record = count + 16 + n.data[bit]
+			}
+			out.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+
+	out.WriteString("\xab\xcd\xefMaxMind.com")
+	encode(&out, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix()),
+		"database_type":               databaseType,
+		"description":                 map[string]interface{}{"en": "Test data for pkg/geoip"},
+		"ip_version":                  uint16(6),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+	})
+
+	if err := os.WriteFile("testdata/"+path, out.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func encode(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		control(buf, 2, len(v))
+		buf.WriteString(v)
+	case float64:
+		control(buf, 3, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		writeUint(buf, 5, uint64(v))
+	case uint32:
+		writeUint(buf, 6, uint64(v))
+	case uint64:
+		writeUint(buf, 9, v)
+	case map[string]interface{}:
+		control(buf, 7, len(v))
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			encode(buf, k)
+			encode(buf, v[k])
+		}
+	case []interface{}:
+		control(buf, 11, len(v))
+		for _, item := range v {
+			encode(buf, item)
+		}
+	default:
+		log.Fatalf("cannot encode %T", value)
+	}
+}
+
+func writeUint(buf *bytes.Buffer, typ int, v uint64) {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	control(buf, typ, len(b))
+	buf.Write(b)
+}
+
+func control(buf *bytes.Buffer, typ, size int) {
+	first := byte(typ << 5)
+	var extended []byte
+	if typ > 7 {
+		first = 0
+		extended = []byte{byte(typ - 7)}
+	}
+
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		first |= byte(size)
+	case size < 285:
+		first |= 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		first |= 30
+		sizeBytes = []byte{byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		first |= 31
+		s := size - 65821
+		sizeBytes = []byte{byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+
+	buf.WriteByte(first)
+	buf.Write(extended)
+	buf.Write(sizeBytes)
+}
@@ -1,54 +1,123 @@
 package hashutil
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"os"
 )
 
 var (
-	// Secret key for HMAC-SHA256 - loaded from environment
-	hmacSecret []byte
+	// Versioned HMAC keys - loaded from configuration at startup
+	keyring *Keyring
 )
 
-// InitializeHMACSecret initializes the HMAC secret from environment variable
-// This should be called once during application startup
+var errNotInitialized = fmt.Errorf("HMAC secret not initialized - call InitializeHMACSecret first")
+
+// InitializeHMACSecret initializes a single-version keyring from the HMAC_SECRET
+// environment variable. Servers that rotate keys load a full keyring with SetKeyring.
 func InitializeHMACSecret() error {
-	secret := os.Getenv("HMAC_SECRET")
-	if secret == "" {
-		return fmt.Errorf("HMAC_SECRET environment variable not set")
+	k, err := LoadKeyring(os.Getenv("HMAC_SECRET"), "", "", 0)
+	if err != nil {
+		return err
 	}
-	hmacSecret = []byte(secret)
+	keyring = k
 	return nil
 }
 
+// SetKeyring installs the keyring used for all hashing and encryption in this package.
+// This should be called once during application startup.
+func SetKeyring(k *Keyring) {
+	keyring = k
+}
+
 // SetHMACSecret sets the HMAC secret (for testing purposes)
 func SetHMACSecret(secret string) {
-	hmacSecret = []byte(secret)
+	keyring, _ = NewKeyring(map[int][]byte{1: []byte(secret)}, 0)
 }
 
-// HMACHash creates a deterministic HMAC-SHA256 hash of the input
-// This is suitable for token and code hashing where lookup is required
+// HMACHash creates a deterministic HMAC-SHA256 hash of the input under the active key
+// version. This is suitable for token and code hashing where lookup is required; hashes
+// stored before a rotation are found with HMACHashCandidates.
 func HMACHash(data string) (string, error) {
-	if len(hmacSecret) == 0 {
-		return "", fmt.Errorf("HMAC secret not initialized - call InitializeHMACSecret first")
+	if keyring == nil {
+		return "", errNotInitialized
 	}
+	return keyring.encode(keyring.hash(data, keyring.active), keyring.active), nil
+}
 
-	h := hmac.New(sha256.New, hmacSecret)
-	h.Write([]byte(data))
-	hash := h.Sum(nil)
-	return hex.EncodeToString(hash), nil
+// HMACHashCandidates returns the hash of data under every loaded key version, newest
+// first, for looking up values that may have been stored under an earlier key.
+func HMACHashCandidates(data string) ([]string, error) {
+	if keyring == nil {
+		return nil, errNotInitialized
+	}
+	candidates := make([]string, 0, len(keyring.versions))
+	digest := data
+	for _, v := range keyring.versions {
+		digest = mac(keyring.keys[v], digest)
+		candidates = append([]string{keyring.encode(digest, v)}, candidates...)
+	}
+	return candidates, nil
 }
 
-// VerifyHMACHash verifies that the input matches the hash
+// RedisLookupHash hashes a short-lived secret (a challenge, device code or ticket) for
+// use in a Redis key or value. The key is derived from the oldest version and does not
+// follow the active one, so entries written just before an active-version change, or by
+// a replica still on the previous configuration, are found. Nothing rehashes these, so
+// they must not be stored anywhere that outlives its Redis TTL.
+func RedisLookupHash(data string) (string, error) {
+	if keyring == nil {
+		return "", errNotInitialized
+	}
+	key, err := keyring.Derive(keyring.base(), "redis-lookup")
+	if err != nil {
+		return "", err
+	}
+	return mac(key, data), nil
+}
+
+// VerifyHMACHash verifies that the input matches the hash, using the key version the
+// hash records
 func VerifyHMACHash(input, expectedHash string) (bool, error) {
-	actualHash, err := HMACHash(input)
+	if keyring == nil {
+		return false, errNotInitialized
+	}
+	version, digest, err := keyring.decode(expectedHash)
 	if err != nil {
 		return false, err
 	}
-	return hmac.Equal([]byte(actualHash), []byte(expectedHash)), nil
+	return hmac.Equal([]byte(keyring.hash(input, version)), []byte(digest)), nil
+}
+
+// RehashHMAC carries a stored hash forward to the active key version without needing the
+// original input. It reports false when the hash is already at (or past) the active version.
+func RehashHMAC(stored string) (string, bool, error) {
+	if keyring == nil {
+		return "", false, errNotInitialized
+	}
+	version, digest, err := keyring.decode(stored)
+	if err != nil {
+		return "", false, err
+	}
+	if version >= keyring.active {
+		return stored, false, nil
+	}
+	return keyring.encode(keyring.rewrap(digest, version, keyring.active), keyring.active), true, nil
+}
+
+// ActiveHMACPrefix returns the prefix every hash made under the active key version
+// starts with, or "" while the oldest key is active and hashes carry no prefix
+func ActiveHMACPrefix() (string, error) {
+	if keyring == nil {
+		return "", errNotInitialized
+	}
+	return keyring.encode("", keyring.active), nil
 }
 
 // SHA256Hash creates a simple SHA256 hash (for non-secret data like user agent, IP)
@@ -59,3 +128,59 @@ func SHA256Hash(data string) string {
 	hash := h.Sum(nil)
 	return hex.EncodeToString(hash)
 }
+
+// EncryptSecret encrypts a secret the server must be able to read back later
+// (e.g. a client secret used as an HMAC key) with AES-256-GCM. The key is derived
+// from the oldest HMAC key, so replacing HMAC_SECRET makes existing ciphertexts unreadable;
+// adding key versions does not.
+func EncryptSecret(plaintext string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret reverses EncryptSecret
+func DecryptSecret(ciphertext string) (string, error) {
+	gcm, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted secret")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func secretCipher() (cipher.AEAD, error) {
+	if keyring == nil {
+		return nil, errNotInitialized
+	}
+
+	// Domain-separate the encryption key from the HMAC key
+	key, err := keyring.Derive(keyring.base(), "secret-encryption")
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
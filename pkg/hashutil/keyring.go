@@ -0,0 +1,195 @@
+package hashutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keyring holds the versioned server secrets used for lookup hashes and the password
+// pepper. Versions are append-only: a hash made under version N is chained through every
+// key up to N, so dropping a version breaks lookups of every hash chained through it, and
+// rows left on older versions keep depending on it until RehashHMAC has run over all of
+// them. Hashes at later versions are chained through it too, so no version is ever dropped;
+// NewKeyring refuses a chain with gaps so a missing key fails at startup instead.
+type Keyring struct {
+	keys     map[int][]byte
+	versions []int // ascending
+	active   int
+}
+
+// NewKeyring builds a keyring from version -> secret. An active version of 0 selects the
+// highest one, which lets a new key be staged by loading it with an explicit lower active.
+func NewKeyring(keys map[int][]byte, active int) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("keyring has no keys")
+	}
+
+	k := &Keyring{keys: make(map[int][]byte, len(keys))}
+	for version, secret := range keys {
+		if version < 1 {
+			return nil, fmt.Errorf("invalid key version %d", version)
+		}
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("key version %d is empty", version)
+		}
+		k.keys[version] = secret
+		k.versions = append(k.versions, version)
+	}
+	sort.Ints(k.versions)
+
+	for i, version := range k.versions {
+		if version != i+1 {
+			return nil, fmt.Errorf("key version %d is missing; versions must run from 1 without gaps", i+1)
+		}
+	}
+
+	if active == 0 {
+		active = k.versions[len(k.versions)-1]
+	}
+	if _, ok := k.keys[active]; !ok {
+		return nil, fmt.Errorf("active key version %d is not loaded", active)
+	}
+	k.active = active
+	return k, nil
+}
+
+// LoadKeyring assembles the keyring from configuration. base (HMAC_SECRET) is version 1;
+// more versions come from keys, a comma-separated "2=secret,3=secret" list, and from
+// "<version>.key" files in dir.
+func LoadKeyring(base, keys, dir string, active int) (*Keyring, error) {
+	if base == "" {
+		return nil, fmt.Errorf("HMAC_SECRET environment variable not set")
+	}
+	secrets := map[int][]byte{1: []byte(base)}
+
+	add := func(version int, secret, source string) error {
+		if _, dup := secrets[version]; dup {
+			return fmt.Errorf("key version %d defined more than once (%s)", version, source)
+		}
+		secrets[version] = []byte(secret)
+		return nil
+	}
+
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		v, secret, ok := strings.Cut(entry, "=")
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if !ok || err != nil {
+			// Never echo the entry: it may be a secret missing its version
+			return nil, fmt.Errorf("invalid HMAC_KEYS entry: expected version=secret")
+		}
+		if err := add(version, strings.TrimSpace(secret), "HMAC_KEYS"); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.key"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list HMAC keys: %w", err)
+		}
+		for _, file := range files {
+			version, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(file), ".key"))
+			if err != nil {
+				return nil, fmt.Errorf("HMAC key file %s is not named <version>.key", file)
+			}
+			secret, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read HMAC key: %w", err)
+			}
+			if err := add(version, strings.TrimSpace(string(secret)), file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return NewKeyring(secrets, active)
+}
+
+// Active returns the version new hashes are made with
+func (k *Keyring) Active() int {
+	return k.active
+}
+
+// Derive returns a key for a single purpose (label) from the given version, so the same
+// secret never serves as both a lookup-hash key and, say, an encryption key.
+func (k *Keyring) Derive(version int, label string) ([]byte, error) {
+	secret, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("key version %d is not loaded", version)
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(label))
+	return h.Sum(nil), nil
+}
+
+// base is the oldest version. Its hashes are bare hex, as they were before versioning.
+func (k *Keyring) base() int {
+	return k.versions[0]
+}
+
+// hash returns the hex HMAC of data at version: the base key applied to data, then each
+// later key up to version applied to the previous hex digest.
+func (k *Keyring) hash(data string, version int) string {
+	digest := data
+	for _, v := range k.versions {
+		if v > version {
+			break
+		}
+		digest = mac(k.keys[v], digest)
+	}
+	return digest
+}
+
+// rewrap carries a digest made at from forward to version without the original input
+func (k *Keyring) rewrap(digest string, from, version int) string {
+	for _, v := range k.versions {
+		if v <= from {
+			continue
+		}
+		if v > version {
+			break
+		}
+		digest = mac(k.keys[v], digest)
+	}
+	return digest
+}
+
+func (k *Keyring) encode(digest string, version int) string {
+	if version == k.base() {
+		return digest
+	}
+	return "v" + strconv.Itoa(version) + "$" + digest
+}
+
+// decode splits a stored hash into its version and hex digest
+func (k *Keyring) decode(stored string) (int, string, error) {
+	if !strings.HasPrefix(stored, "v") {
+		return k.base(), stored, nil
+	}
+	v, digest, ok := strings.Cut(stored[1:], "$")
+	version, err := strconv.Atoi(v)
+	if !ok || err != nil {
+		return 0, "", fmt.Errorf("malformed HMAC hash")
+	}
+	if _, loaded := k.keys[version]; !loaded {
+		return 0, "", fmt.Errorf("HMAC key version %d is not loaded", version)
+	}
+	return version, digest, nil
+}
+
+func mac(key []byte, data string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,151 @@
+package hashutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"auth-service/pkg/hashutil"
+)
+
+func loadKeyring(t *testing.T, keys string, active int) *hashutil.Keyring {
+	t.Helper()
+	k, err := hashutil.LoadKeyring("base-secret", keys, "", active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashutil.SetKeyring(k)
+	return k
+}
+
+func TestHMACHashVersions(t *testing.T) {
+	loadKeyring(t, "", 0)
+	v1, err := hashutil.HMACHash("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v1) != 64 {
+		t.Fatalf("version 1 hashes stay bare hex, got %q", v1)
+	}
+
+	loadKeyring(t, "2=second-secret", 0)
+	v2, _ := hashutil.HMACHash("token")
+	if !strings.HasPrefix(v2, "v2$") {
+		t.Fatalf("expected a v2 hash, got %q", v2)
+	}
+
+	candidates, err := hashutil.HMACHashCandidates("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 || candidates[0] != v2 || candidates[1] != v1 {
+		t.Fatalf("candidates should be newest first: %v", candidates)
+	}
+
+	for _, stored := range []string{v1, v2} {
+		if ok, err := hashutil.VerifyHMACHash("token", stored); err != nil || !ok {
+			t.Errorf("%q not verified: ok=%v err=%v", stored, ok, err)
+		}
+		if ok, _ := hashutil.VerifyHMACHash("other", stored); ok {
+			t.Errorf("%q verified the wrong input", stored)
+		}
+	}
+	if _, err := hashutil.VerifyHMACHash("token", "v9$"+v1); err == nil {
+		t.Error("unknown key version should be an error")
+	}
+}
+
+func TestRehashHMAC(t *testing.T) {
+	loadKeyring(t, "", 0)
+	v1, _ := hashutil.HMACHash("refresh-token")
+
+	loadKeyring(t, "2=second-secret,3=third-secret", 0)
+	want, _ := hashutil.HMACHash("refresh-token")
+
+	got, changed, err := hashutil.RehashHMAC(v1)
+	if err != nil || !changed {
+		t.Fatalf("changed=%v err=%v", changed, err)
+	}
+	if got != want {
+		t.Fatalf("rehashed %q, hashing the input gives %q", got, want)
+	}
+	if _, changed, _ := hashutil.RehashHMAC(got); changed {
+		t.Error("a hash at the active version is left alone")
+	}
+
+	// A staged key that is not active yet is neither used nor rehashed to
+	loadKeyring(t, "2=second-secret,3=third-secret", 2)
+	if prefix, _ := hashutil.ActiveHMACPrefix(); prefix != "v2$" {
+		t.Errorf("active prefix = %q", prefix)
+	}
+	if _, changed, _ := hashutil.RehashHMAC(want); changed {
+		t.Error("hashes ahead of the active version are left alone")
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "3.key"), []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := hashutil.LoadKeyring("base-secret", "2=env-secret", dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Active() != 3 {
+		t.Errorf("active = %d, want the highest version", k.Active())
+	}
+
+	if _, err := hashutil.LoadKeyring("base-secret", "3=dup", dir, 0); err == nil {
+		t.Error("a version defined twice should be rejected")
+	}
+	if _, err := hashutil.LoadKeyring("base-secret", "no-version", "", 0); err == nil || strings.Contains(err.Error(), "no-version") {
+		t.Errorf("malformed entries are rejected without echoing them: %v", err)
+	}
+	if _, err := hashutil.LoadKeyring("base-secret", "", "", 4); err == nil {
+		t.Error("an active version that is not loaded should be rejected")
+	}
+	if _, err := hashutil.LoadKeyring("", "2=x", "", 0); err == nil {
+		t.Error("HMAC_SECRET is required")
+	}
+	if _, err := hashutil.LoadKeyring("base-secret", "2=x,4=y", "", 0); err == nil {
+		t.Error("a keyring with a version missing from the chain should be rejected")
+	}
+	if _, err := hashutil.NewKeyring(map[int][]byte{2: []byte("x"), 3: []byte("y")}, 0); err == nil {
+		t.Error("a keyring without version 1 should be rejected")
+	}
+}
+
+func TestEncryptSecretSurvivesNewKeys(t *testing.T) {
+	loadKeyring(t, "", 0)
+	sealed, err := hashutil.EncryptSecret("client-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadKeyring(t, "2=second-secret", 0)
+	plain, err := hashutil.DecryptSecret(sealed)
+	if err != nil || plain != "client-secret" {
+		t.Fatalf("plain=%q err=%v", plain, err)
+	}
+}
+
+func TestRedisLookupHashIgnoresActiveVersion(t *testing.T) {
+	loadKeyring(t, "", 0)
+	before, err := hashutil.RedisLookupHash("device-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup, _ := hashutil.HMACHash("device-code")
+	if before == lookup {
+		t.Fatal("Redis keys must not share the lookup-hash key")
+	}
+
+	loadKeyring(t, "2=second-secret", 0)
+	after, _ := hashutil.RedisLookupHash("device-code")
+	if after != before {
+		t.Fatalf("hash changed with the active version: %q != %q", after, before)
+	}
+}
@@ -0,0 +1,80 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClientAssertionType is the only assertion type accepted at the token endpoint (RFC 7523)
+const ClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// Algorithms accepted for private_key_jwt and client_secret_jwt respectively
+var (
+	AsymmetricAssertionAlgs = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+	SymmetricAssertionAlgs  = []string{"HS256", "HS384", "HS512"}
+)
+
+// ClientAssertionClaims are the claims of a client authentication JWT (RFC 7523 section 3)
+type ClientAssertionClaims struct {
+	jwt.RegisteredClaims
+}
+
+// ClientAssertionSubject returns the unverified sub claim so the caller can look up
+// the client's keys before verifying the signature
+func ClientAssertionSubject(assertion string) (string, error) {
+	var claims ClientAssertionClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, &claims); err != nil {
+		return "", fmt.Errorf("malformed client assertion: %w", err)
+	}
+	if claims.Subject == "" {
+		return "", errors.New("client assertion has no sub claim")
+	}
+	return claims.Subject, nil
+}
+
+// ParseClientAssertion verifies the assertion signature with the key returned by keyFunc
+// (called with the header alg and kid) and checks iss, sub, aud and the lifetime.
+// audiences lists every value we accept as aud; one match is enough.
+func ParseClientAssertion(assertion, clientID string, audiences []string, algs []string, maxLifetime time.Duration, keyFunc func(alg, kid string) (interface{}, error)) (*ClientAssertionClaims, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(algs))
+
+	var claims ClientAssertionClaims
+	_, err := parser.ParseWithClaims(assertion, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keyFunc(token.Method.Alg(), kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid client assertion: %w", err)
+	}
+
+	if claims.Issuer != clientID || claims.Subject != clientID {
+		return nil, errors.New("client assertion iss and sub must be the client_id")
+	}
+
+	audienceOK := false
+	for _, aud := range audiences {
+		if claims.VerifyAudience(aud, true) {
+			audienceOK = true
+			break
+		}
+	}
+	if !audienceOK {
+		return nil, errors.New("client assertion aud does not identify this server")
+	}
+
+	// The jti replay window is the token lifetime, so exp is mandatory and bounded
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("client assertion has no exp claim")
+	}
+	if time.Until(claims.ExpiresAt.Time) > maxLifetime {
+		return nil, fmt.Errorf("client assertion must expire within %s", maxLifetime)
+	}
+	if claims.ID == "" {
+		return nil, errors.New("client assertion has no jti claim")
+	}
+
+	return &claims, nil
+}
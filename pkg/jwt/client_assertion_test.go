@@ -0,0 +1,156 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testAudience = "https://auth.example.com/api/v1/oauth/token"
+
+func signAssertion(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.RegisteredClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+	return signed
+}
+
+func assertionClaims(clientID, jti string, lifetime time.Duration) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{testAudience},
+		ID:        jti,
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+	}
+}
+
+func TestParseClientAssertion_RSAAndEC(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _ := json.Marshal(JWKSet{Keys: []JWK{
+		{
+			Kty: "RSA", Kid: "rsa-1",
+			N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		{
+			Kty: "EC", Kid: "ec-1", Crv: "P-256",
+			X: base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			Y: base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+		},
+	}})
+	set, err := ParseJWKSet(doc)
+	if err != nil {
+		t.Fatalf("ParseJWKSet failed: %v", err)
+	}
+
+	keyFunc := func(alg, kid string) (interface{}, error) { return set.VerificationKey(alg, kid) }
+	audiences := []string{"https://auth.example.com", testAudience}
+
+	for name, assertion := range map[string]string{
+		"RS256": signAssertion(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", assertionClaims("client-1", "a", time.Minute)),
+		"ES256": signAssertion(t, jwt.SigningMethodES256, ecKey, "ec-1", assertionClaims("client-1", "b", time.Minute)),
+	} {
+		subject, err := ClientAssertionSubject(assertion)
+		if err != nil || subject != "client-1" {
+			t.Errorf("%s: expected subject client-1, got %q (%v)", name, subject, err)
+		}
+		claims, err := ParseClientAssertion(assertion, "client-1", audiences, AsymmetricAssertionAlgs, 5*time.Minute, keyFunc)
+		if err != nil {
+			t.Errorf("%s: expected assertion to verify, got %v", name, err)
+			continue
+		}
+		if claims.ID == "" {
+			t.Errorf("%s: expected jti to be returned", name)
+		}
+	}
+
+	// A key the client never registered must not verify
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := signAssertion(t, jwt.SigningMethodRS256, otherKey, "rsa-1", assertionClaims("client-1", "c", time.Minute))
+	if _, err := ParseClientAssertion(forged, "client-1", audiences, AsymmetricAssertionAlgs, 5*time.Minute, keyFunc); err == nil {
+		t.Error("expected assertion signed with an unregistered key to be rejected")
+	}
+}
+
+func TestParseClientAssertion_RejectsBadClaims(t *testing.T) {
+	secret := []byte("client-secret-used-as-hmac-key")
+	keyFunc := func(alg, kid string) (interface{}, error) { return secret, nil }
+	audiences := []string{testAudience}
+
+	wrongAud := assertionClaims("client-1", "1", time.Minute)
+	wrongAud.Audience = jwt.ClaimStrings{"https://other.example.com/token"}
+
+	noJTI := assertionClaims("client-1", "", time.Minute)
+
+	noExp := assertionClaims("client-1", "2", time.Minute)
+	noExp.ExpiresAt = nil
+
+	cases := map[string]string{
+		"wrong audience":   signAssertion(t, jwt.SigningMethodHS256, secret, "", wrongAud),
+		"missing jti":      signAssertion(t, jwt.SigningMethodHS256, secret, "", noJTI),
+		"missing exp":      signAssertion(t, jwt.SigningMethodHS256, secret, "", noExp),
+		"long lived":       signAssertion(t, jwt.SigningMethodHS256, secret, "", assertionClaims("client-1", "3", time.Hour)),
+		"expired":          signAssertion(t, jwt.SigningMethodHS256, secret, "", assertionClaims("client-1", "4", -time.Minute)),
+		"other client":     signAssertion(t, jwt.SigningMethodHS256, secret, "", assertionClaims("client-2", "5", time.Minute)),
+		"wrong alg family": signAssertion(t, jwt.SigningMethodHS256, secret, "", assertionClaims("client-1", "6", time.Minute)),
+	}
+
+	for name, assertion := range cases {
+		algs := SymmetricAssertionAlgs
+		if name == "wrong alg family" {
+			algs = AsymmetricAssertionAlgs
+		}
+		if _, err := ParseClientAssertion(assertion, "client-1", audiences, algs, 5*time.Minute, keyFunc); err == nil {
+			t.Errorf("%s: expected assertion to be rejected", name)
+		}
+	}
+
+	valid := signAssertion(t, jwt.SigningMethodHS256, secret, "", assertionClaims("client-1", "7", time.Minute))
+	if _, err := ParseClientAssertion(valid, "client-1", audiences, SymmetricAssertionAlgs, 5*time.Minute, keyFunc); err != nil {
+		t.Errorf("expected HS256 assertion to verify, got %v", err)
+	}
+}
+
+func TestParseJWKSet_RejectsWeakOrUnknownKeys(t *testing.T) {
+	weakKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	weak, _ := json.Marshal(JWKSet{Keys: []JWK{{
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(weakKey.N.Bytes()),
+		E:   "AQAB",
+	}}})
+
+	for name, doc := range map[string]string{
+		"empty":    `{"keys":[]}`,
+		"weak RSA": string(weak),
+		"oct key":  `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+		"bad json": `{"keys":`,
+	} {
+		if _, err := ParseJWKSet([]byte(doc)); err == nil {
+			t.Errorf("%s: expected JWK set to be rejected", name)
+		}
+	}
+}
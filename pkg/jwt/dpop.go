@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// DPoPProofType is the typ header every DPoP proof must carry (RFC 9449 section 4.2)
+const DPoPProofType = "dpop+jwt"
+
+// DPoPSigningAlgs are the proof algorithms we accept; proofs are never signed with a shared secret
+var DPoPSigningAlgs = AsymmetricAssertionAlgs
+
+// Proofs issued slightly in the future are tolerated to absorb client clock drift
+const dpopClockSkew = 30 * time.Second
+
+// Confirmation is the cnf claim of a sender-constrained token (RFC 7800)
+type Confirmation struct {
+	JKT string `json:"jkt,omitempty"` // SHA-256 thumbprint of the DPoP public key
+}
+
+// DPoPProofClaims are the claims of a DPoP proof JWT
+type DPoPProofClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"` // Hash of the access token, required at resource servers
+	jwt.RegisteredClaims
+}
+
+// DPoPProof is a verified proof together with the thumbprint of the key that signed it
+type DPoPProof struct {
+	JKT    string
+	Claims DPoPProofClaims
+}
+
+// ParseDPoPProof verifies a DPoP proof against the public key embedded in its header and
+// checks that it was made for this request (htm, htu), is fresh (iat within maxAge) and
+// carries a jti. When accessToken is set the proof must also carry its hash (ath).
+// Replay detection on jti is left to the caller.
+func ParseDPoPProof(proof, method, htu, accessToken string, maxAge time.Duration) (*DPoPProof, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods(DPoPSigningAlgs), jwt.WithoutClaimsValidation())
+
+	result := &DPoPProof{}
+	_, err := parser.ParseWithClaims(proof, &result.Claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != DPoPProofType {
+			return nil, fmt.Errorf("typ header must be %s", DPoPProofType)
+		}
+
+		rawKey, ok := token.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("proof has no jwk header")
+		}
+		if _, private := rawKey["d"]; private {
+			return nil, errors.New("jwk header must not contain a private key")
+		}
+		encoded, err := json.Marshal(rawKey)
+		if err != nil {
+			return nil, err
+		}
+		var key JWK
+		if err := json.Unmarshal(encoded, &key); err != nil {
+			return nil, fmt.Errorf("invalid jwk header: %w", err)
+		}
+
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if result.JKT, err = key.Thumbprint(); err != nil {
+			return nil, err
+		}
+		return publicKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid DPoP proof: %w", err)
+	}
+
+	claims := &result.Claims
+	if claims.ID == "" {
+		return nil, errors.New("DPoP proof has no jti claim")
+	}
+	if !strings.EqualFold(claims.HTM, method) {
+		return nil, errors.New("DPoP proof htm does not match the request method")
+	}
+	if !sameHTU(claims.HTU, htu) {
+		return nil, errors.New("DPoP proof htu does not match the request URL")
+	}
+
+	if claims.IssuedAt == nil {
+		return nil, errors.New("DPoP proof has no iat claim")
+	}
+	age := time.Since(claims.IssuedAt.Time)
+	if age > maxAge || age < -dpopClockSkew {
+		return nil, errors.New("DPoP proof is not fresh")
+	}
+
+	if accessToken != "" {
+		sum := sha256.Sum256([]byte(accessToken))
+		if claims.ATH != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return nil, errors.New("DPoP proof ath does not match the access token")
+		}
+	}
+
+	return result, nil
+}
+
+// sameHTU compares two request URLs ignoring query, fragment and scheme/host case
+// (RFC 9449 section 4.3)
+func sameHTU(claimed, expected string) bool {
+	a, err := url.Parse(claimed)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(expected)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
@@ -0,0 +1,113 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testHTU = "https://auth.example.com/api/v1/oauth/token"
+
+func TestJWKThumbprint_RFC7638Example(t *testing.T) {
+	key := JWK{
+		Kty: "RSA",
+		Kid: "2011-04-29", // Not part of the thumbprint
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	}
+	thumbprint, err := key.Thumbprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("unexpected thumbprint %s", thumbprint)
+	}
+}
+
+func signDPoPProof(t *testing.T, key *ecdsa.PrivateKey, typ string, claims DPoPProofClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = typ
+	token.Header["jwk"] = map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign proof: %v", err)
+	}
+	return signed
+}
+
+func proofClaims(method, htu, jti string, iat time.Time) DPoPProofClaims {
+	return DPoPProofClaims{
+		HTM: method,
+		HTU: htu,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       jti,
+			IssuedAt: jwt.NewNumericDate(iat),
+		},
+	}
+}
+
+func TestParseDPoPProof(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	valid := signDPoPProof(t, key, DPoPProofType, proofClaims("POST", testHTU+"?ignored=1", "a", now))
+	proof, err := ParseDPoPProof(valid, "POST", testHTU, "", time.Minute)
+	if err != nil {
+		t.Fatalf("expected proof to verify, got %v", err)
+	}
+	expected, _ := (&JWK{
+		Kty: "EC", Crv: "P-256",
+		X: base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y: base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}).Thumbprint()
+	if proof.JKT != expected {
+		t.Errorf("expected jkt %s, got %s", expected, proof.JKT)
+	}
+
+	accessToken := "header.payload.signature"
+	sum := sha256.Sum256([]byte(accessToken))
+	withATH := proofClaims("GET", "https://auth.example.com/api/v1/oauth/userinfo", "b", now)
+	withATH.ATH = base64.RawURLEncoding.EncodeToString(sum[:])
+	if _, err := ParseDPoPProof(signDPoPProof(t, key, DPoPProofType, withATH), "GET", "https://auth.example.com/api/v1/oauth/userinfo", accessToken, time.Minute); err != nil {
+		t.Errorf("expected proof with ath to verify, got %v", err)
+	}
+
+	hmacProof := jwt.NewWithClaims(jwt.SigningMethodHS256, proofClaims("POST", testHTU, "i", now))
+	hmacProof.Header["typ"] = DPoPProofType
+	symmetric, _ := hmacProof.SignedString([]byte("secret"))
+
+	cases := map[string]struct {
+		proof       string
+		accessToken string
+	}{
+		"wrong typ":      {proof: signDPoPProof(t, key, "JWT", proofClaims("POST", testHTU, "c", now))},
+		"wrong method":   {proof: signDPoPProof(t, key, DPoPProofType, proofClaims("GET", testHTU, "d", now))},
+		"wrong url":      {proof: signDPoPProof(t, key, DPoPProofType, proofClaims("POST", "https://auth.example.com/other", "e", now))},
+		"missing jti":    {proof: signDPoPProof(t, key, DPoPProofType, proofClaims("POST", testHTU, "", now))},
+		"stale":          {proof: signDPoPProof(t, key, DPoPProofType, proofClaims("POST", testHTU, "f", now.Add(-2*time.Minute)))},
+		"future":         {proof: signDPoPProof(t, key, DPoPProofType, proofClaims("POST", testHTU, "g", now.Add(5*time.Minute)))},
+		"missing ath":    {proof: signDPoPProof(t, key, DPoPProofType, proofClaims("POST", testHTU, "h", now)), accessToken: accessToken},
+		"not a JWT":      {proof: "not-a-proof"},
+		"symmetric sign": {proof: symmetric},
+	}
+	for name, tc := range cases {
+		if _, err := ParseDPoPProof(tc.proof, "POST", testHTU, tc.accessToken, time.Minute); err == nil {
+			t.Errorf("%s: expected proof to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+func TestGenerateIDToken_Claims(t *testing.T) {
+	svc, err := NewService(&config.JWTConfig{Issuer: "test", IssuerURL: "https://auth.example.com", RefreshTokenTTL: 1})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	authTime := time.Now().Add(-time.Minute)
+	idToken, err := svc.GenerateIDToken(&IDTokenContext{
+		Subject:     "user-1",
+		Audience:    "client-1",
+		Nonce:       "n-0S6_WzA2Mj",
+		AuthTime:    &authTime,
+		AMR:         []string{"pwd"},
+		AccessToken: "access-token",
+	})
+	if err != nil {
+		t.Fatalf("GenerateIDToken failed: %v", err)
+	}
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return svc.KeyStore().PublicKey(kid)
+	})
+	if err != nil {
+		t.Fatalf("failed to parse id_token: %v", err)
+	}
+
+	if claims.Issuer != "https://auth.example.com" {
+		t.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if claims.Nonce != "n-0S6_WzA2Mj" {
+		t.Errorf("nonce not echoed, got %q", claims.Nonce)
+	}
+	if claims.AuthTime != authTime.Unix() {
+		t.Errorf("auth_time = %d, want %d", claims.AuthTime, authTime.Unix())
+	}
+	if claims.AtHash != AccessTokenHash("access-token") {
+		t.Errorf("unexpected at_hash %q", claims.AtHash)
+	}
+	if claims.ACR != ACRSingleFactor {
+		t.Errorf("acr = %q, want %q", claims.ACR, ACRSingleFactor)
+	}
+}
+
+func TestACRForAMR(t *testing.T) {
+	tests := []struct {
+		amr  []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"pwd"}, ACRSingleFactor},
+		{[]string{"pwd", "otp"}, ACRMultiFactor},
+		{[]string{"mfa"}, ACRMultiFactor},
+	}
+
+	for _, tt := range tests {
+		if got := ACRForAMR(tt.amr); got != tt.want {
+			t.Errorf("ACRForAMR(%v) = %q, want %q", tt.amr, got, tt.want)
+		}
+	}
+}
+
+func TestACRSatisfies(t *testing.T) {
+	tests := []struct {
+		have, want string
+		ok         bool
+	}{
+		{"", "", true},
+		{"", ACRSingleFactor, false},
+		{ACRSingleFactor, ACRSingleFactor, true},
+		{ACRMultiFactor, ACRSingleFactor, true},
+		{ACRSingleFactor, ACRMultiFactor, false},
+		{ACRMultiFactor, "urn:unknown", false},
+	}
+
+	for _, tt := range tests {
+		if got := ACRSatisfies(tt.have, tt.want); got != tt.ok {
+			t.Errorf("ACRSatisfies(%q, %q) = %v, want %v", tt.have, tt.want, got, tt.ok)
+		}
+	}
+}
+
+func TestAccessToken_CarriesAuthentication(t *testing.T) {
+	svc, err := NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 5, RefreshTokenTTL: 1})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	authTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	tokenCtx := &TokenContext{UserID: uuid.New(), AMR: []string{AMRPassword, AMROTP}, AuthTime: authTime}
+
+	refresh, _, err := svc.GenerateRefreshToken(tokenCtx)
+	if err != nil {
+		t.Fatalf("GenerateRefreshToken failed: %v", err)
+	}
+	access, err := svc.RefreshAccessToken(refresh)
+	if err != nil {
+		t.Fatalf("RefreshAccessToken failed: %v", err)
+	}
+
+	claims, err := svc.ParseAccessToken(access)
+	if err != nil {
+		t.Fatalf("ParseAccessToken failed: %v", err)
+	}
+	if claims.ACR != ACRMultiFactor {
+		t.Errorf("acr = %q, want %q", claims.ACR, ACRMultiFactor)
+	}
+	if !claims.AuthenticatedAt().Equal(authTime) {
+		t.Errorf("auth_time = %v, want %v (refresh must not reset it)", claims.AuthenticatedAt(), authTime)
+	}
+
+	bare, err := svc.GenerateAccessToken(&TokenContext{UserID: uuid.New()})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken failed: %v", err)
+	}
+	claims, err = svc.ParseAccessToken(bare)
+	if err != nil {
+		t.Fatalf("ParseAccessToken failed: %v", err)
+	}
+	if claims.AuthTime != nil || claims.ACR != "" {
+		t.Errorf("token without an authentication event got auth_time=%v acr=%q", claims.AuthTime, claims.ACR)
+	}
+}
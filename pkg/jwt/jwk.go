@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ErrNoMatchingKey is returned when a JWK set has no key usable for a token
+var ErrNoMatchingKey = errors.New("no matching key in JWK set")
+
+// ParseJWKSet decodes a JWK set document and checks that every key is usable
+func ParseJWKSet(data []byte) (*JWKSet, error) {
+	var set JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWK set: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("JWK set has no keys")
+	}
+	for i := range set.Keys {
+		if _, err := set.Keys[i].PublicKey(); err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+	}
+	return &set, nil
+}
+
+// PublicKey converts the JWK into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k *JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		if n.BitLen() < rsaKeyBits {
+			return nil, fmt.Errorf("RSA keys must be at least %d bits", rsaKeyBits)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// VerificationKey picks the key for a token signed with alg and header kid.
+// Without a kid the set must contain exactly one key of the matching type.
+func (s *JWKSet) VerificationKey(alg, kid string) (crypto.PublicKey, error) {
+	kty := "RSA"
+	if len(alg) > 2 && alg[:2] == "ES" {
+		kty = "EC"
+	}
+
+	var match *JWK
+	for i := range s.Keys {
+		key := &s.Keys[i]
+		if key.Kty != kty || (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != alg) {
+			continue
+		}
+		if kid != "" {
+			if key.Kid == kid {
+				match = key
+				break
+			}
+			continue
+		}
+		if match != nil {
+			return nil, errors.New("JWK set has several candidate keys; token must carry a kid")
+		}
+		match = key
+	}
+
+	if match == nil {
+		return nil, ErrNoMatchingKey
+	}
+	return match.PublicKey()
+}
+
+func decodeJWKInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, errors.New("missing value")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of the key, base64url encoded.
+// Only the required members take part, in lexicographic order, so it is stable
+// regardless of kid, use or alg.
+func (k *JWK) Thumbprint() (string, error) {
+	var canonical string
+	switch k.Kty {
+	case "RSA":
+		if k.E == "" || k.N == "" {
+			return "", errors.New("RSA key is missing e or n")
+		}
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "EC":
+		if k.Crv == "" || k.X == "" || k.Y == "" {
+			return "", errors.New("EC key is missing crv, x or y")
+		}
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Crv, k.X, k.Y)
+	default:
+		return "", fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
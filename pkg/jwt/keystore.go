@@ -0,0 +1,449 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	rsaKeyBits   = 2048
+	pemKeySuffix = ".pem"
+
+	// rotateLockFile is created exclusively by the replica that writes the next key
+	rotateLockFile = "rotate.lock"
+	// staleLockAge is how long a lock may be held before a crashed holder is assumed
+	staleLockAge = time.Minute
+	// minReloadInterval bounds how often unknown kids make us re-read the key directory
+	minReloadInterval = 5 * time.Second
+)
+
+// ErrUnknownKeyID is returned when a token references a kid that is not in the key store
+var ErrUnknownKeyID = errors.New("unknown signing key id")
+
+// signingKey is a single RSA key pair tracked by the key store
+type signingKey struct {
+	kid        string
+	privateKey *rsa.PrivateKey
+	createdAt  time.Time
+}
+
+// KeyStore holds the RSA signing keys. The newest key signs new tokens; older keys
+// stay available for verification until the overlap window after they were superseded
+// has elapsed, so tokens signed right before a rotation remain valid.
+//
+// Keys are persisted as PKCS#1 PEM files named <kid>.pem in the configured directory,
+// which lets restarts and replicas sharing the directory verify each other's tokens.
+// A replica that sees an unknown kid re-reads the directory, and a lock file keeps
+// replicas from rotating at the same time.
+// When no directory is configured the store is purely in-memory (tests, local dev).
+type KeyStore struct {
+	mu               sync.RWMutex
+	dir              string
+	rotationInterval time.Duration
+	overlap          time.Duration
+	keys             []*signingKey // sorted by createdAt ascending; last is active
+
+	reloadMu   sync.Mutex
+	lastReload time.Time
+}
+
+// NewKeyStore loads keys from dir (creating the directory and a first key if needed)
+func NewKeyStore(dir string, rotationInterval, overlap time.Duration) (*KeyStore, error) {
+	ks := &KeyStore{
+		dir:              dir,
+		rotationInterval: rotationInterval,
+		overlap:          overlap,
+	}
+
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create key directory: %w", err)
+		}
+		if err := ks.Reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ks.rotateIfDue(time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Another replica holds the rotation lock; wait for the key it is writing
+	for deadline := time.Now().Add(staleLockAge); ks.active() == nil; {
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for another replica to write a signing key")
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := ks.Reload(); err != nil {
+			return nil, err
+		}
+		if err := ks.rotateIfDue(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return ks, nil
+}
+
+// Reload re-reads the key directory, picking up keys written by other replicas
+func (ks *KeyStore) Reload() error {
+	if ks.dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read key directory: %w", err)
+	}
+
+	var keys []*signingKey
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), pemKeySuffix) {
+			continue
+		}
+
+		path := filepath.Join(ks.dir, entry.Name())
+		key, err := loadPEMKey(path)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].createdAt.Before(keys[j].createdAt) })
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+
+	ks.reloadMu.Lock()
+	ks.lastReload = time.Now()
+	ks.reloadMu.Unlock()
+
+	return nil
+}
+
+// reloadForUnknownKey re-reads the key directory when a token names a kid we do not
+// know, which happens when another replica rotated since our last tick. Forged kids
+// must not make every request hit the disk, so this runs at most every minReloadInterval.
+func (ks *KeyStore) reloadForUnknownKey() bool {
+	if ks.dir == "" {
+		return false
+	}
+
+	ks.reloadMu.Lock()
+	if time.Since(ks.lastReload) < minReloadInterval {
+		ks.reloadMu.Unlock()
+		return false
+	}
+	ks.lastReload = time.Now()
+	ks.reloadMu.Unlock()
+
+	return ks.Reload() == nil
+}
+
+// Rotate generates a new active key and persists it
+func (ks *KeyStore) Rotate() (string, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate RSA key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	kid, err := newKeyID(now)
+	if err != nil {
+		return "", err
+	}
+
+	key := &signingKey{kid: kid, privateKey: privateKey, createdAt: now}
+
+	if ks.dir != "" {
+		if err := writePEMKey(filepath.Join(ks.dir, kid+pemKeySuffix), key); err != nil {
+			return "", err
+		}
+	}
+
+	ks.mu.Lock()
+	ks.keys = append(ks.keys, key)
+	ks.mu.Unlock()
+
+	return kid, nil
+}
+
+// rotateIfDue writes a new key when none exists or the active one is due. Replicas
+// sharing the directory take an exclusive lock file first, and re-read the directory
+// under it, so only one of them writes the next key. When another replica holds the
+// lock this returns without rotating; its key is picked up by the next Reload.
+func (ks *KeyStore) rotateIfDue(now time.Time) error {
+	if ks.active() != nil && !ks.rotationDue(now) {
+		return nil
+	}
+	if ks.dir == "" {
+		_, err := ks.Rotate()
+		return err
+	}
+
+	unlock, err := ks.lockRotation()
+	if err != nil || unlock == nil {
+		return err
+	}
+	defer unlock()
+
+	if err := ks.Reload(); err != nil {
+		return err
+	}
+	if ks.active() != nil && !ks.rotationDue(now) {
+		return nil
+	}
+	_, err = ks.Rotate()
+	return err
+}
+
+// lockRotation creates the rotation lock file. It returns a nil unlock func when
+// another replica holds the lock, and breaks locks left behind by crashed replicas.
+func (ks *KeyStore) lockRotation() (func(), error) {
+	path := filepath.Join(ks.dir, rotateLockFile)
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create rotation lock: %w", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to stat rotation lock: %w", err)
+		}
+		if time.Since(info.ModTime()) < staleLockAge {
+			return nil, nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale rotation lock: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// Prune drops keys whose overlap window has elapsed and removes their files
+func (ks *KeyStore) Prune(now time.Time) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	var kept []*signingKey
+	var expired []*signingKey
+	for i, key := range ks.keys {
+		// The active key is never pruned; others expire once their successor is older than the overlap
+		if i < len(ks.keys)-1 && now.Sub(ks.keys[i+1].createdAt) > ks.overlap {
+			expired = append(expired, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	ks.keys = kept
+
+	if ks.dir == "" {
+		return nil
+	}
+	for _, key := range expired {
+		path := filepath.Join(ks.dir, key.kid+pemKeySuffix)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove expired key %s: %w", key.kid, err)
+		}
+	}
+	return nil
+}
+
+// StartRotation checks every interval whether the active key is due for rotation and prunes
+// expired keys. It blocks until ctx is cancelled, so run it in a goroutine.
+func (ks *KeyStore) StartRotation(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.tick(time.Now()); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func (ks *KeyStore) tick(now time.Time) error {
+	// Another replica may have rotated already; only rotate if the newest key on disk is stale
+	if err := ks.Reload(); err != nil {
+		return err
+	}
+	if err := ks.rotateIfDue(now); err != nil {
+		return err
+	}
+	return ks.Prune(now)
+}
+
+func (ks *KeyStore) rotationDue(now time.Time) bool {
+	active := ks.active()
+	return ks.rotationInterval > 0 && active != nil && now.Sub(active.createdAt) >= ks.rotationInterval
+}
+
+// active returns the key used for signing new tokens
+func (ks *KeyStore) active() *signingKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if len(ks.keys) == 0 {
+		return nil
+	}
+	return ks.keys[len(ks.keys)-1]
+}
+
+// ActiveKeyID returns the kid of the current signing key
+func (ks *KeyStore) ActiveKeyID() string {
+	if key := ks.active(); key != nil {
+		return key.kid
+	}
+	return ""
+}
+
+// PublicKey returns the verification key for kid, re-reading the key directory
+// once if kid is not known yet
+func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+	if ks.reloadForUnknownKey() {
+		if key := ks.lookup(kid); key != nil {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+func (ks *KeyStore) lookup(kid string) *rsa.PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	for _, key := range ks.keys {
+		if key.kid == kid {
+			return &key.privateKey.PublicKey
+		}
+	}
+	return nil
+}
+
+// JWK is a single public key in JSON Web Key format (RFC 7517).
+// Our own keys are RSA; client keys may also be EC (crv, x, y).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every key still valid for verification, newest first
+func (ks *KeyStore) JWKS() *JWKSet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := &JWKSet{Keys: make([]JWK, 0, len(ks.keys))}
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		pub := ks.keys[i].privateKey.PublicKey
+		set.Keys = append(set.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: ks.keys[i].kid,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+// newKeyID builds a sortable, collision-resistant kid such as 20240102T150405Z-1a2b3c4d
+func newKeyID(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate key id: %w", err)
+	}
+	return now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix), nil
+}
+
+func loadPEMKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, fmt.Errorf("invalid PEM key %s", path)
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), pemKeySuffix)
+
+	// Prefer the timestamp embedded in the kid; fall back to mtime for hand-placed keys
+	createdAt, err := time.Parse("20060102T150405Z", strings.SplitN(kid, "-", 2)[0])
+	if err != nil {
+		info, statErr := os.Stat(path)
+		if statErr != nil {
+			return nil, fmt.Errorf("failed to stat key %s: %w", path, statErr)
+		}
+		createdAt = info.ModTime().UTC()
+	}
+
+	return &signingKey{kid: kid, privateKey: privateKey, createdAt: createdAt}, nil
+}
+
+func writePEMKey(path string, key *signingKey) error {
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.privateKey),
+	})
+
+	// Write to a temp file and rename so other replicas never read a partial key
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write key %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to persist key %s: %w", path, err)
+	}
+	return nil
+}
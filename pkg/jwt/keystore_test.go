@@ -0,0 +1,153 @@
+package jwt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/google/uuid"
+)
+
+func TestKeyStore_PersistsKeysAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := NewKeyStore(dir, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+
+	second, err := NewKeyStore(dir, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyStore (reload) failed: %v", err)
+	}
+
+	if first.ActiveKeyID() != second.ActiveKeyID() {
+		t.Errorf("expected reloaded store to reuse kid %s, got %s", first.ActiveKeyID(), second.ActiveKeyID())
+	}
+}
+
+func TestKeyStore_RotationKeepsOldKeyDuringOverlap(t *testing.T) {
+	ks, err := NewKeyStore(t.TempDir(), 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+	oldKid := ks.ActiveKeyID()
+
+	newKid, err := ks.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	if err := ks.Prune(time.Now()); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if _, err := ks.PublicKey(oldKid); err != nil {
+		t.Errorf("old key should remain within overlap window: %v", err)
+	}
+	if got := len(ks.JWKS().Keys); got != 2 {
+		t.Errorf("expected 2 keys in JWKS, got %d", got)
+	}
+
+	if err := ks.Prune(time.Now().Add(2 * time.Hour)); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if _, err := ks.PublicKey(oldKid); err != ErrUnknownKeyID {
+		t.Errorf("old key should be pruned after overlap, got %v", err)
+	}
+	if ks.ActiveKeyID() != newKid {
+		t.Errorf("active key changed unexpectedly")
+	}
+}
+
+func TestService_ValidateTokenSelectsKeyByKid(t *testing.T) {
+	svc, err := NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 5, RefreshTokenTTL: 1})
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	before, err := svc.GenerateAccessToken(&TokenContext{UserID: uuid.New()})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken failed: %v", err)
+	}
+
+	if _, err := svc.KeyStore().Rotate(); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	after, err := svc.GenerateAccessToken(&TokenContext{UserID: uuid.New()})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken failed: %v", err)
+	}
+
+	for name, token := range map[string]string{"before rotation": before, "after rotation": after} {
+		if _, err := svc.ParseAccessToken(token); err != nil {
+			t.Errorf("%s: token should validate: %v", name, err)
+		}
+	}
+}
+
+func TestKeyStore_PublicKeyPicksUpKeysFromOtherReplicas(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewKeyStore(dir, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+	other, err := NewKeyStore(dir, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+
+	newKid, err := other.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+
+	// The directory was read just now, so the unknown kid does not trigger another read yet
+	if _, err := local.PublicKey(newKid); err != ErrUnknownKeyID {
+		t.Fatalf("expected the reload to be rate limited, got %v", err)
+	}
+
+	local.lastReload = time.Now().Add(-minReloadInterval)
+	if _, err := local.PublicKey(newKid); err != nil {
+		t.Errorf("key written by another replica should be found after a reload: %v", err)
+	}
+}
+
+func TestKeyStore_RotationIsExclusive(t *testing.T) {
+	dir := t.TempDir()
+	ks, err := NewKeyStore(dir, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("NewKeyStore failed: %v", err)
+	}
+	kid := ks.ActiveKeyID()
+	due := time.Now().Add(25 * time.Hour)
+
+	lock := filepath.Join(dir, rotateLockFile)
+	if err := os.WriteFile(lock, nil, 0o600); err != nil {
+		t.Fatalf("failed to create lock: %v", err)
+	}
+	if err := ks.tick(due); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if ks.ActiveKeyID() != kid {
+		t.Errorf("rotated while another replica held the lock")
+	}
+
+	// A lock left behind by a crashed replica is broken
+	stale := time.Now().Add(-2 * staleLockAge)
+	if err := os.Chtimes(lock, stale, stale); err != nil {
+		t.Fatalf("failed to age lock: %v", err)
+	}
+	if err := ks.tick(due); err != nil {
+		t.Fatalf("tick failed: %v", err)
+	}
+	if ks.ActiveKeyID() == kid {
+		t.Errorf("expected a rotation once the lock went stale")
+	}
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Errorf("rotation lock should be released, got %v", err)
+	}
+}
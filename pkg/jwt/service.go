@@ -1,8 +1,6 @@
 package jwt
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
 	"errors"
 	"fmt"
 	"strings"
@@ -19,9 +17,12 @@ type JWTService interface {
 	GenerateAccessToken(ctx *TokenContext) (string, error)
 	GenerateRefreshToken(ctx *TokenContext) (string, string, error)
 	GenerateOAuthAccessToken(ctx *OAuthTokenContext) (string, error)
+	GenerateIDToken(ctx *IDTokenContext) (string, error)
 	ValidateToken(tokenString string) (*Claims, error)
 	ParseAccessToken(tokenString string) (*Claims, error)
 	ParseRefreshToken(tokenString string) (*Claims, error)
+	JWKS() *JWKSet
+	IssuerURL() string
 }
 
 // TokenContext carries metadata for org-scoped token generation (Slack style)
@@ -35,6 +36,10 @@ type TokenContext struct {
 	OrganizationRole string
 	Permissions      []string // List of permission names for this user in this org
 	IsSuperadmin     bool
+
+	// How and when the user last authenticated; carried unchanged across refreshes
+	AMR      []string
+	AuthTime time.Time
 }
 
 // OAuthTokenContext carries metadata for OAuth2 token generation
@@ -44,9 +49,15 @@ type OAuthTokenContext struct {
 	OrganizationID *uuid.UUID // Optional
 	Roles          []string   // User's role names in the organization
 	Permissions    []string   // Scopes/permissions
+	Scopes         []string   // Granted OpenID Connect scopes (openid, profile, email)
 	Issuer         string     // https://auth.myservice.com/{client_id}
 	Audience       string     // client_id
-	Subject        string     // user_id
+	Subject        string     // user_id (client_id for client_credentials)
+	ClientID       string     // OAuth2 client the token was issued to
+	DPoPJKT        string     // Thumbprint of the client's DPoP key; binds the token to it
+	Act            *Actor     // Delegation chain of an exchanged token
+	MayAct         *Actor     // Party allowed to exchange this token on the subject's behalf
+	ExpiresAt      time.Time  // Optional; defaults to one hour from now
 	IsSuperadmin   bool
 }
 
@@ -64,31 +75,85 @@ type Claims struct {
 	Scope            string     `json:"scope,omitempty"`       // OAuth2 scopes (space-separated)
 	IsSuperadmin     bool       `json:"is_superadmin"`
 	TokenType        string     `json:"token_type"`
-	Org              *uuid.UUID `json:"org,omitempty"` // OAuth2 org claim
+	Org              *uuid.UUID `json:"org,omitempty"`       // OAuth2 org claim
+	ClientID         string     `json:"client_id,omitempty"` // OAuth2 client (RFC 9068)
+
+	// Cnf names the key a sender-constrained token is bound to (DPoP, RFC 9449)
+	Cnf *Confirmation `json:"cnf,omitempty"`
+
+	// Act and MayAct carry delegation for token exchange (RFC 8693)
+	Act    *Actor `json:"act,omitempty"`
+	MayAct *Actor `json:"may_act,omitempty"`
+
+	// Authentication strength and age, for step-up checks (OIDC Core section 2)
+	AMR      []string         `json:"amr,omitempty"`
+	ACR      string           `json:"acr,omitempty"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+
 	jwt.RegisteredClaims
 }
 
-// Service handles JWT operations using RSA keys
+// setAuthentication copies the authentication event from ctxInput into the claims
+func (c *Claims) setAuthentication(ctxInput *TokenContext) {
+	if len(ctxInput.AMR) > 0 {
+		c.AMR = ctxInput.AMR
+		c.ACR = ACRForAMR(ctxInput.AMR)
+	}
+	if !ctxInput.AuthTime.IsZero() {
+		c.AuthTime = jwt.NewNumericDate(ctxInput.AuthTime)
+	}
+}
+
+// Service handles JWT operations using RSA keys from a rotating key store
 type Service struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
-	config     *config.JWTConfig
+	keys   *KeyStore
+	config *config.JWTConfig
 }
 
-// NewService creates a new JWT service (dev uses runtime RSA key)
+// NewService creates a new JWT service. Keys are loaded from cfg.KeysDir when set;
+// otherwise an in-memory key is generated (tokens do not survive a restart).
 func NewService(cfg *config.JWTConfig) (*Service, error) {
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	rotation := time.Duration(cfg.KeyRotationDays) * 24 * time.Hour
+
+	// Retired keys must outlive every token they signed, including refresh tokens
+	overlap := time.Duration(cfg.KeyOverlapDays) * 24 * time.Hour
+	if refreshTTL := time.Duration(cfg.RefreshTokenTTL) * 24 * time.Hour; overlap < refreshTTL {
+		overlap = refreshTTL
+	}
+
+	keys, err := NewKeyStore(cfg.KeysDir, rotation, overlap)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		return nil, fmt.Errorf("failed to initialize signing keys: %w", err)
 	}
 
 	return &Service{
-		privateKey: privateKey,
-		publicKey:  &privateKey.PublicKey,
-		config:     cfg,
+		keys:   keys,
+		config: cfg,
 	}, nil
 }
 
+// KeyStore exposes the signing key store (for rotation scheduling)
+func (s *Service) KeyStore() *KeyStore {
+	return s.keys
+}
+
+// JWKS returns the public signing keys for /.well-known/jwks.json
+func (s *Service) JWKS() *JWKSet {
+	return s.keys.JWKS()
+}
+
+// sign signs claims with the active key and stamps its kid in the header
+func (s *Service) sign(claims jwt.Claims) (string, error) {
+	key := s.keys.active()
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.privateKey)
+}
+
 // GenerateAccessToken creates a short-lived org-scoped access token
 func (s *Service) GenerateAccessToken(ctxInput *TokenContext) (string, error) {
 	if ctxInput == nil {
@@ -119,9 +184,9 @@ func (s *Service) GenerateAccessToken(ctxInput *TokenContext) (string, error) {
 			ID:        uuid.New().String(),
 		},
 	}
+	claims.setAuthentication(ctxInput)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(s.privateKey)
+	return s.sign(claims)
 }
 
 // GenerateOAuthAccessToken creates an OAuth2-compliant access token with iss, aud, scope, roles, permissions
@@ -132,16 +197,22 @@ func (s *Service) GenerateOAuthAccessToken(ctxInput *OAuthTokenContext) (string,
 
 	now := time.Now()
 	exp := now.Add(1 * time.Hour) // OAuth tokens typically 1 hour
+	if !ctxInput.ExpiresAt.IsZero() {
+		exp = ctxInput.ExpiresAt
+	}
+
+	scopes := append(append([]string{}, ctxInput.Permissions...), ctxInput.Scopes...)
 
 	claims := &Claims{
 		UserID:       ctxInput.UserID,
 		Email:        ctxInput.Email,
 		IsSuperadmin: ctxInput.IsSuperadmin,
 		TokenType:    "access",
-		Roles:        ctxInput.Roles,                          // RBAC role names
-		Permissions:  ctxInput.Permissions,                    // RBAC permission names
-		Scope:        strings.Join(ctxInput.Permissions, " "), // OAuth2 scope (space-separated permissions)
+		Roles:        ctxInput.Roles,            // RBAC role names
+		Permissions:  ctxInput.Permissions,      // RBAC permission names
+		Scope:        strings.Join(scopes, " "), // OAuth2 scope (space-separated permissions + OIDC scopes)
 		Org:          ctxInput.OrganizationID,
+		ClientID:     ctxInput.ClientID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    ctxInput.Issuer,                     // https://auth.myservice.com/{client_id}
 			Subject:   ctxInput.Subject,                    // user_id
@@ -153,8 +224,13 @@ func (s *Service) GenerateOAuthAccessToken(ctxInput *OAuthTokenContext) (string,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(s.privateKey)
+	if ctxInput.DPoPJKT != "" {
+		claims.Cnf = &Confirmation{JKT: ctxInput.DPoPJKT}
+	}
+	claims.Act = ctxInput.Act
+	claims.MayAct = ctxInput.MayAct
+
+	return s.sign(claims)
 }
 
 // GenerateRefreshToken creates a long-lived refresh token bound to session + org
@@ -188,9 +264,9 @@ func (s *Service) GenerateRefreshToken(ctxInput *TokenContext) (string, string,
 			ID:        refreshID,
 		},
 	}
+	claims.setAuthentication(ctxInput)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	tokenString, err := token.SignedString(s.privateKey)
+	tokenString, err := s.sign(claims)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -204,7 +280,11 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.publicKey, nil
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid header")
+		}
+		return s.keys.PublicKey(kid)
 	})
 
 	if err != nil {
@@ -263,9 +343,19 @@ func (s *Service) RefreshAccessToken(refreshTokenString string) (string, error)
 		OrganizationRole: c.OrganizationRole,
 		Permissions:      c.Permissions,
 		IsSuperadmin:     c.IsSuperadmin,
+		AMR:              c.AMR,
+		AuthTime:         c.AuthenticatedAt(),
 	})
 }
 
+// AuthenticatedAt returns auth_time, or the zero time for tokens issued without one
+func (c *Claims) AuthenticatedAt() time.Time {
+	if c.AuthTime == nil {
+		return time.Time{}
+	}
+	return c.AuthTime.Time
+}
+
 // Helpers
 func (s *Service) ExtractUserID(tokenString string) (uuid.UUID, error) {
 	c, err := s.ValidateToken(tokenString)
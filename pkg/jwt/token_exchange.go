@@ -0,0 +1,40 @@
+package jwt
+
+// Token type identifiers used by the token exchange grant (RFC 8693 section 3)
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// Actor identifies a party in the act and may_act claims (RFC 8693 sections 4.1 and 4.4).
+// In act, a nested Act is the party that acted before this one, so the outermost
+// actor is the current one and the chain reads most recent first.
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+	Act      *Actor `json:"act,omitempty"`
+}
+
+// Depth returns the number of actors in the chain starting at a (0 for nil)
+func (a *Actor) Depth() int {
+	depth := 0
+	for ; a != nil; a = a.Act {
+		depth++
+	}
+	return depth
+}
+
+// Permits reports whether the party identified by subject and clientID matches this
+// may_act entry. Members left empty in the entry match anything.
+func (a *Actor) Permits(subject, clientID string) bool {
+	if a == nil {
+		return false
+	}
+	if a.Subject != "" && a.Subject != subject {
+		return false
+	}
+	if a.ClientID != "" && a.ClientID != clientID {
+		return false
+	}
+	return true
+}
@@ -266,7 +266,7 @@ func NewSLIMetrics() *SLIMetrics {
 				Name: "auth_suspicious_activity_total",
 				Help: "Total suspicious activity events by type",
 			},
-			[]string{"activity_type"}, // activity_type: multiple_failed_logins|unusual_location|brute_force
+			[]string{"activity_type"}, // activity_type: multiple_failed_logins|unusual_location|brute_force, or a login risk reason (impossible_travel|new_country|new_asn|new_device|failed_attempt_burst)
 		),
 		FailedLoginAttempts: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -0,0 +1,236 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPasswordBreached is returned when a password appears in a known breach corpus
+var ErrPasswordBreached = errors.New("password has appeared in a data breach; choose a different one")
+
+// BreachChecker reports whether a password is known to have been leaked. Implementations
+// work offline so plaintext passwords and their hashes never leave the host.
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// OpenBreachChecker loads the corpus at path: a directory is read as a hash-prefix
+// dataset, a regular file as a bloom filter written by BloomFilter.WriteTo.
+func OpenBreachChecker(path string) (BreachChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password corpus: %w", err)
+	}
+	if info.IsDir() {
+		return NewHashPrefixChecker(path), nil
+	}
+	return LoadBloomFilter(path)
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// HashPrefixChecker looks passwords up in a k-anonymity range dataset laid out like the
+// Pwned Passwords API: one file per five-character SHA-1 prefix (e.g. "5BAA6.txt"), each
+// line holding the remaining 35 hex characters and a count as "SUFFIX:COUNT".
+type HashPrefixChecker struct {
+	dir string
+}
+
+// NewHashPrefixChecker creates a checker over the range files in dir
+func NewHashPrefixChecker(dir string) *HashPrefixChecker {
+	return &HashPrefixChecker{dir: dir}
+}
+
+// IsBreached reads only the range file for the password's prefix. A missing range file
+// means no leaked password shares that prefix.
+func (c *HashPrefixChecker) IsBreached(password string) (bool, error) {
+	digest := sha1Hex(password)
+	prefix, suffix := digest[:5], digest[5:]
+
+	f, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open range file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		// Padding entries in range responses carry a zero count
+		return strings.TrimSpace(count) != "0", nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read range file: %w", err)
+	}
+	return false, nil
+}
+
+var bloomMagic = [4]byte{'P', 'W', 'B', 'F'}
+
+// Limits on filter headers, so a corrupt file cannot make us allocate unbounded memory.
+// A filter over every password in the HIBP corpus at 0.1% false positives needs ~1.7 GiB.
+const (
+	bloomHeaderSize      = 16
+	maxBloomFilterBits   = 1 << 35 // 4 GiB of bits
+	maxBloomFilterHashes = 64
+)
+
+// BloomFilter is a compact probabilistic set of SHA-1 password digests. It never misses a
+// leaked password but may reject a small fraction of clean ones, at the rate it was sized for.
+type BloomFilter struct {
+	bits   []byte
+	m      uint64 // number of bits
+	hashes uint32 // number of probes per digest
+}
+
+// NewBloomFilter sizes an empty filter for n digests at the given false-positive rate
+func NewBloomFilter(n int, falsePositiveRate float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{bits: make([]byte, (m+7)/8), m: m, hashes: k}
+}
+
+// Add inserts a plaintext password
+func (b *BloomFilter) Add(password string) {
+	sum := sha1.Sum([]byte(password))
+	b.add(sum[:])
+}
+
+// AddHash inserts a hex-encoded SHA-1 digest, as found in published breach corpora
+func (b *BloomFilter) AddHash(digestHex string) error {
+	digest, err := hex.DecodeString(strings.TrimSpace(digestHex))
+	if err != nil || len(digest) != sha1.Size {
+		return fmt.Errorf("invalid SHA-1 digest %q", digestHex)
+	}
+	b.add(digest)
+	return nil
+}
+
+// IsBreached reports whether the password may be in the corpus
+func (b *BloomFilter) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	h1, h2 := bloomHashes(sum[:])
+	for i := uint64(0); i < uint64(b.hashes); i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (b *BloomFilter) add(digest []byte) {
+	h1, h2 := bloomHashes(digest)
+	for i := uint64(0); i < uint64(b.hashes); i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// bloomHashes derives the double-hashing pair from the digest itself; SHA-1 output is
+// already uniform, so no further hashing is needed.
+func bloomHashes(digest []byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
+
+// WriteTo serializes the filter: magic, probe count, bit count, then the bit array
+func (b *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 16)
+	copy(header, bloomMagic[:])
+	binary.BigEndian.PutUint32(header[4:8], b.hashes)
+	binary.BigEndian.PutUint64(header[8:16], b.m)
+
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(b.bits)
+	return int64(n + m), err
+}
+
+// ReadBloomFilter parses a filter written by WriteTo
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	return readBloomFilter(r, -1)
+}
+
+// readBloomFilter parses a filter from r. size is the length of the whole input, or -1
+// when unknown; the header must then agree with it before anything is allocated.
+func readBloomFilter(r io.Reader, size int64) (*BloomFilter, error) {
+	header := make([]byte, bloomHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter header: %w", err)
+	}
+	if !bytes.Equal(header[:4], bloomMagic[:]) {
+		return nil, errors.New("not a breached password bloom filter")
+	}
+	hashes := binary.BigEndian.Uint32(header[4:8])
+	m := binary.BigEndian.Uint64(header[8:16])
+	if hashes == 0 || m == 0 || hashes > maxBloomFilterHashes || m > maxBloomFilterBits {
+		return nil, errors.New("bloom filter header is corrupt")
+	}
+
+	length := int64((m + 7) / 8)
+	if size >= 0 && size != bloomHeaderSize+length {
+		return nil, fmt.Errorf("bloom filter header expects %d bytes of bits but the file has %d", length, size-bloomHeaderSize)
+	}
+
+	var bits []byte
+	if size >= 0 {
+		bits = make([]byte, length)
+		if _, err := io.ReadFull(r, bits); err != nil {
+			return nil, fmt.Errorf("failed to read bloom filter: %w", err)
+		}
+	} else {
+		// Without a known size, grow only as data arrives so a short input fails cheaply
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, r, length); err != nil {
+			return nil, fmt.Errorf("failed to read bloom filter: %w", err)
+		}
+		bits = buf.Bytes()
+	}
+	return &BloomFilter{bits: bits, m: m, hashes: hashes}, nil
+}
+
+// LoadBloomFilter reads a filter from a file
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bloom filter: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat bloom filter: %w", err)
+	}
+	return readBloomFilter(bufio.NewReader(f), info.Size())
+}
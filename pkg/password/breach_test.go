@@ -0,0 +1,149 @@
+package password_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"auth-service/pkg/password"
+)
+
+func sha1Upper(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestHashPrefixChecker(t *testing.T) {
+	dir := t.TempDir()
+	leaked := sha1Upper("Password123!")
+	padding := sha1Upper("Padding123!")
+
+	// The padding entry shares the leaked prefix file but carries a zero count
+	rangeFile := strings.ToLower(leaked[5:]) + ":42\r\n" + padding[5:] + ":0\r\n"
+	if err := os.WriteFile(filepath.Join(dir, leaked[:5]+".txt"), []byte(rangeFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if padding[:5] != leaked[:5] {
+		if err := os.WriteFile(filepath.Join(dir, padding[:5]+".txt"), []byte(padding[5:]+":0\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checker := password.NewHashPrefixChecker(dir)
+	for pw, want := range map[string]bool{
+		"Password123!":        true,
+		"Padding123!":         false,
+		"Correct-Horse-42":    false, // no range file for its prefix
+		"Something else 99 !": false,
+	} {
+		got, err := checker.IsBreached(pw)
+		if err != nil {
+			t.Fatalf("IsBreached(%q): %v", pw, err)
+		}
+		if got != want {
+			t.Errorf("IsBreached(%q) = %v, want %v", pw, got, want)
+		}
+	}
+}
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	filter := password.NewBloomFilter(100, 0.001)
+	filter.Add("Password123!")
+	if err := filter.AddHash(sha1Upper("Qwerty123!")); err != nil {
+		t.Fatal(err)
+	}
+	if err := filter.AddHash("not-a-digest"); err == nil {
+		t.Error("AddHash should reject malformed digests")
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.bloom")
+	var buf bytes.Buffer
+	if _, err := filter.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	checker, err := password.OpenBreachChecker(path)
+	if err != nil {
+		t.Fatalf("OpenBreachChecker: %v", err)
+	}
+	for _, pw := range []string{"Password123!", "Qwerty123!"} {
+		if breached, _ := checker.IsBreached(pw); !breached {
+			t.Errorf("%q should be reported as breached", pw)
+		}
+	}
+	if breached, _ := checker.IsBreached("Unlisted-Passphrase-77"); breached {
+		t.Error("a password never added should pass a sparsely filled filter")
+	}
+
+	if _, err := password.ReadBloomFilter(strings.NewReader("garbage header...")); err == nil {
+		t.Error("ReadBloomFilter should reject files without the magic header")
+	}
+}
+
+func TestReadBloomFilterRejectsOversizedHeaders(t *testing.T) {
+	header := func(hashes uint32, bits uint64) []byte {
+		h := []byte{'P', 'W', 'B', 'F', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(h[4:8], hashes)
+		binary.BigEndian.PutUint64(h[8:16], bits)
+		return h
+	}
+
+	for name, data := range map[string][]byte{
+		"absurd bit count":  header(7, math.MaxUint64),
+		"absurd hash count": header(1<<20, 1024),
+		"short body":        append(header(7, 1<<30), make([]byte, 64)...),
+	} {
+		if _, err := password.ReadBloomFilter(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: ReadBloomFilter should fail", name)
+		}
+	}
+
+	// A file whose size disagrees with its header is refused before the bits are read
+	path := filepath.Join(t.TempDir(), "truncated.bloom")
+	if err := os.WriteFile(path, append(header(7, 8192), make([]byte, 100)...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := password.LoadBloomFilter(path); err == nil {
+		t.Error("LoadBloomFilter should reject a file shorter than its header claims")
+	}
+
+	if err := os.WriteFile(path, append(header(7, 8192), make([]byte, 1024)...), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := password.LoadBloomFilter(path); err != nil {
+		t.Errorf("LoadBloomFilter: %v", err)
+	}
+}
+
+func TestServiceCheckBreached(t *testing.T) {
+	svc := password.NewService()
+	if err := svc.CheckBreached("Password123!"); err != nil {
+		t.Fatalf("without a checker every password passes, got %v", err)
+	}
+
+	filter := password.NewBloomFilter(10, 0.001)
+	filter.Add("Password123!")
+	svc.SetBreachChecker(filter)
+
+	if err := svc.CheckBreached("Password123!"); !errors.Is(err, password.ErrPasswordBreached) {
+		t.Errorf("expected ErrPasswordBreached, got %v", err)
+	}
+	if err := svc.CheckBreached("Unlisted-Passphrase-77"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Composition rules are unaffected: breached passwords still hash for seeding and secrets
+	if _, err := svc.Hash("Password123!"); err != nil {
+		t.Errorf("Hash should not consult the breach corpus: %v", err)
+	}
+}
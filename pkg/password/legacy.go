@@ -0,0 +1,89 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Legacy hash formats accepted by Verify for imported accounts
+const (
+	// passlib style: $pbkdf2-sha256$<iterations>$<salt>$<hash>, salt and hash in adapted base64
+	pbkdf2Prefix = "$pbkdf2-sha256$"
+	// Django style: pbkdf2_sha256$<iterations>$<salt>$<hash>, salt as text and hash in base64
+	djangoPBKDF2Prefix = "pbkdf2_sha256$"
+)
+
+func isBcryptHash(hash string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyBcrypt(password, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid bcrypt hash: %w", err)
+	}
+	return true, nil
+}
+
+func verifyPBKDF2SHA256(password, hash string) (bool, error) {
+	var iterField string
+	var salt, expected []byte
+	var err error
+
+	if strings.HasPrefix(hash, pbkdf2Prefix) {
+		parts := strings.Split(strings.TrimPrefix(hash, pbkdf2Prefix), "$")
+		if len(parts) != 3 {
+			return false, fmt.Errorf("invalid hash format")
+		}
+		iterField = parts[0]
+		if salt, err = decodeAdaptedBase64(parts[1]); err != nil {
+			return false, fmt.Errorf("failed to decode salt: %w", err)
+		}
+		if expected, err = decodeAdaptedBase64(parts[2]); err != nil {
+			return false, fmt.Errorf("failed to decode hash: %w", err)
+		}
+	} else {
+		parts := strings.Split(strings.TrimPrefix(hash, djangoPBKDF2Prefix), "$")
+		if len(parts) != 3 {
+			return false, fmt.Errorf("invalid hash format")
+		}
+		iterField = parts[0]
+		salt = []byte(parts[1])
+		if expected, err = base64.StdEncoding.DecodeString(parts[2]); err != nil {
+			return false, fmt.Errorf("failed to decode hash: %w", err)
+		}
+	}
+
+	iterations, err := strconv.Atoi(iterField)
+	if err != nil || iterations < 1 {
+		return false, fmt.Errorf("invalid parameters")
+	}
+	if len(expected) == 0 {
+		return false, fmt.Errorf("invalid hash format")
+	}
+
+	computed := pbkdf2.Key([]byte(password), salt, iterations, len(expected), sha256.New)
+	return subtle.ConstantTimeCompare(computed, expected) == 1, nil
+}
+
+// decodeAdaptedBase64 decodes passlib's base64 variant, which uses "." instead of "+"
+// and drops padding
+func decodeAdaptedBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.ReplaceAll(s, ".", "+"), "="))
+}
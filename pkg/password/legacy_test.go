@@ -0,0 +1,62 @@
+package password_test
+
+import (
+	"testing"
+
+	"auth-service/pkg/password"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyLegacyHashes(t *testing.T) {
+	svc := password.NewService()
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("Legacy-Pass-9"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+	}{
+		{"bcrypt", "Legacy-Pass-9", string(bcryptHash)},
+		// Example from the passlib documentation
+		{"pbkdf2-sha256 passlib", "password", "$pbkdf2-sha256$6400$0ZrzXitFSGltTQnBWOsdAw$Y11AchqV4b0sUisdZd0Xr97KWoymNE0LNNrnEgY4H9M"},
+		{"pbkdf2-sha256 django", "Legacy-Pass-9", "pbkdf2_sha256$20000$Ys0aWq1Z7kLm$8GHURjw6B0tGIC78tot+V5QPBmKvOhX2unGx6wIRots="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, err := svc.Verify(tt.password, tt.hash); err != nil || !ok {
+				t.Fatalf("correct password rejected: ok=%v err=%v", ok, err)
+			}
+			if ok, _ := svc.Verify("wrong-password", tt.hash); ok {
+				t.Error("wrong password accepted")
+			}
+			if !svc.NeedsRehash(tt.hash) {
+				t.Error("legacy hashes should be upgraded")
+			}
+		})
+	}
+
+	if _, err := svc.Verify("password", "md5$abc$def"); err == nil {
+		t.Error("unknown formats should be rejected")
+	}
+}
+
+func TestNeedsRehashArgon2Parameters(t *testing.T) {
+	svc := password.NewService()
+	hash, err := svc.HashWithoutValidation("Current-Pass-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.NeedsRehash(hash) {
+		t.Error("a hash with current parameters should not need rehashing")
+	}
+
+	weak := "$argon2id$v=19$m=16384,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$tTL3sfMV+VP1ScAO5g8GjJxJP6jrKJn+H+QA0xB6Al4"
+	if !svc.NeedsRehash(weak) {
+		t.Error("a hash with older parameters should need rehashing")
+	}
+}
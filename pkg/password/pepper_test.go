@@ -0,0 +1,59 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/password"
+)
+
+func TestPepperedHashes(t *testing.T) {
+	v1, err := hashutil.NewKeyring(map[int][]byte{1: []byte("pepper-one")}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := hashutil.NewKeyring(map[int][]byte{1: []byte("pepper-one"), 2: []byte("pepper-two")}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := password.NewService()
+	unpeppered, _ := plain.HashWithoutValidation("Peppered-Pass-1")
+
+	svc := password.NewService()
+	svc.SetPepper(v1)
+	hash, err := svc.HashWithoutValidation("Peppered-Pass-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(hash, ",keyid=1$") {
+		t.Fatalf("hash should record its pepper version: %s", hash)
+	}
+	if ok, err := svc.Verify("Peppered-Pass-1", hash); err != nil || !ok {
+		t.Fatalf("correct password rejected: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := svc.Verify("Peppered-Pass-2", hash); ok {
+		t.Error("wrong password accepted")
+	}
+	if _, err := plain.Verify("Peppered-Pass-1", hash); err == nil {
+		t.Error("a peppered hash cannot be checked without the pepper")
+	}
+
+	// Hashes from before the pepper still verify and are flagged for upgrade
+	if ok, err := svc.Verify("Peppered-Pass-1", unpeppered); err != nil || !ok {
+		t.Fatalf("unpeppered hash rejected: ok=%v err=%v", ok, err)
+	}
+	if !svc.NeedsRehash(unpeppered) || svc.NeedsRehash(hash) {
+		t.Error("only hashes without the active pepper need a rehash")
+	}
+
+	// After a rotation the old version still verifies but is upgraded at next sign-in
+	svc.SetPepper(v2)
+	if ok, err := svc.Verify("Peppered-Pass-1", hash); err != nil || !ok {
+		t.Fatalf("hash under the previous pepper rejected: ok=%v err=%v", ok, err)
+	}
+	if !svc.NeedsRehash(hash) {
+		t.Error("hash under the previous pepper should be rehashed")
+	}
+}
@@ -1,13 +1,17 @@
 package password
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"auth-service/pkg/hashutil"
+
 	"golang.org/x/crypto/argon2"
 )
 
@@ -17,6 +21,7 @@ type PasswordService interface {
 	HashWithoutValidation(password string) (string, error)
 	Verify(password, hash string) (bool, error)
 	ValidatePassword(password string) error
+	NeedsRehash(hash string) bool
 }
 
 // Service handles password operations
@@ -25,6 +30,8 @@ type Service struct {
 	memory  uint32
 	threads uint8
 	keyLen  uint32
+	breach  BreachChecker
+	pepper  *hashutil.Keyring
 }
 
 // NewService creates a new password service with Argon2id parameters
@@ -37,6 +44,35 @@ func NewService() *Service {
 	}
 }
 
+// SetBreachChecker enables CheckBreached. Hash and ValidatePassword stay composition-only
+// so machine-generated secrets are never looked up.
+func (s *Service) SetBreachChecker(checker BreachChecker) {
+	s.breach = checker
+}
+
+// SetPepper mixes a server-side secret into every new argon2id hash. The hash records the
+// key version it was peppered with (keyid), and NeedsRehash flags hashes under any other
+// version so they move to the active key at the user's next sign-in.
+func (s *Service) SetPepper(keyring *hashutil.Keyring) {
+	s.pepper = keyring
+}
+
+// CheckBreached returns ErrPasswordBreached when the password is in the configured breach
+// corpus. Without a checker every password passes.
+func (s *Service) CheckBreached(password string) error {
+	if s.breach == nil {
+		return nil
+	}
+	breached, err := s.breach.IsBreached(password)
+	if err != nil {
+		return fmt.Errorf("failed to check breached passwords: %w", err)
+	}
+	if breached {
+		return ErrPasswordBreached
+	}
+	return nil
+}
+
 // Hash generates a hash from a password
 func (s *Service) Hash(password string) (string, error) {
 	if err := s.ValidatePassword(password); err != nil {
@@ -54,18 +90,57 @@ func (s *Service) HashWithoutValidation(password string) (string, error) {
 		return "", fmt.Errorf("failed to generate salt: %w", err)
 	}
 
-	hash := argon2.IDKey([]byte(password), salt, s.time, s.memory, s.threads, s.keyLen)
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", s.memory, s.time, s.threads)
+	input := []byte(password)
+	if s.pepper != nil {
+		keyID := s.pepper.Active()
+		var err error
+		if input, err = s.pepperInput(password, keyID); err != nil {
+			return "", err
+		}
+		params += fmt.Sprintf(",keyid=%d", keyID)
+	}
+
+	hash := argon2.IDKey(input, salt, s.time, s.memory, s.threads, s.keyLen)
 
-	// Format: $argon2id$v=19$m=65536,t=1,p=4$salt$hash
+	// Format: $argon2id$v=19$m=65536,t=1,p=4[,keyid=N]$salt$hash
 	encodedSalt := base64.RawStdEncoding.EncodeToString(salt)
 	encodedHash := base64.RawStdEncoding.EncodeToString(hash)
 
-	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s",
-		s.memory, s.time, s.threads, encodedSalt, encodedHash), nil
+	return fmt.Sprintf("$argon2id$v=19$%s$%s$%s", params, encodedSalt, encodedHash), nil
 }
 
-// Verify checks if a password matches a hash
+// pepperInput is the argon2id input for a hash peppered with key version keyID
+func (s *Service) pepperInput(password string, keyID int) ([]byte, error) {
+	if s.pepper == nil {
+		return nil, fmt.Errorf("hash is peppered but no pepper is configured")
+	}
+	key, err := s.pepper.Derive(keyID, "password-pepper")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load password pepper: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return mac.Sum(nil), nil
+}
+
+// Verify checks if a password matches a hash. Besides our own argon2id hashes it accepts
+// the bcrypt and PBKDF2-SHA256 hashes of imported accounts; NeedsRehash reports those so
+// they can be upgraded once the password is known.
 func (s *Service) Verify(password, hash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return s.verifyArgon2id(password, hash)
+	case isBcryptHash(hash):
+		return verifyBcrypt(password, hash)
+	case strings.HasPrefix(hash, pbkdf2Prefix), strings.HasPrefix(hash, djangoPBKDF2Prefix):
+		return verifyPBKDF2SHA256(password, hash)
+	default:
+		return false, fmt.Errorf("unsupported hash format")
+	}
+}
+
+func (s *Service) verifyArgon2id(password, hash string) (bool, error) {
 	// Parse the hash format
 	parts := strings.Split(hash, "$")
 	if len(parts) != 6 || parts[1] != "argon2id" {
@@ -74,12 +149,13 @@ func (s *Service) Verify(password, hash string) (bool, error) {
 
 	// Extract parameters
 	params := strings.Split(parts[3], ",")
-	if len(params) != 3 {
+	if len(params) != 3 && len(params) != 4 {
 		return false, fmt.Errorf("invalid parameters")
 	}
 
 	var memory, time uint32
 	var threads uint8
+	keyID := -1
 
 	for _, param := range params {
 		kv := strings.Split(param, "=")
@@ -99,6 +175,10 @@ func (s *Service) Verify(password, hash string) (bool, error) {
 			if val, err := strconv.ParseUint(kv[1], 10, 8); err == nil {
 				threads = uint8(val)
 			}
+		case "keyid":
+			if val, err := strconv.Atoi(kv[1]); err == nil && val >= 0 {
+				keyID = val
+			}
 		}
 	}
 
@@ -112,7 +192,18 @@ func (s *Service) Verify(password, hash string) (bool, error) {
 		return false, fmt.Errorf("failed to decode hash: %w", err)
 	}
 
-	computedHash := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expectedHash)))
+	if time == 0 || threads == 0 {
+		return false, fmt.Errorf("invalid parameters")
+	}
+
+	input := []byte(password)
+	if keyID >= 0 {
+		if input, err = s.pepperInput(password, keyID); err != nil {
+			return false, err
+		}
+	}
+
+	computedHash := argon2.IDKey(input, salt, time, memory, threads, uint32(len(expectedHash)))
 
 	return subtle.ConstantTimeCompare(computedHash, expectedHash) == 1, nil
 }
@@ -160,7 +251,8 @@ func (s *Service) ValidatePassword(password string) error {
 	return nil
 }
 
-// NeedsRehash checks if a hash needs to be rehashed with current parameters
+// NeedsRehash checks if a hash needs to be rehashed with current parameters. Hashes from
+// other algorithms always do.
 func (s *Service) NeedsRehash(hash string) bool {
 	parts := strings.Split(hash, "$")
 	if len(parts) != 6 || parts[1] != "argon2id" {
@@ -168,12 +260,13 @@ func (s *Service) NeedsRehash(hash string) bool {
 	}
 
 	params := strings.Split(parts[3], ",")
-	if len(params) != 3 {
+	if len(params) != 3 && len(params) != 4 {
 		return true
 	}
 
 	var memory, time uint32
 	var threads uint8
+	keyID := -1
 
 	for _, param := range params {
 		kv := strings.Split(param, "=")
@@ -193,8 +286,15 @@ func (s *Service) NeedsRehash(hash string) bool {
 			if val, err := strconv.ParseUint(kv[1], 10, 8); err == nil {
 				threads = uint8(val)
 			}
+		case "keyid":
+			if val, err := strconv.Atoi(kv[1]); err == nil && val >= 0 {
+				keyID = val
+			}
 		}
 	}
 
+	if s.pepper != nil && keyID != s.pepper.Active() {
+		return true
+	}
 	return memory != s.memory || time != s.time || threads != s.threads
 }
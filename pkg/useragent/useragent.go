@@ -0,0 +1,73 @@
+// Package useragent extracts a coarse browser and operating system from a User-Agent
+// header, enough to label devices and sessions for the people who own them.
+package useragent
+
+import "strings"
+
+// Info is the parsed form of a User-Agent header. Unrecognised parts are left empty.
+type Info struct {
+	Browser string `json:"browser,omitempty"`
+	OS      string `json:"os,omitempty"`
+	Mobile  bool   `json:"mobile,omitempty"`
+}
+
+// Order matters: most browsers also claim to be the ones they are built on
+var browsers = []struct{ token, name string }{
+	{"edg/", "Edge"},
+	{"edga/", "Edge"},
+	{"edgios/", "Edge"},
+	{"opr/", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"safari/", "Safari"},
+	{"curl/", "curl"},
+	{"postmanruntime/", "Postman"},
+}
+
+var systems = []struct{ token, name string }{
+	{"iphone", "iOS"},
+	{"ipad", "iPadOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"windows", "Windows"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// Parse inspects a User-Agent header
+func Parse(ua string) Info {
+	lower := strings.ToLower(ua)
+	var info Info
+	for _, b := range browsers {
+		if strings.Contains(lower, b.token) {
+			info.Browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(lower, s.token) {
+			info.OS = s.name
+			break
+		}
+	}
+	info.Mobile = strings.Contains(lower, "mobile") || info.OS == "iOS" || info.OS == "Android"
+	return info
+}
+
+// String describes the device as e.g. "Chrome on macOS"
+func (i Info) String() string {
+	switch {
+	case i.Browser != "" && i.OS != "":
+		return i.Browser + " on " + i.OS
+	case i.Browser != "":
+		return i.Browser
+	case i.OS != "":
+		return i.OS
+	default:
+		return "Unknown device"
+	}
+}
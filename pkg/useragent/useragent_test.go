@@ -0,0 +1,30 @@
+package useragent
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua      string
+		browser string
+		os      string
+		mobile  bool
+		label   string
+	}{
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36", "Chrome", "macOS", false, "Chrome on macOS"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0", "Edge", "Windows", false, "Edge on Windows"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", "Safari", "iOS", true, "Safari on iOS"},
+		{"Mozilla/5.0 (Android 14; Mobile; rv:125.0) Gecko/125.0 Firefox/125.0", "Firefox", "Android", true, "Firefox on Android"},
+		{"curl/8.5.0", "curl", "", false, "curl"},
+		{"", "", "", false, "Unknown device"},
+	}
+
+	for _, tt := range tests {
+		info := Parse(tt.ua)
+		if info.Browser != tt.browser || info.OS != tt.os || info.Mobile != tt.mobile {
+			t.Errorf("Parse(%q) = %+v, want browser %q os %q mobile %v", tt.ua, info, tt.browser, tt.os, tt.mobile)
+		}
+		if got := info.String(); got != tt.label {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.ua, got, tt.label)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package webauthn implements the relying party side of WebAuthn Level 2 registration
+// and authentication ceremonies for passkeys. Attestation is not requested, so only
+// "none" and packed self-attestation statements are accepted.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Ceremony types recorded in client data
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// Authenticator data flags (WebAuthn section 6.1)
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagBackupEligible         byte = 0x08
+	FlagBackedUp               byte = 0x10
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+// ChallengeSize is the number of random bytes in a ceremony challenge
+const ChallengeSize = 32
+
+var (
+	ErrInvalidClientData       = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch       = errors.New("webauthn: challenge mismatch")
+	ErrOriginNotAllowed        = errors.New("webauthn: origin not allowed")
+	ErrRPIDMismatch            = errors.New("webauthn: relying party ID mismatch")
+	ErrUserNotPresent          = errors.New("webauthn: user presence flag not set")
+	ErrUserNotVerified         = errors.New("webauthn: user verification flag not set")
+	ErrInvalidAuthData         = errors.New("webauthn: invalid authenticator data")
+	ErrUnsupportedAttestation  = errors.New("webauthn: unsupported attestation statement")
+	ErrInvalidSignature        = errors.New("webauthn: invalid signature")
+	ErrUnsupportedKeyAlgorithm = errors.New("webauthn: unsupported credential algorithm")
+)
+
+// RelyingParty identifies this service to authenticators
+type RelyingParty struct {
+	ID      string   // Effective domain, e.g. "example.com"
+	Name    string   // Shown by the authenticator
+	Origins []string // Exact origins ceremonies may run on, e.g. "https://app.example.com"
+}
+
+// NewChallenge returns a random base64url challenge
+func NewChallenge() (string, error) {
+	raw := make([]byte, ChallengeSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ClientData is the collectedClientData the browser signs over (WebAuthn section 5.8.1)
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+// ParseClientData decodes clientDataJSON. The challenge is returned unverified so
+// callers can look up the ceremony it belongs to.
+func ParseClientData(clientDataJSON []byte) (*ClientData, error) {
+	var cd ClientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return nil, ErrInvalidClientData
+	}
+	if cd.Type == "" || cd.Challenge == "" || cd.Origin == "" {
+		return nil, ErrInvalidClientData
+	}
+	return &cd, nil
+}
+
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony, challenge string) error {
+	cd, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if cd.Type != ceremony {
+		return fmt.Errorf("%w: type %q", ErrInvalidClientData, cd.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return ErrChallengeMismatch
+	}
+	if cd.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin ceremonies are not accepted", ErrOriginNotAllowed)
+	}
+	for _, origin := range rp.Origins {
+		if cd.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrOriginNotAllowed, cd.Origin)
+}
+
+// AuthenticatorData is the parsed authenticatorData structure (WebAuthn section 6.1)
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// Present during registration only
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE_Key, stored as-is
+}
+
+// Has reports whether flag is set
+func (a *AuthenticatorData) Has(flag byte) bool { return a.Flags&flag != 0 }
+
+// ParseAuthenticatorData decodes raw authenticator data
+func ParseAuthenticatorData(raw []byte) (*AuthenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthData)
+	}
+	ad := &AuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if ad.Has(FlagAttestedCredentialData) {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: truncated attested credential data", ErrInvalidAuthData)
+		}
+		ad.AAGUID = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: bad credential ID length", ErrInvalidAuthData)
+		}
+		ad.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// The COSE key has no length prefix; decoding it tells us where it ends
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidAuthData, err)
+		}
+		ad.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if ad.Has(FlagExtensionData) {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrInvalidAuthData, err)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAuthData)
+	}
+	return ad, nil
+}
+
+func (rp *RelyingParty) verifyAuthData(ad *AuthenticatorData, requireUV bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(ad.RPIDHash, rpIDHash[:]) {
+		return ErrRPIDMismatch
+	}
+	if !ad.Has(FlagUserPresent) {
+		return ErrUserNotPresent
+	}
+	if requireUV && !ad.Has(FlagUserVerified) {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// Credential is a newly registered public key credential
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key
+	Algorithm      int64
+	SignCount      uint32
+	AAGUID         []byte
+	BackupEligible bool
+	BackedUp       bool
+}
+
+// VerifyRegistration checks the response to a creation ceremony started with challenge
+// (WebAuthn section 7.1) and returns the credential to store
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte, requireUV bool) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	item, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidAuthData)
+	}
+	attObj, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrInvalidAuthData)
+	}
+	format, _ := attObj["fmt"].(string)
+	attStmt, _ := attObj["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attObj["authData"].([]byte)
+
+	ad, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthData(ad, requireUV); err != nil {
+		return nil, err
+	}
+	if ad.CredentialID == nil {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrInvalidAuthData)
+	}
+
+	publicKey, err := ParsePublicKey(ad.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKeyAlgorithm, err)
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := verifyAttestationStatement(format, attStmt, rawAuthData, clientDataHash[:], publicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             ad.CredentialID,
+		PublicKey:      ad.PublicKey,
+		Algorithm:      publicKey.Algorithm,
+		SignCount:      ad.SignCount,
+		AAGUID:         ad.AAGUID,
+		BackupEligible: ad.Has(FlagBackupEligible),
+		BackedUp:       ad.Has(FlagBackedUp),
+	}, nil
+}
+
+// verifyAttestationStatement accepts statements that need no trust anchors: "none", and
+// "packed" self-attestation signed by the credential key itself
+func verifyAttestationStatement(format string, attStmt map[interface{}]interface{}, authData, clientDataHash []byte, credKey *PublicKey) error {
+	switch format {
+	case "none":
+		if len(attStmt) != 0 {
+			return fmt.Errorf("%w: none with a statement", ErrUnsupportedAttestation)
+		}
+		return nil
+	case "packed":
+		if _, hasCert := attStmt["x5c"]; hasCert {
+			return fmt.Errorf("%w: packed with certificate chain", ErrUnsupportedAttestation)
+		}
+		alg, _ := attStmt["alg"].(int64)
+		sig, _ := attStmt["sig"].([]byte)
+		if alg != credKey.Algorithm || len(sig) == 0 {
+			return fmt.Errorf("%w: packed self-attestation algorithm mismatch", ErrUnsupportedAttestation)
+		}
+		return credKey.Verify(append(append([]byte(nil), authData...), clientDataHash...), sig)
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedAttestation, format)
+}
+
+// VerifyAssertion checks the response to an authentication ceremony started with
+// challenge against the stored COSE public key (WebAuthn section 7.2). The caller is
+// responsible for matching the credential to the user and checking the sign count.
+func (rp *RelyingParty) VerifyAssertion(challenge string, publicKey, clientDataJSON, authenticatorData, signature []byte, requireUV bool) (*AuthenticatorData, error) {
+	if err := rp.verifyClientData(clientDataJSON, ceremonyGet, challenge); err != nil {
+		return nil, err
+	}
+
+	ad, err := ParseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthData(ad, requireUV); err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKeyAlgorithm, err)
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	if err := key.Verify(signed, signature); err != nil {
+		return nil, err
+	}
+	return ad, nil
+}
+
+// SignCountValid reports whether an assertion's counter is consistent with the stored
+// one. Authenticators that do not keep a counter always report 0; once a counter has
+// been seen it must strictly increase, otherwise the credential may have been cloned.
+func SignCountValid(stored, received uint32) bool {
+	if stored == 0 && received == 0 {
+		return true
+	}
+	return received > stored
+}
@@ -0,0 +1,106 @@
+package webauthn
+
+import (
+	"errors"
+	"testing"
+
+	"auth-service/pkg/webauthn/webauthntest"
+)
+
+func testRP() *RelyingParty {
+	return &RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRP()
+	authenticator := webauthntest.New("example.com", "https://example.com")
+
+	challenge, _ := NewChallenge()
+	att, err := authenticator.Create(challenge, []byte("user-handle"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := rp.VerifyRegistration(challenge, att.ClientDataJSON, att.AttestationObject, true)
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	if string(cred.ID) != string(att.CredentialID) || cred.Algorithm != AlgES256 {
+		t.Fatalf("unexpected credential: %+v", cred)
+	}
+
+	challenge, _ = NewChallenge()
+	assertion, err := authenticator.Get(challenge, cred.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ad, err := rp.VerifyAssertion(challenge, cred.PublicKey, assertion.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature, true)
+	if err != nil {
+		t.Fatalf("assertion failed: %v", err)
+	}
+	if !SignCountValid(cred.SignCount, ad.SignCount) {
+		t.Errorf("sign count %d should follow %d", ad.SignCount, cred.SignCount)
+	}
+
+	// The signature covers the client data, so a different challenge must fail
+	other, _ := NewChallenge()
+	if _, err := rp.VerifyAssertion(other, cred.PublicKey, assertion.ClientDataJSON, assertion.AuthenticatorData, assertion.Signature, true); !errors.Is(err, ErrChallengeMismatch) {
+		t.Errorf("expected challenge mismatch, got %v", err)
+	}
+
+	tampered := append([]byte(nil), assertion.Signature...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := rp.VerifyAssertion(challenge, cred.PublicKey, assertion.ClientDataJSON, assertion.AuthenticatorData, tampered, true); err == nil {
+		t.Error("tampered signature was accepted")
+	}
+}
+
+func TestRegistrationRejectsWrongOriginAndRP(t *testing.T) {
+	rp := testRP()
+	challenge, _ := NewChallenge()
+
+	phishing := webauthntest.New("example.com", "https://examp1e.com")
+	att, _ := phishing.Create(challenge, []byte("u"))
+	if _, err := rp.VerifyRegistration(challenge, att.ClientDataJSON, att.AttestationObject, true); !errors.Is(err, ErrOriginNotAllowed) {
+		t.Errorf("expected origin error, got %v", err)
+	}
+
+	otherRP := webauthntest.New("evil.com", "https://example.com")
+	att, _ = otherRP.Create(challenge, []byte("u"))
+	if _, err := rp.VerifyRegistration(challenge, att.ClientDataJSON, att.AttestationObject, true); !errors.Is(err, ErrRPIDMismatch) {
+		t.Errorf("expected RP ID error, got %v", err)
+	}
+
+	noUV := webauthntest.New("example.com", "https://example.com")
+	noUV.SkipUserVerification = true
+	att, _ = noUV.Create(challenge, []byte("u"))
+	if _, err := rp.VerifyRegistration(challenge, att.ClientDataJSON, att.AttestationObject, true); !errors.Is(err, ErrUserNotVerified) {
+		t.Errorf("expected user verification error, got %v", err)
+	}
+}
+
+func TestSignCountValid(t *testing.T) {
+	cases := []struct {
+		stored, received uint32
+		want             bool
+	}{
+		{0, 0, true}, // authenticator without a counter
+		{0, 1, true},
+		{5, 6, true},
+		{5, 5, false}, // replayed or cloned
+		{5, 0, false},
+	}
+	for _, tc := range cases {
+		if got := SignCountValid(tc.stored, tc.received); got != tc.want {
+			t.Errorf("SignCountValid(%d, %d) = %v, want %v", tc.stored, tc.received, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeCBORRejectsIndefiniteLength(t *testing.T) {
+	if _, _, err := decodeCBOR([]byte{0x5f, 0x41, 0x00, 0xff}); err == nil {
+		t.Error("indefinite-length byte string was accepted")
+	}
+	if _, _, err := decodeCBOR([]byte{0x58, 0x05, 0x01}); err == nil {
+		t.Error("truncated byte string was accepted")
+	}
+}
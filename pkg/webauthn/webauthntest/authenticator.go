@@ -0,0 +1,209 @@
+// Package webauthntest provides a software authenticator for exercising WebAuthn
+// ceremonies in tests without a browser or hardware key
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Authenticator is an in-memory platform authenticator holding P-256 (ES256) passkeys.
+// It always asserts user presence and, unless SkipUserVerification is set, user verification.
+type Authenticator struct {
+	RPID                 string
+	Origin               string
+	AAGUID               [16]byte
+	SkipUserVerification bool
+
+	credentials map[string]*credential
+}
+
+type credential struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+// AttestationResponse is what navigator.credentials.create() would resolve with
+type AttestationResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// AssertionResponse is what navigator.credentials.get() would resolve with
+type AssertionResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// New creates an authenticator acting on origin for relying party rpID
+func New(rpID, origin string) *Authenticator {
+	return &Authenticator{RPID: rpID, Origin: origin, credentials: map[string]*credential{}}
+}
+
+// Create generates a credential for userHandle and answers a registration challenge with
+// "none" attestation
+func (a *Authenticator) Create(challenge string, userHandle []byte) (*AttestationResponse, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, key: key, userHandle: userHandle}
+	a.credentials[string(id)] = cred
+
+	clientDataJSON, err := a.clientData("webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attested := append([]byte(nil), a.AAGUID[:]...)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(id)))
+	attested = append(attested, id...)
+	attested = append(attested, encodeCOSEKey(&key.PublicKey)...)
+	authData := a.authData(0x40, cred.signCount, attested)
+
+	attestationObject := encodeMap([]mapEntry{
+		{encodeText("fmt"), encodeText("none")},
+		{encodeText("attStmt"), encodeMap(nil)},
+		{encodeText("authData"), encodeBytes(authData)},
+	})
+
+	return &AttestationResponse{
+		CredentialID:      id,
+		ClientDataJSON:    clientDataJSON,
+		AttestationObject: attestationObject,
+	}, nil
+}
+
+// Get signs an authentication challenge with the credential identified by credentialID
+func (a *Authenticator) Get(challenge string, credentialID []byte) (*AssertionResponse, error) {
+	cred, ok := a.credentials[string(credentialID)]
+	if !ok {
+		return nil, fmt.Errorf("webauthntest: unknown credential")
+	}
+	cred.signCount++
+
+	clientDataJSON, err := a.clientData("webauthn.get", challenge)
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authData(0, cred.signCount, nil)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &AssertionResponse{
+		CredentialID:      cred.id,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        cred.userHandle,
+	}, nil
+}
+
+// SetSignCount overrides a credential's counter, e.g. to simulate a cloned authenticator
+func (a *Authenticator) SetSignCount(credentialID []byte, count uint32) {
+	if cred, ok := a.credentials[string(credentialID)]; ok {
+		cred.signCount = count
+	}
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+func (a *Authenticator) authData(extraFlags byte, signCount uint32, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := byte(0x01) | extraFlags // user present
+	if !a.SkipUserVerification {
+		flags |= 0x04
+	}
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+	return append(data, attested...)
+}
+
+func encodeCOSEKey(pub *ecdsa.PublicKey) []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	pub.X.FillBytes(x)
+	pub.Y.FillBytes(y)
+	return encodeMap([]mapEntry{
+		{encodeInt(1), encodeInt(2)},    // kty: EC2
+		{encodeInt(3), encodeInt(-7)},   // alg: ES256
+		{encodeInt(-1), encodeInt(1)},   // crv: P-256
+		{encodeInt(-2), encodeBytes(x)}, // x
+		{encodeInt(-3), encodeBytes(y)}, // y
+	})
+}
+
+// Minimal canonical CBOR encoding for the structures above
+
+type mapEntry struct{ key, value []byte }
+
+func encodeHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
+
+func encodeInt(v int64) []byte {
+	if v < 0 {
+		return encodeHead(1, uint64(-1-v))
+	}
+	return encodeHead(0, uint64(v))
+}
+
+func encodeBytes(b []byte) []byte { return append(encodeHead(2, uint64(len(b))), b...) }
+
+func encodeText(s string) []byte { return append(encodeHead(3, uint64(len(s))), s...) }
+
+// encodeMap sorts keys by their encoding, as CTAP2 canonical CBOR requires
+func encodeMap(entries []mapEntry) []byte {
+	sort.Slice(entries, func(i, j int) bool {
+		ki, kj := entries[i].key, entries[j].key
+		if len(ki) != len(kj) {
+			return len(ki) < len(kj)
+		}
+		return string(ki) < string(kj)
+	})
+	out := encodeHead(5, uint64(len(entries)))
+	for _, e := range entries {
+		out = append(out, e.key...)
+		out = append(out, e.value...)
+	}
+	return out
+}
@@ -103,7 +103,7 @@ func TestOAuthFlow_Integration(t *testing.T) {
 		require.Contains(t, err.Error(), "authorization code has already been used")
 
 		// Step 7: Test refresh token flow
-		newTokenResp, err := oauth2Svc.RefreshAccessToken(ctx, tokenResp.RefreshToken, clientApp.ClientID, "test-agent", "127.0.0.1")
+		newTokenResp, err := oauth2Svc.RefreshAccessToken(ctx, tokenResp.RefreshToken, clientApp.ClientID, "", "test-agent", "127.0.0.1")
 		require.NoError(t, err)
 		require.NotEmpty(t, newTokenResp.AccessToken)
 		require.NotEqual(t, tokenResp.AccessToken, newTokenResp.AccessToken)
@@ -0,0 +1,74 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/geoip"
+	"auth-service/pkg/password"
+	"auth-service/tests/testutils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthLogin_RiskEvaluation(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+
+	const userPassword = "Correct-Horse-42!"
+	pwSvc := password.NewService()
+	hash, err := pwSvc.HashWithoutValidation(userPassword)
+	require.NoError(t, err)
+	user := testutils.CreateTestUser(t, testDB.DB, "oauth-risk@example.com")
+	require.NoError(t, testDB.DB.Model(user).Update("password_hash", hash).Error)
+
+	// The GeoIP test databases place 81.2.69.x in London and 89.160.20.128/25 in Linköping
+	resolver, err := geoip.OpenResolver("../../pkg/geoip/testdata/city-test.mmdb, ../../pkg/geoip/testdata/asn-test.mmdb")
+	require.NoError(t, err)
+	geoip.SetDefault(resolver)
+	defer geoip.SetDefault(nil)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	repo := repository.NewRepository(testDB.DB)
+	userSvc := service.NewUserService(repo, nil, pwSvc)
+	userSvc.SetLoginRiskService(service.NewLoginRiskService(repo, redisClient, nil, nil))
+	ctx := context.Background()
+
+	// A first sign-in has nothing to compare with; it becomes the user's history
+	authed, err := userSvc.AuthenticateByEmail(ctx, user.Email, userPassword, "81.2.69.142")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authed.ID)
+
+	// Minutes later from Sweden is impossible travel, and the user has no MFA for a step-up
+	_, err = userSvc.AuthenticateByEmail(ctx, user.Email, userPassword, "89.160.20.130")
+	assert.ErrorIs(t, err, service.ErrLoginStepUpRequired)
+
+	// Add a burst of failed attempts and the sign-in is refused outright
+	for i := 0; i < 3; i++ {
+		require.NoError(t, testDB.DB.Create(&models.FailedLoginAttempt{
+			Email:       user.Email,
+			IPAddress:   "89.160.20.130",
+			AttemptedAt: time.Now(),
+		}).Error)
+	}
+	_, err = userSvc.AuthenticateByEmail(ctx, user.Email, userPassword, "89.160.20.130")
+	assert.ErrorIs(t, err, service.ErrLoginBlocked)
+
+	// The familiar place still works, and a wrong password never gets as far as scoring
+	_, err = userSvc.AuthenticateByEmail(ctx, user.Email, userPassword, "81.2.69.142")
+	assert.NoError(t, err)
+	_, err = userSvc.AuthenticateByEmail(ctx, user.Email, "wrong-password", "89.160.20.130")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, service.ErrLoginBlocked)
+}
@@ -0,0 +1,66 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/password"
+	"auth-service/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreachedPassword_FlaggedAtLoginAndBlocksOrgSignIn(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+
+	const leaked = "Password123!"
+	pwSvc := password.NewService()
+	filter := password.NewBloomFilter(10, 0.001)
+	filter.Add(leaked)
+	pwSvc.SetBreachChecker(filter)
+
+	user := testutils.CreateTestUser(t, testDB.DB, "breached@example.com")
+	hash, err := pwSvc.HashWithoutValidation(leaked)
+	require.NoError(t, err)
+	verified := time.Now()
+	require.NoError(t, testDB.DB.Model(user).Updates(map[string]interface{}{
+		"password_hash":     hash,
+		"email_verified_at": verified,
+	}).Error)
+
+	repo := repository.NewRepository(testDB.DB)
+	userSvc := service.NewUserService(repo, nil, pwSvc)
+	ctx := context.Background()
+
+	resp, err := userSvc.LoginGlobal(ctx, &service.LoginGlobalRequest{Email: user.Email, Password: leaked})
+	require.NoError(t, err, "the first step still succeeds so the client can prompt for a reset")
+	assert.True(t, resp.User.PasswordResetRequired)
+
+	stored, err := repo.User().GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	require.NotNil(t, stored.PasswordCompromisedAt)
+
+	org := testutils.CreateTestOrganization(t, testDB.DB, "Breach Org", "breach-org")
+	_, err = userSvc.SelectOrganization(ctx, &service.SelectOrganizationRequest{
+		UserID:         user.ID.String(),
+		OrganizationID: org.ID.String(),
+	})
+	assert.ErrorIs(t, err, service.ErrPasswordResetRequired)
+
+	// A breached password cannot be chosen again; a clean one clears the flag
+	err = userSvc.ChangePassword(ctx, user.ID.String(), &service.ChangePasswordRequest{
+		CurrentPassword: leaked, NewPassword: leaked, ConfirmPassword: leaked,
+	})
+	assert.ErrorIs(t, err, service.ErrPasswordBreached)
+
+	require.NoError(t, userSvc.ChangePassword(ctx, user.ID.String(), &service.ChangePasswordRequest{
+		CurrentPassword: leaked, NewPassword: "Unlisted-Pass-77", ConfirmPassword: "Unlisted-Pass-77",
+	}))
+	stored, err = repo.User().GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Nil(t, stored.PasswordCompromisedAt)
+}
@@ -0,0 +1,223 @@
+package unit_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+	"auth-service/tests/testutils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const assertionIssuer = "https://auth.example.com"
+
+func newClientAssertionService(t *testing.T, jwksDir string) (service.ClientAssertionService, repository.Repository, uuid.UUID) {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.InitialAccessToken{}, &models.ClientApp{}))
+
+	org := &models.Organization{ID: uuid.New(), Name: "Assertion Org"}
+	require.NoError(t, testDB.DB.Create(org).Error)
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	// jwks_uri documents are served from a local directory instead of the network; the file
+	// transport ignores the scheme and host and only uses the path
+	httpClient := &http.Client{Transport: http.NewFileTransport(http.Dir(jwksDir))}
+
+	repo := repository.NewRepository(testDB.DB)
+	return service.NewClientAssertionService(repo, redisClient, httpClient, assertionIssuer), repo, org.ID
+}
+
+func signClientAssertion(t *testing.T, method gojwt.SigningMethod, key interface{}, clientID, jti string) string {
+	t.Helper()
+	token := gojwt.NewWithClaims(method, gojwt.RegisteredClaims{
+		Issuer:    clientID,
+		Subject:   clientID,
+		Audience:  gojwt.ClaimStrings{assertionIssuer + "/api/v1/oauth/token"},
+		ID:        jti,
+		ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestClientAssertion_PrivateKeyJWTFromJWKSURI(t *testing.T) {
+	dir := t.TempDir()
+	svc, repo, orgID := newClientAssertionService(t, dir)
+	ctx := context.Background()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	doc, err := json.Marshal(jwt.JWKSet{Keys: []jwt.JWK{{
+		Kty: "RSA",
+		Kid: "key-1",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jwks.json"), doc, 0o600))
+
+	client := &models.ClientApp{
+		Name:                    "Key Client",
+		ClientID:                "pkjwt-client",
+		ClientSecret:            "unused",
+		OrganizationID:          orgID,
+		IsConfidential:          true,
+		TokenEndpointAuthMethod: service.AuthMethodPrivateKeyJWT,
+		JWKSURI:                 "https://keys.partner.example/jwks.json",
+	}
+	require.NoError(t, repo.ClientApp().Create(ctx, client))
+
+	assertion := signClientAssertion(t, gojwt.SigningMethodRS256, key, client.ClientID, uuid.NewString())
+
+	authenticated, err := svc.ValidateClientAssertion(ctx, "", jwt.ClientAssertionType, assertion)
+	require.NoError(t, err)
+	assert.Equal(t, client.ClientID, authenticated.ClientID)
+
+	_, err = svc.ValidateClientAssertion(ctx, "", jwt.ClientAssertionType, assertion)
+	assert.ErrorIs(t, err, service.ErrInvalidClientAssertion, "a jti must not be accepted twice")
+
+	_, err = svc.ValidateClientAssertion(ctx, "other-client", jwt.ClientAssertionType, signClientAssertion(t, gojwt.SigningMethodRS256, key, client.ClientID, uuid.NewString()))
+	assert.ErrorIs(t, err, service.ErrInvalidClientAssertion, "sub must match the client_id parameter")
+}
+
+func TestClientAssertion_ClientSecretJWT(t *testing.T) {
+	svc, repo, orgID := newClientAssertionService(t, t.TempDir())
+	ctx := context.Background()
+
+	secret := "shared-client-secret"
+	encrypted, err := hashutil.EncryptSecret(secret)
+	require.NoError(t, err)
+
+	client := &models.ClientApp{
+		Name:                    "Secret JWT Client",
+		ClientID:                "csjwt-client",
+		ClientSecret:            "hashed",
+		OrganizationID:          orgID,
+		IsConfidential:          true,
+		TokenEndpointAuthMethod: service.AuthMethodClientSecretJWT,
+		ClientSecretEncrypted:   encrypted,
+	}
+	require.NoError(t, repo.ClientApp().Create(ctx, client))
+
+	_, err = svc.ValidateClientAssertion(ctx, client.ClientID, jwt.ClientAssertionType,
+		signClientAssertion(t, gojwt.SigningMethodHS256, []byte(secret), client.ClientID, uuid.NewString()))
+	assert.NoError(t, err)
+
+	_, err = svc.ValidateClientAssertion(ctx, client.ClientID, jwt.ClientAssertionType,
+		signClientAssertion(t, gojwt.SigningMethodHS256, []byte("wrong-secret"), client.ClientID, uuid.NewString()))
+	assert.ErrorIs(t, err, service.ErrInvalidClientAssertion)
+
+	_, err = svc.ValidateClientAssertion(ctx, client.ClientID, "urn:example:other",
+		signClientAssertion(t, gojwt.SigningMethodHS256, []byte(secret), client.ClientID, uuid.NewString()))
+	assert.ErrorIs(t, err, service.ErrInvalidClientAssertion)
+}
+
+type stubClientAppRepository struct {
+	repository.ClientAppRepository
+	client *models.ClientApp
+}
+
+func (r *stubClientAppRepository) GetByClientID(_ context.Context, clientID string) (*models.ClientApp, error) {
+	if r.client == nil || r.client.ClientID != clientID {
+		return nil, errors.New("client app not found")
+	}
+	return r.client, nil
+}
+
+type stubClientAppRepo struct {
+	repository.Repository
+	clientApps *stubClientAppRepository
+}
+
+func (r *stubClientAppRepo) ClientApp() repository.ClientAppRepository { return r.clientApps }
+
+func TestClientAssertion_JWKSURIFetchIsRestricted(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	doc, err := json.Marshal(jwt.JWKSet{Keys: []jwt.JWK{{
+		Kty: "RSA",
+		Kid: "key-1",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jwks.json"), doc, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "huge.json"), make([]byte, 65<<10), 0o600))
+
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(doc)
+	}))
+	defer internal.Close()
+
+	fileClient := &http.Client{Transport: http.NewFileTransport(http.Dir(dir))}
+	for _, tc := range []struct {
+		name       string
+		httpClient *http.Client
+		jwksURI    string
+		wantErr    string
+	}{
+		{"served from this machine", nil, internal.URL + "/jwks.json", "internal address"},
+		{"cloud metadata address", nil, "https://169.254.169.254/jwks.json", "internal address"},
+		{"private network", nil, "https://10.1.2.3/jwks.json", "internal address"},
+		{"plain http", fileClient, "http://keys.partner.example/jwks.json", "https"},
+		{"oversized document", fileClient, "https://keys.partner.example/huge.json", "exceeds"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &stubClientAppRepo{clientApps: &stubClientAppRepository{client: &models.ClientApp{
+				ClientID:                "pkjwt-client",
+				TokenEndpointAuthMethod: service.AuthMethodPrivateKeyJWT,
+				JWKSURI:                 tc.jwksURI,
+			}}}
+			svc := service.NewClientAssertionService(repo, newConsentRedis(t), tc.httpClient, assertionIssuer)
+
+			assertion := signClientAssertion(t, gojwt.SigningMethodRS256, key, "pkjwt-client", uuid.NewString())
+			_, err := svc.ValidateClientAssertion(context.Background(), "", jwt.ClientAssertionType, assertion)
+			assert.ErrorIs(t, err, service.ErrInvalidClientAssertion)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+
+	// The same document is accepted from a public https location
+	repo := &stubClientAppRepo{clientApps: &stubClientAppRepository{client: &models.ClientApp{
+		ClientID:                "pkjwt-client",
+		TokenEndpointAuthMethod: service.AuthMethodPrivateKeyJWT,
+		JWKSURI:                 "https://keys.partner.example/jwks.json",
+	}}}
+	svc := service.NewClientAssertionService(repo, newConsentRedis(t), fileClient, assertionIssuer)
+	_, err = svc.ValidateClientAssertion(context.Background(), "", jwt.ClientAssertionType,
+		signClientAssertion(t, gojwt.SigningMethodRS256, key, "pkjwt-client", uuid.NewString()))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,72 @@
+package unit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/middleware"
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCredentialsToken_HasNoPermissions(t *testing.T) {
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+
+	// A client that registered scopes named like admin permissions
+	client := &models.ClientApp{
+		ID:             uuid.New(),
+		ClientID:       "machine-client",
+		OrganizationID: uuid.New(),
+		IsConfidential: true,
+		AllowedScopes:  pq.StringArray{"users:delete", "reports:read"},
+	}
+	oauth2Service := service.NewOAuth2Service(nil, jwtService)
+	resp, err := oauth2Service.ClientCredentialsGrant(context.Background(), client, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "users:delete reports:read", resp.Scope)
+
+	claims, err := jwtService.ParseAccessToken(resp.AccessToken)
+	require.NoError(t, err)
+	assert.Empty(t, claims.Permissions)
+	assert.Equal(t, "users:delete reports:read", claims.Scope)
+
+	gin.SetMode(gin.TestMode)
+	authMiddleware := middleware.NewAuthMiddleware(service.NewAuthService(nil, jwtService, nil, nil, nil), nil, nil)
+	router := gin.New()
+	// RequirePermission validates the bearer token itself, so AuthRequired (and its API key lookup) is not needed here
+	router.DELETE("/admin/users/:id", authMiddleware.RequirePermission("users:delete"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/users/123", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.AccessToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestClientCredentialsGrant_ErrorKinds(t *testing.T) {
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+	oauth2Service := service.NewOAuth2Service(nil, jwtService)
+	ctx := context.Background()
+
+	client := &models.ClientApp{ClientID: "machine-client", IsConfidential: true, AllowedScopes: pq.StringArray{"reports:read"}}
+	_, err = oauth2Service.ClientCredentialsGrant(ctx, client, "reports:write", "")
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+
+	public := &models.ClientApp{ClientID: "spa", IsConfidential: false}
+	_, err = oauth2Service.ClientCredentialsGrant(ctx, public, "", "")
+	assert.ErrorIs(t, err, service.ErrUnauthorizedClient)
+}
@@ -0,0 +1,136 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+	"auth-service/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClientRegistrationService(t *testing.T) (service.ClientAppService, uuid.UUID) {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.InitialAccessToken{}, &models.ClientApp{}))
+
+	org := &models.Organization{ID: uuid.New(), Name: "Registration Org"}
+	require.NoError(t, testDB.DB.Create(org).Error)
+
+	return service.NewClientAppService(repository.NewRepository(testDB.DB)), org.ID
+}
+
+func issueInitialAccessToken(t *testing.T, svc service.ClientAppService, orgID uuid.UUID, scopes ...string) string {
+	t.Helper()
+	_, token, err := svc.CreateInitialAccessToken(context.Background(), orgID, &service.CreateInitialAccessTokenRequest{
+		Description:   "partner onboarding",
+		AllowedScopes: scopes,
+	}, uuid.New())
+	require.NoError(t, err)
+	return token
+}
+
+func machineClientRequest(scope string) *service.ClientRegistrationRequest {
+	return &service.ClientRegistrationRequest{
+		ClientName: "Partner Sync",
+		GrantTypes: []string{"client_credentials"},
+		Scope:      scope,
+	}
+}
+
+func TestClientRegistration_ScopesAreClamped(t *testing.T) {
+	svc, orgID := newClientRegistrationService(t)
+	ctx := context.Background()
+
+	// A token without a scope list only allows the default scopes
+	unrestricted := issueInitialAccessToken(t, svc, orgID)
+	_, err := svc.RegisterClient(ctx, unrestricted, machineClientRequest("users:delete"))
+	assert.ErrorIs(t, err, service.ErrInvalidClientMetadata)
+
+	resp, err := svc.RegisterClient(ctx, unrestricted, machineClientRequest(""))
+	require.NoError(t, err)
+	assert.Equal(t, "email profile org.read org.write", resp.Scope)
+
+	resp, err = svc.RegisterClient(ctx, unrestricted, machineClientRequest("org.read"))
+	require.NoError(t, err)
+	assert.Equal(t, "org.read", resp.Scope)
+
+	restricted := issueInitialAccessToken(t, svc, orgID, "orders:read")
+	_, err = svc.RegisterClient(ctx, restricted, machineClientRequest("orders:read orders:write"))
+	assert.ErrorIs(t, err, service.ErrInvalidClientMetadata)
+
+	resp, err = svc.RegisterClient(ctx, restricted, machineClientRequest(""))
+	require.NoError(t, err)
+	assert.Equal(t, "orders:read", resp.Scope)
+
+	// Updates stay within the registering token's scopes too
+	_, err = svc.UpdateClientRegistration(ctx, resp.ClientID, resp.RegistrationAccessToken, machineClientRequest("orders:write"))
+	assert.ErrorIs(t, err, service.ErrInvalidClientMetadata)
+
+	_, err = svc.RegisterClient(ctx, "iat_not-a-real-token", machineClientRequest(""))
+	assert.ErrorIs(t, err, service.ErrInvalidInitialAccessToken)
+}
+
+func TestClientRegistration_ReadUpdateDelete(t *testing.T) {
+	svc, orgID := newClientRegistrationService(t)
+	ctx := context.Background()
+	iat := issueInitialAccessToken(t, svc, orgID, "orders:read", "orders:write")
+
+	registered, err := svc.RegisterClient(ctx, iat, machineClientRequest("orders:read"))
+	require.NoError(t, err)
+	require.NotEmpty(t, registered.RegistrationAccessToken)
+	assert.NotEmpty(t, registered.ClientSecret)
+
+	other, err := svc.RegisterClient(ctx, iat, machineClientRequest("orders:read"))
+	require.NoError(t, err)
+
+	read, err := svc.GetClientRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "orders:read", read.Scope)
+	assert.Empty(t, read.ClientSecret, "the secret is only shown once")
+	assert.Empty(t, read.RegistrationAccessToken)
+
+	// Tokens only manage their own client, and the initial access token manages none
+	_, err = svc.GetClientRegistration(ctx, registered.ClientID, other.RegistrationAccessToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRegistrationToken)
+	_, err = svc.GetClientRegistration(ctx, registered.ClientID, iat)
+	assert.ErrorIs(t, err, service.ErrInvalidRegistrationToken)
+	_, err = svc.GetClientRegistration(ctx, registered.ClientID, "")
+	assert.ErrorIs(t, err, service.ErrInvalidRegistrationToken)
+
+	mismatched := machineClientRequest("orders:write")
+	mismatched.ClientID = other.ClientID
+	_, err = svc.UpdateClientRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken, mismatched)
+	assert.ErrorIs(t, err, service.ErrInvalidClientMetadata)
+
+	// An update rotates the registration access token
+	updated, err := svc.UpdateClientRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken, machineClientRequest("orders:read orders:write"))
+	require.NoError(t, err)
+	assert.Equal(t, "orders:read orders:write", updated.Scope)
+	require.NotEmpty(t, updated.RegistrationAccessToken)
+	assert.NotEqual(t, registered.RegistrationAccessToken, updated.RegistrationAccessToken)
+
+	_, err = svc.GetClientRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRegistrationToken, "the old token is rotated out")
+	err = svc.DeleteClientRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRegistrationToken)
+
+	_, err = svc.GetClientRegistration(ctx, registered.ClientID, updated.RegistrationAccessToken)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteClientRegistration(ctx, registered.ClientID, updated.RegistrationAccessToken))
+	_, err = svc.GetClientRegistration(ctx, registered.ClientID, updated.RegistrationAccessToken)
+	assert.ErrorIs(t, err, service.ErrInvalidRegistrationToken)
+
+	// Deleting one client leaves the others alone
+	_, err = svc.GetClientRegistration(ctx, other.ClientID, other.RegistrationAccessToken)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,157 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+	"auth-service/tests/testutils"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConsentRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+	return redisClient
+}
+
+func TestConsentTicket_SingleUse(t *testing.T) {
+	// Tickets live in Redis only, so no repository is needed
+	svc := service.NewConsentService(nil, newConsentRedis(t))
+	ctx := context.Background()
+
+	ticket := &service.ConsentTicket{
+		UserID:         uuid.New(),
+		OrganizationID: uuid.New(),
+		Params:         service.AuthorizationParams{ClientID: "web", Scope: "openid profile", State: "xyz"},
+		AuthTime:       time.Now().UTC().Truncate(time.Second),
+	}
+	ticketID, err := svc.CreateTicket(ctx, ticket)
+	require.NoError(t, err)
+
+	consumed, err := svc.ConsumeTicket(ctx, ticketID)
+	require.NoError(t, err)
+	assert.Equal(t, ticket.UserID, consumed.UserID)
+	assert.Equal(t, "xyz", consumed.Params.State)
+	assert.True(t, ticket.AuthTime.Equal(consumed.AuthTime))
+
+	_, err = svc.ConsumeTicket(ctx, ticketID)
+	assert.ErrorIs(t, err, service.ErrInvalidConsentTicket, "a ticket answers exactly one prompt")
+
+	_, err = svc.ConsumeTicket(ctx, "made-up")
+	assert.ErrorIs(t, err, service.ErrInvalidConsentTicket)
+}
+
+func TestConsentGrants_SkipAndRevoke(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.InitialAccessToken{}, &models.ClientApp{}, &models.OAuthRefreshToken{}, &models.OAuthConsentGrant{}))
+
+	user := testutils.CreateTestUser(t, testDB.DB, "consent@example.com")
+	org := testutils.CreateTestOrganization(t, testDB.DB, "Consent Org", "consent-org")
+	repo := repository.NewRepository(testDB.DB)
+	svc := service.NewConsentService(repo, newConsentRedis(t))
+	ctx := context.Background()
+
+	client := &models.ClientApp{Name: "Calendar", ClientID: "calendar", ClientSecret: "hashed", OrganizationID: org.ID}
+	require.NoError(t, repo.ClientApp().Create(ctx, client))
+
+	granted, err := svc.HasGrant(ctx, user.ID, "calendar", org.ID, "openid")
+	require.NoError(t, err)
+	assert.False(t, granted, "first authorization needs consent")
+
+	require.NoError(t, svc.Grant(ctx, user.ID, "calendar", org.ID, "openid profile"))
+	require.NoError(t, svc.Grant(ctx, user.ID, "calendar", org.ID, "email"))
+
+	granted, _ = svc.HasGrant(ctx, user.ID, "calendar", org.ID, "profile email")
+	assert.True(t, granted, "later grants add to earlier ones")
+	granted, _ = svc.HasGrant(ctx, user.ID, "calendar", org.ID, "openid offline_access")
+	assert.False(t, granted, "new scopes need consent again")
+	granted, _ = svc.HasGrant(ctx, user.ID, "calendar", uuid.New(), "openid")
+	assert.False(t, granted, "grants are per organization")
+
+	orgID := org.ID
+	require.NoError(t, repo.OAuthRefreshToken().Create(ctx, &models.OAuthRefreshToken{
+		TokenHash:      "consent-test-token-hash",
+		FamilyID:       uuid.New(),
+		ClientID:       "calendar",
+		UserID:         user.ID,
+		OrganizationID: &orgID,
+		Scope:          "openid profile",
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}))
+
+	apps, err := svc.ListConnectedApps(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+	assert.Equal(t, "Calendar", apps[0].ClientName)
+	assert.ElementsMatch(t, []string{"openid", "profile", "email"}, apps[0].Scopes)
+	assert.Equal(t, 1, apps[0].ActiveTokenFamilies)
+	assert.NotNil(t, apps[0].LastUsedAt)
+
+	require.NoError(t, svc.RevokeConnectedApp(ctx, user.ID, "calendar"))
+
+	apps, err = svc.ListConnectedApps(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, apps, "revoking removes the grant and every token family")
+
+	assert.ErrorIs(t, svc.RevokeConnectedApp(ctx, user.ID, "calendar"), service.ErrConnectedAppNotFound)
+}
+
+type stubConsentGrants struct {
+	repository.ConsentGrantRepository
+	err      error
+	upserted bool
+}
+
+func (r *stubConsentGrants) Get(context.Context, uuid.UUID, string, uuid.UUID) (*models.OAuthConsentGrant, error) {
+	return nil, r.err
+}
+
+func (r *stubConsentGrants) Upsert(context.Context, *models.OAuthConsentGrant) error {
+	r.upserted = true
+	return nil
+}
+
+type stubConsentRepo struct {
+	repository.Repository
+	grants *stubConsentGrants
+}
+
+func (r *stubConsentRepo) ConsentGrant() repository.ConsentGrantRepository { return r.grants }
+
+func TestConsentGrants_StorageErrorsPassThrough(t *testing.T) {
+	ctx := context.Background()
+	userID, orgID := uuid.New(), uuid.New()
+
+	missing := &stubConsentGrants{err: repository.ErrConsentGrantNotFound}
+	svc := service.NewConsentService(&stubConsentRepo{grants: missing}, newConsentRedis(t))
+	granted, err := svc.HasGrant(ctx, userID, "calendar", orgID, "openid")
+	require.NoError(t, err, "a missing grant only means the user has to be asked")
+	assert.False(t, granted)
+
+	broken := &stubConsentGrants{err: errors.New("connection refused")}
+	svc = service.NewConsentService(&stubConsentRepo{grants: broken}, newConsentRedis(t))
+	_, err = svc.HasGrant(ctx, userID, "calendar", orgID, "openid")
+	assert.Error(t, err)
+
+	// Granting on top of a grant that could not be read would drop its scopes
+	assert.Error(t, svc.Grant(ctx, userID, "calendar", orgID, "profile"))
+	assert.False(t, broken.upserted)
+}
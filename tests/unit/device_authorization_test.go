@@ -0,0 +1,190 @@
+package unit_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDeviceAuthorizationService(t *testing.T) (service.DeviceAuthorizationService, *miniredis.Miniredis) {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	return service.NewDeviceAuthorizationService(redisClient, "https://auth.example.com/api/v1/oauth/device"), mr
+}
+
+func TestDeviceAuthorization_ApproveThenPoll(t *testing.T) {
+	svc, _ := newDeviceAuthorizationService(t)
+	ctx := context.Background()
+	client := &models.ClientApp{ClientID: "cli", AllowedScopes: []string{"openid", "profile"}}
+
+	resp, err := svc.CreateDeviceAuthorization(ctx, client, "openid")
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z]{4}-[A-Z]{4}$`, resp.UserCode)
+	assert.Equal(t, "https://auth.example.com/api/v1/oauth/device?user_code="+resp.UserCode, resp.VerificationURIComplete)
+
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrAuthorizationPending)
+
+	// Polling again immediately violates the interval
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrSlowDown)
+
+	userID := uuid.New()
+	require.NoError(t, svc.Approve(ctx, "  "+resp.UserCode[:4]+resp.UserCode[5:], userID, nil, []string{"pwd"}))
+
+	_, err = svc.Poll(ctx, "other-client", resp.DeviceCode)
+	assert.Error(t, err, "device code must be bound to the requesting client")
+
+	auth, err := svc.Poll(ctx, "cli", resp.DeviceCode)
+	require.NoError(t, err)
+	assert.Equal(t, userID, auth.UserID)
+	assert.Equal(t, "openid", auth.Scope)
+
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrDeviceCodeExpired, "device code must only yield tokens once")
+}
+
+func TestDeviceAuthorization_DenyAndExpiry(t *testing.T) {
+	svc, mr := newDeviceAuthorizationService(t)
+	ctx := context.Background()
+	client := &models.ClientApp{ClientID: "tv"}
+
+	denied, err := svc.CreateDeviceAuthorization(ctx, client, "")
+	require.NoError(t, err)
+	require.NoError(t, svc.Deny(ctx, denied.UserCode))
+
+	_, err = svc.Poll(ctx, "tv", denied.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrDeviceAccessDenied)
+
+	assert.Error(t, svc.Approve(ctx, denied.UserCode, uuid.New(), nil, nil), "user code is single use")
+
+	expired, err := svc.CreateDeviceAuthorization(ctx, client, "")
+	require.NoError(t, err)
+	mr.FastForward(11 * time.Minute)
+
+	_, err = svc.Poll(ctx, "tv", expired.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrDeviceCodeExpired)
+}
+
+func TestDeviceAuthorization_RejectsDisallowedScope(t *testing.T) {
+	svc, _ := newDeviceAuthorizationService(t)
+
+	_, err := svc.CreateDeviceAuthorization(context.Background(), &models.ClientApp{ClientID: "cli", AllowedScopes: []string{"openid"}}, "openid admin")
+	assert.Error(t, err)
+}
+
+func TestDeviceAuthorization_PollingNeverOverwritesDecision(t *testing.T) {
+	svc, _ := newDeviceAuthorizationService(t)
+	ctx := context.Background()
+	client := &models.ClientApp{ClientID: "cli"}
+
+	resp, err := svc.CreateDeviceAuthorization(ctx, client, "")
+	require.NoError(t, err)
+	assert.Equal(t, 5, resp.Interval)
+
+	// Polls racing the approval only touch their own bookkeeping keys
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.Poll(ctx, "cli", resp.DeviceCode)
+		}()
+	}
+	require.NoError(t, svc.Approve(ctx, resp.UserCode, uuid.New(), nil, []string{"pwd"}))
+	wg.Wait()
+
+	// Only one of the concurrent redemptions gets the approval
+	var redeemed int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if auth, err := svc.Poll(ctx, "cli", resp.DeviceCode); err == nil && auth.Status == service.DeviceStatusApproved {
+				atomic.AddInt32(&redeemed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), redeemed)
+}
+
+func TestDeviceAuthorization_ConcurrentDecisionsKeepTheFirst(t *testing.T) {
+	svc, _ := newDeviceAuthorizationService(t)
+	ctx := context.Background()
+
+	resp, err := svc.CreateDeviceAuthorization(ctx, &models.ClientApp{ClientID: "cli"}, "")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var approved, denied int32
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if svc.Approve(ctx, resp.UserCode, uuid.New(), nil, nil) == nil {
+				atomic.AddInt32(&approved, 1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if svc.Deny(ctx, resp.UserCode) == nil {
+				atomic.AddInt32(&denied, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), approved+denied, "exactly one decision is recorded")
+
+	auth, err := svc.Poll(ctx, "cli", resp.DeviceCode)
+	if approved == 1 {
+		require.NoError(t, err)
+		assert.Equal(t, service.DeviceStatusApproved, auth.Status)
+	} else {
+		assert.ErrorIs(t, err, service.ErrDeviceAccessDenied)
+	}
+}
+
+func TestDeviceAuthorization_SlowDownGrowsInterval(t *testing.T) {
+	svc, mr := newDeviceAuthorizationService(t)
+	ctx := context.Background()
+
+	resp, err := svc.CreateDeviceAuthorization(ctx, &models.ClientApp{ClientID: "cli"}, "")
+	require.NoError(t, err)
+
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrAuthorizationPending)
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrSlowDown)
+
+	// The first interval has passed, so the next poll is on time and waits the longer interval
+	mr.FastForward(6 * time.Second)
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrAuthorizationPending)
+	mr.FastForward(6 * time.Second)
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrSlowDown, "the interval grew to 10 seconds")
+	mr.FastForward(5 * time.Second)
+	_, err = svc.Poll(ctx, "cli", resp.DeviceCode)
+	assert.ErrorIs(t, err, service.ErrAuthorizationPending)
+}
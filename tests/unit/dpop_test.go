@@ -0,0 +1,80 @@
+package unit_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDPoPService(t *testing.T) service.DPoPService {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	return service.NewDPoPService(redisClient, "https://auth.example.com/")
+}
+
+func dpopProof(t *testing.T, key *ecdsa.PrivateKey, method, htu string) string {
+	t.Helper()
+	token := gojwt.NewWithClaims(gojwt.SigningMethodES256, jwt.DPoPProofClaims{
+		HTM: method,
+		HTU: htu,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ID:       uuid.NewString(),
+			IssuedAt: gojwt.NewNumericDate(time.Now()),
+		},
+	})
+	token.Header["typ"] = jwt.DPoPProofType
+	token.Header["jwk"] = map[string]string{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestDPoP_ProofIsSingleUseAndKeyStable(t *testing.T) {
+	svc := newDPoPService(t)
+	ctx := context.Background()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	proof := dpopProof(t, key, "POST", "https://auth.example.com/api/v1/oauth/token")
+	jkt, err := svc.ValidateProof(ctx, proof, "POST", "/api/v1/oauth/token", "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, jkt)
+
+	_, err = svc.ValidateProof(ctx, proof, "POST", "/api/v1/oauth/token", "")
+	assert.ErrorIs(t, err, service.ErrInvalidDPoPProof, "a proof must not be accepted twice")
+
+	// Every proof from the same key yields the same thumbprint, which is what tokens are bound to
+	again, err := svc.ValidateProof(ctx, dpopProof(t, key, "POST", "https://auth.example.com/api/v1/oauth/token"), "POST", "/api/v1/oauth/token", "")
+	require.NoError(t, err)
+	assert.Equal(t, jkt, again)
+
+	_, err = svc.ValidateProof(ctx, dpopProof(t, key, "POST", "https://auth.example.com/api/v1/oauth/token"), "POST", "/api/v1/oauth/revoke", "")
+	assert.ErrorIs(t, err, service.ErrInvalidDPoPProof, "proof is bound to the request URL")
+}
@@ -0,0 +1,108 @@
+package unit_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"auth-service/internal/service"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturingMailer records the last sign-in secret instead of sending it
+type capturingMailer struct {
+	token string
+	code  string
+}
+
+func (m *capturingMailer) SendPasswordResetEmail(string, string) error              { return nil }
+func (m *capturingMailer) SendInvitationEmail(string, string, string, string) error { return nil }
+func (m *capturingMailer) SendVerificationEmail(string, string) error               { return nil }
+func (m *capturingMailer) SendLoginLinkEmail(_, token string) error {
+	m.token = token
+	return nil
+}
+func (m *capturingMailer) SendLoginCodeEmail(_, code string) error {
+	m.code = code
+	return nil
+}
+
+func TestEmailLogin_LinkIsSingleUse(t *testing.T) {
+	mailer := &capturingMailer{}
+	svc := service.NewEmailLoginService(newConsentRedis(t), mailer)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	require.NoError(t, svc.Send(ctx, userID, "Link@Example.com", service.EmailLoginMethodLink))
+	require.NotEmpty(t, mailer.token)
+
+	got, err := svc.Redeem(ctx, "link@example.com", mailer.token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	_, err = svc.Redeem(ctx, "link@example.com", mailer.token)
+	assert.ErrorIs(t, err, service.ErrInvalidEmailLoginCode, "a link cannot be replayed")
+}
+
+func TestEmailLogin_CodeAttemptsAndReplacement(t *testing.T) {
+	mailer := &capturingMailer{}
+	svc := service.NewEmailLoginService(newConsentRedis(t), mailer)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	require.NoError(t, svc.Send(ctx, userID, "code@example.com", service.EmailLoginMethodCode))
+	assert.Len(t, mailer.code, 6)
+	first := mailer.code
+
+	// A new request replaces the outstanding code
+	require.NoError(t, svc.Send(ctx, userID, "code@example.com", service.EmailLoginMethodCode))
+	if first != mailer.code {
+		_, err := svc.Redeem(ctx, "code@example.com", first)
+		assert.ErrorIs(t, err, service.ErrInvalidEmailLoginCode)
+	}
+
+	// Wrong guesses report the user so they count towards lockout, then burn the code
+	require.NoError(t, svc.Send(ctx, userID, "code@example.com", service.EmailLoginMethodCode))
+	for i := 0; i < 4; i++ {
+		got, err := svc.Redeem(ctx, "code@example.com", "not-it")
+		assert.ErrorIs(t, err, service.ErrInvalidEmailLoginCode)
+		assert.Equal(t, userID, got)
+	}
+	got, err := svc.Redeem(ctx, "code@example.com", mailer.code)
+	require.NoError(t, err, "still valid after four wrong guesses")
+	assert.Equal(t, userID, got)
+
+	require.NoError(t, svc.Send(ctx, userID, "code@example.com", service.EmailLoginMethodCode))
+	for i := 0; i < 5; i++ {
+		_, _ = svc.Redeem(ctx, "code@example.com", "not-it")
+	}
+	_, err = svc.Redeem(ctx, "code@example.com", mailer.code)
+	assert.ErrorIs(t, err, service.ErrInvalidEmailLoginCode, "too many wrong guesses invalidate the code")
+
+	assert.ErrorIs(t, svc.Send(ctx, userID, "code@example.com", "sms"), service.ErrInvalidData)
+}
+
+func TestEmailLogin_ConcurrentGuessesBurnTheCode(t *testing.T) {
+	mailer := &capturingMailer{}
+	svc := service.NewEmailLoginService(newConsentRedis(t), mailer)
+	ctx := context.Background()
+
+	require.NoError(t, svc.Send(ctx, uuid.New(), "race@example.com", service.EmailLoginMethodCode))
+
+	// Every parallel guess counts, so none of them can keep the code alive
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.Redeem(ctx, "race@example.com", "not-it")
+		}()
+	}
+	wg.Wait()
+
+	_, err := svc.Redeem(ctx, "race@example.com", mailer.code)
+	assert.ErrorIs(t, err, service.ErrInvalidEmailLoginCode)
+}
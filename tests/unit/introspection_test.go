@@ -0,0 +1,196 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/handler"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOAuthRefreshTokens knows a single refresh token, stored under the HMAC of raw
+type stubOAuthRefreshTokens struct {
+	repository.OAuthRefreshTokenRepository
+	err             error
+	raw             string
+	token           *models.OAuthRefreshToken
+	revokedFamilies []uuid.UUID
+}
+
+func (r *stubOAuthRefreshTokens) GetByTokenHash(_ context.Context, tokenHashes []string) (*models.OAuthRefreshToken, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.token != nil {
+		hash, _ := hashutil.HMACHash(r.raw)
+		for _, candidate := range tokenHashes {
+			if candidate == hash {
+				return r.token, nil
+			}
+		}
+	}
+	return nil, repository.ErrOAuthRefreshTokenNotFound
+}
+
+func (r *stubOAuthRefreshTokens) RevokeTokenFamily(_ context.Context, familyID uuid.UUID) error {
+	r.revokedFamilies = append(r.revokedFamilies, familyID)
+	return nil
+}
+
+type stubOAuthRepo struct {
+	repository.Repository
+	refreshTokens *stubOAuthRefreshTokens
+}
+
+func (r *stubOAuthRepo) OAuthRefreshToken() repository.OAuthRefreshTokenRepository {
+	return r.refreshTokens
+}
+
+// stubClientApps authenticates every client_id listed in clients, whatever the secret
+type stubClientApps struct {
+	service.ClientAppService
+	clients map[string]*models.ClientApp
+}
+
+func (s *stubClientApps) ValidateClientCredentials(_ context.Context, clientID, _ string) (*models.ClientApp, error) {
+	if clientApp, ok := s.clients[clientID]; ok {
+		return clientApp, nil
+	}
+	return nil, errors.New("invalid client credentials")
+}
+
+func newIntrospectionService(t *testing.T, repoErr error) (service.IntrospectionService, *jwt.Service) {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+
+	repo := &stubOAuthRepo{refreshTokens: &stubOAuthRefreshTokens{err: repoErr}}
+	revocation := service.NewRevocationService(repo, jwtService, newConsentRedis(t))
+	return service.NewIntrospectionService(repo, jwtService, revocation), jwtService
+}
+
+func issueClientAccessToken(t *testing.T, jwtService *jwt.Service, clientID, audience string) string {
+	t.Helper()
+	return issueOrgAccessToken(t, jwtService, clientID, audience, uuid.New())
+}
+
+func issueOrgAccessToken(t *testing.T, jwtService *jwt.Service, clientID, audience string, orgID uuid.UUID) string {
+	t.Helper()
+	token, err := jwtService.GenerateOAuthAccessToken(&jwt.OAuthTokenContext{
+		UserID:         uuid.New(),
+		Email:          "user@example.com",
+		Scopes:         []string{"orders:read"},
+		Issuer:         "https://auth.example.com",
+		Audience:       audience,
+		Subject:        uuid.NewString(),
+		ClientID:       clientID,
+		OrganizationID: &orgID,
+		ExpiresAt:      time.Now().Add(10 * time.Minute),
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestIntrospect_OnlyRelatedOrPrivilegedClientsSeeAccessTokens(t *testing.T) {
+	svc, jwtService := newIntrospectionService(t, repository.ErrOAuthRefreshTokenNotFound)
+	ctx := context.Background()
+	orgID := uuid.New()
+	token := issueOrgAccessToken(t, jwtService, "orders-app", "orders-api", orgID)
+	introspect := pq.StringArray{service.ScopeTokenIntrospection}
+
+	for _, tc := range []struct {
+		name   string
+		caller *models.ClientApp
+		active bool
+	}{
+		{"issued to the caller", &models.ClientApp{ClientID: "orders-app"}, true},
+		{"caller is the audience", &models.ClientApp{ClientID: "orders-api"}, true},
+		{"unrelated client", &models.ClientApp{ClientID: "billing-app"}, false},
+		{"unrelated client with the introspection scope", &models.ClientApp{ClientID: "gateway", OrganizationID: orgID, AllowedScopes: introspect}, true},
+		{"introspection scope in another organization", &models.ClientApp{ClientID: "gateway", OrganizationID: uuid.New(), AllowedScopes: introspect}, false},
+		{"no caller", nil, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := svc.Introspect(ctx, token, service.TokenTypeHintAccessToken, tc.caller)
+			require.NoError(t, err)
+			assert.Equal(t, tc.active, resp.Active)
+			if !tc.active {
+				assert.Empty(t, resp.ClientID, "inactive responses carry nothing else")
+			}
+		})
+	}
+}
+
+func TestIntrospect_RefreshTokenLookupErrors(t *testing.T) {
+	ctx := context.Background()
+	caller := &models.ClientApp{ClientID: "orders-app"}
+
+	svc, _ := newIntrospectionService(t, repository.ErrOAuthRefreshTokenNotFound)
+	resp, err := svc.Introspect(ctx, "opaque-refresh-token", service.TokenTypeHintRefreshToken, caller)
+	require.NoError(t, err)
+	assert.False(t, resp.Active, "an unknown token is inactive")
+
+	svc, _ = newIntrospectionService(t, errors.New("connection refused"))
+	_, err = svc.Introspect(ctx, "opaque-refresh-token", service.TokenTypeHintRefreshToken, caller)
+	assert.Error(t, err, "a failed lookup must not be reported as an inactive token")
+}
+
+func TestIntrospectHandler_DatabaseErrorIsServerError(t *testing.T) {
+	svc, _ := newIntrospectionService(t, errors.New("connection refused"))
+	clientApps := &stubClientApps{clients: map[string]*models.ClientApp{
+		"orders-app": {ClientID: "orders-app", IsConfidential: true},
+	}}
+	h := handler.NewOAuth2Handler(nil, clientApps, nil, nil, svc, nil, nil, nil, nil, nil, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/oauth/introspect", h.Introspect)
+
+	form := url.Values{"token": {"opaque-refresh-token"}, "client_id": {"orders-app"}, "client_secret": {"secret"}}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "server_error", body["error"])
+}
+
+func TestIntrospectionScope_OnlySuperadminsGrantIt(t *testing.T) {
+	svc := service.NewClientAppService(nil)
+	ctx := context.Background()
+	owner := &models.User{ID: uuid.New()}
+
+	_, _, err := svc.CreateClientApp(ctx, uuid.New(), &service.CreateClientAppRequest{
+		Name:          "Gateway",
+		AllowedScopes: []string{"email", service.ScopeTokenIntrospection},
+	}, owner)
+	assert.ErrorIs(t, err, service.ErrInvalidData)
+
+	_, _, err = svc.CreateInitialAccessToken(ctx, uuid.New(), &service.CreateInitialAccessTokenRequest{
+		AllowedScopes: []string{service.ScopeTokenIntrospection},
+	}, owner.ID)
+	assert.ErrorIs(t, err, service.ErrInvalidData, "clients registered with the token could claim the scope")
+}
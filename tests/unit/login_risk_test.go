@@ -0,0 +1,102 @@
+package unit_test
+
+import (
+	"testing"
+	"time"
+
+	"auth-service/internal/service"
+	"auth-service/pkg/geoip"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	london = &geoip.Location{CountryCode: "GB", City: "London", Latitude: 51.5142, Longitude: -0.0931, ASN: 20712}
+	berlin = &geoip.Location{CountryCode: "DE", City: "Berlin", Latitude: 52.5244, Longitude: 13.4105, ASN: 3320}
+	sydney = &geoip.Location{CountryCode: "AU", City: "Sydney", Latitude: -33.8688, Longitude: 151.2093, ASN: 1221}
+)
+
+// signalsAfter describes a login at location some time after a session started in London
+func signalsAfter(location *geoip.Location, since time.Duration) *service.LoginRiskSignals {
+	now := time.Now()
+	return &service.LoginRiskSignals{
+		At:               now,
+		Location:         location,
+		HasHistory:       true,
+		PreviousLocation: london,
+		PreviousLoginAt:  now.Add(-since),
+		KnownCountries:   map[string]bool{"GB": true},
+		KnownASNs:        map[uint32]bool{20712: true},
+	}
+}
+
+func TestLoginRisk_FamiliarLoginIsAllowed(t *testing.T) {
+	assessment := service.DefaultLoginRiskConfig().Score(signalsAfter(london, time.Hour))
+	assert.Equal(t, service.LoginRiskAllow, assessment.Decision)
+	assert.Zero(t, assessment.Score)
+	assert.Empty(t, assessment.Reasons)
+}
+
+func TestLoginRisk_NewCountryAloneIsAllowed(t *testing.T) {
+	// London to Berlin in two days is plausible travel
+	assessment := service.DefaultLoginRiskConfig().Score(signalsAfter(berlin, 48*time.Hour))
+	assert.Equal(t, service.LoginRiskAllow, assessment.Decision)
+	assert.ElementsMatch(t, []string{service.RiskReasonNewCountry, service.RiskReasonNewASN}, assessment.Reasons)
+}
+
+func TestLoginRisk_ImpossibleTravelRequiresStepUp(t *testing.T) {
+	// About 930 km in half an hour
+	signals := signalsAfter(berlin, 30*time.Minute)
+	signals.KnownCountries["DE"] = true
+	signals.KnownASNs[3320] = true
+
+	assessment := service.DefaultLoginRiskConfig().Score(signals)
+	assert.Equal(t, service.LoginRiskStepUp, assessment.Decision)
+	assert.Equal(t, []string{service.RiskReasonImpossibleTravel}, assessment.Reasons)
+}
+
+func TestLoginRisk_SeveralSignalsBlock(t *testing.T) {
+	signals := signalsAfter(sydney, 2*time.Hour)
+	signals.NewDevice = true
+
+	assessment := service.DefaultLoginRiskConfig().Score(signals)
+	assert.Equal(t, service.LoginRiskBlock, assessment.Decision)
+	assert.ElementsMatch(t, []string{
+		service.RiskReasonImpossibleTravel,
+		service.RiskReasonNewCountry,
+		service.RiskReasonNewASN,
+		service.RiskReasonNewDevice,
+	}, assessment.Reasons)
+
+	// Blocking can be turned off, leaving a step-up
+	cfg := service.DefaultLoginRiskConfig()
+	cfg.BlockScore = 0
+	assert.Equal(t, service.LoginRiskStepUp, cfg.Score(signals).Decision)
+}
+
+func TestLoginRisk_NewDeviceAfterFailedBurstRequiresStepUp(t *testing.T) {
+	signals := signalsAfter(london, time.Hour)
+	signals.NewDevice = true
+	signals.RecentFailures = 4
+
+	assessment := service.DefaultLoginRiskConfig().Score(signals)
+	assert.Equal(t, service.LoginRiskStepUp, assessment.Decision)
+	assert.ElementsMatch(t, []string{service.RiskReasonNewDevice, service.RiskReasonFailedAttempts}, assessment.Reasons)
+}
+
+func TestLoginRisk_NoHistoryOrLocation(t *testing.T) {
+	cfg := service.DefaultLoginRiskConfig()
+
+	// A first login has nothing to be new relative to
+	first := &service.LoginRiskSignals{At: time.Now(), Location: sydney, NewDevice: true}
+	assert.Equal(t, service.LoginRiskAllow, cfg.Score(first).Decision)
+	assert.Empty(t, cfg.Score(first).Reasons)
+
+	// Without GeoIP only the device and failed attempts count
+	unplaced := signalsAfter(nil, time.Minute)
+	unplaced.PreviousLocation = nil
+	unplaced.NewDevice = true
+	assessment := cfg.Score(unplaced)
+	assert.Equal(t, []string{service.RiskReasonNewDevice}, assessment.Reasons)
+	assert.Equal(t, service.LoginRiskAllow, assessment.Decision)
+}
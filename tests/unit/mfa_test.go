@@ -0,0 +1,207 @@
+package unit_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/totp"
+	"auth-service/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAChallenge_RequiresVerification(t *testing.T) {
+	// Creating and checking challenges only touches Redis
+	svc := service.NewMFAService(nil, newConsentRedis(t), "Auth Service")
+	ctx := context.Background()
+	userID := uuid.New()
+
+	challenge, err := svc.CreateChallenge(ctx, userID)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.CheckChallenge(ctx, challenge, userID), service.ErrMFARequired, "an unredeemed challenge proves nothing")
+	assert.ErrorIs(t, svc.CheckChallenge(ctx, "", userID), service.ErrMFARequired)
+	assert.ErrorIs(t, svc.CheckChallenge(ctx, "made-up", userID), service.ErrMFARequired)
+
+	_, err = svc.VerifyChallenge(ctx, "made-up", "123456")
+	assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+}
+
+func TestMFA_EnrollmentAndLoginChallenge(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.UserMFA{}, &models.MFARecoveryCode{}))
+
+	user := testutils.CreateTestUser(t, testDB.DB, "mfa@example.com")
+	repo := repository.NewRepository(testDB.DB)
+	svc := service.NewMFAService(repo, newConsentRedis(t), "Auth Service")
+	ctx := context.Background()
+
+	enrollment, err := svc.BeginEnrollment(ctx, user.ID, user.Email)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+
+	enabled, err := svc.IsEnabled(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled, "MFA stays off until a code is confirmed")
+
+	code, err := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	recoveryCodes, err := svc.ConfirmEnrollment(ctx, user.ID, code)
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	// The code that confirmed enrollment cannot be replayed for login
+	challenge, err := svc.CreateChallenge(ctx, user.ID)
+	require.NoError(t, err)
+	_, err = svc.VerifyChallenge(ctx, challenge, code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	// A recovery code works once, in any case and with or without the dash
+	userID, err := svc.VerifyChallenge(ctx, challenge, "  "+recoveryCodes[0]+" ")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+	require.NoError(t, svc.CheckChallenge(ctx, challenge, user.ID))
+	assert.ErrorIs(t, svc.CheckChallenge(ctx, challenge, uuid.New()), service.ErrMFARequired, "challenges are bound to their user")
+
+	_, err = svc.VerifyCode(ctx, user.ID, recoveryCodes[0])
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode, "recovery codes are single use")
+
+	status, err := svc.Status(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, status.Enabled)
+	assert.EqualValues(t, 9, status.RecoveryCodesRemaining)
+
+	// Turning MFA off takes a current second factor, not just the session
+	assert.ErrorIs(t, svc.Disable(ctx, user.ID, ""), service.ErrInvalidMFACode)
+	assert.ErrorIs(t, svc.Disable(ctx, user.ID, "not-a-code"), service.ErrInvalidMFACode)
+	assert.ErrorIs(t, svc.Disable(ctx, user.ID, recoveryCodes[0]), service.ErrInvalidMFACode)
+	require.NoError(t, svc.Disable(ctx, user.ID, recoveryCodes[1]))
+	enabled, err = svc.IsEnabled(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+// stubMFA reports MFA as enabled and accepts a single code
+type stubMFA struct {
+	service.MFAService
+	code string
+}
+
+func (s *stubMFA) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return s.code != "", nil
+}
+
+func (s *stubMFA) VerifyCode(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	if code != s.code {
+		return false, service.ErrInvalidMFACode
+	}
+	return false, nil
+}
+
+func TestVerifySecondFactor_ReportsAMR(t *testing.T) {
+	userSvc := service.NewUserService(nil, nil, nil)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Without MFA the OAuth sign-in is a password alone
+	userSvc.SetMFAService(&stubMFA{})
+	amr, err := userSvc.VerifySecondFactor(ctx, userID, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{jwt.AMRPassword}, amr)
+	assert.Equal(t, jwt.ACRSingleFactor, jwt.ACRForAMR(amr))
+
+	userSvc.SetMFAService(&stubMFA{code: "123456"})
+	_, err = userSvc.VerifySecondFactor(ctx, userID, "")
+	assert.ErrorIs(t, err, service.ErrMFARequired)
+
+	// A checked code makes the tokens issued for the sign-in multi-factor
+	amr, err = userSvc.VerifySecondFactor(ctx, userID, "123456")
+	require.NoError(t, err)
+	assert.Equal(t, []string{jwt.AMRPassword, jwt.AMROTP}, amr)
+	assert.Equal(t, jwt.ACRMultiFactor, jwt.ACRForAMR(amr))
+}
+
+// stubMFARepository has MFA enabled for every user and one valid recovery code, "good-code"
+type stubMFARepository struct {
+	repository.MFARepository
+	codeChecks int64
+}
+
+func (r *stubMFARepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	return &models.UserMFA{UserID: userID, Enabled: true}, nil
+}
+
+func (r *stubMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	atomic.AddInt64(&r.codeChecks, 1)
+	good, err := hashutil.HMACHash("goodcode")
+	if err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if hash == good {
+			return nil
+		}
+	}
+	return repository.ErrRecoveryCodeNotFound
+}
+
+type stubMFARepo struct {
+	repository.Repository
+	mfa *stubMFARepository
+}
+
+func (r *stubMFARepo) MFA() repository.MFARepository { return r.mfa }
+
+func TestMFAChallenge_ConcurrentGuessesAreCounted(t *testing.T) {
+	hashutil.SetHMACSecret("test-hmac-secret")
+	mfaRepo := &stubMFARepository{}
+	svc := service.NewMFAService(&stubMFARepo{mfa: mfaRepo}, newConsentRedis(t), "Auth Service")
+	ctx := context.Background()
+
+	challenge, err := svc.CreateChallenge(ctx, uuid.New())
+	require.NoError(t, err)
+
+	// Far more parallel guesses than the challenge allows
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.VerifyChallenge(ctx, challenge, "wrong-code")
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 5, atomic.LoadInt64(&mfaRepo.codeChecks), "only the allowed number of codes is ever checked")
+	_, err = svc.VerifyChallenge(ctx, challenge, "good-code")
+	assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge, "the exhausted challenge is gone")
+}
+
+func TestMFAChallenge_AttemptsBeforeSuccess(t *testing.T) {
+	hashutil.SetHMACSecret("test-hmac-secret")
+	svc := service.NewMFAService(&stubMFARepo{mfa: &stubMFARepository{}}, newConsentRedis(t), "Auth Service")
+	ctx := context.Background()
+	userID := uuid.New()
+
+	challenge, err := svc.CreateChallenge(ctx, userID)
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, err = svc.VerifyChallenge(ctx, challenge, "wrong-code")
+		assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	}
+
+	verified, err := svc.VerifyChallenge(ctx, challenge, "good-code")
+	require.NoError(t, err)
+	assert.Equal(t, userID, verified)
+	assert.NoError(t, svc.CheckChallenge(ctx, challenge, userID))
+}
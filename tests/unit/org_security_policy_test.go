@@ -0,0 +1,95 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityPolicy_ParseAndUpdate(t *testing.T) {
+	org := &models.Organization{Settings: `{"theme":"dark"}`}
+
+	policy, err := org.SecurityPolicy()
+	require.NoError(t, err)
+	assert.False(t, policy.RequiresMFA("admin"), "no policy requires nothing")
+
+	enforcedAt := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, org.SetSecurityPolicy(&models.SecurityPolicy{MFARequiredRoles: []string{"admin"}, MFAEnrollmentGraceDays: 7}, enforcedAt))
+
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(org.Settings), &raw))
+	assert.Equal(t, "dark", raw["theme"], "other settings are kept")
+
+	policy, err = org.SecurityPolicy()
+	require.NoError(t, err)
+	assert.True(t, policy.RequiresMFA("Admin"))
+	assert.False(t, policy.RequiresMFA("student"))
+	require.NotNil(t, policy.MFAEnforcedSince)
+
+	// Grace runs from the later of joining and the policy being switched on
+	joinedEarlier := enforcedAt.AddDate(-1, 0, 0)
+	assert.Equal(t, enforcedAt.AddDate(0, 0, 7), policy.MFAEnrollmentDeadline(joinedEarlier))
+	joinedLater := enforcedAt.AddDate(0, 1, 0)
+	assert.Equal(t, joinedLater.AddDate(0, 0, 7), policy.MFAEnrollmentDeadline(joinedLater))
+
+	// Widening the requirement keeps the original enforcement date
+	require.NoError(t, org.SetSecurityPolicy(&models.SecurityPolicy{MFARequired: true}, enforcedAt.AddDate(0, 2, 0)))
+	policy, err = org.SecurityPolicy()
+	require.NoError(t, err)
+	assert.True(t, policy.RequiresMFA("student"))
+	assert.True(t, policy.MFAEnforcedSince.Equal(enforcedAt))
+
+	assert.Error(t, org.SetSecurityPolicy(&models.SecurityPolicy{MFAEnrollmentGraceDays: -1}, enforcedAt))
+}
+
+func TestEnforceMFAPolicy(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.UserMFA{}, &models.MFARecoveryCode{}))
+
+	user := testutils.CreateTestUser(t, testDB.DB, "policy@example.com")
+	org := testutils.CreateTestOrganization(t, testDB.DB, "Policy Org", "policy-org")
+	role := testutils.CreateTestRole(t, testDB.DB, org.ID, "admin")
+
+	repo := repository.NewRepository(testDB.DB)
+	userSvc := service.NewUserService(repo, nil, nil)
+	userSvc.SetMFAService(service.NewMFAService(repo, newConsentRedis(t), "Auth Service"))
+	ctx := context.Background()
+
+	joined := time.Now().Add(-48 * time.Hour)
+	membership := &models.OrganizationMembership{
+		OrganizationID: org.ID,
+		UserID:         user.ID,
+		RoleID:         role.ID,
+		JoinedAt:       &joined,
+		Organization:   org,
+		Role:           role,
+	}
+
+	deadline, err := userSvc.EnforceMFAPolicy(ctx, membership)
+	require.NoError(t, err)
+	assert.Nil(t, deadline, "no policy configured")
+
+	require.NoError(t, org.SetSecurityPolicy(&models.SecurityPolicy{MFARequiredRoles: []string{"admin"}, MFAEnrollmentGraceDays: 7}, joined))
+	deadline, err = userSvc.EnforceMFAPolicy(ctx, membership)
+	require.NoError(t, err)
+	require.NotNil(t, deadline, "member is inside the grace period")
+	assert.WithinDuration(t, joined.AddDate(0, 0, 7), *deadline, time.Second)
+
+	require.NoError(t, org.SetSecurityPolicy(&models.SecurityPolicy{MFARequiredRoles: []string{"admin"}, MFAEnrollmentGraceDays: 1}, joined))
+	_, err = userSvc.EnforceMFAPolicy(ctx, membership)
+	assert.ErrorIs(t, err, service.ErrMFAEnrollmentRequired)
+
+	require.NoError(t, testDB.DB.Create(&models.UserMFA{UserID: user.ID, SecretEnc: "x", Enabled: true}).Error)
+	deadline, err = userSvc.EnforceMFAPolicy(ctx, membership)
+	require.NoError(t, err)
+	assert.Nil(t, deadline, "enrolled members are let through")
+}
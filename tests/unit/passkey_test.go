@@ -0,0 +1,177 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/webauthn"
+	"auth-service/pkg/webauthn/webauthntest"
+	"auth-service/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRelyingParty() *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{ID: "localhost", Name: "Auth Service", Origins: []string{"http://localhost:3000"}}
+}
+
+func passkeyAssertion(resp *webauthntest.AssertionResponse) *service.PasskeyAssertion {
+	b64 := base64.RawURLEncoding.EncodeToString
+	a := &service.PasskeyAssertion{ID: b64(resp.CredentialID), Type: "public-key"}
+	a.Response.ClientDataJSON = b64(resp.ClientDataJSON)
+	a.Response.AuthenticatorData = b64(resp.AuthenticatorData)
+	a.Response.Signature = b64(resp.Signature)
+	a.Response.UserHandle = b64(resp.UserHandle)
+	return a
+}
+
+func TestPasskeyLogin_UnknownChallengeRejected(t *testing.T) {
+	// The challenge is checked before any credential lookup, so no repository is needed
+	svc := service.NewPasskeyService(nil, newConsentRedis(t), testRelyingParty())
+	authenticator := webauthntest.New("localhost", "http://localhost:3000")
+
+	att, err := authenticator.Create("bogus-challenge", []byte("user"))
+	require.NoError(t, err)
+	resp, err := authenticator.Get("never-issued", att.CredentialID)
+	require.NoError(t, err)
+
+	_, err = svc.FinishLogin(context.Background(), passkeyAssertion(resp))
+	assert.ErrorIs(t, err, service.ErrInvalidPasskeyChallenge)
+}
+
+func TestPasskey_RegisterAndLogin(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.WebAuthnCredential{}))
+
+	user := testutils.CreateTestUser(t, testDB.DB, "passkey@example.com")
+	repo := repository.NewRepository(testDB.DB)
+	svc := service.NewPasskeyService(repo, newConsentRedis(t), testRelyingParty())
+	authenticator := webauthntest.New("localhost", "http://localhost:3000")
+	ctx := context.Background()
+
+	creation, err := svc.BeginRegistration(ctx, user.ID, user.Email, "")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", creation.RP.ID)
+
+	att, err := authenticator.Create(creation.Challenge, user.ID[:])
+	require.NoError(t, err)
+	req := &service.PasskeyRegistrationRequest{Name: "Laptop", ID: base64.RawURLEncoding.EncodeToString(att.CredentialID), Type: "public-key"}
+	req.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(att.ClientDataJSON)
+	req.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(att.AttestationObject)
+
+	cred, err := svc.FinishRegistration(ctx, user.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop", cred.Name)
+
+	_, err = svc.FinishRegistration(ctx, user.ID, req)
+	assert.ErrorIs(t, err, service.ErrInvalidPasskeyChallenge, "registration challenges are single use")
+
+	request, err := svc.BeginLogin(ctx)
+	require.NoError(t, err)
+	resp, err := authenticator.Get(request.Challenge, att.CredentialID)
+	require.NoError(t, err)
+
+	userID, err := svc.FinishLogin(ctx, passkeyAssertion(resp))
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, userID)
+
+	_, err = svc.FinishLogin(ctx, passkeyAssertion(resp))
+	assert.ErrorIs(t, err, service.ErrInvalidPasskeyChallenge, "an assertion cannot be replayed")
+
+	// A second copy of the key that lags behind the counter looks like a clone
+	request, err = svc.BeginLogin(ctx)
+	require.NoError(t, err)
+	authenticator.SetSignCount(att.CredentialID, 0)
+	resp, err = authenticator.Get(request.Challenge, att.CredentialID)
+	require.NoError(t, err)
+	_, err = svc.FinishLogin(ctx, passkeyAssertion(resp))
+	assert.ErrorIs(t, err, service.ErrPasskeyCloned)
+
+	require.NoError(t, svc.DeleteCredential(ctx, user.ID, cred.ID))
+	assert.ErrorIs(t, svc.DeleteCredential(ctx, uuid.New(), cred.ID), service.ErrPasskeyNotFound)
+}
+
+type stubPasskeys struct {
+	service.PasskeyService
+	userID uuid.UUID
+}
+
+func (s *stubPasskeys) FinishLogin(_ context.Context, _ *service.PasskeyAssertion) (uuid.UUID, error) {
+	return s.userID, nil
+}
+
+type stubPasskeyUsers struct {
+	repository.UserRepository
+	user *models.User
+}
+
+func (r *stubPasskeyUsers) GetByID(_ context.Context, id string) (*models.User, error) {
+	if id != r.user.ID.String() {
+		return nil, errors.New("user not found")
+	}
+	return r.user, nil
+}
+
+type stubPasskeyRepo struct {
+	repository.Repository
+	users *stubPasskeyUsers
+}
+
+func (r *stubPasskeyRepo) User() repository.UserRepository {
+	return r.users
+}
+
+type stubLoginRisk struct {
+	decision service.LoginRiskDecision
+	inputs   []*service.LoginRiskInput
+}
+
+func (r *stubLoginRisk) Evaluate(_ context.Context, login *service.LoginRiskInput) *service.LoginRiskAssessment {
+	r.inputs = append(r.inputs, login)
+	return &service.LoginRiskAssessment{Decision: r.decision}
+}
+
+func (r *stubLoginRisk) RecordLogin(_ context.Context, _ uuid.UUID, _ string) {}
+
+func TestPasskeyLogin_RiskyLoginsAreBlocked(t *testing.T) {
+	verifiedAt := time.Now()
+	user := &models.User{ID: uuid.New(), Email: "passkey@example.com", Status: models.UserStatusActive, EmailVerifiedAt: &verifiedAt}
+
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+	risk := &stubLoginRisk{decision: service.LoginRiskBlock}
+	userSvc := service.NewUserService(&stubPasskeyRepo{users: &stubPasskeyUsers{user: user}}, jwtService, nil)
+	userSvc.SetRedisClient(newConsentRedis(t))
+	userSvc.SetPasskeyService(&stubPasskeys{userID: user.ID})
+	userSvc.SetLoginRiskService(risk)
+	ctx := context.Background()
+
+	_, err = userSvc.LoginWithPasskey(ctx, &service.PasskeyLoginRequest{ClientIP: "89.160.20.130"})
+	assert.ErrorIs(t, err, service.ErrLoginBlocked)
+
+	// Re-authenticating with a passkey is scored the same way
+	accessToken, err := jwtService.GenerateAccessToken(&jwt.TokenContext{UserID: user.ID, OrganizationID: uuid.New(), SessionID: uuid.New(), Email: user.Email})
+	require.NoError(t, err)
+	_, err = userSvc.Reauthenticate(ctx, &service.ReauthenticateRequest{
+		Passkey:     &service.PasskeyAssertion{},
+		AccessToken: accessToken,
+		ClientIP:    "89.160.20.130",
+	})
+	assert.ErrorIs(t, err, service.ErrLoginBlocked)
+
+	require.Len(t, risk.inputs, 2)
+	for _, input := range risk.inputs {
+		assert.Equal(t, user.ID, input.UserID)
+		assert.Equal(t, "89.160.20.130", input.IPAddress)
+	}
+}
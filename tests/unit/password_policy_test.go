@@ -0,0 +1,102 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/password"
+	"auth-service/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_StrictestAndExpiry(t *testing.T) {
+	platform := models.PasswordPolicy{HistoryCount: 3, MaxAgeDays: 180}
+
+	merged := platform.Strictest(models.PasswordPolicy{HistoryCount: 5})
+	assert.Equal(t, models.PasswordPolicy{HistoryCount: 5, MaxAgeDays: 180}, merged, "an org without a max age keeps the default")
+
+	merged = merged.Strictest(models.PasswordPolicy{HistoryCount: 1, MaxAgeDays: 90})
+	assert.Equal(t, models.PasswordPolicy{HistoryCount: 5, MaxAgeDays: 90}, merged)
+
+	merged = merged.Strictest(models.PasswordPolicy{HistoryCount: 1000})
+	assert.Equal(t, models.MaxPasswordHistory, merged.HistoryCount)
+
+	setAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, merged.Expired(setAt, setAt.AddDate(0, 0, 89)))
+	assert.True(t, merged.Expired(setAt, setAt.AddDate(0, 0, 90)))
+	assert.False(t, models.PasswordPolicy{}.Expired(setAt, setAt.AddDate(10, 0, 0)), "no max age never expires")
+
+	org := &models.Organization{}
+	assert.Error(t, org.SetSecurityPolicy(&models.SecurityPolicy{PasswordHistoryCount: -1}, setAt))
+	assert.Error(t, org.SetSecurityPolicy(&models.SecurityPolicy{PasswordMaxAgeDays: -1}, setAt))
+	require.NoError(t, org.SetSecurityPolicy(&models.SecurityPolicy{PasswordHistoryCount: 4, PasswordMaxAgeDays: 60}, setAt))
+	policy, err := org.SecurityPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, models.PasswordPolicy{HistoryCount: 4, MaxAgeDays: 60}, policy.PasswordPolicy())
+}
+
+func TestPasswordPolicy_HistoryAndExpiredLogin(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.PasswordHistory{}))
+
+	pwSvc := password.NewService()
+	user := testutils.CreateTestUser(t, testDB.DB, "rotation@example.com")
+	hash, err := pwSvc.Hash("First-Pass-01")
+	require.NoError(t, err)
+	longAgo := time.Now().AddDate(0, 0, -120)
+	require.NoError(t, testDB.DB.Model(user).Updates(map[string]interface{}{
+		"password_hash":       hash,
+		"password_changed_at": longAgo,
+		"email_verified_at":   time.Now(),
+	}).Error)
+
+	// The org is stricter than the platform default on both counts
+	org := testutils.CreateTestOrganization(t, testDB.DB, "Compliance Org", "compliance-org")
+	role := testutils.CreateTestRole(t, testDB.DB, org.ID, "member")
+	require.NoError(t, org.SetSecurityPolicy(&models.SecurityPolicy{PasswordHistoryCount: 3, PasswordMaxAgeDays: 90}, time.Now()))
+	require.NoError(t, testDB.DB.Save(org).Error)
+	require.NoError(t, testDB.DB.Create(&models.OrganizationMembership{
+		OrganizationID: org.ID, UserID: user.ID, RoleID: role.ID, Status: models.MembershipStatusActive,
+	}).Error)
+
+	repo := repository.NewRepository(testDB.DB)
+	userSvc := service.NewUserService(repo, nil, pwSvc)
+	userSvc.SetPasswordPolicy(models.PasswordPolicy{HistoryCount: 1, MaxAgeDays: 365})
+	ctx := context.Background()
+
+	resp, err := userSvc.LoginGlobal(ctx, &service.LoginGlobalRequest{Email: user.Email, Password: "First-Pass-01"})
+	require.NoError(t, err)
+	assert.True(t, resp.PasswordExpired)
+	assert.Empty(t, resp.Organizations, "no orgs until the password is changed")
+	require.NotEmpty(t, resp.PasswordResetToken)
+
+	_, err = userSvc.SelectOrganization(ctx, &service.SelectOrganizationRequest{UserID: user.ID.String(), OrganizationID: org.ID.String()})
+	assert.ErrorIs(t, err, service.ErrPasswordExpired)
+
+	reset := func(token, pw string) error {
+		return userSvc.ResetPassword(ctx, &service.ResetPasswordRequest{Token: token, NewPassword: pw, ConfirmPassword: pw})
+	}
+	assert.ErrorIs(t, reset(resp.PasswordResetToken, "First-Pass-01"), service.ErrPasswordReused, "the current password counts as history")
+	require.NoError(t, reset(resp.PasswordResetToken, "Second-Pass-02"))
+
+	change := func(current, next string) error {
+		return userSvc.ChangePassword(ctx, user.ID.String(), &service.ChangePasswordRequest{
+			CurrentPassword: current, NewPassword: next, ConfirmPassword: next,
+		})
+	}
+	require.NoError(t, change("Second-Pass-02", "Third-Pass-03"))
+	assert.ErrorIs(t, change("Third-Pass-03", "First-Pass-01"), service.ErrPasswordReused, "within the last three")
+	require.NoError(t, change("Third-Pass-03", "Fourth-Pass-04"))
+	require.NoError(t, change("Fourth-Pass-04", "First-Pass-01"), "old enough to reuse")
+
+	resp, err = userSvc.LoginGlobal(ctx, &service.LoginGlobalRequest{Email: user.Email, Password: "First-Pass-01"})
+	require.NoError(t, err)
+	assert.False(t, resp.PasswordExpired)
+	assert.Len(t, resp.Organizations, 1)
+}
@@ -0,0 +1,54 @@
+package unit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/password"
+	"auth-service/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLogin_UpgradesLegacyPasswordHash(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+
+	// Imported accounts may not satisfy today's composition rules
+	const legacyPassword = "legacypass"
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(legacyPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	user := testutils.CreateTestUser(t, testDB.DB, "imported@example.com")
+	changedAt := time.Now().AddDate(0, -1, 0).Truncate(time.Second)
+	require.NoError(t, testDB.DB.Model(user).Updates(map[string]interface{}{
+		"password_hash":       string(bcryptHash),
+		"password_changed_at": changedAt,
+		"email_verified_at":   time.Now(),
+	}).Error)
+
+	repo := repository.NewRepository(testDB.DB)
+	pwSvc := password.NewService()
+	userSvc := service.NewUserService(repo, nil, pwSvc)
+	ctx := context.Background()
+
+	_, err = userSvc.LoginGlobal(ctx, &service.LoginGlobalRequest{Email: user.Email, Password: legacyPassword})
+	require.NoError(t, err)
+
+	stored, err := repo.User().GetByID(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(stored.PasswordHash, "$argon2id$"))
+	assert.False(t, pwSvc.NeedsRehash(stored.PasswordHash))
+	require.NotNil(t, stored.PasswordChangedAt)
+	assert.True(t, stored.PasswordChangedAt.Equal(changedAt), "an upgrade is not a password change")
+
+	// The upgraded hash keeps working, including for the OAuth login form
+	authed, err := userSvc.AuthenticateByEmail(ctx, user.Email, legacyPassword, "")
+	require.NoError(t, err)
+	assert.Equal(t, stored.PasswordHash, authed.PasswordHash)
+}
@@ -0,0 +1,102 @@
+package unit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPushedAuthorizationService(t *testing.T) (service.PushedAuthorizationService, *miniredis.Miniredis) {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	return service.NewPushedAuthorizationService(redisClient), mr
+}
+
+var parClient = &models.ClientApp{
+	ClientID:      "web",
+	RedirectURIs:  []string{"https://app.example.com/callback"},
+	AllowedScopes: []string{"openid", "profile"},
+}
+
+func validPushedRequest() *service.AuthorizationParams {
+	return &service.AuthorizationParams{
+		ClientID:            "spoofed", // Ignored: the authenticated client is recorded
+		ResponseType:        "code",
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               "openid",
+		State:               "xyz",
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func TestPushedAuthorization_GetThenConsumeOnce(t *testing.T) {
+	svc, _ := newPushedAuthorizationService(t)
+	ctx := context.Background()
+
+	resp, err := svc.Push(ctx, parClient, validPushedRequest())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.RequestURI, service.RequestURIPrefix))
+	assert.Equal(t, 90, resp.ExpiresIn)
+
+	_, err = svc.Get(ctx, "other-client", resp.RequestURI)
+	assert.ErrorIs(t, err, service.ErrInvalidRequestURI, "request_uri is bound to the pushing client")
+
+	// Showing the consent page (and reloading it) does not use up the request
+	for i := 0; i < 2; i++ {
+		params, err := svc.Get(ctx, "web", resp.RequestURI)
+		require.NoError(t, err)
+		assert.Equal(t, "web", params.ClientID)
+		assert.Equal(t, "xyz", params.State)
+	}
+
+	_, err = svc.Consume(ctx, "web", resp.RequestURI)
+	require.NoError(t, err)
+
+	_, err = svc.Consume(ctx, "web", resp.RequestURI)
+	assert.ErrorIs(t, err, service.ErrInvalidRequestURI, "request_uri is single use")
+}
+
+func TestPushedAuthorization_ExpiresAndValidates(t *testing.T) {
+	svc, mr := newPushedAuthorizationService(t)
+	ctx := context.Background()
+
+	resp, err := svc.Push(ctx, parClient, validPushedRequest())
+	require.NoError(t, err)
+	mr.FastForward(2 * time.Minute)
+	_, err = svc.Get(ctx, "web", resp.RequestURI)
+	assert.ErrorIs(t, err, service.ErrInvalidRequestURI)
+
+	badRedirect := validPushedRequest()
+	badRedirect.RedirectURI = "https://evil.example.com/callback"
+	_, err = svc.Push(ctx, parClient, badRedirect)
+	assert.Error(t, err)
+
+	badScope := validPushedRequest()
+	badScope.Scope = "openid admin"
+	_, err = svc.Push(ctx, parClient, badScope)
+	assert.Error(t, err)
+
+	noPKCE := validPushedRequest()
+	noPKCE.CodeChallengeMethod = "plain"
+	_, err = svc.Push(ctx, parClient, noPKCE)
+	assert.Error(t, err)
+}
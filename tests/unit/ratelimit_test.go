@@ -2,6 +2,10 @@ package unit_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,7 +13,10 @@ import (
 	"auth-service/internal/middleware"
 
 	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -348,6 +355,9 @@ func TestRateLimiter_GetScopeConfig(t *testing.T) {
 		OAuth2TokenWindow:   30,
 		APICalls:            100,
 		APICallsWindow:      60,
+
+		EmailLogin:       4,
+		EmailLoginWindow: 900,
 	}
 
 	limiter := middleware.NewRateLimiter(nil, cfg)
@@ -363,6 +373,7 @@ func TestRateLimiter_GetScopeConfig(t *testing.T) {
 		{middleware.ScopePasswordReset, 3, 3600 * time.Second, "Password Reset"},
 		{middleware.ScopeTokenRefresh, 10, 60 * time.Second, "Token Refresh"},
 		{middleware.ScopeOAuth2Token, 15, 30 * time.Second, "OAuth2 Token"},
+		{middleware.ScopeEmailLogin, 4, 900 * time.Second, "Email Login"},
 		{middleware.ScopeAPICalls, 100, 60 * time.Second, "API Calls"},
 	}
 
@@ -374,3 +385,84 @@ func TestRateLimiter_GetScopeConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestRateLimiter_ByEmailKeepsBody(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	limiter := middleware.NewRateLimiter(redisClient, &config.RateLimitConfig{
+		Enabled:          true,
+		EmailLogin:       1,
+		EmailLoginWindow: 60,
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/email-login", limiter.ByEmail(middleware.ScopeEmailLogin, "email"), func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, req.Email)
+	})
+
+	send := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/email-login", strings.NewReader(`{"email":"`+email+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("Limit@Example.com")
+	require.Equal(t, http.StatusOK, w.Code, "the handler can still bind the body")
+	assert.Equal(t, "Limit@Example.com", w.Body.String())
+
+	assert.Equal(t, http.StatusTooManyRequests, send(" limit@example.com").Code, "addresses are counted case-insensitively")
+	assert.Equal(t, http.StatusOK, send("other@example.com").Code)
+}
+
+func TestRateLimiter_ByClientIDReadsClientAssertions(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer redisClient.Close()
+
+	limiter := middleware.NewRateLimiter(redisClient, &config.RateLimitConfig{
+		Enabled:                 true,
+		ClientCredentials:       1,
+		ClientCredentialsWindow: 60,
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/token", limiter.ByClientID(middleware.ScopeClientCredentials, "client_credentials"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	send := func(clientID string) int {
+		form := url.Values{
+			"grant_type":            {"client_credentials"},
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {signClientAssertion(t, gojwt.SigningMethodHS256, []byte("client-secret"), clientID, uuid.NewString())},
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("partner-sync"))
+	assert.Equal(t, http.StatusTooManyRequests, send("partner-sync"), "clients without client_id are limited by the assertion subject")
+	assert.Equal(t, http.StatusOK, send("other-client"))
+}
@@ -0,0 +1,149 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeSessionTokens_DeniesOnlyThatSession(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+	// Tracking and revoking session tokens only touches Redis
+	revocation := service.NewRevocationService(nil, jwtService, redisClient)
+	ctx := context.Background()
+
+	issue := func(sessionID uuid.UUID) string {
+		token, err := jwtService.GenerateAccessToken(&jwt.TokenContext{
+			UserID:         uuid.New(),
+			OrganizationID: uuid.New(),
+			SessionID:      sessionID,
+			Email:          "user@example.com",
+		})
+		require.NoError(t, err)
+		require.NoError(t, revocation.TrackSessionToken(ctx, token))
+		return token
+	}
+
+	revokedSession, keptSession := uuid.New(), uuid.New()
+	first, second := issue(revokedSession), issue(revokedSession)
+	other := issue(keptSession)
+
+	require.NoError(t, revocation.RevokeSessionTokens(ctx, revokedSession))
+
+	for _, token := range []string{first, second} {
+		revoked, err := revocation.IsTokenRevoked(ctx, token)
+		require.NoError(t, err)
+		assert.True(t, revoked, "every access token of the revoked session is denied")
+	}
+	revoked, err := revocation.IsTokenRevoked(ctx, other)
+	require.NoError(t, err)
+	assert.False(t, revoked, "other sessions keep working")
+
+	// Denylist entries expire with the tokens they deny
+	assert.Positive(t, mr.TTL("revoked:token:"+mustJTI(t, jwtService, first)))
+}
+
+func mustJTI(t *testing.T, jwtService *jwt.Service, token string) string {
+	t.Helper()
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+	return claims.ID
+}
+
+type stubDeviceSessions struct {
+	repository.UserSessionRepository
+	sessions map[string]*models.UserSession
+}
+
+func (r *stubDeviceSessions) GetActiveByDeviceID(_ context.Context, deviceID string) ([]*models.UserSession, error) {
+	var active []*models.UserSession
+	for _, session := range r.sessions {
+		if session.IsActive && session.DeviceID != nil && session.DeviceID.String() == deviceID {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+func (r *stubDeviceSessions) GetByID(_ context.Context, id string) (*models.UserSession, error) {
+	return r.sessions[id], nil
+}
+
+func (r *stubDeviceSessions) Revoke(_ context.Context, id, reason string) error {
+	r.sessions[id].IsActive = false
+	r.sessions[id].RevokedReason = reason
+	return nil
+}
+
+type stubSessionRefreshTokens struct {
+	repository.RefreshTokenRepository
+}
+
+func (stubSessionRefreshTokens) RevokeBySession(context.Context, string, string) error { return nil }
+
+type stubSessionRepo struct {
+	repository.Repository
+	sessions *stubDeviceSessions
+}
+
+func (r *stubSessionRepo) UserSession() repository.UserSessionRepository { return r.sessions }
+func (r *stubSessionRepo) RefreshToken() repository.RefreshTokenRepository {
+	return stubSessionRefreshTokens{}
+}
+
+func TestRevokeDeviceSessions_DeniesAccessTokens(t *testing.T) {
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+	revocation := service.NewRevocationService(nil, jwtService, newConsentRedis(t))
+	ctx := context.Background()
+
+	deviceID, otherDeviceID := uuid.New(), uuid.New()
+	sessions := &stubDeviceSessions{sessions: map[string]*models.UserSession{}}
+	tokens := map[uuid.UUID]string{}
+	for _, device := range []uuid.UUID{deviceID, deviceID, otherDeviceID} {
+		device := device
+		session := &models.UserSession{ID: uuid.New(), DeviceID: &device, IsActive: true}
+		sessions.sessions[session.ID.String()] = session
+
+		token, err := jwtService.GenerateAccessToken(&jwt.TokenContext{
+			UserID:         uuid.New(),
+			OrganizationID: uuid.New(),
+			SessionID:      session.ID,
+			Email:          "user@example.com",
+		})
+		require.NoError(t, err)
+		require.NoError(t, revocation.TrackSessionToken(ctx, token))
+		tokens[session.ID] = token
+	}
+
+	sessionSvc := service.NewSessionService(&stubSessionRepo{sessions: sessions}, &service.SessionConfig{SessionTimeout: time.Hour})
+	sessionSvc.SetRevocationService(revocation)
+	require.NoError(t, sessionSvc.RevokeDeviceSessions(ctx, deviceID.String(), "device_revoked"))
+
+	for sessionID, token := range tokens {
+		revoked, err := revocation.IsTokenRevoked(ctx, token)
+		require.NoError(t, err)
+		onDevice := *sessions.sessions[sessionID.String()].DeviceID == deviceID
+		assert.Equal(t, onDevice, revoked, "only access tokens of the device's sessions are denied")
+		assert.Equal(t, !onDevice, sessions.sessions[sessionID.String()].IsActive)
+	}
+}
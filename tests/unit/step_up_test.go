@@ -0,0 +1,89 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"auth-service/internal/middleware"
+	"auth-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stepUpRouter serves one route behind RequireRecentAuth, with the token's
+// acr and auth_time injected the way AuthRequired would set them
+func stepUpRouter(acr string, authTime *time.Time, requiredACR string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(nil, nil, nil)
+	router.DELETE("/sensitive", func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), "acr", acr)
+		if authTime != nil {
+			ctx = context.WithValue(ctx, "auth_time", *authTime)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}, authMiddleware.RequireRecentAuth(5*time.Minute, requiredACR), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestRequireRecentAuth(t *testing.T) {
+	recent := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		acr         string
+		authTime    *time.Time
+		requiredACR string
+		wantStatus  int
+	}{
+		{"recent single factor", jwt.ACRSingleFactor, &recent, jwt.ACRSingleFactor, http.StatusNoContent},
+		{"multi factor meets single factor", jwt.ACRMultiFactor, &recent, jwt.ACRSingleFactor, http.StatusNoContent},
+		{"stale sign-in", jwt.ACRMultiFactor, &stale, jwt.ACRSingleFactor, http.StatusUnauthorized},
+		{"no auth_time (API key or legacy token)", "", nil, "", http.StatusUnauthorized},
+		{"too weak", jwt.ACRSingleFactor, &recent, jwt.ACRMultiFactor, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			stepUpRouter(tt.acr, tt.authTime, tt.requiredACR).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/sensitive", nil))
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRequireRecentAuth_Challenge(t *testing.T) {
+	stale := time.Now().Add(-time.Hour)
+	w := httptest.NewRecorder()
+	stepUpRouter(jwt.ACRSingleFactor, &stale, jwt.ACRMultiFactor).ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/sensitive", nil))
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	header := w.Header().Get("WWW-Authenticate")
+	assert.Contains(t, header, `error="insufficient_user_authentication"`)
+	assert.Contains(t, header, "max_age=300")
+	assert.Contains(t, header, `acr_values="`+jwt.ACRMultiFactor+`"`)
+
+	var body struct {
+		Error  string `json:"error"`
+		StepUp struct {
+			MaxAge         int    `json:"max_age"`
+			ACRValues      string `json:"acr_values"`
+			Reauthenticate string `json:"reauthenticate"`
+		} `json:"step_up"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "insufficient_user_authentication", body.Error)
+	assert.Equal(t, 300, body.StepUp.MaxAge)
+	assert.Equal(t, jwt.ACRMultiFactor, body.StepUp.ACRValues)
+	assert.Equal(t, middleware.ReauthenticatePath, body.StepUp.Reauthenticate)
+}
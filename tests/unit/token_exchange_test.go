@@ -0,0 +1,267 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"auth-service/pkg/jwt"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tokenExchangeFixture struct {
+	svc        service.TokenExchangeService
+	jwtService *jwt.Service
+	revocation service.RevocationService
+	gateway    *models.ClientApp
+	userID     uuid.UUID
+}
+
+func newTokenExchangeFixture(t *testing.T) *tokenExchangeFixture {
+	t.Helper()
+
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	t.Cleanup(mr.Close)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { redisClient.Close() })
+
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+
+	// Exchanges below target the calling client itself, so no repository lookups happen
+	revocation := service.NewRevocationService(nil, jwtService, redisClient)
+	return &tokenExchangeFixture{
+		svc:        service.NewTokenExchangeService(nil, jwtService, revocation),
+		jwtService: jwtService,
+		revocation: revocation,
+		gateway: &models.ClientApp{
+			ID:             uuid.New(),
+			ClientID:       "api-gateway",
+			OrganizationID: uuid.New(),
+			IsConfidential: true,
+			GrantTypes:     pq.StringArray{service.GrantTypeTokenExchange},
+		},
+		userID: uuid.New(),
+	}
+}
+
+func (f *tokenExchangeFixture) issue(t *testing.T, subject, clientID string, mayAct *jwt.Actor) string {
+	t.Helper()
+	orgID := f.gateway.OrganizationID
+	token, err := f.jwtService.GenerateOAuthAccessToken(&jwt.OAuthTokenContext{
+		UserID:         f.userID,
+		Email:          "user@example.com",
+		OrganizationID: &orgID,
+		Permissions:    []string{"orders:read", "orders:write"},
+		Scopes:         []string{"openid"},
+		Issuer:         "https://auth.myservice.com/" + clientID,
+		Audience:       clientID,
+		Subject:        subject,
+		ClientID:       clientID,
+		MayAct:         mayAct,
+		ExpiresAt:      time.Now().Add(10 * time.Minute),
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func TestTokenExchange_NarrowsScopeAndLifetime(t *testing.T) {
+	f := newTokenExchangeFixture(t)
+	ctx := context.Background()
+	subjectToken := f.issue(t, f.userID.String(), "api-gateway", nil)
+
+	result, err := f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+		Scope:            "orders:read",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, jwt.TokenTypeAccessToken, result.Response.IssuedTokenType)
+	assert.Equal(t, "orders:read", result.Response.Scope)
+	assert.LessOrEqual(t, result.Response.ExpiresIn, 600, "must not outlive the subject token")
+	require.NotNil(t, result.UserID)
+	assert.Equal(t, f.userID, *result.UserID)
+
+	claims, err := f.jwtService.ParseAccessToken(result.Response.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, f.userID.String(), claims.Subject)
+	assert.Equal(t, "api-gateway", claims.ClientID)
+	assert.Nil(t, claims.Act, "impersonation adds no actor")
+	assert.False(t, claims.IsSuperadmin)
+	assert.Empty(t, claims.Permissions, "scopes must not turn into RBAC permissions")
+	assert.Empty(t, claims.Roles)
+	assert.Equal(t, "orders:read", claims.Scope)
+
+	_, err = f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+		Scope:            "orders:read admin",
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidExchangeScope)
+
+	_, err = f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:       subjectToken,
+		SubjectTokenType:   jwt.TokenTypeAccessToken,
+		RequestedTokenType: "urn:ietf:params:oauth:token-type:refresh_token",
+	})
+	assert.ErrorIs(t, err, service.ErrUnsupportedTokenType)
+
+	otherOrg := *f.gateway
+	otherOrg.OrganizationID = uuid.New()
+	_, err = f.svc.Exchange(ctx, &otherOrg, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidSubjectToken)
+
+	require.NoError(t, f.revocation.RevokeToken(ctx, subjectToken))
+	_, err = f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidSubjectToken, "revoked tokens cannot be exchanged")
+}
+
+func TestTokenExchange_DelegationChainAndMayAct(t *testing.T) {
+	f := newTokenExchangeFixture(t)
+	ctx := context.Background()
+
+	subjectToken := f.issue(t, f.userID.String(), "api-gateway", &jwt.Actor{ClientID: "billing"})
+
+	_, err := f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+		ActorToken:       f.issue(t, "reporting", "reporting", nil),
+		ActorTokenType:   jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrActorNotPermitted, "may_act names billing only")
+
+	first, err := f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+		ActorToken:       f.issue(t, "billing", "billing", nil),
+		ActorTokenType:   jwt.TokenTypeJWT,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, first.Act)
+	assert.Equal(t, "billing", first.Act.Subject)
+
+	// A second hop puts the new actor in front of the previous one
+	second, err := f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     first.Response.AccessToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+		ActorToken:       f.issue(t, "ledger", "ledger", nil),
+		ActorTokenType:   jwt.TokenTypeAccessToken,
+	})
+	require.NoError(t, err)
+
+	claims, err := f.jwtService.ParseAccessToken(second.Response.AccessToken)
+	require.NoError(t, err)
+	require.NotNil(t, claims.Act)
+	assert.Equal(t, "ledger", claims.Act.Subject)
+	require.NotNil(t, claims.Act.Act)
+	assert.Equal(t, "billing", claims.Act.Act.Subject)
+	assert.Equal(t, 2, claims.Act.Depth())
+	assert.Equal(t, f.userID.String(), claims.Subject)
+}
+
+func TestTokenExchange_RejectsForeignAndFirstPartyTokens(t *testing.T) {
+	f := newTokenExchangeFixture(t)
+	ctx := context.Background()
+
+	// A token another client holds for the user
+	_, err := f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     f.issue(t, f.userID.String(), "web", nil),
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidSubjectToken)
+
+	// The user's own session token
+	session, err := f.jwtService.GenerateAccessToken(&jwt.TokenContext{
+		UserID:         f.userID,
+		OrganizationID: f.gateway.OrganizationID,
+		SessionID:      uuid.New(),
+		Email:          "user@example.com",
+		Permissions:    []string{"orders:read"},
+	})
+	require.NoError(t, err)
+	_, err = f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+		SubjectToken:     session,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidSubjectToken)
+}
+
+func TestTokenExchange_RequiresRegisteredGrantType(t *testing.T) {
+	f := newTokenExchangeFixture(t)
+	subjectToken := f.issue(t, f.userID.String(), "api-gateway", nil)
+
+	// No grant types means any grant elsewhere, but not token exchange
+	unrestricted := *f.gateway
+	unrestricted.GrantTypes = nil
+	_, err := f.svc.Exchange(context.Background(), &unrestricted, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrUnauthorizedClient)
+
+	codeOnly := *f.gateway
+	codeOnly.GrantTypes = pq.StringArray{"authorization_code"}
+	_, err = f.svc.Exchange(context.Background(), &codeOnly, &service.TokenExchangeRequest{
+		SubjectToken:     subjectToken,
+		SubjectTokenType: jwt.TokenTypeAccessToken,
+	})
+	assert.ErrorIs(t, err, service.ErrUnauthorizedClient)
+}
+
+func TestTokenExchange_ActorTokenChecks(t *testing.T) {
+	f := newTokenExchangeFixture(t)
+	ctx := context.Background()
+	subjectToken := f.issue(t, f.userID.String(), "api-gateway", nil)
+
+	issueActor := func(orgID uuid.UUID, dpopJKT string) string {
+		token, err := f.jwtService.GenerateOAuthAccessToken(&jwt.OAuthTokenContext{
+			UserID:         uuid.New(),
+			OrganizationID: &orgID,
+			Scopes:         []string{"openid"},
+			Issuer:         "https://auth.myservice.com/billing",
+			Audience:       "billing",
+			Subject:        "billing",
+			ClientID:       "billing",
+			DPoPJKT:        dpopJKT,
+			ExpiresAt:      time.Now().Add(10 * time.Minute),
+		})
+		require.NoError(t, err)
+		return token
+	}
+	exchange := func(actorToken, dpopJKT string) error {
+		_, err := f.svc.Exchange(ctx, f.gateway, &service.TokenExchangeRequest{
+			SubjectToken:     subjectToken,
+			SubjectTokenType: jwt.TokenTypeAccessToken,
+			ActorToken:       actorToken,
+			ActorTokenType:   jwt.TokenTypeAccessToken,
+			DPoPJKT:          dpopJKT,
+		})
+		return err
+	}
+
+	// A DPoP-bound actor token needs a proof for its key
+	bound := issueActor(f.gateway.OrganizationID, "actor-key-thumbprint")
+	assert.ErrorIs(t, exchange(bound, ""), service.ErrInvalidActorToken)
+	assert.ErrorIs(t, exchange(bound, "other-key-thumbprint"), service.ErrInvalidActorToken)
+	assert.NoError(t, exchange(bound, "actor-key-thumbprint"))
+
+	// An actor from another organization cannot act for our users
+	assert.ErrorIs(t, exchange(issueActor(uuid.New(), ""), ""), service.ErrInvalidActorToken)
+	assert.NoError(t, exchange(issueActor(f.gateway.OrganizationID, ""), ""))
+}
@@ -0,0 +1,179 @@
+package unit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/handler"
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"auth-service/pkg/hashutil"
+	"auth-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubOAuthAudit struct {
+	service.AuditService
+	outcomes []bool
+}
+
+func (a *stubOAuthAudit) LogOAuth(_ context.Context, _ string, _ *uuid.UUID, _ *uuid.UUID, success bool, _ map[string]interface{}, _ error) {
+	a.outcomes = append(a.outcomes, success)
+}
+
+type revocationFixture struct {
+	router        *gin.Engine
+	jwtService    *jwt.Service
+	revocation    service.RevocationService
+	refreshTokens *stubOAuthRefreshTokens
+	audit         *stubOAuthAudit
+}
+
+// newRevocationFixture serves /oauth/revoke and /oauth/logout for the clients
+// "orders-app" and "billing-app". The only refresh token on record belongs to orders-app.
+func newRevocationFixture(t *testing.T) *revocationFixture {
+	t.Helper()
+	hashutil.SetHMACSecret("test-hmac-secret")
+
+	jwtService, err := jwt.NewService(&config.JWTConfig{Issuer: "test", AccessTokenTTL: 15, RefreshTokenTTL: 7})
+	require.NoError(t, err)
+
+	refreshTokens := &stubOAuthRefreshTokens{
+		raw:   "orders-refresh-token",
+		token: &models.OAuthRefreshToken{ID: uuid.New(), ClientID: "orders-app", FamilyID: uuid.New()},
+	}
+	repo := &stubOAuthRepo{refreshTokens: refreshTokens}
+	revocation := service.NewRevocationService(repo, jwtService, newConsentRedis(t))
+	clientApps := &stubClientApps{clients: map[string]*models.ClientApp{
+		"orders-app":  {ID: uuid.New(), ClientID: "orders-app", IsConfidential: true},
+		"billing-app": {ID: uuid.New(), ClientID: "billing-app", IsConfidential: true},
+	}}
+	audit := &stubOAuthAudit{}
+
+	h := handler.NewOAuth2Handler(
+		service.NewOAuth2Service(repo, jwtService), clientApps, nil, audit,
+		service.NewIntrospectionService(repo, jwtService, revocation), revocation,
+		nil, nil, nil, nil, nil, nil,
+	)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/oauth/revoke", h.Revoke)
+	router.POST("/oauth/logout", h.Logout)
+
+	return &revocationFixture{
+		router:        router,
+		jwtService:    jwtService,
+		revocation:    revocation,
+		refreshTokens: refreshTokens,
+		audit:         audit,
+	}
+}
+
+func (f *revocationFixture) revoke(clientID, token, hint string) *httptest.ResponseRecorder {
+	form := url.Values{"token": {token}, "client_id": {clientID}, "client_secret": {"secret"}}
+	if hint != "" {
+		form.Set("token_type_hint", hint)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/revoke", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRevoke_RefreshTokenRevokesFamily(t *testing.T) {
+	f := newRevocationFixture(t)
+
+	// The hint is only advisory: an opaque token is looked up as a refresh token either way
+	w := f.revoke("orders-app", "orders-refresh-token", service.TokenTypeHintAccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, []uuid.UUID{f.refreshTokens.token.FamilyID}, f.refreshTokens.revokedFamilies)
+
+	// Unknown tokens are not an error (RFC 7009 section 2.2)
+	w = f.revoke("orders-app", "never-issued", service.TokenTypeHintRefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, f.refreshTokens.revokedFamilies, 1)
+}
+
+func TestRevoke_AccessTokenIsDenied(t *testing.T) {
+	f := newRevocationFixture(t)
+	ctx := context.Background()
+	token := issueClientAccessToken(t, f.jwtService, "orders-app", "orders-api")
+
+	w := f.revoke("orders-app", token, service.TokenTypeHintAccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	revoked, err := f.revocation.IsTokenRevoked(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	assert.Empty(t, f.refreshTokens.revokedFamilies, "revoking an access token leaves refresh tokens alone")
+
+	// Revoking it again is still a success
+	assert.Equal(t, http.StatusOK, f.revoke("orders-app", token, "").Code)
+}
+
+func TestRevoke_OtherClientsTokensAreNoOps(t *testing.T) {
+	f := newRevocationFixture(t)
+	ctx := context.Background()
+	token := issueClientAccessToken(t, f.jwtService, "orders-app", "orders-api")
+
+	w := f.revoke("billing-app", token, service.TokenTypeHintAccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	revoked, err := f.revocation.IsTokenRevoked(ctx, token)
+	require.NoError(t, err)
+	assert.False(t, revoked, "another client's access token stays valid")
+
+	w = f.revoke("billing-app", "orders-refresh-token", service.TokenTypeHintRefreshToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, f.refreshTokens.revokedFamilies, "another client's refresh token stays valid")
+
+	// To billing-app the access token looks unknown; the refresh token attempt is audited as a failure
+	assert.Equal(t, []bool{true, false}, f.audit.outcomes)
+}
+
+func TestRevoke_StorageErrorIsNotReportedAsSuccess(t *testing.T) {
+	f := newRevocationFixture(t)
+	f.refreshTokens.err = errors.New("connection refused")
+
+	w := f.revoke("orders-app", "orders-refresh-token", service.TokenTypeHintRefreshToken)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "temporarily_unavailable")
+}
+
+func TestOAuthLogout(t *testing.T) {
+	f := newRevocationFixture(t)
+
+	logout := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/logout", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		f.router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, logout(`{}`).Code)
+
+	w := logout(`{"refresh_token":"orders-refresh-token"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uuid.UUID{f.refreshTokens.token.FamilyID}, f.refreshTokens.revokedFamilies)
+
+	// Logging out with a token that is already gone still succeeds
+	assert.Equal(t, http.StatusOK, logout(`{"refresh_token":"never-issued"}`).Code)
+
+	f.refreshTokens.err = errors.New("connection refused")
+	assert.Equal(t, http.StatusInternalServerError, logout(`{"refresh_token":"orders-refresh-token"}`).Code)
+}
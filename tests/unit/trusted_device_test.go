@@ -0,0 +1,84 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"auth-service/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chromeOnMac = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+
+func TestTrustedDevices(t *testing.T) {
+	testDB := testutils.SetupTestDB(t)
+	require.NoError(t, testDB.DB.AutoMigrate(&models.TrustedDevice{}, &models.UserSession{}))
+	newConsentRedis(t) // sets the HMAC secret
+
+	user := testutils.CreateTestUser(t, testDB.DB, "devices@example.com")
+	other := testutils.CreateTestUser(t, testDB.DB, "someone-else@example.com")
+	org := testutils.CreateTestOrganization(t, testDB.DB, "Device Org", "device-org")
+
+	repo := repository.NewRepository(testDB.DB)
+	sessionSvc := service.NewSessionService(repo, &service.SessionConfig{SessionTimeout: time.Hour})
+	svc := service.NewTrustedDeviceService(repo, sessionSvc, 30)
+	ctx := context.Background()
+
+	device, token, err := svc.Recognize(ctx, user.ID, "", "203.0.113.7", chromeOnMac)
+	require.NoError(t, err)
+	require.NotEmpty(t, token, "a new device gets a cookie")
+	assert.Equal(t, "Chrome on macOS", device.Name)
+	assert.False(t, device.IsTrusted(time.Now()))
+
+	again, newToken, err := svc.Recognize(ctx, user.ID, token, "198.51.100.2", chromeOnMac)
+	require.NoError(t, err)
+	assert.Empty(t, newToken, "a known device keeps its cookie")
+	assert.Equal(t, device.ID, again.ID)
+	assert.Equal(t, "198.51.100.2", again.LastIP)
+
+	// Someone else signing in on the same browser gets their own device
+	theirs, theirToken, err := svc.Recognize(ctx, other.ID, token, "198.51.100.2", chromeOnMac)
+	require.NoError(t, err)
+	assert.NotEmpty(t, theirToken)
+	assert.NotEqual(t, device.ID, theirs.ID)
+
+	require.NoError(t, svc.Trust(ctx, device))
+	found, err := svc.Lookup(ctx, user.ID, token)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.True(t, found.IsTrusted(time.Now()))
+	assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), *found.TrustedUntil, time.Minute)
+
+	devices, err := svc.List(ctx, user.ID, token)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.True(t, devices[0].Current)
+
+	session, err := sessionSvc.CreateSession(ctx, user.ID.String(), org.ID.String(), "203.0.113.7", chromeOnMac, &device.ID)
+	require.NoError(t, err)
+	unrelated, err := sessionSvc.CreateSession(ctx, user.ID.String(), org.ID.String(), "203.0.113.7", chromeOnMac, nil)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.Revoke(ctx, other.ID, device.ID), service.ErrDeviceNotFound, "only the owner can revoke a device")
+	require.NoError(t, svc.Revoke(ctx, user.ID, device.ID))
+	assert.ErrorIs(t, svc.Revoke(ctx, user.ID, uuid.New()), service.ErrDeviceNotFound)
+
+	revoked, err := repo.UserSession().GetByID(ctx, session.ID.String())
+	require.NoError(t, err)
+	assert.False(t, revoked.IsActive, "sessions from the device are signed out")
+	assert.Equal(t, "device_revoked", revoked.RevokedReason)
+	kept, err := repo.UserSession().GetByID(ctx, unrelated.ID.String())
+	require.NoError(t, err)
+	assert.True(t, kept.IsActive)
+
+	found, err = svc.Lookup(ctx, user.ID, token)
+	require.NoError(t, err)
+	assert.Nil(t, found, "a revoked device's cookie is no longer recognised")
+}